@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"os"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/audit"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/config"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db/migrations"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+	"github.com/jackc/pgx/v5"
+)
+
+// runBootstrapAdmin runs the bootstrap-admin subcommand,
+// "auth-service bootstrap-admin --admin-email <email>". It grants the admin
+// role to an existing account, which is the only way to get the first admin:
+// GrantRole itself requires roles:manage.
+func runBootstrapAdmin(args []string) {
+	cfg := config.LoadBootstrapConfig(args)
+	if err := bootstrapAdmin(context.Background(), cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("granted %s to %s\n", model.RoleAdmin, cfg.AdminEmail)
+}
+
+func bootstrapAdmin(ctx context.Context, cfg *config.BootstrapConfig) error {
+	if err := migrate.Prepare(ctx, cfg.DatabaseURL, migrations.FS, false); err != nil {
+		return fmt.Errorf("database schema is not ready: %w", err)
+	}
+	if err := db.Connect(cfg.DatabaseURL); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	repo := repository.NewRepository(db.Pool)
+	account, err := repo.GetAccountByEmail(ctx, cfg.AdminEmail)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("no account with email %s; register it first", cfg.AdminEmail)
+	}
+	if err != nil {
+		return err
+	}
+	if account.DeletedAt != nil {
+		return fmt.Errorf("account %s is scheduled for deletion", cfg.AdminEmail)
+	}
+
+	if err := repo.GrantRole(ctx, &model.GrantRoleInput{AccountID: account.ID, Role: model.RoleAdmin}); err != nil {
+		return err
+	}
+	audit.NewRecorder(repo, nil).Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventRoleGranted,
+		AccountID: &account.ID,
+		Email:     account.Email,
+		Outcome:   model.AuditOutcomeSuccess,
+		Reason:    "role=" + model.RoleAdmin + ",source=bootstrap",
+	})
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -10,38 +11,74 @@ import (
 	"time"
 
 	authpb "github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/api/auth/v1"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/audit"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/config"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db/migrations"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/handler"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/job"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/service"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/auth"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/hasher"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/gateway"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/health"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/ratelimit"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
 	// Init logger
 	logger.InitLogger(true)
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "bootstrap-admin":
+			runBootstrapAdmin(os.Args[2:])
+			return
+		}
+	}
+
 	// Load config
 	cfg := config.LoadConfig()
 
+	// Tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "auth-service", cfg.Tracing)
+	if err != nil {
+		logger.Log.Fatal("failed to init tracing", zap.Error(err))
+	}
+
+	// Schema
+	if err := migrate.Prepare(context.Background(), cfg.DatabaseURL, migrations.FS, cfg.AutoMigrate); err != nil {
+		logger.Log.Fatal("database schema is not ready", zap.Error(err))
+	}
+
 	// Connect DB
 	if err := db.Connect(cfg.DatabaseURL); err != nil {
 		logger.Log.Fatal("failed to connect database", zap.Error(err))
 	}
 	defer db.Close()
+	if err := metrics.RegisterPgxPool(db.Pool, "auth"); err != nil {
+		logger.Log.Fatal("failed to register pool metrics", zap.Error(err))
+	}
 
 	// Init RabbitMQ
-	publisher, err := mq.NewRabbitPublisher(cfg.RAbbitMQURL)
+	publisher, err := mq.NewRabbitPublisher(cfg.RAbbitMQURL, cfg.RabbitMQConnectRetries, cfg.RabbitMQRetryDelay)
 	if err != nil {
 		logger.Log.Fatal("failed to create RabbitMQ publisher", zap.Error(err))
 	}
@@ -49,80 +86,170 @@ func main() {
 
 	// Repo, service, handler
 	repo := repository.NewRepository(db.Pool)
-	svc := service.NewService(repo, auth.NewTokenMaker(cfg.JWTSecret), hasher.NewHasher())
-	h := handler.NewAuthServiceServer(svc, publisher)
+	svc := service.NewService(repo, auth.NewTokenMaker(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL, cfg.ServiceTokenTTL), hasher.NewHasher(), cfg.AccountDeletionGrace)
+	var auditPublisher audit.Publisher
+	if cfg.AuditExchange != "" {
+		if err := publisher.EnableAuditStream(cfg.AuditExchange); err != nil {
+			logger.Log.Fatal("failed to enable audit stream", zap.Error(err))
+		}
+		auditPublisher = publisher
+	}
+	recorder := audit.NewRecorder(repo, auditPublisher)
+	h := handler.NewAuthServiceServer(svc, publisher, recorder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// RabbitMQ consumers
+	consumer, err := mq.NewRabbitConsumer(cfg.RAbbitMQURL, cfg.RabbitMQConnectRetries, cfg.RabbitMQRetryDelay)
+	if err != nil {
+		logger.Log.Fatal("failed to create RabbitMQ consumer", zap.Error(err))
+	}
+	defer consumer.Close()
+
+	contributor := job.NewDataExportContributor(svc, publisher, cfg.ExportPartMaxBytes)
+	if err := consumer.ConsumeExportRequested(ctx, contributor.Handle); err != nil {
+		logger.Log.Fatal("failed to consume data export requests", zap.Error(err))
+	}
+
+	// Health checks
+	checks := health.NewRegistry(cfg.HealthCheckTimeout)
+	checks.Register("postgres", health.PingChecker(db.Pool))
+	checks.Register("rabbitmq", publisher)
+	checks.Register("rabbitmq_consumer", consumer)
+
+	// Rate limits, keyed by gRPC method for direct clients and by path for the HTTP gateway
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Rules{
+		authpb.AuthService_Register_FullMethodName:       cfg.RateLimitRegister,
+		authpb.AuthService_Login_FullMethodName:          cfg.RateLimitLogin,
+		authpb.AuthService_RestoreAccount_FullMethodName: cfg.RateLimitLogin,
+		authpb.AuthService_ChangePassword_FullMethodName: cfg.RateLimitLogin,
+		"/api/v1/auth/register":                          cfg.RateLimitRegister,
+		"/api/v1/auth/login":                             cfg.RateLimitLogin,
+		"/api/v1/auth/account/restore":                   cfg.RateLimitLogin,
+		"/api/v1/auth/account/password":                  cfg.RateLimitLogin,
+		ratelimit.DefaultRoute:                           cfg.RateLimitDefault,
+	})
+	// Calls from the HTTP gateway are limited by the Gin middleware instead.
+	gatewayKey := ratelimit.NewGatewayKey()
 
 	// gRPC server
-	lis, err := net.Listen("tcp", ":50051")
+	grpcAddr := fmt.Sprintf(":%d", cfg.GRPCPort)
+	lis, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
-		logger.Log.Fatal("failed to listen on port 50051", zap.Error(err))
+		logger.Log.Fatal("failed to listen for gRPC", zap.String("addr", grpcAddr), zap.Error(err))
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		tracing.GRPCServerOption(),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			logger.UnaryServerInterceptor(),
+			ratelimit.UnaryServerInterceptor(limiter, gatewayKey.FromGateway),
+			authz.UnaryServerInterceptor(cfg.JWTSecret, handler.MethodPermissions),
+		),
+	)
 	authpb.RegisterAuthServiceServer(grpcServer, h)
+	grpc_health_v1.RegisterHealthServer(grpcServer, checks.GRPCServer())
 
 	// run gRPC server
 	go func() {
-		logger.Log.Info("🚀 gRPC server started on :50051")
+		logger.Log.Info("🚀 gRPC server started", zap.String("addr", grpcAddr))
 		if err := grpcServer.Serve(lis); err != nil {
 			logger.Log.Fatal("failed to serve gRPC", zap.Error(err))
 		}
 	}()
 
-	// gRPC gateway (mux)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Background jobs
+	purger := job.NewAccountPurger(repo, publisher, recorder, cfg.AccountPurgeInterval)
+	go purger.Run(ctx)
+	go checks.Run(ctx, cfg.HealthCheckInterval)
 
-	gwMux := runtime.NewServeMux()
+	gwMux := runtime.NewServeMux(
+		runtime.WithErrorHandler(gateway.ErrorHandler),
+		runtime.WithMiddlewares(metrics.GatewayMiddleware()),
+	)
 	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		tracing.GRPCDialOption(),
+		grpc.WithChainUnaryInterceptor(logger.UnaryClientInterceptor()),
+		gatewayKey.DialOption(),
 	}
 
-	err = authpb.RegisterAuthServiceHandlerFromEndpoint(ctx, gwMux, "localhost:50051", dialOpts)
+	err = authpb.RegisterAuthServiceHandlerFromEndpoint(ctx, gwMux, fmt.Sprintf("localhost:%d", cfg.GRPCPort), dialOpts)
 	if err != nil {
 		logger.Log.Fatal("failed to register grpc-gateway handler", zap.Error(err))
 	}
 
 	// GIN
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Log.Fatal("invalid trusted proxies", zap.Error(err))
+	}
+	r.Use(tracing.GinMiddleware("auth-service"), logger.GinMiddleware(), metrics.GinMiddleware())
 
-	// Health check
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Liveness and readiness
+	r.GET("/livez", checks.LivezHandler())
+	r.GET("/readyz", checks.ReadyzHandler())
+	r.GET("/health", checks.ReadyzHandler())
 
 	// Mount grpc-gateway under /api/v1/auth/*
-	r.Any("/api/v1/auth/*any", gin.WrapH(gwMux))
+	r.Any("/api/v1/auth/*any", ratelimit.GinMiddleware(limiter), gin.WrapH(gwMux))
 
 	// Run Gin server
 	httpServer := &http.Server{
-		Addr:         ":8080",
+		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
 		Handler:      r,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
 	}
 
 	go func() {
-		logger.Log.Info("🚀 Gin HTTP gateway started on :8080, prefix /api/v1/auth")
+		logger.Log.Info("🚀 Gin HTTP gateway started, prefix /api/v1/auth", zap.String("addr", httpServer.Addr))
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Log.Fatal("Gin HTTP server error", zap.Error(err))
 		}
 	}()
 
+	// Metrics get their own listener, kept off the public port.
+	metricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:      metrics.Handler(),
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	go func() {
+		logger.Log.Info("🚀 metrics server started", zap.String("addr", metricsServer.Addr))
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Fatal("metrics server error", zap.Error(err))
+		}
+	}()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Log.Info("🔥 Shutting down servers...")
+	checks.Shutdown()
+	// Keep serving until load balancers have seen the service is not ready.
+	time.Sleep(cfg.ShutdownDrainDelay)
 
 	grpcServer.GracefulStop()
 
-	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancelShutdown()
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Log.Error("Gin HTTP shutdown error", zap.Error(err))
 	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("metrics server shutdown error", zap.Error(err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Log.Error("tracing shutdown error", zap.Error(err))
+	}
 
 	logger.Log.Info("✅ Servers stopped cleanly")
 }
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/config"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db/migrations"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+)
+
+// runMigrate runs the migrate subcommand, "auth-service migrate [flags] <command>".
+func runMigrate(args []string) {
+	cfg, args := config.LoadMigrateConfig(args)
+	if err := migrate.Command(context.Background(), cfg.DatabaseURL, migrations.FS, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
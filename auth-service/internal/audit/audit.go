@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Publisher streams recorded events to a message broker.
+type Publisher interface {
+	PublishAuditEvent(ctx context.Context, event mq.AuditEvent) error
+}
+
+// Recorder appends security events to the audit log. Recording never fails the
+// request that triggered it: errors are logged and swallowed.
+type Recorder interface {
+	Record(ctx context.Context, event *model.AuditEvent)
+}
+
+type recorder struct {
+	repo      repository.Repository
+	publisher Publisher
+}
+
+// NewRecorder returns a Recorder backed by the repository. publisher may be nil
+// to disable streaming.
+func NewRecorder(repo repository.Repository, publisher Publisher) Recorder {
+	return &recorder{
+		repo:      repo,
+		publisher: publisher,
+	}
+}
+
+func (r *recorder) Record(ctx context.Context, event *model.AuditEvent) {
+	// The row must be written even when the request that triggered it has been
+	// cancelled or has timed out.
+	ctx = context.WithoutCancel(ctx)
+
+	if event.IPAddress == "" && event.UserAgent == "" {
+		event.IPAddress, event.UserAgent = ClientInfo(ctx)
+	}
+
+	if err := r.repo.InsertAuditEvent(ctx, event); err != nil {
+		logger.FromContext(ctx).Error("failed to write audit event", zap.String("event_type", event.EventType), zap.Error(err))
+		return
+	}
+
+	if r.publisher == nil {
+		return
+	}
+	if err := r.publisher.PublishAuditEvent(ctx, mq.AuditEvent{
+		ID:        event.ID,
+		EventType: event.EventType,
+		AccountID: event.AccountID,
+		Email:     event.Email,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Outcome:   event.Outcome,
+		Reason:    event.Reason,
+		CreatedAt: event.CreatedAt,
+	}); err != nil {
+		logger.FromContext(ctx).Warn("failed to publish audit event", zap.Int64("id", event.ID), zap.Error(err))
+	}
+}
+
+// ClientInfo returns the caller's IP address and user agent. Requests coming
+// through grpc-gateway carry the original values in X-Forwarded-For and the
+// prefixed User-Agent header; direct gRPC calls use the peer address.
+func ClientInfo(ctx context.Context) (ip string, userAgent string) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-forwarded-for"); len(v) > 0 {
+			ip = strings.TrimSpace(strings.Split(v[0], ",")[0])
+		}
+		if v := md.Get("grpcgateway-user-agent"); len(v) > 0 {
+			userAgent = v[0]
+		} else if v := md.Get("user-agent"); len(v) > 0 {
+			userAgent = v[0]
+		}
+	}
+
+	if ip == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+	}
+	return ip, userAgent
+}
@@ -1,30 +1,97 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"os"
+	"time"
 
+	sharedconfig "github.com/Thanhbinh1905/realtime-chat-v2-go/shared/config"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/ratelimit"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	"go.uber.org/zap"
 )
 
 type Config struct {
-	DatabaseURL string `mapstructure:"DATABASE_URL"`
-	JWTSecret   string `mapstructure:"JWT_SECRET"`
+	sharedconfig.Server
+	Tracing tracing.Config `mapstructure:",squash"`
 
-	RAbbitMQURL string `mapstructure:"RABBITMQ_URL"`
+	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+	JWTSecret   string `mapstructure:"JWT_SECRET" validate:"required"`
+	// AutoMigrate applies pending migrations at startup. Otherwise the service
+	// refuses to start until they are applied with the migrate subcommand.
+	AutoMigrate bool `mapstructure:"AUTO_MIGRATE" default:"false"`
+
+	AccessTokenTTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL" default:"24h" validate:"gt=0"`
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL" default:"168h" validate:"gt=0"`
+	ServiceTokenTTL time.Duration `mapstructure:"SERVICE_TOKEN_TTL" default:"15m" validate:"gt=0"`
+
+	RAbbitMQURL            string        `mapstructure:"RABBITMQ_URL" validate:"required"`
+	RabbitMQConnectRetries int           `mapstructure:"RABBITMQ_CONNECT_RETRIES" default:"5" validate:"min=1"`
+	RabbitMQRetryDelay     time.Duration `mapstructure:"RABBITMQ_RETRY_DELAY" default:"5s"`
+
+	// AuditExchange enables streaming audit events to RabbitMQ when set.
+	AuditExchange string `mapstructure:"AUDIT_EXCHANGE"`
+
+	// ExportPartMaxBytes bounds the size of each message carrying part of the
+	// data export section of auth-service.
+	ExportPartMaxBytes int `mapstructure:"EXPORT_PART_MAX_BYTES" default:"1048576" validate:"min=1024"`
+
+	AccountDeletionGrace time.Duration `mapstructure:"ACCOUNT_DELETION_GRACE" default:"720h"`
+	AccountPurgeInterval time.Duration `mapstructure:"ACCOUNT_PURGE_INTERVAL" default:"1h" validate:"gt=0"`
+
+	// Rate limit policies, see ratelimit.ParsePolicy for the format.
+	RateLimitRegister ratelimit.Policy `mapstructure:"RATE_LIMIT_REGISTER" default:"5/1h,burst=3,by=ip"`
+	RateLimitLogin    ratelimit.Policy `mapstructure:"RATE_LIMIT_LOGIN" default:"10/1m,burst=5,by=ip+email"`
+	RateLimitDefault  ratelimit.Policy `mapstructure:"RATE_LIMIT_DEFAULT" default:"120/1m,by=ip"`
 }
 
 func LoadConfig() *Config {
-	dbURL := os.Getenv("DATABASE_URL")
-	jwt := os.Getenv("JWT_SECRET")
-	rabbitMQURL := os.Getenv("RABBITMQ_URL")
+	cfg := &Config{}
+	if err := sharedconfig.Load(cfg, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		logger.Log.Fatal("failed to load configuration", zap.Error(err))
+	}
+	return cfg
+}
+
+// MigrateConfig is the configuration of the migrate subcommand.
+type MigrateConfig struct {
+	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+}
 
-	if dbURL == "" || jwt == "" || rabbitMQURL == "" {
-		logger.Log.Fatal("Missing required environment variables")
+// LoadMigrateConfig loads the migrate subcommand configuration from args and
+// returns the arguments after the flags.
+func LoadMigrateConfig(args []string) (*MigrateConfig, []string) {
+	cfg := &MigrateConfig{}
+	rest, err := sharedconfig.LoadArgs(cfg, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		logger.Log.Fatal("failed to load configuration", zap.Error(err))
 	}
+	return cfg, rest
+}
+
+// BootstrapConfig is the configuration of the bootstrap-admin subcommand.
+type BootstrapConfig struct {
+	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+	// AdminEmail is the email of the registered account to make admin.
+	AdminEmail string `mapstructure:"ADMIN_EMAIL" validate:"required,email"`
+}
 
-	return &Config{
-		DatabaseURL: dbURL,
-		JWTSecret:   jwt,
-		RAbbitMQURL: rabbitMQURL,
+// LoadBootstrapConfig loads the bootstrap-admin subcommand configuration from args.
+func LoadBootstrapConfig(args []string) *BootstrapConfig {
+	cfg := &BootstrapConfig{}
+	if err := sharedconfig.Load(cfg, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		logger.Log.Fatal("failed to load configuration", zap.Error(err))
 	}
+	return cfg
 }
@@ -0,0 +1,41 @@
+CREATE TABLE roles (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(64) UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE permissions (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(128) UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  permission_id INT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE account_roles (
+  account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  granted_by UUID REFERENCES accounts(id) ON DELETE SET NULL,
+  granted_at TIMESTAMPTZ DEFAULT NOW(),
+  PRIMARY KEY (account_id, role_id)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Full administrative access'),
+  ('user', 'Default role for registered accounts');
+
+INSERT INTO permissions (name, description) VALUES
+  ('roles:read', 'List the roles of any account'),
+  ('roles:manage', 'Grant and revoke roles');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+-- Existing accounts get the default role.
+INSERT INTO account_roles (account_id, role_id)
+SELECT a.id, r.id FROM accounts a CROSS JOIN roles r WHERE r.name = 'user';
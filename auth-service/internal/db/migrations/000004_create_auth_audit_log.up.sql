@@ -0,0 +1,37 @@
+CREATE TABLE auth_audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  event_type VARCHAR(64) NOT NULL,
+  -- No foreign key: the trail must outlive the account it refers to.
+  account_id UUID,
+  email VARCHAR(255),
+  ip_address TEXT,
+  user_agent TEXT,
+  outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'failure')),
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auth_audit_log_account_id ON auth_audit_log(account_id, id);
+CREATE INDEX idx_auth_audit_log_event_type ON auth_audit_log(event_type, id);
+CREATE INDEX idx_auth_audit_log_created_at ON auth_audit_log(created_at);
+
+CREATE FUNCTION auth_audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auth_audit_log_no_update_delete
+  BEFORE UPDATE OR DELETE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+CREATE TRIGGER auth_audit_log_no_truncate
+  BEFORE TRUNCATE ON auth_audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION auth_audit_log_append_only();
+
+INSERT INTO permissions (name, description) VALUES
+  ('audit:read', 'Read the authentication audit log');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'audit:read';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_accounts_purge_after;
+
+ALTER TABLE accounts
+  DROP COLUMN IF EXISTS purge_after,
+  DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+ALTER TABLE accounts
+  ADD COLUMN deleted_at TIMESTAMPTZ,
+  ADD COLUMN purge_after TIMESTAMPTZ;
+
+CREATE INDEX idx_accounts_purge_after ON accounts(purge_after) WHERE deleted_at IS NOT NULL;
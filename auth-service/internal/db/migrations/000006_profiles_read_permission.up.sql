@@ -0,0 +1,8 @@
+-- Other services read profiles in bulk with API keys scoped to profiles:read.
+-- Every account holds it so that any owner can grant it to a key.
+INSERT INTO permissions (name, description) VALUES
+  ('profiles:read', 'Read user profiles in bulk');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name IN ('admin', 'user') AND p.name = 'profiles:read';
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS auth_audit_log_scrub_only ON auth_audit_log;
+DROP TRIGGER IF EXISTS auth_audit_log_no_delete ON auth_audit_log;
+DROP FUNCTION IF EXISTS auth_audit_log_scrub_only();
+DROP INDEX IF EXISTS idx_auth_audit_log_email;
+
+CREATE TRIGGER auth_audit_log_no_update_delete
+  BEFORE UPDATE OR DELETE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
@@ -0,0 +1,27 @@
+-- Purging an account scrubs its personal data from the audit trail. The only
+-- update allowed is nulling email, ip_address and user_agent; rows still cannot
+-- be deleted or otherwise changed.
+-- Failed logins carry only the email typed; purges scrub them by email.
+CREATE INDEX idx_auth_audit_log_email ON auth_audit_log (lower(email)) WHERE email IS NOT NULL;
+
+CREATE FUNCTION auth_audit_log_scrub_only() RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.email IS NULL AND NEW.ip_address IS NULL AND NEW.user_agent IS NULL
+    AND (NEW.id, NEW.event_type, NEW.outcome, NEW.created_at) = (OLD.id, OLD.event_type, OLD.outcome, OLD.created_at)
+    AND NEW.account_id IS NOT DISTINCT FROM OLD.account_id
+    AND NEW.reason IS NOT DISTINCT FROM OLD.reason THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER auth_audit_log_no_update_delete ON auth_audit_log;
+
+CREATE TRIGGER auth_audit_log_no_delete
+  BEFORE DELETE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+CREATE TRIGGER auth_audit_log_scrub_only
+  BEFORE UPDATE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_scrub_only();
@@ -3,14 +3,20 @@ package db
 import (
 	"context"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var Pool *pgxpool.Pool
 
 func Connect(dns string) error {
+	cfg, err := pgxpool.ParseConfig(dns)
+	if err != nil {
+		return err
+	}
+	cfg.ConnConfig.Tracer = tracing.PgxTracer()
 
-	pool, err := pgxpool.New(context.Background(), dns)
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
 		return err
 	}
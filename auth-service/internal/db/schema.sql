@@ -5,12 +5,133 @@ CREATE TABLE accounts (
   email VARCHAR(255) UNIQUE NOT NULL,
   password_hash TEXT NOT NULL,
   created_at TIMESTAMPTZ DEFAULT NOW(),
-  updated_at TIMESTAMPTZ DEFAULT NOW()
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ,
+  purge_after TIMESTAMPTZ
 );
 
+CREATE INDEX idx_accounts_purge_after ON accounts(purge_after) WHERE deleted_at IS NOT NULL;
+
 CREATE TABLE refresh_tokens (
   token TEXT PRIMARY KEY,
   user_id UUID REFERENCES accounts(id) ON DELETE CASCADE,
   expires_at TIMESTAMPTZ NOT NULL,
   created_at TIMESTAMPTZ DEFAULT NOW()
-);
\ No newline at end of file
+);
+
+CREATE TABLE roles (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(64) UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE permissions (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(128) UNIQUE NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  permission_id INT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE account_roles (
+  account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+  granted_by UUID REFERENCES accounts(id) ON DELETE SET NULL,
+  granted_at TIMESTAMPTZ DEFAULT NOW(),
+  PRIMARY KEY (account_id, role_id)
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Full administrative access'),
+  ('user', 'Default role for registered accounts');
+
+INSERT INTO permissions (name, description) VALUES
+  ('roles:read', 'List the roles of any account'),
+  ('roles:manage', 'Grant and revoke roles');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) UNIQUE NOT NULL,
+  key_hash TEXT NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_account_id ON api_keys(account_id);
+
+CREATE TABLE auth_audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  event_type VARCHAR(64) NOT NULL,
+  -- No foreign key: the trail must outlive the account it refers to.
+  account_id UUID,
+  email VARCHAR(255),
+  ip_address TEXT,
+  user_agent TEXT,
+  outcome VARCHAR(16) NOT NULL CHECK (outcome IN ('success', 'failure')),
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auth_audit_log_account_id ON auth_audit_log(account_id, id);
+CREATE INDEX idx_auth_audit_log_event_type ON auth_audit_log(event_type, id);
+CREATE INDEX idx_auth_audit_log_created_at ON auth_audit_log(created_at);
+CREATE INDEX idx_auth_audit_log_email ON auth_audit_log (lower(email)) WHERE email IS NOT NULL;
+
+CREATE FUNCTION auth_audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+-- Purging an account scrubs its personal data: nulling email, ip_address and
+-- user_agent is the only update allowed.
+CREATE FUNCTION auth_audit_log_scrub_only() RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.email IS NULL AND NEW.ip_address IS NULL AND NEW.user_agent IS NULL
+    AND (NEW.id, NEW.event_type, NEW.outcome, NEW.created_at) = (OLD.id, OLD.event_type, OLD.outcome, OLD.created_at)
+    AND NEW.account_id IS NOT DISTINCT FROM OLD.account_id
+    AND NEW.reason IS NOT DISTINCT FROM OLD.reason THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auth_audit_log_no_delete
+  BEFORE DELETE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+CREATE TRIGGER auth_audit_log_scrub_only
+  BEFORE UPDATE ON auth_audit_log
+  FOR EACH ROW EXECUTE FUNCTION auth_audit_log_scrub_only();
+
+CREATE TRIGGER auth_audit_log_no_truncate
+  BEFORE TRUNCATE ON auth_audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION auth_audit_log_append_only();
+
+INSERT INTO permissions (name, description) VALUES
+  ('audit:read', 'Read the authentication audit log');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'audit:read';
+
+INSERT INTO permissions (name, description) VALUES
+  ('profiles:read', 'Read user profiles in bulk');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name IN ('admin', 'user') AND p.name = 'profiles:read';
@@ -2,32 +2,51 @@ package handler
 
 import (
 	"context"
+	"time"
 
 	authpb "github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/api/auth/v1"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/audit"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/metrics"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/service"
+	apperrors "github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/errors"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type authHandler struct {
 	service service.Service
 	authpb.UnimplementedAuthServiceServer
 	publisher *mq.RabbitPublisher
+	audit     audit.Recorder
 }
 
 type AuthHandler interface {
 	Register(ctx context.Context, req *authpb.RegisterRequest) (*authpb.AuthResponse, error)
 	Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.AuthResponse, error)
+	RefreshToken(ctx context.Context, req *authpb.RefreshTokenRequest) (*authpb.AuthResponse, error)
+	Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error)
+	ChangePassword(ctx context.Context, req *authpb.ChangePasswordRequest) (*authpb.ChangePasswordResponse, error)
+	DeleteAccount(ctx context.Context, req *authpb.DeleteAccountRequest) (*authpb.DeleteAccountResponse, error)
+	RestoreAccount(ctx context.Context, req *authpb.RestoreAccountRequest) (*authpb.AuthResponse, error)
+	GrantRole(ctx context.Context, req *authpb.GrantRoleRequest) (*authpb.RoleActionResponse, error)
+	RevokeRole(ctx context.Context, req *authpb.RevokeRoleRequest) (*authpb.RoleActionResponse, error)
+	ListAccountRoles(ctx context.Context, req *authpb.ListAccountRolesRequest) (*authpb.ListAccountRolesResponse, error)
+	CreateAPIKey(ctx context.Context, req *authpb.CreateAPIKeyRequest) (*authpb.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, req *authpb.ListAPIKeysRequest) (*authpb.ListAPIKeysResponse, error)
+	RevokeAPIKey(ctx context.Context, req *authpb.RevokeAPIKeyRequest) (*authpb.APIKeyActionResponse, error)
+	ExchangeAPIKey(ctx context.Context, req *authpb.ExchangeAPIKeyRequest) (*authpb.ExchangeAPIKeyResponse, error)
+	ListAuditEvents(ctx context.Context, req *authpb.ListAuditEventsRequest) (*authpb.ListAuditEventsResponse, error)
 }
 
-func NewAuthServiceServer(s service.Service, pub *mq.RabbitPublisher) authpb.AuthServiceServer {
+func NewAuthServiceServer(s service.Service, pub *mq.RabbitPublisher, recorder audit.Recorder) authpb.AuthServiceServer {
 	return &authHandler{
 		service:   s,
 		publisher: pub,
+		audit:     recorder,
 	}
 }
 
@@ -38,9 +57,12 @@ func (h *authHandler) Register(ctx context.Context, req *authpb.RegisterRequest)
 	}
 	resp, err := h.service.Register(ctx, input)
 	if err != nil {
-		logger.Log.Error("user registration failed", zap.String("email", req.GetEmail()), zap.Error(err))
-		return nil, status.Errorf(codes.Internal, "register failed: %v", err)
+		h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventRegister, Email: req.GetEmail()}, err)
+		logger.FromContext(ctx).Error("user registration failed", zap.String("email", req.GetEmail()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
 	}
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventRegister, AccountID: &resp.RegisterRequest.ID, Email: req.GetEmail()}, nil)
+	metrics.Registrations.Inc()
 
 	// 📨 Publish event to RabbitMQ
 	event := mq.UserSignUpEvent{
@@ -48,12 +70,12 @@ func (h *authHandler) Register(ctx context.Context, req *authpb.RegisterRequest)
 		Email:  resp.RegisterRequest.Email,
 	}
 
-	if err := h.publisher.PublishUserSignedUp(event); err != nil {
-		logger.Log.Warn("failed to publish signup event", zap.String("user_id", resp.RegisterRequest.ID.String()), zap.Error(err))
+	if err := h.publisher.PublishUserSignedUp(ctx, event); err != nil {
+		logger.FromContext(ctx).Warn("failed to publish signup event", zap.String("user_id", resp.RegisterRequest.ID.String()), zap.Error(err))
 		// optional: return error or just log
 	}
 
-	logger.Log.Info("user registered successfully", zap.String("email", req.GetEmail()))
+	logger.FromContext(ctx).Info("user registered successfully", zap.String("email", req.GetEmail()))
 	return &authpb.AuthResponse{
 		AccessToken:  resp.AccessToken,
 		RefreshToken: resp.RefreshToken,
@@ -67,12 +89,313 @@ func (h *authHandler) Login(ctx context.Context, req *authpb.LoginRequest) (*aut
 	}
 	resp, err := h.service.Login(ctx, input)
 	if err != nil {
-		logger.Log.Error("user login failed", zap.String("email", req.GetEmail()), zap.Error(err))
-		return nil, status.Errorf(codes.Unauthenticated, "login failed: %v", err)
+		h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventLogin, Email: req.GetEmail()}, err)
+		metrics.LoginAttempts.WithLabelValues(metrics.LoginFailure).Inc()
+		logger.FromContext(ctx).Error("user login failed", zap.String("email", req.GetEmail()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
 	}
-	logger.Log.Info("user logged in successfully", zap.String("email", req.GetEmail()))
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventLogin, AccountID: &resp.AccountID, Email: req.GetEmail()}, nil)
+	metrics.LoginAttempts.WithLabelValues(metrics.LoginSuccess).Inc()
+	logger.FromContext(ctx).Info("user logged in successfully", zap.String("email", req.GetEmail()))
 	return &authpb.AuthResponse{
 		AccessToken:  resp.AccessToken,
 		RefreshToken: resp.RefreshToken,
 	}, nil
 }
+
+func (h *authHandler) RefreshToken(ctx context.Context, req *authpb.RefreshTokenRequest) (*authpb.AuthResponse, error) {
+	resp, err := h.service.RefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventTokenRefresh}, err)
+		logger.FromContext(ctx).Warn("token refresh failed", zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventTokenRefresh, AccountID: &resp.AccountID}, nil)
+	return &authpb.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+func (h *authHandler) Logout(ctx context.Context, req *authpb.LogoutRequest) (*authpb.LogoutResponse, error) {
+	accountID, err := h.service.Logout(ctx, req.GetRefreshToken())
+	var auditAccountID *uuid.UUID
+	if accountID != uuid.Nil {
+		auditAccountID = &accountID
+	}
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventLogout, AccountID: auditAccountID}, err)
+	if err != nil {
+		logger.FromContext(ctx).Warn("logout failed", zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+	return &authpb.LogoutResponse{Message: "logged out"}, nil
+}
+
+func (h *authHandler) ChangePassword(ctx context.Context, req *authpb.ChangePasswordRequest) (*authpb.ChangePasswordResponse, error) {
+	accountID, err := callerAccountID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	err = h.service.ChangePassword(ctx, &model.ChangePasswordInput{
+		AccountID:       accountID,
+		CurrentPassword: req.GetCurrentPassword(),
+		NewPassword:     req.GetNewPassword(),
+	})
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventPasswordChanged, AccountID: &accountID}, err)
+	if err != nil {
+		logger.FromContext(ctx).Warn("password change failed", zap.String("account_id", accountID.String()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("password changed", zap.String("account_id", accountID.String()))
+	return &authpb.ChangePasswordResponse{Message: "password changed"}, nil
+}
+
+func (h *authHandler) DeleteAccount(ctx context.Context, req *authpb.DeleteAccountRequest) (*authpb.DeleteAccountResponse, error) {
+	accountID, err := callerAccountID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp, err := h.service.DeleteAccount(ctx, &model.DeleteAccountInput{
+		AccountID: accountID,
+		Password:  req.GetPassword(),
+	})
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAccountDeleted, AccountID: &accountID}, err)
+	if err != nil {
+		logger.FromContext(ctx).Warn("account deletion failed", zap.String("account_id", accountID.String()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("account scheduled for deletion", zap.String("account_id", accountID.String()), zap.Time("purge_after", resp.PurgeAfter))
+	return &authpb.DeleteAccountResponse{
+		Message:    "account scheduled for deletion",
+		PurgeAfter: resp.PurgeAfter.Format(time.RFC3339),
+	}, nil
+}
+
+func (h *authHandler) RestoreAccount(ctx context.Context, req *authpb.RestoreAccountRequest) (*authpb.AuthResponse, error) {
+	resp, err := h.service.RestoreAccount(ctx, &model.LoginInput{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	})
+	if err != nil {
+		h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAccountRestored, Email: req.GetEmail()}, err)
+		logger.FromContext(ctx).Warn("account restore failed", zap.String("email", req.GetEmail()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAccountRestored, AccountID: &resp.AccountID, Email: req.GetEmail()}, nil)
+
+	return &authpb.AuthResponse{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+// MethodPermissions declares the permissions required by each protected RPC.
+var MethodPermissions = authz.MethodPermissions{
+	authpb.AuthService_GrantRole_FullMethodName:        {model.PermissionRolesManage},
+	authpb.AuthService_RevokeRole_FullMethodName:       {model.PermissionRolesManage},
+	authpb.AuthService_ListAccountRoles_FullMethodName: {model.PermissionRolesRead},
+	authpb.AuthService_CreateAPIKey_FullMethodName:     {},
+	authpb.AuthService_ListAPIKeys_FullMethodName:      {},
+	authpb.AuthService_RevokeAPIKey_FullMethodName:     {},
+	authpb.AuthService_ListAuditEvents_FullMethodName:  {model.PermissionAuditRead},
+	authpb.AuthService_DeleteAccount_FullMethodName:    {},
+	authpb.AuthService_ChangePassword_FullMethodName:   {},
+}
+
+func (h *authHandler) GrantRole(ctx context.Context, req *authpb.GrantRoleRequest) (*authpb.RoleActionResponse, error) {
+	accountID, err := uuid.Parse(req.GetAccountId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("account_id", "must be a valid UUID"))
+	}
+
+	input := &model.GrantRoleInput{
+		AccountID: accountID,
+		Role:      req.GetRole(),
+	}
+	if claims, ok := authz.FromContext(ctx); ok {
+		if grantedBy, err := uuid.Parse(claims.UserID); err == nil {
+			input.GrantedBy = &grantedBy
+		}
+	}
+
+	err = h.service.GrantRole(ctx, input)
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventRoleGranted, AccountID: &accountID, Reason: "role=" + req.GetRole()}, err)
+	if err != nil {
+		logger.FromContext(ctx).Error("grant role failed", zap.String("account_id", req.GetAccountId()), zap.String("role", req.GetRole()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("role granted", zap.String("account_id", req.GetAccountId()), zap.String("role", req.GetRole()))
+	return &authpb.RoleActionResponse{Message: "role granted"}, nil
+}
+
+func (h *authHandler) RevokeRole(ctx context.Context, req *authpb.RevokeRoleRequest) (*authpb.RoleActionResponse, error) {
+	accountID, err := uuid.Parse(req.GetAccountId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("account_id", "must be a valid UUID"))
+	}
+
+	err = h.service.RevokeRole(ctx, accountID, req.GetRole())
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventRoleRevoked, AccountID: &accountID, Reason: "role=" + req.GetRole()}, err)
+	if err != nil {
+		logger.FromContext(ctx).Error("revoke role failed", zap.String("account_id", req.GetAccountId()), zap.String("role", req.GetRole()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("role revoked", zap.String("account_id", req.GetAccountId()), zap.String("role", req.GetRole()))
+	return &authpb.RoleActionResponse{Message: "role revoked"}, nil
+}
+
+func (h *authHandler) ListAccountRoles(ctx context.Context, req *authpb.ListAccountRolesRequest) (*authpb.ListAccountRolesResponse, error) {
+	accountID, err := uuid.Parse(req.GetAccountId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("account_id", "must be a valid UUID"))
+	}
+
+	roles, err := h.service.ListAccountRoles(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("list account roles failed", zap.String("account_id", req.GetAccountId()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &authpb.ListAccountRolesResponse{}
+	for _, r := range roles {
+		role := &authpb.AccountRole{
+			Role:      r.Role,
+			GrantedAt: r.GrantedAt.Format(time.RFC3339),
+		}
+		if r.GrantedBy != nil {
+			role.GrantedBy = r.GrantedBy.String()
+		}
+		resp.Roles = append(resp.Roles, role)
+	}
+	return resp, nil
+}
+
+func (h *authHandler) CreateAPIKey(ctx context.Context, req *authpb.CreateAPIKeyRequest) (*authpb.CreateAPIKeyResponse, error) {
+	accountID, err := callerAccountID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	input := &model.CreateAPIKeyInput{
+		AccountID: accountID,
+		Name:      req.GetName(),
+		Scopes:    req.GetScopes(),
+	}
+	if req.GetExpiresAt() != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.GetExpiresAt())
+		if err != nil {
+			return nil, apperrors.ToGRPC(apperrors.InvalidField("expires_at", "must be an RFC 3339 timestamp"))
+		}
+		input.ExpiresAt = &expiresAt
+	}
+
+	resp, err := h.service.CreateAPIKey(ctx, input)
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAPIKeyCreated, AccountID: &accountID, Reason: "name=" + req.GetName()}, err)
+	if err != nil {
+		logger.FromContext(ctx).Error("create api key failed", zap.String("account_id", accountID.String()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("api key created", zap.String("account_id", accountID.String()), zap.String("prefix", resp.APIKey.Prefix))
+	return &authpb.CreateAPIKeyResponse{
+		Key:    resp.Key,
+		ApiKey: toAPIKeyProto(&resp.APIKey),
+	}, nil
+}
+
+func (h *authHandler) ListAPIKeys(ctx context.Context, req *authpb.ListAPIKeysRequest) (*authpb.ListAPIKeysResponse, error) {
+	accountID, err := callerAccountID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	keys, err := h.service.ListAPIKeys(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("list api keys failed", zap.String("account_id", accountID.String()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &authpb.ListAPIKeysResponse{}
+	for i := range keys {
+		resp.Keys = append(resp.Keys, toAPIKeyProto(&keys[i]))
+	}
+	return resp, nil
+}
+
+func (h *authHandler) RevokeAPIKey(ctx context.Context, req *authpb.RevokeAPIKeyRequest) (*authpb.APIKeyActionResponse, error) {
+	accountID, err := callerAccountID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	keyID, err := uuid.Parse(req.GetKeyId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("key_id", "must be a valid UUID"))
+	}
+
+	err = h.service.RevokeAPIKey(ctx, accountID, keyID)
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAPIKeyRevoked, AccountID: &accountID, Reason: "key_id=" + req.GetKeyId()}, err)
+	if err != nil {
+		logger.FromContext(ctx).Error("revoke api key failed", zap.String("key_id", req.GetKeyId()), zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	logger.FromContext(ctx).Info("api key revoked", zap.String("key_id", req.GetKeyId()))
+	return &authpb.APIKeyActionResponse{Message: "api key revoked"}, nil
+}
+
+func (h *authHandler) ExchangeAPIKey(ctx context.Context, req *authpb.ExchangeAPIKeyRequest) (*authpb.ExchangeAPIKeyResponse, error) {
+	resp, err := h.service.ExchangeAPIKey(ctx, req.GetApiKey())
+	if err != nil {
+		h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAPIKeyExchanged}, err)
+		logger.FromContext(ctx).Warn("api key exchange failed", zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+	h.recordAudit(ctx, model.AuditEvent{EventType: model.AuditEventAPIKeyExchanged, AccountID: &resp.AccountID}, nil)
+
+	return &authpb.ExchangeAPIKeyResponse{
+		AccessToken: resp.AccessToken,
+		ExpiresAt:   resp.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// callerAccountID returns the account of the authenticated user. Tokens obtained
+// from an API key are rejected so that a key cannot mint further keys.
+func callerAccountID(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := authz.FromContext(ctx)
+	if !ok || claims.IsServiceAccount() {
+		return uuid.Nil, apperrors.ErrUnauthorized
+	}
+
+	accountID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, apperrors.ErrUnauthorized
+	}
+	return accountID, nil
+}
+
+func toAPIKeyProto(key *model.APIKey) *authpb.APIKey {
+	return &authpb.APIKey{
+		Id:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  formatTime(key.ExpiresAt),
+		LastUsedAt: formatTime(key.LastUsedAt),
+		RevokedAt:  formatTime(key.RevokedAt),
+		CreatedAt:  key.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,94 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/audit"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+)
+
+const purgeBatchSize = 100
+
+type UserDeletedPublisher interface {
+	PublishUserDeleted(ctx context.Context, event mq.UserDeletedEvent) error
+}
+
+// AccountPurger permanently deletes accounts whose deletion grace period has
+// elapsed and announces it on user.events so other services erase their data.
+type AccountPurger struct {
+	repo      repository.Repository
+	publisher UserDeletedPublisher
+	audit     audit.Recorder
+	interval  time.Duration
+}
+
+func NewAccountPurger(repo repository.Repository, publisher UserDeletedPublisher, recorder audit.Recorder, interval time.Duration) *AccountPurger {
+	return &AccountPurger{
+		repo:      repo,
+		publisher: publisher,
+		audit:     recorder,
+		interval:  interval,
+	}
+}
+
+// Run purges on every tick until ctx is cancelled.
+func (p *AccountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce deletes one batch of expired accounts. The row is deleted only
+// once the broker has confirmed that the user.deleted event is queued: if it is
+// unavailable, refuses the event or cannot route it, the account is kept and
+// retried on the next run. An event may therefore be sent twice, which
+// user-service tolerates as erasing is idempotent.
+func (p *AccountPurger) PurgeOnce(ctx context.Context) {
+	now := time.Now()
+
+	accounts, err := p.repo.ListPurgeableAccounts(ctx, now, purgeBatchSize)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list purgeable accounts", zap.Error(err))
+		return
+	}
+
+	for _, account := range accounts {
+		if err := p.publisher.PublishUserDeleted(ctx, mq.UserDeletedEvent{UserID: account.ID, DeletedAt: now}); err != nil {
+			logger.FromContext(ctx).Warn("failed to publish user deleted event, will retry", zap.String("account_id", account.ID.String()), zap.Error(err))
+			continue
+		}
+
+		deleted, err := p.repo.HardDeleteAccount(ctx, account.ID, now)
+		if err != nil {
+			logger.FromContext(ctx).Error("failed to purge account", zap.String("account_id", account.ID.String()), zap.Error(err))
+			continue
+		}
+		if !deleted {
+			// Already purged by another replica. Restoring is no longer
+			// possible once purge_after has passed.
+			continue
+		}
+
+		accountID := account.ID
+		p.audit.Record(ctx, &model.AuditEvent{
+			EventType: model.AuditEventAccountPurged,
+			AccountID: &accountID,
+			Outcome:   model.AuditOutcomeSuccess,
+		})
+		logger.FromContext(ctx).Info("account purged", zap.String("account_id", account.ID.String()))
+	}
+}
@@ -0,0 +1,164 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/errors"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+)
+
+// exportService is the name auth-service's section is filed under in exports.
+const exportService = "auth-service"
+
+type ExportSectionPublisher interface {
+	PublishExportSection(ctx context.Context, event mq.ExportSectionEvent) error
+}
+
+// DataExportContributor answers the data export requests of user-service with
+// the section auth-service owns: account, sessions, API keys and audit log.
+type DataExportContributor struct {
+	svc       service.Service
+	publisher ExportSectionPublisher
+	// partMaxBytes bounds the files sent in one message. A single document
+	// larger than that, such as a huge audit event, still goes alone.
+	partMaxBytes int
+}
+
+func NewDataExportContributor(svc service.Service, publisher ExportSectionPublisher, partMaxBytes int) *DataExportContributor {
+	return &DataExportContributor{svc: svc, publisher: publisher, partMaxBytes: partMaxBytes}
+}
+
+// Handle publishes the section for one request, part by part. An error means
+// the request should be retried; parts already sent are then sent again and
+// replace their earlier copy.
+func (c *DataExportContributor) Handle(ctx context.Context, event mq.ExportRequestedEvent) error {
+	var parts []map[string]any
+	var failure string
+
+	data, err := c.svc.ExportAccountData(ctx, event.UserID)
+	switch {
+	case stdErrors.Is(err, errors.ErrUserNotFound):
+		// Purged meanwhile: say so rather than leave the export waiting.
+		parts, failure = []map[string]any{nil}, "account not found"
+	case err != nil:
+		return err
+	default:
+		if parts, err = c.split(data); err != nil {
+			return err
+		}
+	}
+
+	for i, files := range parts {
+		section := mq.ExportSectionEvent{
+			ExportID: event.ExportID,
+			UserID:   event.UserID,
+			Service:  exportService,
+			Part:     i,
+			Parts:    len(parts),
+			Files:    files,
+			Error:    failure,
+		}
+		if err := c.publisher.PublishExportSection(ctx, section); err != nil {
+			return err
+		}
+	}
+	logger.FromContext(ctx).Info("data export section sent", zap.String("export_id", event.ExportID.String()),
+		zap.Int("parts", len(parts)), zap.Bool("ok", failure == ""))
+	return nil
+}
+
+type exportFile struct {
+	name string
+	data json.RawMessage
+}
+
+// split lays data out as files and packs them into parts of at most
+// partMaxBytes. The audit log, which grows without bound, is paged into
+// audit_log/0001.json, audit_log/0002.json and so on when it does not fit in
+// one part, and is audit_log.json otherwise.
+func (c *DataExportContributor) split(data *model.AccountExport) ([]map[string]any, error) {
+	var files []exportFile
+	for name, doc := range map[string]any{
+		"account.json":  data.Account,
+		"sessions.json": data.Sessions,
+		"api_keys.json": data.APIKeys,
+	} {
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, exportFile{name: name, data: raw})
+	}
+	slices.SortFunc(files, func(a, b exportFile) int { return strings.Compare(a.name, b.name) })
+
+	pages, err := c.pageAuditLog(data.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 1 {
+		files = append(files, exportFile{name: "audit_log.json", data: pages[0]})
+	} else {
+		for i, page := range pages {
+			files = append(files, exportFile{name: fmt.Sprintf("audit_log/%04d.json", i+1), data: page})
+		}
+	}
+
+	var parts []map[string]any
+	size := 0
+	for _, f := range files {
+		if len(parts) == 0 || (size > 0 && size+len(f.data) > c.partMaxBytes) {
+			parts = append(parts, map[string]any{})
+			size = 0
+		}
+		parts[len(parts)-1][f.name] = f.data
+		size += len(f.data)
+	}
+	return parts, nil
+}
+
+// pageAuditLog returns events, in order, as JSON arrays of at most
+// partMaxBytes each. An empty log is one empty page.
+func (c *DataExportContributor) pageAuditLog(events []model.ExportedAuditEvent) ([]json.RawMessage, error) {
+	var pages []json.RawMessage
+	var page []json.RawMessage
+	size := len("[]")
+	flush := func() error {
+		if page == nil {
+			page = []json.RawMessage{}
+		}
+		raw, err := json.Marshal(page)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, raw)
+		page, size = nil, len("[]")
+		return nil
+	}
+
+	for _, e := range events {
+		raw, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) > 0 && size+len(raw)+len(",") > c.partMaxBytes {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		page = append(page, raw)
+		size += len(raw) + len(",")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
@@ -0,0 +1,119 @@
+package job_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/job"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zaptest"
+)
+
+const partMaxBytes = 1024
+
+type exportingService struct {
+	service.Service
+	data *model.AccountExport
+}
+
+func (s exportingService) ExportAccountData(ctx context.Context, accountID uuid.UUID) (*model.AccountExport, error) {
+	return s.data, nil
+}
+
+type recordingPublisher struct {
+	sections []mq.ExportSectionEvent
+}
+
+func (p *recordingPublisher) PublishExportSection(ctx context.Context, event mq.ExportSectionEvent) error {
+	p.sections = append(p.sections, event)
+	return nil
+}
+
+func accountExport(auditEvents int) *model.AccountExport {
+	data := &model.AccountExport{
+		Account:  model.ExportedAccount{ID: uuid.New(), Email: "alice@example.com", Roles: []model.ExportedRole{}},
+		Sessions: []model.ExportedSession{},
+		APIKeys:  []model.ExportedAPIKey{},
+		AuditLog: []model.ExportedAuditEvent{},
+	}
+	for i := range auditEvents {
+		data.AuditLog = append(data.AuditLog, model.ExportedAuditEvent{
+			EventType: "login",
+			Outcome:   "success",
+			Reason:    fmt.Sprintf("event %d", i),
+			CreatedAt: time.Unix(int64(i), 0).UTC(),
+		})
+	}
+	return data
+}
+
+func contribute(t *testing.T, data *model.AccountExport) []mq.ExportSectionEvent {
+	t.Helper()
+	publisher := &recordingPublisher{}
+	contributor := job.NewDataExportContributor(exportingService{data: data}, publisher, partMaxBytes)
+	ctx := logger.NewContext(context.Background(), zaptest.NewLogger(t))
+	if err := contributor.Handle(ctx, mq.ExportRequestedEvent{ExportID: uuid.New(), UserID: data.Account.ID}); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	return publisher.sections
+}
+
+func TestDataExportContributorSmallSection(t *testing.T) {
+	sections := contribute(t, accountExport(2))
+	if len(sections) != 1 || sections[0].Parts != 1 {
+		t.Fatalf("sent %d parts, want 1", len(sections))
+	}
+	for _, name := range []string{"account.json", "sessions.json", "api_keys.json", "audit_log.json"} {
+		if _, ok := sections[0].Files[name]; !ok {
+			t.Errorf("section lacks %s", name)
+		}
+	}
+}
+
+func TestDataExportContributorSplitsLargeSection(t *testing.T) {
+	const events = 100
+	sections := contribute(t, accountExport(events))
+	if len(sections) < 2 {
+		t.Fatalf("sent %d parts, want several", len(sections))
+	}
+
+	var audit []model.ExportedAuditEvent
+	for i, section := range sections {
+		if section.Part != i || section.Parts != len(sections) {
+			t.Errorf("part %d is numbered %d of %d, want %d of %d", i, section.Part, section.Parts, i, len(sections))
+		}
+		size := 0
+		for name, file := range section.Files {
+			raw := file.(json.RawMessage)
+			size += len(raw)
+			if !strings.HasPrefix(name, "audit_log/") {
+				continue
+			}
+			var page []model.ExportedAuditEvent
+			if err := json.Unmarshal(raw, &page); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			audit = append(audit, page...)
+		}
+		if size > partMaxBytes {
+			t.Errorf("part %d carries %d bytes, want at most %d", i, size, partMaxBytes)
+		}
+	}
+
+	if len(audit) != events {
+		t.Fatalf("audit log has %d events across pages, want %d", len(audit), events)
+	}
+	for i, e := range audit {
+		if e.Reason != fmt.Sprintf("event %d", i) {
+			t.Fatalf("audit event %d is %q, want the events in order", i, e.Reason)
+		}
+	}
+}
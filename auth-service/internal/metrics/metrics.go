@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Login outcomes.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
+
+// Issued token types.
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+	TokenService = "service"
+)
+
+var Registrations = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "auth_registrations_total",
+	Help: "Total number of accounts registered.",
+})
+
+var LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_login_attempts_total",
+	Help: "Total number of login attempts, by outcome.",
+}, []string{"outcome"})
+
+var TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "auth_tokens_issued_total",
+	Help: "Total number of tokens issued, by type.",
+}, []string{"type"})
@@ -12,6 +12,10 @@ type Account struct {
 	PasswordHash string
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	// DeletedAt is set while the account is pending deletion; it can be
+	// restored until PurgeAfter.
+	DeletedAt  *time.Time
+	PurgeAfter *time.Time
 }
 
 type RegisterRequest struct {
@@ -31,6 +35,7 @@ type LoginInput struct {
 }
 
 type AuthResponse struct {
+	AccountID    uuid.UUID
 	AccessToken  string
 	RefreshToken string
 }
@@ -39,3 +44,176 @@ type RegisterResponse struct {
 	RefreshToken    string
 	RegisterRequest RegisterRequest
 }
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+const (
+	PermissionRolesRead   = "roles:read"
+	PermissionRolesManage = "roles:manage"
+	PermissionAuditRead   = "audit:read"
+	// PermissionProfilesRead lets API keys of other services read user profiles
+	// in bulk from user-service. Every role holds it.
+	PermissionProfilesRead = "profiles:read"
+)
+
+type AccountRole struct {
+	Role      string
+	GrantedBy *uuid.UUID
+	GrantedAt time.Time
+}
+
+type GrantRoleInput struct {
+	AccountID uuid.UUID `validate:"required"`
+	Role      string    `validate:"required"`
+	GrantedBy *uuid.UUID
+}
+
+type APIKey struct {
+	ID         uuid.UUID
+	AccountID  uuid.UUID
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type CreateAPIKeyInput struct {
+	AccountID uuid.UUID `validate:"required"`
+	Name      string    `validate:"required,max=100"`
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type CreateAPIKeyResponse struct {
+	// Key is the plaintext key; it is only returned once, at creation.
+	Key    string
+	APIKey APIKey
+}
+
+type ServiceTokenResponse struct {
+	AccountID   uuid.UUID
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+const (
+	AuditEventRegister        = "register"
+	AuditEventLogin           = "login"
+	AuditEventTokenRefresh    = "token_refresh"
+	AuditEventLogout          = "logout"
+	AuditEventRoleGranted     = "role_granted"
+	AuditEventRoleRevoked     = "role_revoked"
+	AuditEventAPIKeyCreated   = "api_key_created"
+	AuditEventAPIKeyRevoked   = "api_key_revoked"
+	AuditEventAPIKeyExchanged = "api_key_exchanged"
+	AuditEventAccountDeleted  = "account_deleted"
+	AuditEventAccountRestored = "account_restored"
+	AuditEventAccountPurged   = "account_purged"
+	AuditEventPasswordChanged = "password_changed"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+type AuditEvent struct {
+	ID        int64
+	EventType string
+	AccountID *uuid.UUID
+	Email     string
+	IPAddress string
+	UserAgent string
+	Outcome   string
+	Reason    string
+	CreatedAt time.Time
+}
+
+type AuditEventFilter struct {
+	AccountID *uuid.UUID
+	EventType string
+	Outcome   string
+	Since     *time.Time
+	Until     *time.Time
+	// BeforeID is the pagination cursor: only events with a smaller id are returned.
+	BeforeID int64
+	Limit    int
+}
+
+type DeleteAccountInput struct {
+	AccountID uuid.UUID `validate:"required"`
+	Password  string    `validate:"required"`
+}
+
+type ChangePasswordInput struct {
+	AccountID       uuid.UUID `validate:"required"`
+	CurrentPassword string    `validate:"required"`
+	NewPassword     string    `validate:"required,min=6"`
+}
+
+type DeleteAccountResponse struct {
+	PurgeAfter time.Time
+}
+
+// Session is an active refresh token, without the token itself.
+type Session struct {
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// AccountExport is everything auth-service holds about an account, as included
+// in a personal data export. Secrets such as password and key hashes are left out.
+type AccountExport struct {
+	Account  ExportedAccount
+	Sessions []ExportedSession
+	APIKeys  []ExportedAPIKey
+	AuditLog []ExportedAuditEvent
+}
+
+type ExportedAccount struct {
+	ID         uuid.UUID      `json:"id"`
+	Email      string         `json:"email"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  *time.Time     `json:"deleted_at,omitempty"`
+	PurgeAfter *time.Time     `json:"purge_after,omitempty"`
+	Roles      []ExportedRole `json:"roles"`
+}
+
+type ExportedRole struct {
+	Role      string    `json:"role"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+type ExportedSession struct {
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ExportedAPIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ExportedAuditEvent struct {
+	EventType string    `json:"event_type"`
+	Email     string    `json:"email,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
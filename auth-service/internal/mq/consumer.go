@@ -0,0 +1,167 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+)
+
+const exportRequestedQueue = "auth-service.user.export.requested"
+
+type RabbitConsumer struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewRabbitConsumer(rabbitURL string, maxRetries int, retryDelay time.Duration) (*RabbitConsumer, error) {
+	var conn *amqp.Connection
+	var err error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		logger.Log.Info("connecting to RabbitMQ", zap.Int("attempt", attempt))
+
+		conn, err = amqp.Dial(rabbitURL)
+		if err == nil {
+			break
+		}
+
+		logger.Log.Warn("failed to connect to RabbitMQ, will retry", zap.Error(err))
+		time.Sleep(retryDelay)
+	}
+
+	if err != nil {
+		logger.Log.Error("giving up connecting to RabbitMQ", zap.Error(err))
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		logger.Log.Error("failed to open a channel", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	err = ch.ExchangeDeclare(
+		"user.events", // name
+		"topic",       // type
+		true,          // durable
+		false,         // auto-deleted
+		false,         // internal
+		false,         // no-wait
+		nil,           // args
+	)
+	if err != nil {
+		logger.Log.Error("failed to declare exchange", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	// Export sections are built one at a time per consumer.
+	if err := ch.Qos(1, 0, false); err != nil {
+		logger.Log.Error("failed to set channel QoS", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	logger.Log.Info("RabbitMQ consumer connected successfully")
+	return &RabbitConsumer{conn: conn, channel: ch}, nil
+}
+
+// ConsumeExportRequested delivers data export requests to handle until ctx is
+// cancelled. Messages are acknowledged only after handle succeeds; failures are
+// requeued, malformed messages are dropped.
+func (c *RabbitConsumer) ConsumeExportRequested(ctx context.Context, handle func(context.Context, ExportRequestedEvent) error) error {
+	q, err := c.channel.QueueDeclare(
+		exportRequestedQueue, // name
+		true,                 // durable
+		false,                // auto-deleted
+		false,                // exclusive
+		false,                // no-wait
+		nil,                  // args
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := c.channel.QueueBind(q.Name, ExportRequested, "user.events", false, nil); err != nil {
+		return err
+	}
+
+	deliveries, err := c.channel.Consume(
+		q.Name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					logger.Log.Warn("export request delivery channel closed")
+					return
+				}
+
+				msgCtx, span := tracing.StartConsume(ctx, q.Name, d)
+				msgCtx = logger.ExtractAMQP(msgCtx, d)
+
+				var event ExportRequestedEvent
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					logger.FromContext(msgCtx).Error("invalid export request message", zap.Error(err))
+					d.Nack(false, false)
+					metrics.ObserveConsume(q.Name, metrics.ConsumeDrop)
+					tracing.EndSpan(span, err)
+					continue
+				}
+
+				if err := handle(msgCtx, event); err != nil {
+					logger.FromContext(msgCtx).Error("failed to handle export request", zap.String("export_id", event.ExportID.String()), zap.Error(err))
+					d.Nack(false, true)
+					metrics.ObserveConsume(q.Name, metrics.ConsumeRequeue)
+					tracing.EndSpan(span, err)
+					continue
+				}
+				d.Ack(false)
+				metrics.ObserveConsume(q.Name, metrics.ConsumeAck)
+				tracing.EndSpan(span, nil)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Check reports whether the broker connection is still open.
+func (c *RabbitConsumer) Check(ctx context.Context) error {
+	if c.conn.IsClosed() {
+		return errConnectionClosed
+	}
+	return nil
+}
+
+func (c *RabbitConsumer) Close() {
+	if err := c.channel.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ channel: %v", err)
+	}
+	if err := c.conn.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ connection: %v", err)
+	} else {
+		log.Println("RabbitMQ connection closed successfully")
+	}
+}
@@ -1,8 +1,59 @@
 package mq
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type UserSignUpEvent struct {
 	UserID uuid.UUID `json:"id"`
 	Email  string    `json:"email"`
 }
+
+// UserDeletedEvent is published once an account has been permanently erased.
+type UserDeletedEvent struct {
+	UserID    uuid.UUID `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type AuditEvent struct {
+	ID        int64      `json:"id"`
+	EventType string     `json:"event_type"`
+	AccountID *uuid.UUID `json:"account_id,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	IPAddress string     `json:"ip_address,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	Outcome   string     `json:"outcome"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Routing keys on user.events of the personal data export flow.
+const (
+	ExportRequested = "user.export.requested"
+	ExportSection   = "user.export.section"
+)
+
+// ExportRequestedEvent is published by user-service when a user asks for a
+// copy of their data. Every service holding some answers with an
+// ExportSectionEvent.
+type ExportRequestedEvent struct {
+	ExportID    uuid.UUID `json:"export_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// ExportSectionEvent carries part Part of Parts of the data one service holds
+// about the user, as JSON documents by file name. A section is sent in parts
+// to keep messages small. Error is set instead, in a single part, if the
+// service could not produce its section.
+type ExportSectionEvent struct {
+	ExportID uuid.UUID      `json:"export_id"`
+	UserID   uuid.UUID      `json:"user_id"`
+	Service  string         `json:"service"`
+	Part     int            `json:"part"`
+	Parts    int            `json:"parts"`
+	Files    map[string]any `json:"files,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
@@ -1,23 +1,40 @@
 package mq
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/rabbitmq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
 	"github.com/streadway/amqp"
 	"go.uber.org/zap"
 )
 
+var errConnectionClosed = errors.New("rabbitmq connection closed")
+
+// userServiceQueues are the queues user-service consumes the confirmed
+// messages of auth-service from, by routing key. They are declared here as
+// well, with the same arguments, so that a message published before
+// user-service first starts is kept rather than dropped.
+var userServiceQueues = map[string]string{
+	"user.deleted": "user-service.user.deleted",
+	ExportSection:  "user-service.user.export.section",
+}
+
 type RabbitPublisher struct {
+	conn    *amqp.Connection
 	channel *amqp.Channel
+	// confirm carries the messages that must not be lost, see publishConfirmed.
+	confirm       *rabbitmq.ConfirmChannel
+	auditExchange string
 }
 
-func NewRabbitPublisher(rabbitURL string) (*RabbitPublisher, error) {
-	const maxRetries = 5
-	const retryDelay = 5 * time.Second
-
+func NewRabbitPublisher(rabbitURL string, maxRetries int, retryDelay time.Duration) (*RabbitPublisher, error) {
 	var conn *amqp.Connection
 	var err error
 
@@ -59,31 +76,161 @@ func NewRabbitPublisher(rabbitURL string) (*RabbitPublisher, error) {
 		return nil, err
 	}
 
+	for routingKey, queue := range userServiceQueues {
+		_, err = ch.QueueDeclare(
+			queue, // name
+			true,  // durable
+			false, // auto-deleted
+			false, // exclusive
+			false, // no-wait
+			nil,   // args
+		)
+		if err == nil {
+			err = ch.QueueBind(queue, routingKey, "user.events", false, nil)
+		}
+		if err != nil {
+			logger.Log.Error("failed to declare queue", zap.String("queue", queue), zap.Error(err))
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	confirm, err := rabbitmq.NewConfirmChannel(conn)
+	if err != nil {
+		logger.Log.Error("failed to open a confirm channel", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
 	logger.Log.Info("RabbitMQ publisher connected successfully")
-	return &RabbitPublisher{channel: ch}, nil
+	return &RabbitPublisher{conn: conn, channel: ch, confirm: confirm}, nil
+}
+
+func (p *RabbitPublisher) PublishUserSignedUp(ctx context.Context, event UserSignUpEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.publish(ctx, "user.events", "user.signup", amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	})
+}
+
+// PublishUserDeleted returns once the broker has queued the event, see
+// publishConfirmed.
+func (p *RabbitPublisher) PublishUserDeleted(ctx context.Context, event UserDeletedEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.publishConfirmed(ctx, "user.events", "user.deleted", amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
 }
 
-func (p *RabbitPublisher) PublishUserSignedUp(event UserSignUpEvent) error {
+// PublishExportSection returns once the broker has queued the part, see
+// publishConfirmed.
+func (p *RabbitPublisher) PublishExportSection(ctx context.Context, event ExportSectionEvent) error {
 	body, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 
-	return p.channel.Publish(
-		"user.events", // exchange
-		"user.signup", // routing key
-		false, false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
+	return p.publishConfirmed(ctx, "user.events", ExportSection, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+	})
+}
+
+// EnableAuditStream declares the topic exchange audit events are streamed to.
+// Until it is called PublishAuditEvent is a no-op.
+func (p *RabbitPublisher) EnableAuditStream(exchange string) error {
+	err := p.channel.ExchangeDeclare(
+		exchange, // name
+		"topic",  // type
+		true,     // durable
+		false,    // auto-deleted
+		false,    // internal
+		false,    // no-wait
+		nil,      // args
 	)
+	if err != nil {
+		logger.Log.Error("failed to declare audit exchange", zap.String("exchange", exchange), zap.Error(err))
+		return err
+	}
+
+	p.auditExchange = exchange
+	return nil
+}
+
+func (p *RabbitPublisher) PublishAuditEvent(ctx context.Context, event AuditEvent) error {
+	if p.auditExchange == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.publish(ctx, p.auditExchange, "audit."+event.EventType, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    event.CreatedAt,
+		Body:         body,
+	})
+}
+
+func (p *RabbitPublisher) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	return p.send(ctx, exchange, routingKey, msg, func(msg amqp.Publishing) error {
+		return p.channel.Publish(exchange, routingKey, false, false, msg)
+	})
+}
+
+// publishConfirmed publishes msg as mandatory and waits until the broker has
+// routed it to a queue and confirmed it. It fails if the message is nacked or
+// no queue is bound for it.
+func (p *RabbitPublisher) publishConfirmed(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	return p.send(ctx, exchange, routingKey, msg, func(msg amqp.Publishing) error {
+		return p.confirm.Publish(ctx, exchange, routingKey, true, msg)
+	})
+}
+
+// send hands msg to publish with the trace context and request id of ctx in
+// its headers and counts it.
+func (p *RabbitPublisher) send(ctx context.Context, exchange, routingKey string, msg amqp.Publishing, publish func(amqp.Publishing) error) error {
+	_, span := tracing.StartPublish(ctx, exchange, routingKey, &msg)
+	logger.InjectAMQP(ctx, &msg)
+	err := publish(msg)
+	metrics.ObservePublish(exchange, routingKey, err)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// Check reports whether the broker connection is still open.
+func (p *RabbitPublisher) Check(ctx context.Context) error {
+	if p.conn.IsClosed() {
+		return errConnectionClosed
+	}
+	return nil
 }
 
 func (p *RabbitPublisher) Close() {
+	if err := p.confirm.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ confirm channel: %v", err)
+	}
 	if err := p.channel.Close(); err != nil {
 		log.Printf("failed to close RabbitMQ channel: %v", err)
 	} else {
 		log.Println("RabbitMQ channel closed successfully")
 	}
+	if err := p.conn.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ connection: %v", err)
+	}
 }
@@ -0,0 +1,467 @@
+// Package memory is an in-memory Repository for tests. It enforces the unique
+// constraints of the schema and reports violations and missing rows with the
+// errors pgx would return, so callers handle them as they do in production.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type refreshToken struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+type repo struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	accounts      map[uuid.UUID]model.Account
+	refreshTokens map[string]refreshToken
+	// rolePermissions holds the permissions of each existing role.
+	rolePermissions map[string][]string
+	accountRoles    map[uuid.UUID]map[string]model.AccountRole
+	apiKeys         map[uuid.UUID]model.APIKey
+	auditLog        []model.AuditEvent
+}
+
+// NewRepository returns an empty repository with the roles and permissions the
+// migrations seed. now stands in for NOW(); nil means time.Now.
+func NewRepository(now func() time.Time) repository.Repository {
+	if now == nil {
+		now = time.Now
+	}
+	return &repo{
+		now:           now,
+		accounts:      make(map[uuid.UUID]model.Account),
+		refreshTokens: make(map[string]refreshToken),
+		rolePermissions: map[string][]string{
+			model.RoleAdmin: {model.PermissionAuditRead, model.PermissionProfilesRead, model.PermissionRolesManage, model.PermissionRolesRead},
+			model.RoleUser:  {model.PermissionProfilesRead},
+		},
+		accountRoles: make(map[uuid.UUID]map[string]model.AccountRole),
+		apiKeys:      make(map[uuid.UUID]model.APIKey),
+	}
+}
+
+// uniqueViolation is the error Postgres reports when constraint is violated.
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23503",
+		Message:        fmt.Sprintf("insert or update violates foreign key constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func (r *repo) Register(ctx context.Context, req *model.RegisterRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[req.ID]; ok {
+		return uniqueViolation("accounts_pkey")
+	}
+	for _, a := range r.accounts {
+		if a.Email == req.Email {
+			return uniqueViolation("accounts_email_key")
+		}
+	}
+
+	now := r.now()
+	r.accounts[req.ID] = model.Account{
+		ID:           req.ID,
+		Email:        req.Email,
+		PasswordHash: req.PasswordHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	r.accountRoles[req.ID] = map[string]model.AccountRole{
+		model.RoleUser: {Role: model.RoleUser, GrantedAt: now},
+	}
+	return nil
+}
+
+func (r *repo) GetAccountByEmail(ctx context.Context, email string) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range r.accounts {
+		if a.Email == email {
+			return &a, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *repo) CheckEmailExists(ctx context.Context, email string) (bool, error) {
+	_, err := r.GetAccountByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (r *repo) SaveRefreshToken(ctx context.Context, token, userID string, expiresAt time.Time) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.refreshTokens[token]; ok {
+		return uniqueViolation("refresh_tokens_pkey")
+	}
+	if _, ok := r.accounts[id]; !ok {
+		return foreignKeyViolation("refresh_tokens_user_id_fkey")
+	}
+	r.refreshTokens[token] = refreshToken{userID: id, expiresAt: expiresAt, createdAt: r.now()}
+	return nil
+}
+
+func (r *repo) ConsumeRefreshToken(ctx context.Context, token string) (uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.refreshTokens[token]
+	if !ok || !t.expiresAt.After(r.now()) {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	delete(r.refreshTokens, token)
+	return t.userID, nil
+}
+
+func (r *repo) DeleteRefreshToken(ctx context.Context, token string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.refreshTokens[token]
+	delete(r.refreshTokens, token)
+	return ok, nil
+}
+
+func (r *repo) ListSessions(ctx context.Context, accountID uuid.UUID) ([]model.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var sessions []model.Session
+	for _, t := range r.refreshTokens {
+		if t.userID == accountID && t.expiresAt.After(now) {
+			sessions = append(sessions, model.Session{CreatedAt: t.createdAt, ExpiresAt: t.expiresAt})
+		}
+	}
+	slices.SortFunc(sessions, func(a, b model.Session) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return sessions, nil
+}
+
+func (r *repo) GetAccountByID(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &a, nil
+}
+
+func (r *repo) RoleExists(ctx context.Context, role string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.rolePermissions[role]
+	return ok, nil
+}
+
+func (r *repo) GetAccountRoles(ctx context.Context, accountID uuid.UUID) ([]model.AccountRole, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var roles []model.AccountRole
+	for _, role := range r.accountRoles[accountID] {
+		roles = append(roles, role)
+	}
+	slices.SortFunc(roles, func(a, b model.AccountRole) int { return cmp.Compare(a.Role, b.Role) })
+	return roles, nil
+}
+
+func (r *repo) GetAccountPermissions(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var permissions []string
+	for role := range r.accountRoles[accountID] {
+		permissions = append(permissions, r.rolePermissions[role]...)
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions), nil
+}
+
+func (r *repo) GrantRole(ctx context.Context, input *model.GrantRoleInput) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rolePermissions[input.Role]; !ok {
+		// INSERT ... SELECT finds no role to insert.
+		return nil
+	}
+	if _, ok := r.accounts[input.AccountID]; !ok {
+		return foreignKeyViolation("account_roles_account_id_fkey")
+	}
+
+	roles := r.accountRoles[input.AccountID]
+	if roles == nil {
+		roles = make(map[string]model.AccountRole)
+		r.accountRoles[input.AccountID] = roles
+	}
+	if _, ok := roles[input.Role]; !ok {
+		roles[input.Role] = model.AccountRole{Role: input.Role, GrantedBy: input.GrantedBy, GrantedAt: r.now()}
+	}
+	return nil
+}
+
+func (r *repo) RevokeRole(ctx context.Context, accountID uuid.UUID, role string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.accountRoles[accountID][role]
+	delete(r.accountRoles[accountID], role)
+	return ok, nil
+}
+
+func (r *repo) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apiKeys[key.ID]; ok {
+		return uniqueViolation("api_keys_pkey")
+	}
+	for _, k := range r.apiKeys {
+		if k.Prefix == key.Prefix {
+			return uniqueViolation("api_keys_prefix_key")
+		}
+	}
+	if _, ok := r.accounts[key.AccountID]; !ok {
+		return foreignKeyViolation("api_keys_account_id_fkey")
+	}
+
+	key.CreatedAt = r.now()
+	stored := *key
+	stored.Scopes = slices.Clone(key.Scopes)
+	stored.LastUsedAt, stored.RevokedAt = nil, nil
+	r.apiKeys[key.ID] = stored
+	return nil
+}
+
+func (r *repo) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []model.APIKey
+	for _, k := range r.apiKeys {
+		if k.AccountID == accountID {
+			k.Scopes = slices.Clone(k.Scopes)
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b model.APIKey) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return keys, nil
+}
+
+func (r *repo) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.apiKeys {
+		if k.Prefix == prefix {
+			k.Scopes = slices.Clone(k.Scopes)
+			return &k, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (r *repo) RevokeAPIKey(ctx context.Context, id, accountID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.apiKeys[id]
+	if !ok || k.AccountID != accountID || k.RevokedAt != nil {
+		return false, nil
+	}
+	now := r.now()
+	k.RevokedAt = &now
+	r.apiKeys[id] = k
+	return true, nil
+}
+
+func (r *repo) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k, ok := r.apiKeys[id]; ok {
+		k.LastUsedAt = &usedAt
+		r.apiKeys[id] = k
+	}
+	return nil
+}
+
+func (r *repo) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.accounts[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	a.PasswordHash, a.UpdatedAt = passwordHash, r.now()
+	r.accounts[id] = a
+	for token, t := range r.refreshTokens {
+		if t.userID == id {
+			delete(r.refreshTokens, token)
+		}
+	}
+	return nil
+}
+
+func (r *repo) SoftDeleteAccount(ctx context.Context, id uuid.UUID, purgeAfter time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if a, ok := r.accounts[id]; ok && a.DeletedAt == nil {
+		a.DeletedAt, a.PurgeAfter, a.UpdatedAt = &now, &purgeAfter, now
+		r.accounts[id] = a
+	}
+	for token, t := range r.refreshTokens {
+		if t.userID == id {
+			delete(r.refreshTokens, token)
+		}
+	}
+	for keyID, k := range r.apiKeys {
+		if k.AccountID == id && k.RevokedAt == nil {
+			k.RevokedAt = &now
+			r.apiKeys[keyID] = k
+		}
+	}
+	return nil
+}
+
+func (r *repo) RestoreAccount(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	a, ok := r.accounts[id]
+	if !ok || a.DeletedAt == nil || !a.PurgeAfter.After(now) {
+		return false, nil
+	}
+	a.DeletedAt, a.PurgeAfter, a.UpdatedAt = nil, nil, now
+	r.accounts[id] = a
+	return true, nil
+}
+
+func (r *repo) ListPurgeableAccounts(ctx context.Context, now time.Time, limit int) ([]model.Account, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var accounts []model.Account
+	for _, a := range r.accounts {
+		if a.DeletedAt != nil && !a.PurgeAfter.After(now) {
+			accounts = append(accounts, a)
+		}
+	}
+	slices.SortFunc(accounts, func(a, b model.Account) int { return a.PurgeAfter.Compare(*b.PurgeAfter) })
+	return accounts[:min(limit, len(accounts))], nil
+}
+
+func (r *repo) HardDeleteAccount(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.accounts[id]
+	if !ok || a.DeletedAt == nil || a.PurgeAfter.After(now) {
+		return false, nil
+	}
+
+	// ON DELETE CASCADE and SET NULL.
+	delete(r.accounts, id)
+	delete(r.accountRoles, id)
+	for token, t := range r.refreshTokens {
+		if t.userID == id {
+			delete(r.refreshTokens, token)
+		}
+	}
+	for keyID, k := range r.apiKeys {
+		if k.AccountID == id {
+			delete(r.apiKeys, keyID)
+		}
+	}
+	for _, roles := range r.accountRoles {
+		for name, role := range roles {
+			if role.GrantedBy != nil && *role.GrantedBy == id {
+				role.GrantedBy = nil
+				roles[name] = role
+			}
+		}
+	}
+	for i, e := range r.auditLog {
+		if (e.AccountID != nil && *e.AccountID == id) || strings.EqualFold(e.Email, a.Email) {
+			e.Email, e.IPAddress, e.UserAgent = "", "", ""
+			r.auditLog[i] = e
+		}
+	}
+	return true, nil
+}
+
+func (r *repo) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.auditLog)) + 1
+	event.CreatedAt = r.now()
+	r.auditLog = append(r.auditLog, *event)
+	return nil
+}
+
+func (r *repo) ListAuditEvents(ctx context.Context, filter *model.AuditEventFilter) ([]model.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []model.AuditEvent
+	for i := len(r.auditLog) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		e := r.auditLog[i]
+		switch {
+		case filter.AccountID != nil && (e.AccountID == nil || *e.AccountID != *filter.AccountID),
+			filter.EventType != "" && e.EventType != filter.EventType,
+			filter.Outcome != "" && e.Outcome != filter.Outcome,
+			filter.Since != nil && e.CreatedAt.Before(*filter.Since),
+			filter.Until != nil && !e.CreatedAt.Before(*filter.Until),
+			filter.BeforeID > 0 && e.ID >= filter.BeforeID:
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
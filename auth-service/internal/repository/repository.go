@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,6 +18,38 @@ type Repository interface {
 	GetAccountByEmail(ctx context.Context, email string) (*model.Account, error)
 	CheckEmailExists(ctx context.Context, email string) (bool, error)
 	SaveRefreshToken(ctx context.Context, token, userID string, expiresAt time.Time) error
+	ConsumeRefreshToken(ctx context.Context, token string) (uuid.UUID, error)
+	DeleteRefreshToken(ctx context.Context, token string) (bool, error)
+	// ListSessions returns the unexpired refresh tokens of the account, newest first.
+	ListSessions(ctx context.Context, accountID uuid.UUID) ([]model.Session, error)
+	// UpdatePassword replaces the password hash and signs the account out of
+	// every session.
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+
+	// Roles
+	GetAccountByID(ctx context.Context, id uuid.UUID) (*model.Account, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+	GetAccountRoles(ctx context.Context, accountID uuid.UUID) ([]model.AccountRole, error)
+	GetAccountPermissions(ctx context.Context, accountID uuid.UUID) ([]string, error)
+	GrantRole(ctx context.Context, input *model.GrantRoleInput) error
+	RevokeRole(ctx context.Context, accountID uuid.UUID, role string) (bool, error)
+
+	// API keys
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]model.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, accountID uuid.UUID) (bool, error)
+	TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+
+	// Account deletion
+	SoftDeleteAccount(ctx context.Context, id uuid.UUID, purgeAfter time.Time) error
+	RestoreAccount(ctx context.Context, id uuid.UUID) (bool, error)
+	ListPurgeableAccounts(ctx context.Context, now time.Time, limit int) ([]model.Account, error)
+	HardDeleteAccount(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
+
+	// Audit log
+	InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter *model.AuditEventFilter) ([]model.AuditEvent, error)
 }
 
 type repository struct {
@@ -26,17 +62,41 @@ func NewRepository(db *pgxpool.Pool) Repository {
 	}
 }
 
+const accountColumns = "id, email, password_hash, created_at, updated_at, deleted_at, purge_after"
+
+func scanAccount(row pgx.Row, account *model.Account) error {
+	return row.Scan(&account.ID, &account.Email, &account.PasswordHash, &account.CreatedAt, &account.UpdatedAt,
+		&account.DeletedAt, &account.PurgeAfter)
+}
+
+// Register creates the account together with its default role.
 func (r *repository) Register(ctx context.Context, req *model.RegisterRequest) error {
-	_, err := r.db.Exec(ctx, "INSERT INTO accounts (id, email, password_hash) VALUES ($1, $2, $3)",
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "INSERT INTO accounts (id, email, password_hash) VALUES ($1, $2, $3)",
 		req.ID, req.Email, req.PasswordHash)
-	return err
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
+		"INSERT INTO account_roles (account_id, role_id) SELECT $1, id FROM roles WHERE name = $2",
+		req.ID, model.RoleUser)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *repository) Login(ctx context.Context, input *model.LoginInput) (*model.Account, error) {
 	var account model.Account
 
-	err := r.db.QueryRow(ctx, "SELECT id, email, password_hash, created_at, updated_at FROM accounts WHERE email = $1", input.Email).
-		Scan(&account.ID, &account.Email, &account.PasswordHash, &account.CreatedAt, &account.UpdatedAt)
+	err := scanAccount(r.db.QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE email = $1", input.Email), &account)
 
 	if err != nil {
 		return nil, err
@@ -47,7 +107,7 @@ func (r *repository) Login(ctx context.Context, input *model.LoginInput) (*model
 
 func (r *repository) GetAccountByEmail(ctx context.Context, email string) (*model.Account, error) {
 	var account model.Account
-	err := r.db.QueryRow(ctx, "SELECT id, email, password_hash, created_at, updated_at FROM accounts WHERE email = $1", email).Scan(&account.ID, &account.Email, &account.PasswordHash, &account.CreatedAt, &account.UpdatedAt)
+	err := scanAccount(r.db.QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE email = $1", email), &account)
 	if err != nil {
 		return nil, err
 	}
@@ -73,12 +133,386 @@ func (r *repository) SaveRefreshToken(ctx context.Context, token, userID string,
 	return err
 }
 
-// func (r *repository) GetAccountById(ctx context.Context, id uuid.UUID) (*model.Account, error) {
-// 	var account model.Account
-// 	err := r.db.QueryRow(ctx, "SELECT id, email, created_at, updated_at FROM accounts WHERE id = $1", email).Scan(&account.ID, &account.Email, &account.CreatedAt, &account.UpdatedAt)
-// 	if err != nil {
-// 		return nil, err
-// 	}
+// ConsumeRefreshToken deletes an unexpired refresh token and returns its owner,
+// so that each refresh token can only be used once.
+func (r *repository) ConsumeRefreshToken(ctx context.Context, token string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRow(ctx,
+		"DELETE FROM refresh_tokens WHERE token = $1 AND expires_at > NOW() RETURNING user_id",
+		token).Scan(&userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func (r *repository) DeleteRefreshToken(ctx context.Context, token string) (bool, error) {
+	tag, err := r.db.Exec(ctx, "DELETE FROM refresh_tokens WHERE token = $1", token)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) ListSessions(ctx context.Context, accountID uuid.UUID) ([]model.Session, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT created_at, expires_at FROM refresh_tokens
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.Session
+	for rows.Next() {
+		var s model.Session
+		if err := rows.Scan(&s.CreatedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *repository) GetAccountByID(ctx context.Context, id uuid.UUID) (*model.Account, error) {
+	var account model.Account
+	err := scanAccount(r.db.QueryRow(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = $1", id), &account)
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (r *repository) RoleExists(ctx context.Context, role string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", role).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *repository) GetAccountRoles(ctx context.Context, accountID uuid.UUID) ([]model.AccountRole, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT r.name, ar.granted_by, ar.granted_at
+		FROM account_roles ar
+		JOIN roles r ON r.id = ar.role_id
+		WHERE ar.account_id = $1
+		ORDER BY r.name`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []model.AccountRole
+	for rows.Next() {
+		var role model.AccountRole
+		if err := rows.Scan(&role.Role, &role.GrantedBy, &role.GrantedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *repository) GetAccountPermissions(ctx context.Context, accountID uuid.UUID) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT p.name
+		FROM account_roles ar
+		JOIN role_permissions rp ON rp.role_id = ar.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE ar.account_id = $1
+		ORDER BY p.name`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *repository) GrantRole(ctx context.Context, input *model.GrantRoleInput) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO account_roles (account_id, role_id, granted_by)
+		SELECT $1, id, $3 FROM roles WHERE name = $2
+		ON CONFLICT (account_id, role_id) DO NOTHING`,
+		input.AccountID, input.Role, input.GrantedBy)
+	return err
+}
+
+// RevokeRole reports whether the account held the role.
+func (r *repository) RevokeRole(ctx context.Context, accountID uuid.UUID, role string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM account_roles
+		WHERE account_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`,
+		accountID, role)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+const apiKeyColumns = "id, account_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+func scanAPIKey(row pgx.Row, key *model.APIKey) error {
+	return row.Scan(&key.ID, &key.AccountID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+}
+
+func (r *repository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO api_keys (id, account_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`,
+		key.ID, key.AccountID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt).
+		Scan(&key.CreatedAt)
+}
+
+func (r *repository) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]model.APIKey, error) {
+	rows, err := r.db.Query(ctx,
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE account_id = $1 ORDER BY created_at DESC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var key model.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := scanAPIKey(r.db.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix), &key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// RevokeAPIKey reports whether an active key with that id belonged to the account.
+func (r *repository) RevokeAPIKey(ctx context.Context, id, accountID uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx,
+		"UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND account_id = $2 AND revoked_at IS NULL",
+		id, accountID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) TouchAPIKey(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.db.Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt)
+	return err
+}
+
+func (r *repository) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO auth_audit_log (event_type, account_id, email, ip_address, user_agent, outcome, reason)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''))
+		RETURNING id, created_at`,
+		event.EventType, event.AccountID, event.Email, event.IPAddress, event.UserAgent, event.Outcome, event.Reason).
+		Scan(&event.ID, &event.CreatedAt)
+}
+
+func (r *repository) ListAuditEvents(ctx context.Context, filter *model.AuditEventFilter) ([]model.AuditEvent, error) {
+	query := `
+		SELECT id, event_type, account_id, COALESCE(email, ''), COALESCE(ip_address, ''),
+			COALESCE(user_agent, ''), outcome, COALESCE(reason, ''), created_at
+		FROM auth_audit_log
+		WHERE 1 = 1`
+	var args []any
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		query += fmt.Sprintf(" AND "+cond, len(args))
+	}
+
+	if filter.AccountID != nil {
+		addCond("account_id = $%d", *filter.AccountID)
+	}
+	if filter.EventType != "" {
+		addCond("event_type = $%d", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		addCond("outcome = $%d", filter.Outcome)
+	}
+	if filter.Since != nil {
+		addCond("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		addCond("created_at < $%d", *filter.Until)
+	}
+	if filter.BeforeID > 0 {
+		addCond("id < $%d", filter.BeforeID)
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.AuditEvent
+	for rows.Next() {
+		var e model.AuditEvent
+		if err := rows.Scan(&e.ID, &e.EventType, &e.AccountID, &e.Email, &e.IPAddress,
+			&e.UserAgent, &e.Outcome, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// UpdatePassword replaces the password hash and, in the same transaction,
+// drops the account's refresh tokens so a stolen session does not outlive it.
+func (r *repository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE accounts SET password_hash = $2, updated_at = NOW() WHERE id = $1", id, passwordHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM refresh_tokens WHERE user_id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SoftDeleteAccount marks the account as deleted and, in the same transaction,
+// signs it out everywhere by dropping its refresh tokens and revoking its API keys.
+func (r *repository) SoftDeleteAccount(ctx context.Context, id uuid.UUID, purgeAfter time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		"UPDATE accounts SET deleted_at = NOW(), purge_after = $2, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL",
+		id, purgeAfter)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM refresh_tokens WHERE user_id = $1", id); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE account_id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RestoreAccount reports whether the account was pending deletion and still
+// within its grace period.
+func (r *repository) RestoreAccount(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE accounts SET deleted_at = NULL, purge_after = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL AND purge_after > NOW()`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) ListPurgeableAccounts(ctx context.Context, now time.Time, limit int) ([]model.Account, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+accountColumns+` FROM accounts
+		WHERE deleted_at IS NOT NULL AND purge_after <= $1
+		ORDER BY purge_after
+		LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []model.Account
+	for rows.Next() {
+		var account model.Account
+		if err := scanAccount(rows, &account); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
+// HardDeleteAccount removes the account if it is still past its grace period.
+// Refresh tokens, roles and API keys go with it through ON DELETE CASCADE. In
+// the same transaction, the email, IP address and user agent are scrubbed from
+// the audit events of the account and from those naming its email, such as
+// failed logins.
+func (r *repository) HardDeleteAccount(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+	err = tx.QueryRow(ctx,
+		"DELETE FROM accounts WHERE id = $1 AND deleted_at IS NOT NULL AND purge_after <= $2 RETURNING email",
+		id, now).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
 
-// 	return &account, nil
-// }
+	_, err = tx.Exec(ctx, `
+		UPDATE auth_audit_log SET email = NULL, ip_address = NULL, user_agent = NULL
+		WHERE (account_id = $1 OR lower(email) = lower($2))
+			AND (email IS NOT NULL OR ip_address IS NOT NULL OR user_agent IS NOT NULL)`,
+		id, email)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,392 @@
+package repository_test
+
+import (
+	"context"
+	stdErrors "errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/db/migrations"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository/memory"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/pgtest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Run(m)) }
+
+// forEachRepository runs test against the Postgres repository and against the
+// in-memory fake the service tests use, so that the two stay in agreement.
+// The Postgres run is skipped when no server is available.
+func forEachRepository(t *testing.T, test func(t *testing.T, ctx context.Context, repo repository.Repository)) {
+	t.Run("postgres", func(t *testing.T) {
+		test(t, context.Background(), repository.NewRepository(pgtest.New(t, migrations.FS)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, context.Background(), memory.NewRepository(nil))
+	})
+}
+
+func register(t *testing.T, ctx context.Context, repo repository.Repository, email string) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	if err := repo.Register(ctx, &model.RegisterRequest{ID: id, Email: email, PasswordHash: "hash"}); err != nil {
+		t.Fatalf("Register(%q): %v", email, err)
+	}
+	return id
+}
+
+func wantUniqueViolation(t *testing.T, err error, constraint string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	if !stdErrors.As(err, &pgErr) || pgErr.Code != "23505" || pgErr.ConstraintName != constraint {
+		t.Fatalf("error = %v, want a unique violation of %s", err, constraint)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		id := register(t, ctx, repo, "alice@example.com")
+
+		account, err := repo.GetAccountByEmail(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("GetAccountByEmail: %v", err)
+		}
+		if account.ID != id || account.PasswordHash != "hash" || account.DeletedAt != nil {
+			t.Errorf("account = %+v, want %s with its hash and not deleted", account, id)
+		}
+		if _, err := repo.GetAccountByEmail(ctx, "Alice@example.com"); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetAccountByEmail with other case: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if _, err := repo.GetAccountByID(ctx, uuid.New()); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetAccountByID of unknown id: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if exists, err := repo.CheckEmailExists(ctx, "alice@example.com"); err != nil || !exists {
+			t.Errorf("CheckEmailExists = %v, %v, want true", exists, err)
+		}
+
+		err = repo.Register(ctx, &model.RegisterRequest{ID: uuid.New(), Email: "alice@example.com", PasswordHash: "hash"})
+		wantUniqueViolation(t, err, "accounts_email_key")
+	})
+}
+
+func TestRefreshTokens(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		id := register(t, ctx, repo, "alice@example.com")
+		now := time.Now()
+		for token, expiresAt := range map[string]time.Time{
+			"current": now.Add(time.Hour),
+			"expired": now.Add(-time.Minute),
+			"logout":  now.Add(time.Hour),
+		} {
+			if err := repo.SaveRefreshToken(ctx, token, id.String(), expiresAt); err != nil {
+				t.Fatalf("SaveRefreshToken(%q): %v", token, err)
+			}
+		}
+
+		sessions, err := repo.ListSessions(ctx, id)
+		if err != nil {
+			t.Fatalf("ListSessions: %v", err)
+		}
+		if len(sessions) != 2 {
+			t.Errorf("ListSessions returned %d sessions, want the 2 unexpired ones", len(sessions))
+		}
+
+		if owner, err := repo.ConsumeRefreshToken(ctx, "current"); err != nil || owner != id {
+			t.Errorf("ConsumeRefreshToken = %s, %v, want %s", owner, err, id)
+		}
+		if _, err := repo.ConsumeRefreshToken(ctx, "current"); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("second ConsumeRefreshToken: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if _, err := repo.ConsumeRefreshToken(ctx, "expired"); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("ConsumeRefreshToken of expired token: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+
+		if deleted, err := repo.DeleteRefreshToken(ctx, "logout"); err != nil || !deleted {
+			t.Errorf("DeleteRefreshToken = %v, %v, want true", deleted, err)
+		}
+		if deleted, err := repo.DeleteRefreshToken(ctx, "logout"); err != nil || deleted {
+			t.Errorf("second DeleteRefreshToken = %v, %v, want false", deleted, err)
+		}
+	})
+}
+
+func TestRoles(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		admin := register(t, ctx, repo, "admin@example.com")
+		alice := register(t, ctx, repo, "alice@example.com")
+
+		roles, err := repo.GetAccountRoles(ctx, alice)
+		if err != nil {
+			t.Fatalf("GetAccountRoles: %v", err)
+		}
+		if len(roles) != 1 || roles[0].Role != model.RoleUser || roles[0].GrantedBy != nil {
+			t.Errorf("roles of a new account = %+v, want only %q", roles, model.RoleUser)
+		}
+		if exists, err := repo.RoleExists(ctx, "superuser"); err != nil || exists {
+			t.Errorf("RoleExists(superuser) = %v, %v, want false", exists, err)
+		}
+
+		for range 2 {
+			if err := repo.GrantRole(ctx, &model.GrantRoleInput{AccountID: alice, Role: model.RoleAdmin, GrantedBy: &admin}); err != nil {
+				t.Fatalf("GrantRole: %v", err)
+			}
+		}
+		roles, err = repo.GetAccountRoles(ctx, alice)
+		if err != nil {
+			t.Fatalf("GetAccountRoles: %v", err)
+		}
+		if len(roles) != 2 || roles[0].Role != model.RoleAdmin || roles[0].GrantedBy == nil || *roles[0].GrantedBy != admin {
+			t.Errorf("roles after grant = %+v, want %q granted by %s and %q", roles, model.RoleAdmin, admin, model.RoleUser)
+		}
+
+		permissions, err := repo.GetAccountPermissions(ctx, alice)
+		if err != nil {
+			t.Fatalf("GetAccountPermissions: %v", err)
+		}
+		want := []string{model.PermissionAuditRead, model.PermissionProfilesRead, model.PermissionRolesManage, model.PermissionRolesRead}
+		if !slices.Equal(permissions, want) {
+			t.Errorf("permissions = %v, want %v", permissions, want)
+		}
+
+		if revoked, err := repo.RevokeRole(ctx, alice, model.RoleAdmin); err != nil || !revoked {
+			t.Errorf("RevokeRole = %v, %v, want true", revoked, err)
+		}
+		if revoked, err := repo.RevokeRole(ctx, alice, model.RoleAdmin); err != nil || revoked {
+			t.Errorf("second RevokeRole = %v, %v, want false", revoked, err)
+		}
+		want = []string{model.PermissionProfilesRead}
+		if permissions, err := repo.GetAccountPermissions(ctx, alice); err != nil || !slices.Equal(permissions, want) {
+			t.Errorf("permissions after revoke = %v, %v, want %v", permissions, err, want)
+		}
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := register(t, ctx, repo, "alice@example.com")
+		bob := register(t, ctx, repo, "bob@example.com")
+
+		key := &model.APIKey{ID: uuid.New(), AccountID: alice, Name: "ci", Prefix: "abc123", KeyHash: "hash", Scopes: []string{"messages:read"}}
+		if err := repo.CreateAPIKey(ctx, key); err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+		if key.CreatedAt.IsZero() {
+			t.Error("CreateAPIKey did not set CreatedAt")
+		}
+		err := repo.CreateAPIKey(ctx, &model.APIKey{ID: uuid.New(), AccountID: bob, Name: "other", Prefix: "abc123", KeyHash: "hash"})
+		wantUniqueViolation(t, err, "api_keys_prefix_key")
+
+		got, err := repo.GetAPIKeyByPrefix(ctx, "abc123")
+		if err != nil {
+			t.Fatalf("GetAPIKeyByPrefix: %v", err)
+		}
+		if got.ID != key.ID || !slices.Equal(got.Scopes, key.Scopes) || got.LastUsedAt != nil {
+			t.Errorf("GetAPIKeyByPrefix = %+v, want %+v", got, key)
+		}
+
+		if err := repo.TouchAPIKey(ctx, key.ID, time.Now()); err != nil {
+			t.Fatalf("TouchAPIKey: %v", err)
+		}
+		if revoked, err := repo.RevokeAPIKey(ctx, key.ID, bob); err != nil || revoked {
+			t.Errorf("RevokeAPIKey by another account = %v, %v, want false", revoked, err)
+		}
+		if revoked, err := repo.RevokeAPIKey(ctx, key.ID, alice); err != nil || !revoked {
+			t.Errorf("RevokeAPIKey = %v, %v, want true", revoked, err)
+		}
+
+		keys, err := repo.ListAPIKeys(ctx, alice)
+		if err != nil {
+			t.Fatalf("ListAPIKeys: %v", err)
+		}
+		if len(keys) != 1 || keys[0].LastUsedAt == nil || keys[0].RevokedAt == nil {
+			t.Errorf("ListAPIKeys = %+v, want the key used and revoked", keys)
+		}
+	})
+}
+
+func TestAccountDeletion(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := register(t, ctx, repo, "alice@example.com")
+		bob := register(t, ctx, repo, "bob@example.com")
+		now := time.Now()
+		if err := repo.SaveRefreshToken(ctx, "alice-token", alice.String(), now.Add(time.Hour)); err != nil {
+			t.Fatalf("SaveRefreshToken: %v", err)
+		}
+		key := &model.APIKey{ID: uuid.New(), AccountID: alice, Name: "ci", Prefix: "abc123", KeyHash: "hash"}
+		if err := repo.CreateAPIKey(ctx, key); err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+
+		if err := repo.SoftDeleteAccount(ctx, alice, now.Add(time.Hour)); err != nil {
+			t.Fatalf("SoftDeleteAccount: %v", err)
+		}
+		if _, err := repo.ConsumeRefreshToken(ctx, "alice-token"); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("ConsumeRefreshToken after delete: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if got, err := repo.GetAPIKeyByPrefix(ctx, "abc123"); err != nil || got.RevokedAt == nil {
+			t.Errorf("API key after delete = %+v, %v, want it revoked", got, err)
+		}
+		if restored, err := repo.RestoreAccount(ctx, alice); err != nil || !restored {
+			t.Fatalf("RestoreAccount = %v, %v, want true", restored, err)
+		}
+		if restored, err := repo.RestoreAccount(ctx, alice); err != nil || restored {
+			t.Errorf("RestoreAccount of an active account = %v, %v, want false", restored, err)
+		}
+
+		for _, id := range []uuid.UUID{alice, bob} {
+			if err := repo.SoftDeleteAccount(ctx, id, now.Add(-time.Minute)); err != nil {
+				t.Fatalf("SoftDeleteAccount: %v", err)
+			}
+		}
+		if restored, err := repo.RestoreAccount(ctx, alice); err != nil || restored {
+			t.Errorf("RestoreAccount past the grace period = %v, %v, want false", restored, err)
+		}
+
+		purgeable, err := repo.ListPurgeableAccounts(ctx, now, 1)
+		if err != nil {
+			t.Fatalf("ListPurgeableAccounts: %v", err)
+		}
+		if len(purgeable) != 1 {
+			t.Fatalf("ListPurgeableAccounts returned %d accounts, want the limit of 1", len(purgeable))
+		}
+		if deleted, err := repo.HardDeleteAccount(ctx, alice, now); err != nil || !deleted {
+			t.Errorf("HardDeleteAccount = %v, %v, want true", deleted, err)
+		}
+		if _, err := repo.GetAPIKeyByPrefix(ctx, "abc123"); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("API key after purge: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if purgeable, err := repo.ListPurgeableAccounts(ctx, now, 10); err != nil || len(purgeable) != 1 || purgeable[0].ID != bob {
+			t.Errorf("ListPurgeableAccounts after purge = %+v, %v, want only %s", purgeable, err, bob)
+		}
+	})
+}
+
+func TestAuditEvents(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := register(t, ctx, repo, "alice@example.com")
+		events := []*model.AuditEvent{
+			{EventType: model.AuditEventRegister, AccountID: &alice, Email: "alice@example.com", Outcome: "success"},
+			{EventType: model.AuditEventLogin, Email: "alice@example.com", IPAddress: "192.0.2.1", Outcome: "failure", Reason: "invalid_credentials"},
+			{EventType: model.AuditEventLogin, AccountID: &alice, Email: "alice@example.com", IPAddress: "192.0.2.1", Outcome: "success"},
+		}
+		for _, event := range events {
+			if err := repo.InsertAuditEvent(ctx, event); err != nil {
+				t.Fatalf("InsertAuditEvent: %v", err)
+			}
+		}
+		if events[0].ID == 0 || events[1].ID <= events[0].ID || events[0].CreatedAt.IsZero() {
+			t.Errorf("InsertAuditEvent assigned ids %d, %d and created_at %v", events[0].ID, events[1].ID, events[0].CreatedAt)
+		}
+
+		tests := []struct {
+			name   string
+			filter model.AuditEventFilter
+			want   []int64
+		}{
+			{name: "all", filter: model.AuditEventFilter{Limit: 10}, want: []int64{events[2].ID, events[1].ID, events[0].ID}},
+			{name: "limit", filter: model.AuditEventFilter{Limit: 1}, want: []int64{events[2].ID}},
+			{name: "account", filter: model.AuditEventFilter{AccountID: &alice, Limit: 10}, want: []int64{events[2].ID, events[0].ID}},
+			{name: "type and outcome", filter: model.AuditEventFilter{EventType: model.AuditEventLogin, Outcome: "failure", Limit: 10}, want: []int64{events[1].ID}},
+			{name: "before id", filter: model.AuditEventFilter{BeforeID: events[2].ID, Limit: 10}, want: []int64{events[1].ID, events[0].ID}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := repo.ListAuditEvents(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("ListAuditEvents: %v", err)
+				}
+				var ids []int64
+				for _, e := range got {
+					ids = append(ids, e.ID)
+				}
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("ListAuditEvents ids = %v, want %v", ids, tt.want)
+				}
+			})
+		}
+
+		got, err := repo.ListAuditEvents(ctx, &model.AuditEventFilter{EventType: model.AuditEventLogin, Outcome: "failure", Limit: 1})
+		if err != nil || len(got) != 1 {
+			t.Fatalf("ListAuditEvents = %v, %v", got, err)
+		}
+		if got[0].AccountID != nil || got[0].IPAddress != "192.0.2.1" || got[0].Reason != "invalid_credentials" || got[0].UserAgent != "" {
+			t.Errorf("audit event = %+v, want the failed login as inserted", got[0])
+		}
+	})
+}
+
+func TestHardDeleteAccountScrubsAuditLog(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := register(t, ctx, repo, "alice@example.com")
+		bob := register(t, ctx, repo, "bob@example.com")
+		events := []*model.AuditEvent{
+			{EventType: model.AuditEventRegister, AccountID: &alice, Email: "alice@example.com", IPAddress: "192.0.2.1", UserAgent: "curl", Outcome: "success"},
+			{EventType: model.AuditEventLogin, Email: "Alice@Example.com", IPAddress: "192.0.2.2", Outcome: "failure", Reason: "invalid_credentials"},
+			{EventType: model.AuditEventLogin, AccountID: &bob, Email: "bob@example.com", IPAddress: "192.0.2.3", UserAgent: "curl", Outcome: "success"},
+		}
+		for _, event := range events {
+			if err := repo.InsertAuditEvent(ctx, event); err != nil {
+				t.Fatalf("InsertAuditEvent: %v", err)
+			}
+		}
+
+		now := time.Now()
+		if err := repo.SoftDeleteAccount(ctx, alice, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("SoftDeleteAccount: %v", err)
+		}
+		if deleted, err := repo.HardDeleteAccount(ctx, alice, now); err != nil || !deleted {
+			t.Fatalf("HardDeleteAccount = %v, %v, want true", deleted, err)
+		}
+
+		got, err := repo.ListAuditEvents(ctx, &model.AuditEventFilter{Limit: 10})
+		if err != nil || len(got) != 3 {
+			t.Fatalf("ListAuditEvents = %v, %v, want 3 events", got, err)
+		}
+		// Newest first: bob's login, then alice's failed login and registration.
+		for _, e := range got[1:] {
+			if e.Email != "" || e.IPAddress != "" || e.UserAgent != "" {
+				t.Errorf("audit event %d of the purged account kept %q, %q, %q", e.ID, e.Email, e.IPAddress, e.UserAgent)
+			}
+		}
+		if got[1].Reason != "invalid_credentials" || got[2].AccountID == nil || *got[2].AccountID != alice {
+			t.Errorf("scrubbed events = %+v, want the rest of the trail kept", got[1:])
+		}
+		if got[0].Email != "bob@example.com" || got[0].IPAddress != "192.0.2.3" || got[0].UserAgent != "curl" {
+			t.Errorf("audit event of another account = %+v, want it untouched", got[0])
+		}
+	})
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.New(t, migrations.FS)
+	repo := repository.NewRepository(pool)
+	alice := register(t, ctx, repo, "alice@example.com")
+	event := &model.AuditEvent{EventType: model.AuditEventRegister, AccountID: &alice, Email: "alice@example.com", IPAddress: "192.0.2.1", Outcome: "success"}
+	if err := repo.InsertAuditEvent(ctx, event); err != nil {
+		t.Fatalf("InsertAuditEvent: %v", err)
+	}
+
+	for _, stmt := range []string{
+		"UPDATE auth_audit_log SET reason = 'edited' WHERE id = $1",
+		"UPDATE auth_audit_log SET email = 'mallory@example.com' WHERE id = $1",
+		"UPDATE auth_audit_log SET email = NULL, ip_address = NULL, user_agent = NULL, outcome = 'failure' WHERE id = $1",
+		"DELETE FROM auth_audit_log WHERE id = $1",
+	} {
+		if _, err := pool.Exec(ctx, stmt, event.ID); err == nil {
+			t.Errorf("%s succeeded, want it refused", stmt)
+		}
+	}
+	if _, err := pool.Exec(ctx, "TRUNCATE auth_audit_log"); err == nil {
+		t.Error("TRUNCATE succeeded, want it refused")
+	}
+	if _, err := pool.Exec(ctx, "UPDATE auth_audit_log SET email = NULL, ip_address = NULL, user_agent = NULL WHERE id = $1", event.ID); err != nil {
+		t.Errorf("scrubbing personal data: %v", err)
+	}
+}
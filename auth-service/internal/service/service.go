@@ -2,16 +2,24 @@ package service
 
 import (
 	"context"
+	stdErrors "errors"
+	"slices"
+	"time"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/metrics"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/apikey"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/auth"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/errors"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/hasher"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 
 	"github.com/go-playground/validator/v10"
@@ -20,44 +28,78 @@ import (
 type Service interface {
 	Register(ctx context.Context, input *model.RegisterInput) (*model.RegisterResponse, error)
 	Login(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (*model.AuthResponse, error)
+	Logout(ctx context.Context, refreshToken string) (uuid.UUID, error)
+	ChangePassword(ctx context.Context, input *model.ChangePasswordInput) error
+
+	// Roles
+	GrantRole(ctx context.Context, input *model.GrantRoleInput) error
+	RevokeRole(ctx context.Context, accountID uuid.UUID, role string) error
+	ListAccountRoles(ctx context.Context, accountID uuid.UUID) ([]model.AccountRole, error)
+
+	// API keys
+	CreateAPIKey(ctx context.Context, input *model.CreateAPIKeyInput) (*model.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, accountID, keyID uuid.UUID) error
+	ExchangeAPIKey(ctx context.Context, key string) (*model.ServiceTokenResponse, error)
+
+	// Account deletion
+	DeleteAccount(ctx context.Context, input *model.DeleteAccountInput) (*model.DeleteAccountResponse, error)
+	RestoreAccount(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error)
+
+	// Audit log
+	ListAuditEvents(ctx context.Context, filter *model.AuditEventFilter) (events []model.AuditEvent, nextCursor int64, err error)
+
+	// ExportAccountData collects what auth-service holds about the account for
+	// a personal data export.
+	ExportAccountData(ctx context.Context, accountID uuid.UUID) (*model.AccountExport, error)
 }
 
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
 type service struct {
 	repo       repository.Repository
 	tokenMaker auth.TokenMaker
 	hasher     hasher.Hasher
+
+	// deletionGrace is how long a deleted account can still be restored.
+	deletionGrace time.Duration
 }
 
-func NewService(repo repository.Repository, tokenMaker auth.TokenMaker, hasher hasher.Hasher) Service {
+func NewService(repo repository.Repository, tokenMaker auth.TokenMaker, hasher hasher.Hasher, deletionGrace time.Duration) Service {
 	return &service{
-		repo:       repo,
-		tokenMaker: tokenMaker,
-		hasher:     hasher,
+		repo:          repo,
+		tokenMaker:    tokenMaker,
+		hasher:        hasher,
+		deletionGrace: deletionGrace,
 	}
 }
 
 func (s *service) Register(ctx context.Context, input *model.RegisterInput) (*model.RegisterResponse, error) {
 	// Validate input
 	if err := validator.New().Struct(input); err != nil {
-		logger.Log.Error("validation error: ", zap.Error(err))
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
 		return nil, err
 	}
 
 	// Check if email exists
 	exists, err := s.repo.CheckEmailExists(ctx, input.Email)
 	if err != nil {
-		logger.Log.Error("error checking email existence", zap.Error(err))
+		logger.FromContext(ctx).Error("error checking email existence", zap.Error(err))
 		return nil, err
 	}
 
 	if exists {
-		logger.Log.Error("email already exists", zap.String("email", input.Email))
+		logger.FromContext(ctx).Error("email already exists", zap.String("email", input.Email))
 		return nil, errors.ErrEmailExists
 	}
 
 	hashed, err := s.hasher.Hash(input.Password)
 	if err != nil {
-		logger.Log.Error("error hashing password", zap.Error(err))
+		logger.FromContext(ctx).Error("error hashing password", zap.Error(err))
 		return nil, errors.ErrInternalServerError
 	}
 
@@ -70,66 +112,525 @@ func (s *service) Register(ctx context.Context, input *model.RegisterInput) (*mo
 
 	err = s.repo.Register(ctx, req)
 	if err != nil {
-		logger.Log.Error("error registering user", zap.Error(err))
+		// Two concurrent registrations can both pass the existence check above.
+		var pgErr *pgconn.PgError
+		if stdErrors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, errors.ErrEmailExists
+		}
+		logger.FromContext(ctx).Error("error registering user", zap.Error(err))
 		return nil, err
 	}
 
-	accessToken, refreshToken, refreshExpiresAt, err := s.tokenMaker.GenerateTokens(req.ID.String(), req.Email)
-	if err != nil {
-		logger.Log.Error("error generating tokens", zap.Error(err))
-		return nil, errors.ErrInternalServerError
-	}
-
-	err = s.repo.SaveRefreshToken(ctx, refreshToken, req.ID.String(), refreshExpiresAt)
+	tokens, err := s.issueTokens(ctx, req.ID, req.Email)
 	if err != nil {
-		logger.Log.Error("error saving refresh token", zap.Error(err))
 		return nil, err
 	}
 
 	return &model.RegisterResponse{
-		AccessToken:     accessToken,
-		RefreshToken:    refreshToken,
+		AccessToken:     tokens.AccessToken,
+		RefreshToken:    tokens.RefreshToken,
 		RegisterRequest: *req,
 	}, nil
 }
 
 func (s *service) Login(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error) {
 	if err := validator.New().Struct(input); err != nil {
-		logger.Log.Error("validation error:", zap.Error(err))
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
 		return nil, err
 	}
 
 	account, err := s.repo.GetAccountByEmail(ctx, input.Email)
 	if err != nil {
-		logger.Log.Error("account not found:", zap.Error(err))
+		logger.FromContext(ctx).Error("account not found:", zap.Error(err))
 		return nil, errors.ErrInvalidCredentials // Tránh leak info
 	}
 
 	if account == nil {
-		logger.Log.Error("account not found for email", zap.String("email", input.Email))
+		logger.FromContext(ctx).Error("account not found for email", zap.String("email", input.Email))
 		return nil, errors.ErrInvalidCredentials
 	}
 
 	matched := s.hasher.Compare(account.PasswordHash, input.Password)
 	if !matched {
-		logger.Log.Error("password mismatch:", zap.Error(err))
+		logger.FromContext(ctx).Error("password mismatch:", zap.Error(err))
 		return nil, errors.ErrInvalidCredentials
 	}
 
-	accessToken, refreshToken, refreshExpiresAt, err := s.tokenMaker.GenerateTokens(account.ID.String(), input.Email)
+	if account.DeletedAt != nil {
+		logger.FromContext(ctx).Warn("login to account pending deletion", zap.String("account_id", account.ID.String()))
+		return nil, errors.ErrAccountDeleted
+	}
+
+	return s.issueTokens(ctx, account.ID, account.Email)
+}
+
+// issueTokens generates a token pair carrying the account's current roles and
+// permissions and stores the refresh token.
+func (s *service) issueTokens(ctx context.Context, accountID uuid.UUID, email string) (*model.AuthResponse, error) {
+	accountRoles, err := s.repo.GetAccountRoles(ctx, accountID)
 	if err != nil {
-		logger.Log.Error("error generating tokens", zap.Error(err))
+		logger.FromContext(ctx).Error("error loading account roles", zap.Error(err))
+		return nil, err
+	}
+	roles := make([]string, 0, len(accountRoles))
+	for _, r := range accountRoles {
+		roles = append(roles, r.Role)
+	}
+
+	permissions, err := s.repo.GetAccountPermissions(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account permissions", zap.Error(err))
+		return nil, err
+	}
+
+	accessToken, refreshToken, refreshExpiresAt, err := s.tokenMaker.GenerateTokens(accountID.String(), email, roles, permissions)
+	if err != nil {
+		logger.FromContext(ctx).Error("error generating tokens", zap.Error(err))
 		return nil, errors.ErrInternalServerError
 	}
 
-	err = s.repo.SaveRefreshToken(ctx, refreshToken, account.ID.String(), refreshExpiresAt)
+	err = s.repo.SaveRefreshToken(ctx, refreshToken, accountID.String(), refreshExpiresAt)
 	if err != nil {
-		logger.Log.Error("error saving refresh token", zap.Error(err))
+		logger.FromContext(ctx).Error("error saving refresh token", zap.Error(err))
 		return nil, err
 	}
+	metrics.TokensIssued.WithLabelValues(metrics.TokenAccess).Inc()
+	metrics.TokensIssued.WithLabelValues(metrics.TokenRefresh).Inc()
 
 	return &model.AuthResponse{
+		AccountID:    accountID,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+// RefreshToken rotates a refresh token: the presented token is consumed and a
+// new pair carrying the account's current roles is issued.
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthResponse, error) {
+	claims, err := s.tokenMaker.VerifyToken(refreshToken)
+	if err != nil || claims.TokenType != authz.TokenTypeRefresh {
+		return nil, errors.ErrTokenInvalid
+	}
+
+	accountID, err := s.repo.ConsumeRefreshToken(ctx, refreshToken)
+	if err != nil {
+		logger.FromContext(ctx).Warn("refresh token not found or expired", zap.Error(err))
+		return nil, errors.ErrTokenInvalid
+	}
+	if accountID.String() != claims.UserID {
+		return nil, errors.ErrTokenInvalid
+	}
+
+	account, err := s.repo.GetAccountByID(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account", zap.Error(err))
+		return nil, errors.ErrTokenInvalid
+	}
+
+	return s.issueTokens(ctx, account.ID, account.Email)
+}
+
+// Logout revokes the refresh token and returns the account it belonged to.
+func (s *service) Logout(ctx context.Context, refreshToken string) (uuid.UUID, error) {
+	claims, err := s.tokenMaker.VerifyToken(refreshToken)
+	if err != nil || claims.TokenType != authz.TokenTypeRefresh {
+		return uuid.Nil, errors.ErrTokenInvalid
+	}
+
+	accountID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, errors.ErrTokenInvalid
+	}
+
+	deleted, err := s.repo.DeleteRefreshToken(ctx, refreshToken)
+	if err != nil {
+		logger.FromContext(ctx).Error("error deleting refresh token", zap.Error(err))
+		return accountID, err
+	}
+	if !deleted {
+		return accountID, errors.ErrTokenInvalid
+	}
+	return accountID, nil
+}
+
+func (s *service) GrantRole(ctx context.Context, input *model.GrantRoleInput) error {
+	if err := validator.New().Struct(input); err != nil {
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
+		return err
+	}
+
+	if err := s.ensureAccountExists(ctx, input.AccountID); err != nil {
+		return err
+	}
+
+	exists, err := s.repo.RoleExists(ctx, input.Role)
+	if err != nil {
+		logger.FromContext(ctx).Error("error checking role existence", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return errors.ErrRoleNotFound
+	}
+
+	if err := s.repo.GrantRole(ctx, input); err != nil {
+		logger.FromContext(ctx).Error("error granting role", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *service) RevokeRole(ctx context.Context, accountID uuid.UUID, role string) error {
+	if err := s.ensureAccountExists(ctx, accountID); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeRole(ctx, accountID, role)
+	if err != nil {
+		logger.FromContext(ctx).Error("error revoking role", zap.Error(err))
+		return err
+	}
+	if !revoked {
+		return errors.ErrRoleNotFound
+	}
+	return nil
+}
+
+func (s *service) ListAccountRoles(ctx context.Context, accountID uuid.UUID) ([]model.AccountRole, error) {
+	if err := s.ensureAccountExists(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAccountRoles(ctx, accountID)
+}
+
+func (s *service) ensureAccountExists(ctx context.Context, accountID uuid.UUID) error {
+	_, err := s.repo.GetAccountByID(ctx, accountID)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *service) CreateAPIKey(ctx context.Context, input *model.CreateAPIKeyInput) (*model.CreateAPIKeyResponse, error) {
+	if err := validator.New().Struct(input); err != nil {
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
+		return nil, err
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, errors.InvalidField("expires_at", "must be in the future")
+	}
+
+	// A key grants nothing beyond its scopes, which must be permissions its
+	// owner already holds.
+	if len(input.Scopes) == 0 {
+		return nil, errors.InvalidField("scopes", "at least one scope is required")
+	}
+	permissions, err := s.repo.GetAccountPermissions(ctx, input.AccountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account permissions", zap.Error(err))
+		return nil, err
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(permissions, scope) {
+			return nil, errors.ErrInvalidScope
+		}
+	}
+
+	plaintext, prefix, err := apikey.Generate()
+	if err != nil {
+		logger.FromContext(ctx).Error("error generating api key", zap.Error(err))
+		return nil, errors.ErrInternalServerError
+	}
+
+	key := &model.APIKey{
+		ID:        uuid.New(),
+		AccountID: input.AccountID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   apikey.Hash(plaintext),
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		logger.FromContext(ctx).Error("error creating api key", zap.Error(err))
+		return nil, err
+	}
+
+	return &model.CreateAPIKeyResponse{
+		Key:    plaintext,
+		APIKey: *key,
+	}, nil
+}
+
+func (s *service) ListAPIKeys(ctx context.Context, accountID uuid.UUID) ([]model.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, accountID)
+}
+
+func (s *service) RevokeAPIKey(ctx context.Context, accountID, keyID uuid.UUID) error {
+	revoked, err := s.repo.RevokeAPIKey(ctx, keyID, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error revoking api key", zap.Error(err))
+		return err
+	}
+	if !revoked {
+		return errors.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *service) ExchangeAPIKey(ctx context.Context, plaintext string) (*model.ServiceTokenResponse, error) {
+	prefix, err := apikey.Parse(plaintext)
+	if err != nil {
+		return nil, errors.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		logger.FromContext(ctx).Warn("api key not found", zap.String("prefix", prefix), zap.Error(err))
+		return nil, errors.ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !apikey.Compare(key.KeyHash, plaintext) ||
+		key.RevokedAt != nil ||
+		(key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		logger.FromContext(ctx).Warn("api key rejected", zap.String("prefix", prefix))
+		return nil, errors.ErrInvalidAPIKey
+	}
+
+	// Permissions are resolved at exchange time so that revoking a role from the
+	// owner also takes effect for their keys. A key without scopes, which older
+	// versions allowed, gets no permissions.
+	permissions, err := s.repo.GetAccountPermissions(ctx, key.AccountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account permissions", zap.Error(err))
+		return nil, err
+	}
+	permissions = slices.DeleteFunc(permissions, func(p string) bool {
+		return !slices.Contains(key.Scopes, p)
+	})
+
+	accessToken, expiresAt, err := s.tokenMaker.GenerateServiceToken(key.AccountID.String(), key.ID.String(), permissions)
+	if err != nil {
+		logger.FromContext(ctx).Error("error generating service token", zap.Error(err))
+		return nil, errors.ErrInternalServerError
+	}
+	metrics.TokensIssued.WithLabelValues(metrics.TokenService).Inc()
+
+	if err := s.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+		logger.FromContext(ctx).Warn("error updating api key last use", zap.String("key_id", key.ID.String()), zap.Error(err))
+	}
+
+	return &model.ServiceTokenResponse{
+		AccountID:   key.AccountID,
+		AccessToken: accessToken,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+func (s *service) ListAuditEvents(ctx context.Context, filter *model.AuditEventFilter) ([]model.AuditEvent, int64, error) {
+	pageSize := filter.Limit
+	if pageSize <= 0 {
+		pageSize = defaultAuditPageSize
+	}
+	if pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	query := *filter
+	query.Limit = pageSize + 1
+	events, err := s.repo.ListAuditEvents(ctx, &query)
+	if err != nil {
+		logger.FromContext(ctx).Error("error listing audit events", zap.Error(err))
+		return nil, 0, err
+	}
+
+	var nextCursor int64
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		nextCursor = events[pageSize-1].ID
+	}
+	return events, nextCursor, nil
+}
+
+func (s *service) ExportAccountData(ctx context.Context, accountID uuid.UUID) (*model.AccountExport, error) {
+	account, err := s.repo.GetAccountByID(ctx, accountID)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account", zap.Error(err))
+		return nil, err
+	}
+
+	export := &model.AccountExport{
+		Account: model.ExportedAccount{
+			ID:         account.ID,
+			Email:      account.Email,
+			CreatedAt:  account.CreatedAt,
+			UpdatedAt:  account.UpdatedAt,
+			DeletedAt:  account.DeletedAt,
+			PurgeAfter: account.PurgeAfter,
+			Roles:      []model.ExportedRole{},
+		},
+		Sessions: []model.ExportedSession{},
+		APIKeys:  []model.ExportedAPIKey{},
+		AuditLog: []model.ExportedAuditEvent{},
+	}
+
+	roles, err := s.repo.GetAccountRoles(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading roles", zap.Error(err))
+		return nil, err
+	}
+	for _, r := range roles {
+		export.Account.Roles = append(export.Account.Roles, model.ExportedRole{Role: r.Role, GrantedAt: r.GrantedAt})
+	}
+
+	sessions, err := s.repo.ListSessions(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading sessions", zap.Error(err))
+		return nil, err
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, model.ExportedSession(session))
+	}
+
+	keys, err := s.repo.ListAPIKeys(ctx, accountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading api keys", zap.Error(err))
+		return nil, err
+	}
+	for _, k := range keys {
+		export.APIKeys = append(export.APIKeys, model.ExportedAPIKey{
+			ID:         k.ID,
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scopes:     k.Scopes,
+			ExpiresAt:  k.ExpiresAt,
+			LastUsedAt: k.LastUsedAt,
+			RevokedAt:  k.RevokedAt,
+			CreatedAt:  k.CreatedAt,
+		})
+	}
+
+	// The audit log can be long: read it in pages, newest first.
+	filter := model.AuditEventFilter{AccountID: &accountID, Limit: maxAuditPageSize}
+	for {
+		events, err := s.repo.ListAuditEvents(ctx, &filter)
+		if err != nil {
+			logger.FromContext(ctx).Error("error loading audit events", zap.Error(err))
+			return nil, err
+		}
+		for _, e := range events {
+			export.AuditLog = append(export.AuditLog, model.ExportedAuditEvent{
+				EventType: e.EventType,
+				Email:     e.Email,
+				IPAddress: e.IPAddress,
+				UserAgent: e.UserAgent,
+				Outcome:   e.Outcome,
+				Reason:    e.Reason,
+				CreatedAt: e.CreatedAt,
+			})
+		}
+		if len(events) < filter.Limit {
+			break
+		}
+		filter.BeforeID = events[len(events)-1].ID
+	}
+
+	return export, nil
+}
+
+// ChangePassword replaces the password after checking the current one. Every
+// session of the account is signed out.
+func (s *service) ChangePassword(ctx context.Context, input *model.ChangePasswordInput) error {
+	if err := validator.New().Struct(input); err != nil {
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
+		return err
+	}
+
+	account, err := s.repo.GetAccountByID(ctx, input.AccountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account", zap.Error(err))
+		return errors.ErrUserNotFound
+	}
+	if account.DeletedAt != nil {
+		return errors.ErrAccountDeleted
+	}
+
+	if !s.hasher.Compare(account.PasswordHash, input.CurrentPassword) {
+		return errors.ErrInvalidCredentials
+	}
+
+	hashed, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		logger.FromContext(ctx).Error("error hashing password", zap.Error(err))
+		return errors.ErrInternalServer
+	}
+	if err := s.repo.UpdatePassword(ctx, account.ID, hashed); err != nil {
+		logger.FromContext(ctx).Error("error updating password", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteAccount schedules the account for deletion. The caller must confirm
+// with their password; the account is purged once the grace period elapses.
+func (s *service) DeleteAccount(ctx context.Context, input *model.DeleteAccountInput) (*model.DeleteAccountResponse, error) {
+	if err := validator.New().Struct(input); err != nil {
+		logger.FromContext(ctx).Info("validation error", zap.Error(err))
+		return nil, err
+	}
+
+	account, err := s.repo.GetAccountByID(ctx, input.AccountID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading account", zap.Error(err))
+		return nil, errors.ErrUserNotFound
+	}
+	if account.DeletedAt != nil {
+		return nil, errors.ErrAccountDeleted
+	}
+
+	if !s.hasher.Compare(account.PasswordHash, input.Password) {
+		return nil, errors.ErrInvalidCredentials
+	}
+
+	purgeAfter := time.Now().Add(s.deletionGrace)
+	if err := s.repo.SoftDeleteAccount(ctx, account.ID, purgeAfter); err != nil {
+		logger.FromContext(ctx).Error("error deleting account", zap.Error(err))
+		return nil, err
+	}
+
+	return &model.DeleteAccountResponse{PurgeAfter: purgeAfter}, nil
+}
+
+// RestoreAccount cancels a pending deletion and signs the user back in.
+func (s *service) RestoreAccount(ctx context.Context, input *model.LoginInput) (*model.AuthResponse, error) {
+	account, err := s.repo.GetAccountByEmail(ctx, input.Email)
+	if err != nil {
+		logger.FromContext(ctx).Error("account not found:", zap.Error(err))
+		return nil, errors.ErrInvalidCredentials
+	}
+
+	if !s.hasher.Compare(account.PasswordHash, input.Password) {
+		return nil, errors.ErrInvalidCredentials
+	}
+
+	restored, err := s.repo.RestoreAccount(ctx, account.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error restoring account", zap.Error(err))
+		return nil, err
+	}
+	if !restored {
+		return nil, errors.ErrAccountNotRestorable
+	}
+
+	return s.issueTokens(ctx, account.ID, account.Email)
+}
@@ -0,0 +1,413 @@
+package service_test
+
+import (
+	"context"
+	stdErrors "errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/repository/memory"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/auth"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/auth/authtest"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/errors"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/hasher/hashertest"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/apperror"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	accessDuration  = 15 * time.Minute
+	refreshDuration = 7 * 24 * time.Hour
+)
+
+type testEnv struct {
+	ctx    context.Context
+	svc    service.Service
+	tokens auth.TokenMaker
+	clock  *authtest.Clock
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	clock := authtest.NewClock(time.Now())
+	tokens := authtest.NewTokenMaker(clock, accessDuration, refreshDuration, time.Hour)
+	return &testEnv{
+		ctx:    logger.NewContext(context.Background(), zaptest.NewLogger(t)),
+		svc:    service.NewService(memory.NewRepository(clock.Now), tokens, hashertest.NewHasher(), 30*24*time.Hour),
+		tokens: tokens,
+		clock:  clock,
+	}
+}
+
+func (e *testEnv) register(t *testing.T, email, password string) *model.RegisterResponse {
+	t.Helper()
+	resp, err := e.svc.Register(e.ctx, &model.RegisterInput{Email: email, Password: password})
+	if err != nil {
+		t.Fatalf("Register(%q): %v", email, err)
+	}
+	return resp
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		input    model.RegisterInput
+		wantErr  error
+		// invalid is set when validation should fail.
+		invalid bool
+	}{
+		{name: "new account", input: model.RegisterInput{Email: "alice@example.com", Password: "secret1"}},
+		{name: "duplicate email", existing: "alice@example.com", input: model.RegisterInput{Email: "alice@example.com", Password: "secret1"}, wantErr: errors.ErrEmailExists},
+		{name: "another account exists", existing: "bob@example.com", input: model.RegisterInput{Email: "alice@example.com", Password: "secret1"}},
+		{name: "invalid email", input: model.RegisterInput{Email: "alice", Password: "secret1"}, invalid: true},
+		{name: "short password", input: model.RegisterInput{Email: "alice@example.com", Password: "12345"}, invalid: true},
+		{name: "missing password", input: model.RegisterInput{Email: "alice@example.com"}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.existing != "" {
+				env.register(t, tt.existing, "password")
+			}
+
+			resp, err := env.svc.Register(env.ctx, &tt.input)
+			switch {
+			case tt.invalid:
+				if err == nil {
+					t.Fatal("Register succeeded, want a validation error")
+				}
+				return
+			case tt.wantErr != nil:
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("Register error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Register: %v", err)
+			}
+
+			if resp.RegisterRequest.Email != tt.input.Email {
+				t.Errorf("email = %q, want %q", resp.RegisterRequest.Email, tt.input.Email)
+			}
+			if resp.RegisterRequest.PasswordHash == tt.input.Password {
+				t.Error("password stored unhashed")
+			}
+			claims, err := env.tokens.VerifyToken(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("VerifyToken(access): %v", err)
+			}
+			if claims.UserID != resp.RegisterRequest.ID.String() || !slices.Equal(claims.Roles, []string{model.RoleUser}) {
+				t.Errorf("access claims = %+v, want user %s with role %q", claims, resp.RegisterRequest.ID, model.RoleUser)
+			}
+		})
+	}
+}
+
+func TestRegisterConcurrentDuplicateEmail(t *testing.T) {
+	env := newTestEnv(t)
+
+	const attempts = 10
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.svc.Register(env.ctx, &model.RegisterInput{Email: "alice@example.com", Password: "secret1"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !stdErrors.Is(err, errors.ErrEmailExists):
+			t.Errorf("Register error = %v, want %v", err, errors.ErrEmailExists)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d registrations succeeded, want 1", succeeded)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   model.LoginInput
+		deleted bool
+		wantErr error
+	}{
+		{name: "valid credentials", input: model.LoginInput{Email: "alice@example.com", Password: "secret1"}},
+		{name: "bad password", input: model.LoginInput{Email: "alice@example.com", Password: "secret2"}, wantErr: errors.ErrInvalidCredentials},
+		{name: "empty password", input: model.LoginInput{Email: "alice@example.com"}, wantErr: errors.ErrInvalidCredentials},
+		{name: "unknown email", input: model.LoginInput{Email: "bob@example.com", Password: "secret1"}, wantErr: errors.ErrInvalidCredentials},
+		{name: "email differs in case", input: model.LoginInput{Email: "Alice@example.com", Password: "secret1"}, wantErr: errors.ErrInvalidCredentials},
+		{name: "account pending deletion", input: model.LoginInput{Email: "alice@example.com", Password: "secret1"}, deleted: true, wantErr: errors.ErrAccountDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			registered := env.register(t, "alice@example.com", "secret1")
+			if tt.deleted {
+				_, err := env.svc.DeleteAccount(env.ctx, &model.DeleteAccountInput{AccountID: registered.RegisterRequest.ID, Password: "secret1"})
+				if err != nil {
+					t.Fatalf("DeleteAccount: %v", err)
+				}
+			}
+
+			resp, err := env.svc.Login(env.ctx, &tt.input)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("Login error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+			if resp.AccountID != registered.RegisterRequest.ID {
+				t.Errorf("account = %s, want %s", resp.AccountID, registered.RegisterRequest.ID)
+			}
+			if _, err := env.svc.RefreshToken(env.ctx, resp.RefreshToken); err != nil {
+				t.Errorf("RefreshToken of the new session: %v", err)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the token to refresh with.
+		prepare func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string
+		wantErr error
+	}{
+		{
+			name: "valid token",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				return resp.RefreshToken
+			},
+		},
+		{
+			name: "token already used",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				if _, err := env.svc.RefreshToken(env.ctx, resp.RefreshToken); err != nil {
+					t.Fatalf("first RefreshToken: %v", err)
+				}
+				return resp.RefreshToken
+			},
+			wantErr: errors.ErrTokenInvalid,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				env.clock.Advance(refreshDuration)
+				return resp.RefreshToken
+			},
+			wantErr: errors.ErrTokenInvalid,
+		},
+		{
+			name: "access token",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				return resp.AccessToken
+			},
+			wantErr: errors.ErrTokenInvalid,
+		},
+		{
+			name: "logged out",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				if _, err := env.svc.Logout(env.ctx, resp.RefreshToken); err != nil {
+					t.Fatalf("Logout: %v", err)
+				}
+				return resp.RefreshToken
+			},
+			wantErr: errors.ErrTokenInvalid,
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, env *testEnv, resp *model.RegisterResponse) string {
+				return "not-a-token"
+			},
+			wantErr: errors.ErrTokenInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			registered := env.register(t, "alice@example.com", "secret1")
+			token := tt.prepare(t, env, registered)
+
+			resp, err := env.svc.RefreshToken(env.ctx, token)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("RefreshToken error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RefreshToken: %v", err)
+			}
+			if resp.RefreshToken == token {
+				t.Error("refresh token was not rotated")
+			}
+			claims, err := env.tokens.VerifyToken(resp.RefreshToken)
+			if err != nil || claims.TokenType != authz.TokenTypeRefresh {
+				t.Errorf("VerifyToken(new refresh token) = %+v, %v", claims, err)
+			}
+		})
+	}
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	resp := env.register(t, "alice@example.com", "secret1")
+
+	env.clock.Advance(accessDuration - time.Second)
+	if _, err := env.tokens.VerifyToken(resp.AccessToken); err != nil {
+		t.Fatalf("VerifyToken before expiry: %v", err)
+	}
+	env.clock.Advance(time.Second)
+	if _, err := env.tokens.VerifyToken(resp.AccessToken); err == nil {
+		t.Fatal("VerifyToken succeeded after expiry")
+	}
+}
+
+func TestRevokeRole(t *testing.T) {
+	env := newTestEnv(t)
+	registered := env.register(t, "alice@example.com", "secret1")
+
+	tests := []struct {
+		name      string
+		accountID uuid.UUID
+		role      string
+		wantErr   error
+	}{
+		{name: "held role", accountID: registered.RegisterRequest.ID, role: model.RoleUser},
+		{name: "role not held", accountID: registered.RegisterRequest.ID, role: model.RoleAdmin, wantErr: errors.ErrRoleNotFound},
+		{name: "unknown account", accountID: uuid.New(), role: model.RoleUser, wantErr: errors.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.svc.RevokeRole(env.ctx, tt.accountID, tt.role)
+			if !stdErrors.Is(err, tt.wantErr) {
+				t.Fatalf("RevokeRole error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	env := newTestEnv(t)
+	accountID := env.register(t, "alice@example.com", "secret1").RegisterRequest.ID
+	if err := env.svc.GrantRole(env.ctx, &model.GrantRoleInput{AccountID: accountID, Role: model.RoleAdmin}); err != nil {
+		t.Fatalf("GrantRole: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		scopes  []string
+		invalid bool
+		wantErr error
+	}{
+		{name: "no scopes", invalid: true},
+		{name: "scope not held", scopes: []string{"messages:delete"}, wantErr: errors.ErrInvalidScope},
+		{name: "held scope", scopes: []string{model.PermissionRolesRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := env.svc.CreateAPIKey(env.ctx, &model.CreateAPIKeyInput{AccountID: accountID, Name: "ci", Scopes: tt.scopes})
+			switch {
+			case tt.invalid:
+				var validationErr *apperror.ValidationError
+				if !stdErrors.As(err, &validationErr) {
+					t.Fatalf("CreateAPIKey error = %v, want a validation error", err)
+				}
+				return
+			case tt.wantErr != nil:
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateAPIKey error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatalf("CreateAPIKey: %v", err)
+			}
+
+			token, err := env.svc.ExchangeAPIKey(env.ctx, resp.Key)
+			if err != nil {
+				t.Fatalf("ExchangeAPIKey: %v", err)
+			}
+			claims, err := env.tokens.VerifyToken(token.AccessToken)
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if !slices.Equal(claims.Permissions, tt.scopes) {
+				t.Errorf("service token permissions = %v, want only the key scopes %v", claims.Permissions, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		invalid bool
+		wantErr error
+	}{
+		{name: "valid", current: "secret1", next: "secret2"},
+		{name: "wrong current password", current: "secret9", next: "secret2", wantErr: errors.ErrInvalidCredentials},
+		{name: "new password too short", current: "secret1", next: "abc", invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			registered := env.register(t, "alice@example.com", "secret1")
+
+			err := env.svc.ChangePassword(env.ctx, &model.ChangePasswordInput{
+				AccountID:       registered.RegisterRequest.ID,
+				CurrentPassword: tt.current,
+				NewPassword:     tt.next,
+			})
+			switch {
+			case tt.invalid:
+				if err == nil {
+					t.Fatal("ChangePassword succeeded, want a validation error")
+				}
+				return
+			case tt.wantErr != nil:
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("ChangePassword error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatalf("ChangePassword: %v", err)
+			}
+
+			if _, err := env.svc.RefreshToken(env.ctx, registered.RefreshToken); !stdErrors.Is(err, errors.ErrTokenInvalid) {
+				t.Errorf("RefreshToken of an old session error = %v, want %v", err, errors.ErrTokenInvalid)
+			}
+			if _, err := env.svc.Login(env.ctx, &model.LoginInput{Email: "alice@example.com", Password: "secret1"}); !stdErrors.Is(err, errors.ErrInvalidCredentials) {
+				t.Errorf("Login with the old password error = %v, want %v", err, errors.ErrInvalidCredentials)
+			}
+			if _, err := env.svc.Login(env.ctx, &model.LoginInput{Email: "alice@example.com", Password: tt.next}); err != nil {
+				t.Errorf("Login with the new password: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Keys look like "rck_<prefix>_<secret>". The prefix is stored in clear so a
+// key can be looked up and shown in listings; only a hash of the whole key is kept.
+const (
+	keyTag       = "rck"
+	prefixLength = 8
+	secretLength = 32
+)
+
+var ErrMalformedKey = errors.New("malformed api key")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Generate returns a new plaintext key and its visible prefix.
+func Generate() (key string, prefix string, err error) {
+	prefix, err = randomString(prefixLength)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomString(secretLength)
+	if err != nil {
+		return "", "", err
+	}
+	return keyTag + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// Parse extracts the prefix of a plaintext key.
+func Parse(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != keyTag || len(parts[1]) != prefixLength || len(parts[2]) != secretLength {
+		return "", ErrMalformedKey
+	}
+	return parts[1], nil
+}
+
+// Hash returns the SHA-256 digest of the key. Keys carry enough entropy that a
+// slow password hash is not needed.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func Compare(hash, key string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(Hash(key))) == 1
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strings.ToLower(encoding.EncodeToString(buf))[:n], nil
+}
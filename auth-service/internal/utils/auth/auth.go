@@ -3,41 +3,45 @@ package auth
 import (
 	"time"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type TokenMaker interface {
-	GenerateTokens(userID string, email string) (accessToken string, refreshToken string, refreshExpiresAt time.Time, err error)
-	VerifyToken(token string) (userID string, email string, err error)
+	GenerateTokens(userID string, email string, roles []string, permissions []string) (accessToken string, refreshToken string, refreshExpiresAt time.Time, err error)
+	// GenerateServiceToken issues a short-lived access token for an API key.
+	GenerateServiceToken(accountID string, keyID string, permissions []string) (accessToken string, expiresAt time.Time, err error)
+	VerifyToken(token string) (*authz.Claims, error)
 }
 
 type jwtMaker struct {
 	secretKey       string
 	accessDuration  time.Duration
 	refreshDuration time.Duration
+	serviceDuration time.Duration
 }
 
-func NewTokenMaker(jwtSecret string) TokenMaker {
+func NewTokenMaker(jwtSecret string, accessDuration, refreshDuration, serviceDuration time.Duration) TokenMaker {
 	return &jwtMaker{
 		secretKey:       jwtSecret,
-		accessDuration:  24 * time.Hour,
-		refreshDuration: 7 * 24 * time.Hour,
+		accessDuration:  accessDuration,
+		refreshDuration: refreshDuration,
+		serviceDuration: serviceDuration,
 	}
 }
 
-type jwtCustomClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	jwt.RegisteredClaims
-}
-
-func (j *jwtMaker) GenerateTokens(userID string, email string) (string, string, time.Time, error) {
+func (j *jwtMaker) GenerateTokens(userID string, email string, roles []string, permissions []string) (string, string, time.Time, error) {
 	now := time.Now()
 
 	// Access Token
-	accessClaims := jwtCustomClaims{
-		UserID: userID,
-		Email:  email,
+	accessClaims := authz.Claims{
+		UserID:      userID,
+		Email:       email,
+		Roles:       roles,
+		Permissions: permissions,
+		TokenType:   authz.TokenTypeAccess,
+		SubjectType: authz.SubjectTypeUser,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessDuration)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -50,10 +54,13 @@ func (j *jwtMaker) GenerateTokens(userID string, email string) (string, string,
 
 	// Refresh Token
 	refreshExpiresAt := now.Add(j.refreshDuration)
-	refreshClaims := jwtCustomClaims{
-		UserID: userID,
-		Email:  email,
+	refreshClaims := authz.Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: authz.TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// Unique id so two refresh tokens issued in the same second differ.
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
@@ -66,18 +73,29 @@ func (j *jwtMaker) GenerateTokens(userID string, email string) (string, string,
 	return accessToken, refreshToken, refreshExpiresAt, nil
 }
 
-func (j *jwtMaker) VerifyToken(token string) (string, string, error) {
-	parsedToken, err := jwt.ParseWithClaims(token, &jwtCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+func (j *jwtMaker) GenerateServiceToken(accountID string, keyID string, permissions []string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(j.serviceDuration)
+
+	claims := authz.Claims{
+		UserID:      accountID,
+		Permissions: permissions,
+		TokenType:   authz.TokenTypeAccess,
+		SubjectType: authz.SubjectTypeServiceAccount,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   keyID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.secretKey))
 	if err != nil {
-		return "", "", err
+		return "", time.Time{}, err
 	}
 
-	claims, ok := parsedToken.Claims.(*jwtCustomClaims)
-	if !ok || !parsedToken.Valid {
-		return "", "", err
-	}
+	return token, expiresAt, nil
+}
 
-	return claims.UserID, claims.Email, nil
+func (j *jwtMaker) VerifyToken(token string) (*authz.Claims, error) {
+	return authz.ParseToken(j.secretKey, token)
 }
@@ -0,0 +1,131 @@
+// Package authtest provides a TokenMaker for tests whose tokens expire by a
+// clock the test controls.
+package authtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/auth"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Clock is a time source that only moves when told to.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type tokenMaker struct {
+	clock           *Clock
+	accessDuration  time.Duration
+	refreshDuration time.Duration
+	serviceDuration time.Duration
+
+	mu     sync.Mutex
+	issued int
+	// claims holds the claims of every token issued, by token.
+	claims map[string]authz.Claims
+}
+
+// NewTokenMaker returns a TokenMaker issuing opaque tokens that it verifies
+// against clock. Expired tokens fail with jwt.ErrTokenExpired and unknown
+// ones with authz.ErrInvalidToken, as with signed tokens.
+func NewTokenMaker(clock *Clock, accessDuration, refreshDuration, serviceDuration time.Duration) auth.TokenMaker {
+	return &tokenMaker{
+		clock:           clock,
+		accessDuration:  accessDuration,
+		refreshDuration: refreshDuration,
+		serviceDuration: serviceDuration,
+		claims:          make(map[string]authz.Claims),
+	}
+}
+
+func (m *tokenMaker) GenerateTokens(userID string, email string, roles []string, permissions []string) (string, string, time.Time, error) {
+	now := m.clock.Now()
+	refreshExpiresAt := now.Add(m.refreshDuration)
+
+	accessToken := m.issue(authz.Claims{
+		UserID:      userID,
+		Email:       email,
+		Roles:       roles,
+		Permissions: permissions,
+		TokenType:   authz.TokenTypeAccess,
+		SubjectType: authz.SubjectTypeUser,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessDuration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	refreshToken := m.issue(authz.Claims{
+		UserID:    userID,
+		Email:     email,
+		TokenType: authz.TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	return accessToken, refreshToken, refreshExpiresAt, nil
+}
+
+func (m *tokenMaker) GenerateServiceToken(accountID string, keyID string, permissions []string) (string, time.Time, error) {
+	now := m.clock.Now()
+	expiresAt := now.Add(m.serviceDuration)
+
+	token := m.issue(authz.Claims{
+		UserID:      accountID,
+		Permissions: permissions,
+		TokenType:   authz.TokenTypeAccess,
+		SubjectType: authz.SubjectTypeServiceAccount,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   keyID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+	return token, expiresAt, nil
+}
+
+func (m *tokenMaker) VerifyToken(token string) (*authz.Claims, error) {
+	m.mu.Lock()
+	claims, ok := m.claims[token]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, authz.ErrInvalidToken
+	}
+	if !m.clock.Now().Before(claims.ExpiresAt.Time) {
+		return nil, jwt.ErrTokenExpired
+	}
+	return &claims, nil
+}
+
+func (m *tokenMaker) issue(claims authz.Claims) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.issued++
+	token := fmt.Sprintf("%s-token-%d", claims.TokenType, m.issued)
+	m.claims[token] = claims
+	return token
+}
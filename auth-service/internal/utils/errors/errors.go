@@ -1,25 +1,45 @@
 package errors
 
 import (
-	"errors"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/apperror"
+	"google.golang.org/grpc/codes"
 )
 
-var ErrUserNotFound = errors.New("user not found")
-var ErrInvalidCredentials = errors.New("invalid credentials")
-var ErrUserAlreadyExists = errors.New("user already exists")
-var ErrTokenGeneration = errors.New("error generating token")
-var ErrTokenInvalid = errors.New("invalid token")
-var ErrTokenExpired = errors.New("token expired")
-var ErrInternalServer = errors.New("internal server error")
-var ErrBadRequest = errors.New("bad request")
-var ErrUnauthorized = errors.New("unauthorized")
-var ErrForbidden = errors.New("forbidden")
-var ErrNotFound = errors.New("not found")
-var ErrConflict = errors.New("conflict")
-var ErrTooManyRequests = errors.New("too many requests")
-var ErrServiceUnavailable = errors.New("service unavailable")
-var ErrGatewayTimeout = errors.New("gateway timeout")
-var ErrInvalidUserID = errors.New("invalid user ID")
-var ErrInvalidEmail = errors.New("invalid email format")
-var ErrEmailExists = errors.New("email already exists")
-var ErrInternalServerError = errors.New("internal server error")
+// Domain is reported in google.rpc.ErrorInfo for errors raised by this service.
+const Domain = "auth-service"
+
+// ToGRPC maps an error returned by the service layer to a gRPC status error.
+func ToGRPC(err error) error {
+	return apperror.ToGRPC(Domain, err)
+}
+
+// InvalidField returns a validation error for a single request field.
+func InvalidField(field, description string) error {
+	return apperror.InvalidField(field, description)
+}
+
+var ErrUserNotFound = apperror.New(codes.NotFound, "USER_NOT_FOUND", "user not found")
+var ErrInvalidCredentials = apperror.New(codes.Unauthenticated, "INVALID_CREDENTIALS", "invalid credentials")
+var ErrUserAlreadyExists = apperror.New(codes.AlreadyExists, "USER_ALREADY_EXISTS", "user already exists")
+var ErrTokenGeneration = apperror.New(codes.Internal, "TOKEN_GENERATION_FAILED", "error generating token")
+var ErrTokenInvalid = apperror.New(codes.Unauthenticated, "TOKEN_INVALID", "invalid token")
+var ErrTokenExpired = apperror.New(codes.Unauthenticated, "TOKEN_EXPIRED", "token expired")
+var ErrInternalServer = apperror.New(codes.Internal, "INTERNAL", "internal server error")
+var ErrBadRequest = apperror.New(codes.InvalidArgument, "BAD_REQUEST", "bad request")
+var ErrUnauthorized = apperror.New(codes.Unauthenticated, "UNAUTHORIZED", "unauthorized")
+var ErrForbidden = apperror.New(codes.PermissionDenied, "FORBIDDEN", "forbidden")
+var ErrNotFound = apperror.New(codes.NotFound, "NOT_FOUND", "not found")
+var ErrConflict = apperror.New(codes.Aborted, "CONFLICT", "conflict")
+var ErrTooManyRequests = apperror.New(codes.ResourceExhausted, "TOO_MANY_REQUESTS", "too many requests")
+var ErrServiceUnavailable = apperror.New(codes.Unavailable, "SERVICE_UNAVAILABLE", "service unavailable")
+var ErrGatewayTimeout = apperror.New(codes.DeadlineExceeded, "GATEWAY_TIMEOUT", "gateway timeout")
+var ErrInvalidUserID = apperror.New(codes.InvalidArgument, "INVALID_USER_ID", "invalid user ID")
+var ErrInvalidEmail = apperror.New(codes.InvalidArgument, "INVALID_EMAIL", "invalid email format")
+var ErrEmailExists = apperror.New(codes.AlreadyExists, "EMAIL_EXISTS", "email already exists")
+var ErrInternalServerError = ErrInternalServer
+var ErrRoleNotFound = apperror.New(codes.NotFound, "ROLE_NOT_FOUND", "role not found")
+var ErrAPIKeyNotFound = apperror.New(codes.NotFound, "API_KEY_NOT_FOUND", "api key not found")
+var ErrInvalidAPIKey = apperror.New(codes.Unauthenticated, "INVALID_API_KEY", "invalid api key")
+var ErrInvalidScope = apperror.New(codes.PermissionDenied, "SCOPE_NOT_GRANTED", "scope not granted to account")
+var ErrAccountDeleted = apperror.New(codes.FailedPrecondition, "ACCOUNT_DELETED", "account is scheduled for deletion")
+var ErrAccountNotRestorable = apperror.New(codes.FailedPrecondition, "ACCOUNT_NOT_RESTORABLE", "account cannot be restored")
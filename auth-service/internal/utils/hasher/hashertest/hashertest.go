@@ -0,0 +1,24 @@
+// Package hashertest provides a Hasher for tests that skips bcrypt's
+// deliberate slowness.
+package hashertest
+
+import (
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/auth-service/internal/utils/hasher"
+)
+
+const prefix = "hashed:"
+
+type plainHasher struct{}
+
+// NewHasher returns a Hasher whose hashes are the password behind a prefix.
+func NewHasher() hasher.Hasher {
+	return plainHasher{}
+}
+
+func (plainHasher) Hash(password string) (string, error) {
+	return prefix + password, nil
+}
+
+func (plainHasher) Compare(hash, password string) bool {
+	return hash == prefix+password
+}
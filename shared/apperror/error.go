@@ -0,0 +1,21 @@
+// Package apperror defines the errors service layers return and their mapping
+// to gRPC statuses with google.rpc error details.
+package apperror
+
+import "google.golang.org/grpc/codes"
+
+// Error is a domain error. Code is the gRPC code it maps to and Reason a stable,
+// machine-readable identifier returned to clients in google.rpc.ErrorInfo.
+type Error struct {
+	Code    codes.Code
+	Reason  string
+	Message string
+}
+
+func New(code codes.Code, reason, message string) *Error {
+	return &Error{Code: code, Reason: reason, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,95 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ToGRPC maps an error returned by a service layer to a gRPC status error.
+// Domain errors keep their code and reason, validation errors carry their field
+// violations, and anything unexpected becomes an opaque Internal error so that
+// no internal detail reaches the client. domain is reported in ErrorInfo.
+func ToGRPC(domain string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		err = FromValidator(err)
+	}
+
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		br := &errdetails.BadRequest{}
+		for _, v := range ve.Violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		return withDetails(codes.InvalidArgument, domain, ve.Error(), "INVALID_ARGUMENT", br)
+	}
+
+	var de *Error
+	if errors.As(err, &de) {
+		return withDetails(de.Code, domain, de.Message, de.Reason)
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	return withDetails(codes.Internal, domain, "internal server error", "INTERNAL")
+}
+
+// ReasonOf returns the reason code ToGRPC would report for err, for places
+// such as logs and audit records that must not hold the error text.
+func ReasonOf(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var validationErrs validator.ValidationErrors
+	var ve *ValidationError
+	if errors.As(err, &validationErrs) || errors.As(err, &ve) {
+		return "INVALID_ARGUMENT"
+	}
+
+	var de *Error
+	if errors.As(err, &de) {
+		return de.Reason
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "CANCELED"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DEADLINE_EXCEEDED"
+	}
+	return "INTERNAL"
+}
+
+func withDetails(code codes.Code, domain, message, reason string, extra ...protoadapt.MessageV1) error {
+	st := status.New(code, message)
+
+	details := append([]protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: domain}}, extra...)
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
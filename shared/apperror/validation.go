@@ -0,0 +1,82 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldViolation describes one invalid request field.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned for invalid input and maps to InvalidArgument
+// with a google.rpc.BadRequest detail.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+": "+v.Description)
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
+
+// InvalidField returns a ValidationError for a single field.
+func InvalidField(field, description string) error {
+	return &ValidationError{Violations: []FieldViolation{{Field: field, Description: description}}}
+}
+
+// FromValidator converts validator errors into a ValidationError with
+// client-friendly descriptions; any other error is returned unchanged.
+func FromValidator(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	ve := &ValidationError{}
+	for _, fe := range validationErrs {
+		ve.Violations = append(ve.Violations, FieldViolation{
+			Field:       snakeCase(fe.Field()),
+			Description: describe(fe),
+		})
+	}
+	return ve
+}
+
+func describe(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	default:
+		return "is invalid"
+	}
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(rune(s[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package authz
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+const (
+	SubjectTypeUser           = "user"
+	SubjectTypeServiceAccount = "service_account"
+)
+
+// Claims is the payload of the access tokens issued by auth-service.
+type Claims struct {
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+	// SubjectType distinguishes tokens issued to people from tokens exchanged
+	// for an API key. For service accounts UserID is the key owner and Subject
+	// the key id.
+	SubjectType string `json:"sub_type,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) IsServiceAccount() bool {
+	return c.SubjectType == SubjectTypeServiceAccount
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// ParseToken verifies an HS256 signed token and returns its claims.
+func ParseToken(secret, token string) (*Claims, error) {
+	parsedToken, err := jwt.ParseWithClaims(token, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsedToken.Claims.(*Claims)
+	if !ok || !parsedToken.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,152 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	secret = "test-secret"
+
+	publicMethod    = "/test.Service/Public"
+	protectedMethod = "/test.Service/Protected"
+	adminMethod     = "/test.Service/Admin"
+)
+
+var perms = authz.MethodPermissions{
+	protectedMethod: {},
+	adminMethod:     {"roles:write"},
+}
+
+// token signs claims for user-1 with key, expiring after ttl.
+func token(t *testing.T, key, tokenType string, ttl time.Duration, permissions ...string) string {
+	t.Helper()
+	claims := authz.Claims{
+		UserID:      "user-1",
+		Permissions: permissions,
+		TokenType:   tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+// incoming returns a server context carrying authorization, if not empty.
+func incoming(t *testing.T, authorization string) context.Context {
+	ctx := logger.NewContext(context.Background(), zaptest.NewLogger(t))
+	if authorization == "" {
+		return ctx
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	access := token(t, secret, authz.TokenTypeAccess, time.Hour)
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantCode      codes.Code
+		// wantClaims tells whether the handler must see the caller's claims.
+		wantClaims bool
+	}{
+		{name: "protected without token", method: protectedMethod, wantCode: codes.Unauthenticated},
+		{name: "protected with another scheme", method: protectedMethod, authorization: "Basic " + access, wantCode: codes.Unauthenticated},
+		{name: "protected with a bad signature", method: protectedMethod, authorization: "Bearer " + token(t, "other-secret", authz.TokenTypeAccess, time.Hour), wantCode: codes.Unauthenticated},
+		{name: "protected with an expired token", method: protectedMethod, authorization: "Bearer " + token(t, secret, authz.TokenTypeAccess, -time.Minute), wantCode: codes.Unauthenticated},
+		{name: "protected with garbage", method: protectedMethod, authorization: "Bearer not-a-jwt", wantCode: codes.Unauthenticated},
+		{name: "refresh token as access token", method: protectedMethod, authorization: "Bearer " + token(t, secret, authz.TokenTypeRefresh, time.Hour), wantCode: codes.Unauthenticated},
+		{name: "protected with access token", method: protectedMethod, authorization: "Bearer " + access, wantCode: codes.OK, wantClaims: true},
+		{name: "public without token", method: publicMethod, wantCode: codes.OK},
+		{name: "public with a bad token", method: publicMethod, authorization: "Bearer not-a-jwt", wantCode: codes.OK},
+		{name: "public with a refresh token", method: publicMethod, authorization: "Bearer " + token(t, secret, authz.TokenTypeRefresh, time.Hour), wantCode: codes.OK},
+		{name: "public with access token", method: publicMethod, authorization: "bearer " + access, wantCode: codes.OK, wantClaims: true},
+		{name: "missing permission", method: adminMethod, authorization: "Bearer " + token(t, secret, authz.TokenTypeAccess, time.Hour, "roles:read"), wantCode: codes.PermissionDenied},
+		{name: "granted permission", method: adminMethod, authorization: "Bearer " + token(t, secret, authz.TokenTypeAccess, time.Hour, "roles:read", "roles:write"), wantCode: codes.OK, wantClaims: true},
+	}
+
+	interceptor := authz.UnaryServerInterceptor(secret, perms)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				claims, ok := authz.FromContext(ctx)
+				if ok != tt.wantClaims {
+					t.Errorf("handler got claims = %v, want %v", ok, tt.wantClaims)
+				}
+				if ok && claims.UserID != "user-1" {
+					t.Errorf("claims.UserID = %q, want user-1", claims.UserID)
+				}
+				return "ok", nil
+			}
+
+			_, err := interceptor(incoming(t, tt.authorization), nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (err %v)", code, tt.wantCode, err)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == codes.OK)
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		wantCode      codes.Code
+	}{
+		{name: "without token", wantCode: codes.Unauthenticated},
+		{name: "refresh token", authorization: "Bearer " + token(t, secret, authz.TokenTypeRefresh, time.Hour), wantCode: codes.Unauthenticated},
+		{name: "access token", authorization: "Bearer " + token(t, secret, authz.TokenTypeAccess, time.Hour), wantCode: codes.OK},
+	}
+
+	interceptor := authz.StreamServerInterceptor(secret, perms)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(srv interface{}, ss grpc.ServerStream) error {
+				called = true
+				if claims, ok := authz.FromContext(ss.Context()); !ok || claims.UserID != "user-1" {
+					t.Errorf("stream context claims = %+v, %v, want user-1", claims, ok)
+				}
+				return nil
+			}
+
+			ss := &fakeServerStream{ctx: incoming(t, tt.authorization)}
+			err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: protectedMethod}, handler)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (err %v)", code, tt.wantCode, err)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == codes.OK)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package config loads service configuration into a struct from layered
+// sources. Each field is identified by its mapstructure tag, e.g.
+//
+//	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+//	GRPCPort    int    `mapstructure:"GRPC_PORT" default:"50051"`
+//
+// and is resolved from, in increasing order of precedence:
+//
+//  1. the default tag
+//  2. a YAML file given by the --config flag or the CONFIG_FILE environment
+//     variable; keys are matched case-insensitively and nested maps are joined
+//     with "_", so rate_limit: {login: ...} sets RATE_LIMIT_LOGIN
+//  3. the environment variable named after the tag
+//  4. a command-line flag, the tag in lower case with dashes (--database-url)
+//
+// Nested structs extend the key of their fields with their own tag followed by
+// "_", unless they are embedded or tagged ",squash".
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FileEnv is the environment variable naming the YAML configuration file.
+const FileEnv = "CONFIG_FILE"
+
+// ValidationError lists every problem found while loading the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Load populates dst, a pointer to a struct, from the sources described in the
+// package documentation and validates it with its validate tags. args are the
+// command-line arguments without the program name; it returns flag.ErrHelp
+// when they ask for help.
+func Load(dst interface{}, args []string) error {
+	_, err := LoadArgs(dst, args)
+	return err
+}
+
+// LoadArgs is Load for commands that take arguments after their flags; it
+// returns those arguments.
+func LoadArgs(dst interface{}, args []string) ([]string, error) {
+	fields, err := collectFields(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	flagValues, configFile, rest, err := parseFlags(fields, args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(FileEnv)
+	}
+
+	var fileValues map[string]string
+	if configFile != "" {
+		if fileValues, err = readFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	for _, f := range fields {
+		raw, source, ok := lookup(f, fileValues, flagValues)
+		if !ok {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v (from %s)", f.key, err, source))
+		}
+	}
+
+	problems = append(problems, validate(dst, fields)...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return rest, nil
+}
+
+// lookup returns the value of highest precedence set for f and where it came from.
+func lookup(f field, fileValues, flagValues map[string]string) (string, string, bool) {
+	if v, ok := flagValues[f.key]; ok {
+		return v, "flag --" + f.flagName(), true
+	}
+	if v, ok := os.LookupEnv(f.key); ok {
+		return v, "environment", true
+	}
+	if v, ok := fileValues[f.key]; ok {
+		return v, "config file", true
+	}
+	if f.hasDefault {
+		return f.defaultValue, "default", true
+	}
+	return "", "", false
+}
+
+func parseFlags(fields []field, args []string) (map[string]string, string, []string, error) {
+	values := make(map[string]string)
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML configuration file")
+	for _, f := range fields {
+		fs.Var(&flagValue{key: f.key, values: values, isBool: f.isBool()}, f.flagName(), f.usage())
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", nil, err
+	}
+	return values, *configFile, fs.Args(), nil
+}
+
+// flagValue records a flag into the values map, keyed by the field key.
+type flagValue struct {
+	key    string
+	values map[string]string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.values == nil {
+		return ""
+	}
+	return v.values[v.key]
+}
+
+func (v *flagValue) Set(s string) error {
+	v.values[v.key] = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+func validate(dst interface{}, fields []field) []string {
+	err := validator.New().Struct(dst)
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	keys := make(map[string]string, len(fields))
+	for _, f := range fields {
+		keys[f.namespace] = f.key
+	}
+
+	problems := make([]string, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		key, ok := keys[fe.StructNamespace()]
+		if !ok {
+			key = fe.StructNamespace()
+		}
+
+		switch {
+		case fe.Tag() == "required":
+			problems = append(problems, key+" is required")
+		case fe.Param() != "":
+			problems = append(problems, fmt.Sprintf("%s must satisfy %s=%s", key, fe.Tag(), fe.Param()))
+		default:
+			problems = append(problems, fmt.Sprintf("%s must satisfy %s", key, fe.Tag()))
+		}
+	}
+	return problems
+}
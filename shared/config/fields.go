@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// field is a configurable leaf of the destination struct.
+type field struct {
+	key          string
+	namespace    string // struct path as reported by the validator, e.g. Config.DatabaseURL
+	value        reflect.Value
+	defaultValue string
+	hasDefault   bool
+}
+
+func collectFields(dst interface{}) ([]field, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: destination must be a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+	return walk(v, "", v.Type().Name()), nil
+}
+
+func walk(v reflect.Value, prefix, namespace string) []field {
+	var fields []field
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !isLeaf(fv.Type()) {
+			nestedPrefix := prefix
+			if !sf.Anonymous && !strings.Contains(opts, "squash") {
+				if name == "" {
+					name = strings.ToUpper(sf.Name)
+				}
+				nestedPrefix = prefix + name + "_"
+			}
+			fields = append(fields, walk(fv, nestedPrefix, namespace+"."+sf.Name)...)
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToUpper(sf.Name)
+		}
+		def, hasDefault := sf.Tag.Lookup("default")
+		fields = append(fields, field{
+			key:          prefix + name,
+			namespace:    namespace + "." + sf.Name,
+			value:        fv,
+			defaultValue: def,
+			hasDefault:   hasDefault,
+		})
+	}
+
+	return fields
+}
+
+// isLeaf reports whether a struct type is set from a single string rather than
+// field by field.
+func isLeaf(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.key), "_", "-")
+}
+
+func (f field) usage() string {
+	if f.hasDefault {
+		return fmt.Sprintf("overrides %s (default %q)", f.key, f.defaultValue)
+	}
+	return "overrides " + f.key
+}
+
+func (f field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// set parses raw into the field.
+func (f field) set(raw string) error {
+	v := f.value
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
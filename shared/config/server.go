@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// Server holds the listener settings shared by services exposing gRPC and an
+// HTTP gateway. Embed it in a service configuration.
+type Server struct {
+	GRPCPort int `mapstructure:"GRPC_PORT" default:"50051" validate:"min=1,max=65535"`
+	HTTPPort int `mapstructure:"HTTP_PORT" default:"8080" validate:"min=1,max=65535"`
+	// MetricsPort serves /metrics on its own listener, to be kept internal.
+	MetricsPort int `mapstructure:"METRICS_PORT" default:"9090" validate:"min=1,max=65535"`
+
+	HTTPReadTimeout  time.Duration `mapstructure:"HTTP_READ_TIMEOUT" default:"5s"`
+	HTTPWriteTimeout time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT" default:"10s"`
+	HTTPIdleTimeout  time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	ShutdownTimeout  time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" default:"5s"`
+	// ShutdownDrainDelay is how long the service keeps serving after it starts
+	// reporting not ready, so load balancers see it and stop routing to it
+	// before the listeners close.
+	ShutdownDrainDelay time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+
+	// TrustedProxies lists the addresses or CIDRs of the reverse proxies whose
+	// X-Forwarded-For is believed when resolving the client IP. Empty trusts
+	// none: the client is the peer of the connection.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	HealthCheckTimeout  time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	HealthCheckInterval time.Duration `mapstructure:"HEALTH_CHECK_INTERVAL" default:"10s" validate:"gt=0"`
+}
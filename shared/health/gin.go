@@ -0,0 +1,31 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LivezHandler reports that the process is up. It does not check
+// dependencies: a failing database should not get the process restarted.
+func (r *Registry) LivezHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+	}
+}
+
+// ReadyzHandler answers with the status of the latest refresh of Run, 503
+// unless all checks passed and the service is not shutting down. It does not
+// run the checks itself, and leaves out which ones failed and why: those are
+// logged instead.
+func (r *Registry) ReadyzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := r.Status()
+
+		code := http.StatusOK
+		if status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{"status": status})
+	}
+}
@@ -0,0 +1,216 @@
+// Package health runs dependency checks for the liveness and readiness
+// endpoints and the grpc.health.v1 Health service.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Statuses reported for a check and for the service as a whole.
+const (
+	StatusOK           = "ok"
+	StatusError        = "error"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// ErrShuttingDown is reported once graceful shutdown has started.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Checker reports whether a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckFunc adapts a function to a Checker.
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger is implemented by connection pools such as *pgxpool.Pool.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingChecker checks a dependency by pinging it.
+func PingChecker(p Pinger) Checker {
+	return CheckFunc(p.Ping)
+}
+
+// CheckResult is the outcome of one check.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Registry holds the checks of a service. It also owns the gRPC health server
+// so both transports report the same state.
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []namedChecker
+
+	// last is the report of the latest refresh, served by the readiness
+	// endpoint so that probes do not reach the dependencies.
+	last         atomic.Pointer[Report]
+	shuttingDown atomic.Bool
+	grpcServer   *health.Server
+}
+
+// NewRegistry returns an empty registry; each check is given at most timeout.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout:    timeout,
+		grpcServer: health.NewServer(),
+	}
+}
+
+// Register adds a dependency check.
+func (r *Registry) Register(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers = append(r.checkers, namedChecker{name: name, checker: c})
+}
+
+// Check runs every check concurrently.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checkers := r.checkers
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checkers))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, nc := range checkers {
+		wg.Add(1)
+		go func(nc namedChecker) {
+			defer wg.Done()
+			result := r.run(ctx, nc.checker)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(nc)
+	}
+	wg.Wait()
+
+	if r.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Check(ctx)
+	result := CheckResult{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// GRPCServer returns the grpc.health.v1 implementation to register on the
+// gRPC server. Its status follows Run and Shutdown.
+func (r *Registry) GRPCServer() grpc_health_v1.HealthServer {
+	return r.grpcServer
+}
+
+// Run refreshes the readiness report and the gRPC serving status every
+// interval until ctx is cancelled.
+func (r *Registry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) refresh(ctx context.Context) {
+	if r.shuttingDown.Load() {
+		return
+	}
+
+	report := r.Check(ctx)
+	previous := r.last.Swap(&report)
+	for name, result := range report.Checks {
+		if result.Status == StatusOK {
+			continue
+		}
+		if previous == nil || previous.Checks[name].Status == StatusOK {
+			logger.FromContext(ctx).Warn("health check failing", zap.String("check", name), zap.String("error", result.Error))
+		}
+	}
+
+	status := grpc_health_v1.HealthCheckResponse_SERVING
+	if !report.Healthy() {
+		status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+	r.grpcServer.SetServingStatus("", status)
+}
+
+// Status is the overall status of the latest refresh: unavailable until Run
+// has completed one, shutting down once Shutdown was called.
+func (r *Registry) Status() string {
+	if r.shuttingDown.Load() {
+		return StatusShuttingDown
+	}
+	if report := r.last.Load(); report != nil {
+		return report.Status
+	}
+	return StatusUnavailable
+}
+
+// Shutdown marks the service as not ready, so load balancers stop routing to
+// it while in-flight requests drain. Call it first in graceful shutdown and
+// give them time to notice before closing the listeners.
+func (r *Registry) Shutdown() {
+	r.shuttingDown.Store(true)
+	r.grpcServer.Shutdown()
+}
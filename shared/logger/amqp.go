@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+)
+
+// InjectAMQP copies the request id of ctx into the headers of msg.
+func InjectAMQP(ctx context.Context, msg *amqp.Publishing) {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return
+	}
+	if msg.Headers == nil {
+		msg.Headers = amqp.Table{}
+	}
+	msg.Headers[requestIDKey] = id
+}
+
+// ExtractAMQP returns a copy of ctx carrying the request id of the delivery, a
+// new one if it has none, and a logger describing the message.
+func ExtractAMQP(ctx context.Context, d amqp.Delivery) context.Context {
+	id, _ := d.Headers[requestIDKey].(string)
+	if !validRequestID(id) {
+		id = NewRequestID()
+	}
+
+	ctx = ContextWithRequestID(ctx, id)
+	return WithFields(ctx, append(requestFields(ctx, id, "consume "+d.RoutingKey), zap.String("message_id", d.MessageId))...)
+}
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware assigns a request id, or keeps a valid one sent in
+// X-Request-ID, echoes it in the response and stores a logger carrying it in
+// the request context. Install it after the tracing middleware so the trace id
+// is known.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := ContextWithRequestID(c.Request.Context(), id)
+		ctx = WithFields(ctx, requestFields(ctx, id, c.Request.Method+" "+c.Request.URL.Path)...)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
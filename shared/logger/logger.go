@@ -2,16 +2,18 @@ package logger
 
 import (
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 var Log *zap.Logger
 
 func InitLogger(isDev bool) {
 	var err error
+	redact := zap.WrapCore(func(c zapcore.Core) zapcore.Core { return redactCore{c} })
 	if isDev {
-		Log, err = zap.NewDevelopment()
+		Log, err = zap.NewDevelopment(redact)
 	} else {
-		Log, err = zap.NewProduction()
+		Log, err = zap.NewProduction(redact)
 	}
 
 	if err != nil {
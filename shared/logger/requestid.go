@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader is the HTTP header, and in lower case the gRPC metadata key
+// and AMQP header, carrying the request id.
+const (
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "x-request-id"
+)
+
+// maxRequestIDLength bounds ids accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDKeyType struct{}
+
+// NewRequestID returns a random request id.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID returns a copy of ctx carrying id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKeyType{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKeyType{}).(string)
+	return id
+}
+
+// validRequestID rejects ids a client could use to forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// GatewayMiddleware reports the grpc-gateway pattern matched by a request to
+// GinMiddleware, so requests to a catch-all Gin route mounting the gateway are
+// labelled per endpoint. Pass it to runtime.WithMiddlewares.
+func GatewayMiddleware() runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			pattern, ok := runtime.HTTPPattern(r.Context())
+			if route, set := r.Context().Value(routeKey{}).(*string); ok && set {
+				*route = pattern.String()
+			}
+			next(w, r, pathParams)
+		}
+	}
+}
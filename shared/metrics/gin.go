@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests, by method, route and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// routeKey holds a *string in the request context where GatewayMiddleware
+// stores the gateway pattern that served the request.
+type routeKey struct{}
+
+// GinMiddleware records HTTP request metrics. Requests are labelled with the
+// route pattern rather than the path to keep the cardinality bounded: the
+// grpc-gateway pattern if GatewayMiddleware saw the request, else the Gin one.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		gatewayRoute := new(string)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), routeKey{}, gatewayRoute))
+		c.Next()
+
+		route := *gatewayRoute
+		if route == "" {
+			route = c.FullPath()
+		}
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpRequestSeconds.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
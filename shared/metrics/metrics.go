@@ -0,0 +1,15 @@
+// Package metrics exposes Prometheus metrics for the services: gRPC and HTTP
+// request metrics, connection pool statistics and RabbitMQ message counters.
+// Everything is registered with the default registry.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of a consumed message.
+const (
+	ConsumeAck     = "ack"
+	ConsumeRequeue = "requeue"
+	ConsumeDrop    = "drop"
+)
+
+var (
+	rabbitPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_published_total",
+		Help: "Total number of messages published, by exchange and routing key.",
+	}, []string{"exchange", "routing_key"})
+
+	rabbitPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_publish_failures_total",
+		Help: "Total number of messages that could not be published.",
+	}, []string{"exchange", "routing_key"})
+
+	rabbitConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_messages_consumed_total",
+		Help: "Total number of messages consumed, by queue and outcome (ack, requeue, drop).",
+	}, []string{"queue", "outcome"})
+)
+
+// ObservePublish counts a publish attempt.
+func ObservePublish(exchange, routingKey string, err error) {
+	if err != nil {
+		rabbitPublishFailures.WithLabelValues(exchange, routingKey).Inc()
+		return
+	}
+	rabbitPublished.WithLabelValues(exchange, routingKey).Inc()
+}
+
+// ObserveConsume counts a consumed message with one of the Consume* outcomes.
+func ObserveConsume(queue, outcome string) {
+	rabbitConsumed.WithLabelValues(queue, outcome).Inc()
+}
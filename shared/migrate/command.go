@@ -0,0 +1,117 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+)
+
+// Usage describes the arguments of Command.
+const Usage = `usage: migrate <command>
+
+commands:
+  up           apply every pending migration
+  down N       revert the last N migrations
+  status       show the applied version and pending migrations
+  force V      record version V as applied without running anything (0: none)`
+
+// Prepare readies the schema for the service to start. With auto it applies
+// pending migrations first; either way it fails unless the schema matches
+// the migrations of this binary exactly.
+func Prepare(ctx context.Context, databaseURL string, fsys fs.FS, auto bool) error {
+	m, err := Open(ctx, databaseURL, fsys)
+	if err != nil {
+		return err
+	}
+	defer m.Close(ctx)
+
+	if auto {
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			logger.Log.Info("applied migration", zap.String("migration", mig.String()))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return m.Check(ctx)
+}
+
+// arity is the number of arguments of each command.
+var arity = map[string]int{"up": 0, "down": 1, "status": 0, "force": 1}
+
+// Command runs the migrate subcommand of a service with args, the arguments
+// after "migrate", and writes what it did to out.
+func Command(ctx context.Context, databaseURL string, fsys fs.FS, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+	if n, ok := arity[args[0]]; !ok || len(args)-1 != n {
+		return errors.New(Usage)
+	}
+
+	m, err := Open(ctx, databaseURL, fsys)
+	if err != nil {
+		return err
+	}
+	defer m.Close(ctx)
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Fprintf(out, "applied %s\n", mig)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("down: invalid number of migrations %q", args[0])
+		}
+		reverted, err := m.Down(ctx, n)
+		for _, mig := range reverted {
+			fmt.Fprintf(out, "reverted %s\n", mig)
+		}
+		return err
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "version: %d\n", status.Version)
+		if status.Dirty {
+			fmt.Fprintln(out, "dirty:   true")
+		}
+		fmt.Fprintf(out, "latest:  %d\n", status.Latest)
+		if status.Version > status.Latest {
+			fmt.Fprintln(out, "the database is ahead of this binary")
+		}
+		for _, mig := range status.Pending {
+			fmt.Fprintf(out, "pending: %s\n", mig)
+		}
+		return nil
+
+	case "force":
+		version, err := strconv.ParseUint(args[0], 10, 63)
+		if err != nil {
+			return fmt.Errorf("force: invalid version %q", args[0])
+		}
+		if err := m.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "forced version %d\n", version)
+		return nil
+	}
+	return errors.New(Usage)
+}
@@ -0,0 +1,306 @@
+// Package migrate applies the versioned SQL migrations embedded in a service
+// binary. Migrations are files named
+//
+//	000001_create_accounts.up.sql
+//	000001_create_accounts.down.sql
+//
+// and the applied version is kept in the schema_migrations table in the same
+// format as the golang-migrate CLI, so databases migrated with either tool can
+// be managed by the other.
+//
+// Changes are serialized across processes with a Postgres advisory lock, so
+// replicas started together can all run Up safely: the first applies the
+// migrations and the others find nothing left to do.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockKey identifies the advisory lock held while migrating.
+const lockKey int64 = 0x6d6967726174 // "migrat"
+
+var (
+	// ErrDirty means a migration failed half-way. Fix the schema by hand, then
+	// Force the version it is at.
+	ErrDirty = errors.New("database schema is dirty")
+	// ErrSchemaAhead means the database was migrated by a newer binary.
+	ErrSchemaAhead = errors.New("database schema is newer than this binary")
+	// ErrSchemaBehind means migrations of this binary have not been applied.
+	ErrSchemaBehind = errors.New("database schema is older than this binary")
+)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	up      string
+	down    string
+	hasDown bool
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%06d_%s", m.Version, m.Name)
+}
+
+// Status describes where the database schema stands.
+type Status struct {
+	// Version is the last applied migration, 0 if none.
+	Version uint64
+	Dirty   bool
+	// Latest is the version of the last migration of this binary.
+	Latest  uint64
+	Pending []Migration
+}
+
+// Migrator applies migrations over a dedicated connection.
+type Migrator struct {
+	conn       *pgx.Conn
+	migrations []Migration
+}
+
+// Open connects to databaseURL and loads the migrations at the root of fsys.
+func Open(ctx context.Context, databaseURL string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`)
+	if err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return &Migrator{conn: conn, migrations: migrations}, nil
+}
+
+func (m *Migrator) Close(ctx context.Context) error {
+	return m.conn.Close(ctx)
+}
+
+// load reads and orders the migrations in fsys.
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, name := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", name)
+		}
+		digits, title, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(digits, 10, 63)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version above 0", name)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Clean(name))
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: title}
+			byVersion[version] = mig
+		} else if mig.Name != title {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, mig.Name, title)
+		}
+		if direction == "up" {
+			mig.up = string(data)
+		} else {
+			mig.down, mig.hasDown = string(data), true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.up == "" {
+			return nil, fmt.Errorf("migration %s: missing up file", mig)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status reports the applied version and what Up would apply.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	version, dirty, err := m.version(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Version: version, Dirty: dirty}
+	if n := len(m.migrations); n > 0 {
+		status.Latest = m.migrations[n-1].Version
+	}
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			status.Pending = append(status.Pending, mig)
+		}
+	}
+	return status, nil
+}
+
+// Check returns an error unless every migration of this binary, and nothing
+// newer, has been applied cleanly.
+func (m *Migrator) Check(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	return status.check(false)
+}
+
+func (s Status) check(allowPending bool) error {
+	switch {
+	case s.Dirty:
+		return fmt.Errorf("%w at version %d", ErrDirty, s.Version)
+	case s.Version > s.Latest:
+		return fmt.Errorf("%w: at version %d, binary knows up to %d", ErrSchemaAhead, s.Version, s.Latest)
+	case len(s.Pending) > 0 && !allowPending:
+		return fmt.Errorf("%w: at version %d, binary needs %d", ErrSchemaBehind, s.Version, s.Latest)
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func() error {
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if err := status.check(true); err != nil {
+			return err
+		}
+
+		for _, mig := range status.Pending {
+			if err := m.apply(ctx, mig.Version, mig.up, mig.Version); err != nil {
+				return fmt.Errorf("migration %s: %w", mig, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last n applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if n < 1 {
+		return nil, errors.New("number of migrations to revert must be at least 1")
+	}
+
+	var reverted []Migration
+	err := m.locked(ctx, func() error {
+		version, dirty, err := m.version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d", ErrDirty, version)
+		}
+
+		for ; n > 0 && version > 0; n-- {
+			i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+			if i == len(m.migrations) || m.migrations[i].Version != version {
+				return fmt.Errorf("%w: no migration %d to revert", ErrSchemaAhead, version)
+			}
+			mig := m.migrations[i]
+			if !mig.hasDown {
+				return fmt.Errorf("migration %s: missing down file", mig)
+			}
+
+			var previous uint64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(ctx, mig.Version, mig.down, previous); err != nil {
+				return fmt.Errorf("reverting migration %s: %w", mig, err)
+			}
+			reverted = append(reverted, mig)
+			version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as applied cleanly without running anything, e.g.
+// once a dirty schema has been repaired by hand. 0 means no migration.
+func (m *Migrator) Force(ctx context.Context, version uint64) error {
+	return m.locked(ctx, func() error {
+		return m.setVersion(ctx, version, false)
+	})
+}
+
+// apply runs sql, flagging the schema dirty at version until it succeeds and
+// the schema is at result.
+func (m *Migrator) apply(ctx context.Context, version uint64, sql string, result uint64) error {
+	if err := m.setVersion(ctx, version, true); err != nil {
+		return err
+	}
+	if _, err := m.conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return m.setVersion(ctx, result, false)
+}
+
+func (m *Migrator) version(ctx context.Context) (uint64, bool, error) {
+	var version int64
+	var dirty bool
+	err := m.conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if version < 0 {
+		// golang-migrate's marker for no version.
+		return 0, dirty, nil
+	}
+	return uint64(version), dirty, nil
+}
+
+func (m *Migrator) setVersion(ctx context.Context, version uint64, dirty bool) error {
+	return pgx.BeginFunc(ctx, m.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+			return err
+		}
+		if version == 0 && !dirty {
+			return nil
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty)
+		return err
+	})
+}
+
+// locked runs fn holding the migration lock, waiting for other processes to
+// release it.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if _, err := m.conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer m.conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+	return fn()
+}
@@ -0,0 +1,216 @@
+// Package pgtest gives each test a Postgres database of its own, migrated
+// with the migrations of the service under test.
+//
+// The server is the one DATABASE_URL points at if it is set; its role needs
+// CREATEDB, and only databases named pgtest_* are created or dropped.
+// Otherwise a throwaway server is started in a temporary directory from the
+// PostgreSQL binaries found in PGTEST_BIN, on the PATH or in the usual install
+// locations, and stopped by Run. Without either, tests calling New are
+// skipped.
+//
+// Migrations are applied once into a template database, named after a hash
+// of the migrations so that changing them yields a new one, and every test
+// gets a copy of it that is dropped when the test ends.
+package pgtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockKey identifies the advisory lock held while creating a template, so
+// test binaries run in parallel do not migrate the same one twice.
+const lockKey int64 = 0x706774657374 // "pgtest"
+
+var (
+	templatesMu sync.Mutex
+	// templates holds the template databases known to exist, by migrations hash.
+	templates = make(map[string]bool)
+)
+
+// Run runs the tests of m and then stops the server started for them, if any.
+// Packages using New call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Run(m)) }
+func Run(m *testing.M) int {
+	code := m.Run()
+	stopServer()
+	return code
+}
+
+// New creates a database migrated with the migrations at the root of fsys and
+// returns a pool connected to it. The database is dropped when t ends.
+func New(t testing.TB, fsys fs.FS) *pgxpool.Pool {
+	t.Helper()
+	serverURL, err := startServer()
+	if err != nil {
+		t.Skipf("pgtest: no Postgres available: %v", err)
+	}
+	ctx := context.Background()
+
+	template, err := ensureTemplate(ctx, serverURL, fsys)
+	if err != nil {
+		t.Fatalf("pgtest: preparing template database: %v", err)
+	}
+
+	name := "pgtest_" + randomHex(8)
+	if err := execServer(ctx, serverURL, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()+" TEMPLATE "+pgx.Identifier{template}.Sanitize()); err != nil {
+		t.Fatalf("pgtest: creating database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := execServer(ctx, serverURL, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+			t.Errorf("pgtest: dropping database %s: %v", name, err)
+		}
+	})
+
+	dbURL, err := withDatabase(serverURL, name)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		t.Fatalf("pgtest: connecting to %s: %v", name, err)
+	}
+	// Registered after the drop, so it runs before it.
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// ensureTemplate returns the name of a database with the migrations of fsys
+// applied, creating it if needed.
+func ensureTemplate(ctx context.Context, serverURL string, fsys fs.FS) (string, error) {
+	hash, err := hashMigrations(fsys)
+	if err != nil {
+		return "", err
+	}
+	name := "pgtest_template_" + hash
+
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if templates[name] {
+		return name, nil
+	}
+
+	conn, err := pgx.Connect(ctx, serverURL)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return "", err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	var exists bool
+	err = conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, name).Scan(&exists)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		if err := createTemplate(ctx, conn, serverURL, name, fsys); err != nil {
+			return "", err
+		}
+	}
+
+	templates[name] = true
+	return name, nil
+}
+
+// createTemplate creates database name and applies the migrations of fsys to
+// it. A template left half-migrated is dropped.
+func createTemplate(ctx context.Context, conn *pgx.Conn, serverURL, name string, fsys fs.FS) (err error) {
+	ident := pgx.Identifier{name}.Sanitize()
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+ident); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			conn.Exec(context.WithoutCancel(ctx), "DROP DATABASE IF EXISTS "+ident)
+		}
+	}()
+
+	templateURL, err := withDatabase(serverURL, name)
+	if err != nil {
+		return err
+	}
+	m, err := migrate.Open(ctx, templateURL, fsys)
+	if err != nil {
+		return err
+	}
+	// Copies cannot be made while anyone is connected to the template.
+	defer m.Close(ctx)
+
+	_, err = m.Up(ctx)
+	return err
+}
+
+// hashMigrations identifies the migrations in fsys by their names and contents.
+func hashMigrations(fsys fs.FS) (string, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no migrations found")
+	}
+
+	h := sha256.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// execServer runs sql over a new connection to the server, as CREATE DATABASE and
+// DROP DATABASE cannot run in a transaction or from the database they affect.
+func execServer(ctx context.Context, serverURL, sql string) error {
+	conn, err := pgx.Connect(ctx, serverURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, sql)
+	return err
+}
+
+// withDatabase returns connString with its database replaced by name. Both URLs
+// and key/value connection strings are accepted.
+func withDatabase(connString, name string) (string, error) {
+	if !strings.HasPrefix(connString, "postgres://") && !strings.HasPrefix(connString, "postgresql://") {
+		// Later keys override earlier ones.
+		return connString + " dbname=" + name, nil
+	}
+
+	u, err := url.Parse(connString)
+	if err != nil {
+		return "", fmt.Errorf("parsing database URL: %w", err)
+	}
+	u.Path = "/" + name
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,125 @@
+package pgtest
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	serverOnce sync.Once
+	serverURL  string
+	serverErr  error
+	// serverDir is the directory of the server started by startServer, if any.
+	serverDir string
+	pgCtl     string
+)
+
+// startServer returns the URL of the maintenance database of the server tests
+// run against, starting one on first use if DATABASE_URL is not set.
+func startServer() (string, error) {
+	serverOnce.Do(func() {
+		if u := os.Getenv("DATABASE_URL"); u != "" {
+			serverURL = u
+			return
+		}
+		serverURL, serverErr = startEphemeral()
+	})
+	return serverURL, serverErr
+}
+
+// startEphemeral initialises a cluster in a temporary directory and starts it
+// listening only on a Unix socket in that directory.
+func startEphemeral() (string, error) {
+	bin, err := findBinaries()
+	if err != nil {
+		return "", err
+	}
+	if os.Geteuid() == 0 {
+		return "", errors.New("PostgreSQL refuses to run as root; set DATABASE_URL instead")
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest-")
+	if err != nil {
+		return "", err
+	}
+	data := filepath.Join(dir, "data")
+
+	initdb := exec.Command(filepath.Join(bin, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync")
+	if out, err := initdb.CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("initdb: %w\n%s", err, out)
+	}
+
+	pgCtl = filepath.Join(bin, "pg_ctl")
+	// fsync is off: the cluster is thrown away when the tests end.
+	opts := fmt.Sprintf("-k %s -c listen_addresses='' -F", dir)
+	start := exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "log"), "-w", "-o", opts, "start")
+	if out, err := start.CombinedOutput(); err != nil {
+		log, _ := os.ReadFile(filepath.Join(dir, "log"))
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("pg_ctl start: %w\n%s%s", err, out, log)
+	}
+
+	serverDir = dir
+	return "postgres://postgres@/postgres?sslmode=disable&host=" + url.QueryEscape(dir), nil
+}
+
+// stopServer stops the server started by startServer and removes its files.
+func stopServer() {
+	if serverDir == "" {
+		return
+	}
+	stop := exec.Command(pgCtl, "-D", filepath.Join(serverDir, "data"), "-m", "immediate", "-w", "stop")
+	if out, err := stop.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "pgtest: pg_ctl stop: %v\n%s", err, out)
+	}
+	os.RemoveAll(serverDir)
+	serverDir = ""
+}
+
+// findBinaries returns the directory holding initdb and pg_ctl, looking in
+// PGTEST_BIN, on the PATH and then in the usual install locations, newest
+// version first.
+func findBinaries() (string, error) {
+	if dir := os.Getenv("PGTEST_BIN"); dir != "" {
+		if hasBinaries(dir) {
+			return dir, nil
+		}
+		return "", fmt.Errorf("initdb and pg_ctl not found in PGTEST_BIN %s", dir)
+	}
+
+	if initdb, err := exec.LookPath("initdb"); err == nil {
+		if dir := filepath.Dir(initdb); hasBinaries(dir) {
+			return dir, nil
+		}
+	}
+
+	var candidates []string
+	for _, pattern := range []string{"/usr/lib/postgresql/*/bin", "/usr/pgsql-*/bin", "/opt/homebrew/opt/postgresql@*/bin", "/usr/local/opt/postgresql@*/bin"} {
+		matches, _ := filepath.Glob(pattern)
+		candidates = append(candidates, matches...)
+	}
+	// Lexical order is good enough for the two-digit versions in use.
+	sort.Sort(sort.Reverse(sort.StringSlice(candidates)))
+	for _, dir := range candidates {
+		if hasBinaries(dir) {
+			return dir, nil
+		}
+	}
+	return "", errors.New("DATABASE_URL is not set and initdb was not found; install PostgreSQL or set PGTEST_BIN")
+}
+
+func hasBinaries(dir string) bool {
+	for _, name := range []string{"initdb", "pg_ctl"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
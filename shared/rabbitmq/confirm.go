@@ -0,0 +1,216 @@
+package rabbitmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrNacked is returned when the broker refuses responsibility for a message.
+	ErrNacked = errors.New("rabbitmq did not confirm the message")
+	// ErrUnroutable is returned when no queue is bound for a message.
+	ErrUnroutable = errors.New("rabbitmq could not route the message to any queue")
+	// ErrChannelClosed is returned once the confirm channel is gone.
+	ErrChannelClosed = errors.New("rabbitmq confirm channel closed")
+)
+
+// ConfirmChannel publishes on a channel in confirm mode. Publish returns only
+// once the broker has taken responsibility for the message and, for mandatory
+// messages, routed it to a queue.
+//
+// Confirmations and returns are read by a goroutine of their own for as long
+// as the channel is open, so a publisher that stops waiting, for instance
+// because its context was cancelled, never leaves them unread: streadway/amqp
+// blocks the whole connection when a notification channel is full.
+type ConfirmChannel struct {
+	channel *amqp.Channel
+
+	// publishMu keeps publishing and numbering a message atomic: tags are
+	// assigned by the broker in publish order.
+	publishMu sync.Mutex
+
+	// mu guards the fields below. It is never held while publishing, so the
+	// dispatcher is not held up by a slow write.
+	mu      sync.Mutex
+	closed  bool
+	lastTag uint64
+	// pending holds the messages awaiting confirmation, by delivery tag.
+	pending map[uint64]*pendingPublish
+	// returned holds the ids of the pending messages the broker returned.
+	returned map[string]bool
+}
+
+type pendingPublish struct {
+	messageID string
+	done      chan error
+}
+
+// NewConfirmChannel opens a channel on conn and puts it in confirm mode.
+func NewConfirmChannel(conn *amqp.Connection) (*ConfirmChannel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	c := &ConfirmChannel{
+		channel:  ch,
+		pending:  make(map[uint64]*pendingPublish),
+		returned: make(map[string]bool),
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 64))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 64))
+	go c.dispatch(confirms, returns)
+	return c, nil
+}
+
+// Publish sends msg and waits for the broker to confirm it. A mandatory
+// message that no queue is bound for fails with ErrUnroutable; otherwise it is
+// confirmed and dropped, which suits events nobody may be listening to.
+//
+// A message id is generated if msg has none; consumers should use it to drop
+// duplicates, since a message whose confirmation was lost may be published
+// again. When ctx is done Publish stops waiting, but the message
+// may still have been delivered.
+func (c *ConfirmChannel) Publish(ctx context.Context, exchange, routingKey string, mandatory bool, msg amqp.Publishing) error {
+	if msg.MessageId == "" {
+		msg.MessageId = newMessageID()
+	}
+	done := make(chan error, 1)
+
+	c.publishMu.Lock()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		c.publishMu.Unlock()
+		return ErrChannelClosed
+	}
+	// Registered before publishing, as the confirmation may arrive before
+	// Publish returns.
+	tag := c.lastTag + 1
+	c.pending[tag] = &pendingPublish{messageID: msg.MessageId, done: done}
+	c.mu.Unlock()
+
+	err := c.channel.Publish(exchange, routingKey, mandatory, false, msg)
+
+	c.mu.Lock()
+	if err != nil {
+		delete(c.pending, tag)
+	} else {
+		c.lastTag = tag
+	}
+	c.mu.Unlock()
+	c.publishMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
+// dispatch resolves pending messages until the channel closes.
+func (c *ConfirmChannel) dispatch(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			c.markReturned(r)
+		case conf, ok := <-confirms:
+			if !ok {
+				c.closeAll()
+				return
+			}
+			// The broker sends basic.return before the ack of the same
+			// message, and streadway/amqp delivers them in that order, so any
+			// return for it is already buffered.
+			c.drainReturns(returns)
+			c.resolve(conf)
+		}
+	}
+}
+
+func (c *ConfirmChannel) drainReturns(returns <-chan amqp.Return) {
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				return
+			}
+			c.markReturned(r)
+		default:
+			return
+		}
+	}
+}
+
+func (c *ConfirmChannel) markReturned(r amqp.Return) {
+	c.mu.Lock()
+	c.returned[r.MessageId] = true
+	c.mu.Unlock()
+}
+
+func (c *ConfirmChannel) resolve(conf amqp.Confirmation) {
+	c.mu.Lock()
+	p, ok := c.pending[conf.DeliveryTag]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, conf.DeliveryTag)
+	returned := c.returned[p.messageID]
+	delete(c.returned, p.messageID)
+	c.mu.Unlock()
+
+	switch {
+	case !conf.Ack:
+		p.done <- ErrNacked
+	case returned:
+		p.done <- ErrUnroutable
+	default:
+		p.done <- nil
+	}
+}
+
+// closeAll fails every pending message once the channel is closed.
+func (c *ConfirmChannel) closeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for tag, p := range c.pending {
+		p.done <- ErrChannelClosed
+		delete(c.pending, tag)
+	}
+}
+
+// Channel returns the underlying channel, to declare the exchanges and queues
+// messages are published to.
+func (c *ConfirmChannel) Channel() *amqp.Channel {
+	return c.channel
+}
+
+func (c *ConfirmChannel) Close() error {
+	return c.channel.Close()
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/gateway"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxEmailBodySize bounds how much of a request body is read to find the email.
+const maxEmailBodySize = 64 << 10
+
+// GinMiddleware enforces the limiter's rules keyed by route, see ginRoute, and
+// sets the RateLimit-* headers; rejected requests get 429 with Retry-After.
+// The client IP is Gin's ClientIP, so the engine's trusted proxies must be set
+// for it not to be taken from a spoofed X-Forwarded-For.
+func GinMiddleware(l *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := Client{
+			IP:    c.ClientIP(),
+			Email: emailFromBody(c.Request),
+		}
+
+		res, ok := l.Allow(c.Request.Context(), ginRoute(l, c), client)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.FormatInt(seconds(res.ResetAfter), 10))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.FormatInt(seconds(res.RetryAfter), 10))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gateway.NewErrorResponse(status.New(codes.ResourceExhausted, "too many requests")))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ginRoute names the rule a request falls under: the template of the matched
+// Gin route if it has a rule, else the cleaned request path, for routes behind
+// a catch-all such as the grpc-gateway mount. Variants of a path, such as a
+// trailing slash or a doubled one, map to the same rule, and paths without a
+// rule share the buckets of DefaultRoute.
+func ginRoute(l *Limiter, c *gin.Context) string {
+	if full := c.FullPath(); full != "" && l.hasRule(full) {
+		return full
+	}
+	return path.Clean("/" + c.Request.URL.Path)
+}
+
+// emailFromBody peeks at a JSON body for an "email" field and puts the body
+// back for the next handler.
+func emailFromBody(r *http.Request) string {
+	if r.Body == nil || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEmailBodySize))
+	if err != nil {
+		return ""
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return payload.Email
+}
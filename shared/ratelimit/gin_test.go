@@ -0,0 +1,93 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func newEngine(t *testing.T, rules ratelimit.Rules, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), rules)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.Any("/api/v1/auth/*any", ratelimit.GinMiddleware(limiter), ok)
+	r.GET("/users/:id", ratelimit.GinMiddleware(limiter), ok)
+	return r
+}
+
+func serve(r *gin.Engine, target, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestGinMiddlewarePathVariantsShareBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []string
+	}{
+		{name: "gateway path variants", targets: []string{"/api/v1/auth/login", "/api/v1/auth/login/", "/api/v1/auth//login?x=1"}},
+		{name: "route template", targets: []string{"/users/1", "/users/2", "/users/3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newEngine(t, ratelimit.Rules{
+				"/api/v1/auth/login": policy(2),
+				"/users/:id":         policy(2),
+			}, nil)
+
+			for i, target := range tt.targets {
+				want := http.StatusOK
+				if i >= 2 {
+					want = http.StatusTooManyRequests
+				}
+				if got := serve(r, target, "192.0.2.1:1234", ""); got != want {
+					t.Fatalf("GET %s = %d, want %d", target, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGinMiddlewareTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		// spoofable is whether a client can get a fresh bucket by sending a
+		// new X-Forwarded-For.
+		spoofable bool
+	}{
+		{name: "no trusted proxy"},
+		{name: "request not from a trusted proxy", trustedProxies: []string{"10.0.0.0/8"}},
+		{name: "request from a trusted proxy", trustedProxies: []string{"192.0.2.0/24"}, spoofable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newEngine(t, ratelimit.Rules{"/api/v1/auth/login": policy(1)}, tt.trustedProxies)
+
+			if got := serve(r, "/api/v1/auth/login", "192.0.2.1:1234", "203.0.113.1"); got != http.StatusOK {
+				t.Fatalf("first request = %d, want %d", got, http.StatusOK)
+			}
+			want := http.StatusTooManyRequests
+			if tt.spoofable {
+				want = http.StatusOK
+			}
+			if got := serve(r, "/api/v1/auth/login", "192.0.2.1:1234", "203.0.113.2"); got != want {
+				t.Errorf("request with another X-Forwarded-For = %d, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type emailGetter interface {
+	GetEmail() string
+}
+
+// UnaryServerInterceptor enforces the limiter's rules keyed by full method name.
+// The email key is taken from requests that have a GetEmail method. Calls for
+// which skip returns true are not limited.
+func UnaryServerInterceptor(l *Limiter, skip func(context.Context) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if skip != nil && skip(ctx) {
+			return handler(ctx, req)
+		}
+
+		client := Client{IP: peerIP(ctx)}
+		if r, ok := req.(emailGetter); ok {
+			client.Email = r.GetEmail()
+		}
+
+		res, ok := l.Allow(ctx, info.FullMethod, client)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		md := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(res.Limit),
+			"ratelimit-remaining", strconv.Itoa(res.Remaining),
+			"ratelimit-reset", strconv.FormatInt(seconds(res.ResetAfter), 10),
+		)
+		if !res.Allowed {
+			md.Append("retry-after", strconv.FormatInt(seconds(res.RetryAfter), 10))
+			_ = grpc.SetHeader(ctx, md)
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+		_ = grpc.SetHeader(ctx, md)
+		return handler(ctx, req)
+	}
+}
+
+// gatewayKeyHeader is the metadata key GatewayKey is sent in.
+const gatewayKeyHeader = "x-ratelimit-gateway-key"
+
+// GatewayKey marks the calls the in-process HTTP gateway makes to the gRPC
+// server. They are limited by GinMiddleware already and should not be counted
+// twice. They are recognised by a secret made up at startup rather than by
+// their loopback address, which any other local process or proxy shares.
+type GatewayKey struct {
+	secret string
+}
+
+func NewGatewayKey() *GatewayKey {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return &GatewayKey{secret: hex.EncodeToString(b)}
+}
+
+// DialOption sends the key with every call made over the gateway connection.
+func (k *GatewayKey) DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.AppendToOutgoingContext(ctx, gatewayKeyHeader, k.secret), method, req, reply, cc, opts...)
+	})
+}
+
+// FromGateway reports whether the call carries the key. It is meant as the
+// skip function of UnaryServerInterceptor.
+func (k *GatewayKey) FromGateway(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, v := range md.Get(gatewayKeyHeader) {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(k.secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+)
+
+// Rules maps a route (an HTTP path or a full gRPC method name) to its policy.
+// The DefaultRoute entry, if any, applies to every other route.
+type Rules map[string]Policy
+
+// lookup returns the rule applying to route and its policy. Routes without a
+// rule of their own fall under DefaultRoute.
+func (r Rules) lookup(route string) (string, Policy, bool) {
+	if p, ok := r[route]; ok {
+		return route, p, true
+	}
+	p, ok := r[DefaultRoute]
+	return DefaultRoute, p, ok
+}
+
+// Client identifies the caller for the keys a policy can use.
+type Client struct {
+	IP    string
+	Email string
+}
+
+type Limiter struct {
+	store Store
+	rules Rules
+}
+
+func NewLimiter(store Store, rules Rules) *Limiter {
+	return &Limiter{
+		store: store,
+		rules: rules,
+	}
+}
+
+// Allow takes a token from every bucket the route's policy keys the client on,
+// or from none of them if one is empty. Buckets belong to the rule rather than
+// the route, so all routes falling under DefaultRoute share them. ok is false
+// when no policy applies. Store errors fail open.
+func (l *Limiter) Allow(ctx context.Context, route string, client Client) (res Result, ok bool) {
+	rule, policy, ok := l.rules.lookup(route)
+	if !ok {
+		return Result{}, false
+	}
+
+	res = Result{Allowed: true, Limit: policy.Limit, Remaining: policy.Limit}
+	var keys []string
+	for _, keyBy := range policy.KeyBy {
+		var value string
+		switch keyBy {
+		case KeyIP:
+			value = client.IP
+		case KeyEmail:
+			value = strings.ToLower(strings.TrimSpace(client.Email))
+		}
+		if value == "" {
+			continue
+		}
+		keys = append(keys, rule+"|"+keyBy+"|"+value)
+	}
+	if len(keys) == 0 {
+		return res, true
+	}
+
+	taken, err := l.store.Take(ctx, keys, policy)
+	if err != nil {
+		logger.FromContext(ctx).Warn("rate limit store error", zap.String("route", route), zap.Error(err))
+		return res, true
+	}
+	return taken, true
+}
+
+// hasRule reports whether route has a rule of its own.
+func (l *Limiter) hasRule(route string) bool {
+	_, ok := l.rules[route]
+	return ok
+}
+
+// seconds rounds d up to whole seconds, as used by the RateLimit headers.
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
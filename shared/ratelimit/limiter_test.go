@@ -0,0 +1,166 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// policy allows n requests an hour, so no token is refilled during a test. It
+// is keyed by IP unless told otherwise, like ParsePolicy.
+func policy(n int, keyBy ...string) ratelimit.Policy {
+	if len(keyBy) == 0 {
+		keyBy = []string{ratelimit.KeyIP}
+	}
+	return ratelimit.Policy{Limit: n, Period: time.Hour, KeyBy: keyBy}
+}
+
+// allow calls l.Allow and reports whether the request was let through.
+func allow(t *testing.T, l *ratelimit.Limiter, route string, client ratelimit.Client) bool {
+	t.Helper()
+	res, ok := l.Allow(context.Background(), route, client)
+	if !ok {
+		t.Fatalf("Allow(%q): no policy applies", route)
+	}
+	return res.Allowed
+}
+
+func TestAllowDeniedRequestDrainsNoBucket(t *testing.T) {
+	l := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Rules{
+		"/login": policy(2, ratelimit.KeyIP, ratelimit.KeyEmail),
+	})
+
+	// Exhaust the bucket of the IP.
+	for i := 0; i < 2; i++ {
+		if !allow(t, l, "/login", ratelimit.Client{IP: "10.0.0.1", Email: "alice@example.com"}) {
+			t.Fatalf("request %d denied, want allowed", i+1)
+		}
+	}
+	// Denied by the IP bucket: bob's bucket must not lose a token.
+	if allow(t, l, "/login", ratelimit.Client{IP: "10.0.0.1", Email: "bob@example.com"}) {
+		t.Fatal("request over the IP limit allowed")
+	}
+
+	for i := 0; i < 2; i++ {
+		if !allow(t, l, "/login", ratelimit.Client{IP: "10.0.0.2", Email: "bob@example.com"}) {
+			t.Fatalf("request %d for bob from another IP denied, want allowed", i+1)
+		}
+	}
+	if allow(t, l, "/login", ratelimit.Client{IP: "10.0.0.3", Email: "Bob@example.com "}) {
+		t.Error("request over the email limit allowed")
+	}
+}
+
+func TestAllowRoutesWithoutRuleShareDefault(t *testing.T) {
+	l := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Rules{
+		"/login":               policy(5),
+		ratelimit.DefaultRoute: policy(2),
+	})
+	client := ratelimit.Client{IP: "10.0.0.1"}
+
+	for _, route := range []string{"/users/1", "/users/2"} {
+		if !allow(t, l, route, client) {
+			t.Fatalf("%s denied, want allowed", route)
+		}
+	}
+	if allow(t, l, "/users/3", client) {
+		t.Error("third request under the default rule allowed, want the routes to share one bucket")
+	}
+	if !allow(t, l, "/login", client) {
+		t.Error("route with a rule of its own denied")
+	}
+}
+
+func TestAllowWithoutPolicy(t *testing.T) {
+	l := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Rules{"/login": policy(1)})
+	if _, ok := l.Allow(context.Background(), "/users", ratelimit.Client{IP: "10.0.0.1"}); ok {
+		t.Error("Allow applied a policy to a route without rule or default")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ratelimit.Policy
+		wantErr bool
+	}{
+		{in: "5/1m", want: ratelimit.Policy{Limit: 5, Period: time.Minute, KeyBy: []string{ratelimit.KeyIP}}},
+		{in: "5/1m,burst=10,by=ip+email", want: ratelimit.Policy{Limit: 5, Period: time.Minute, Burst: 10, KeyBy: []string{ratelimit.KeyIP, ratelimit.KeyEmail}}},
+		{in: "5", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "5/0s", wantErr: true},
+		{in: "5/1m,burst=0", wantErr: true},
+		{in: "5/1m,by=user", wantErr: true},
+		{in: "5/1m,every=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ratelimit.ParsePolicy(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParsePolicy(%q) = %+v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePolicy(%q): %v", tt.in, err)
+			}
+			if got.Limit != tt.want.Limit || got.Period != tt.want.Period || got.Burst != tt.want.Burst || len(got.KeyBy) != len(tt.want.KeyBy) {
+				t.Fatalf("ParsePolicy(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			for i := range got.KeyBy {
+				if got.KeyBy[i] != tt.want.KeyBy[i] {
+					t.Fatalf("ParsePolicy(%q) = %+v, want %+v", tt.in, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayKey(t *testing.T) {
+	key := ratelimit.NewGatewayKey()
+
+	// Capture the metadata the dial option adds; the call never reaches the
+	// network.
+	var sent metadata.MD
+	capture := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	conn, err := grpc.NewClient("passthrough:///gateway", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		key.DialOption(), grpc.WithChainUnaryInterceptor(capture))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Invoke(context.Background(), "/test.Service/Method", nil, nil); err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want bool
+	}{
+		{name: "sent by the gateway", md: sent, want: true},
+		{name: "guessed key", md: metadata.Pairs("x-ratelimit-gateway-key", "guess")},
+		{name: "key of another process", md: metadata.Pairs("x-ratelimit-gateway-key", sent.Get("x-ratelimit-gateway-key")[0]+"0")},
+		{name: "no metadata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := key.FromGateway(ctx); got != tt.want {
+				t.Errorf("FromGateway = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// MemoryStore is a process-local Store. Buckets that have refilled are dropped
+// periodically so memory stays bounded by the number of active clients.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, keys []string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := policy.capacity()
+	rate := policy.ratePerSecond()
+
+	buckets := make([]*bucket, len(keys))
+	allowed := true
+	for i, key := range keys {
+		b, ok := s.buckets[key]
+		if !ok {
+			b = &bucket{tokens: capacity, last: now}
+			s.buckets[key] = b
+		}
+
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.last = now
+		allowed = allowed && b.tokens >= 1
+		buckets[i] = b
+	}
+
+	res := Result{Allowed: allowed, Limit: policy.Limit, Remaining: policy.Limit}
+	for _, b := range buckets {
+		if allowed {
+			b.tokens--
+		} else if b.tokens < 1 {
+			res.RetryAfter = max(res.RetryAfter, secondsToDuration((1-b.tokens)/rate))
+		}
+
+		resetAfter := secondsToDuration((capacity - b.tokens) / rate)
+		b.full = now.Add(resetAfter)
+		res.Remaining = min(res.Remaining, int(b.tokens))
+		res.ResetAfter = max(res.ResetAfter, resetAfter)
+	}
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys a policy can be applied to.
+const (
+	KeyIP    = "ip"
+	KeyEmail = "email"
+)
+
+// DefaultRoute is the route name of the policy applied to routes without one.
+const DefaultRoute = "*"
+
+// Policy is a token bucket: Limit tokens are refilled every Period and at most
+// Burst can be spent at once.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+	// KeyBy lists what the bucket is keyed on; every key gets its own bucket
+	// and a request must get a token from all of them.
+	KeyBy []string
+}
+
+func (p Policy) capacity() float64 {
+	if p.Burst > 0 {
+		return float64(p.Burst)
+	}
+	return float64(p.Limit)
+}
+
+// ratePerSecond is the refill rate of the bucket.
+func (p Policy) ratePerSecond() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// ParsePolicy parses the compact form used in configuration:
+//
+//	<limit>/<period>[,burst=<n>][,by=ip+email]
+//
+// for example "5/1m,burst=10,by=ip+email". Without "by" the policy is keyed by IP.
+func ParsePolicy(s string) (Policy, error) {
+	parts := strings.Split(s, ",")
+
+	limit, period, ok := strings.Cut(strings.TrimSpace(parts[0]), "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q: expected <limit>/<period>", s)
+	}
+
+	var p Policy
+	var err error
+	if p.Limit, err = strconv.Atoi(limit); err != nil || p.Limit <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid limit", s)
+	}
+	if p.Period, err = time.ParseDuration(period); err != nil || p.Period <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid period", s)
+	}
+
+	for _, opt := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "burst":
+			if p.Burst, err = strconv.Atoi(value); err != nil || p.Burst <= 0 {
+				return Policy{}, fmt.Errorf("rate limit %q: invalid burst", s)
+			}
+		case "by":
+			for _, k := range strings.Split(value, "+") {
+				if k != KeyIP && k != KeyEmail {
+					return Policy{}, fmt.Errorf("rate limit %q: unknown key %q", s, k)
+				}
+				p.KeyBy = append(p.KeyBy, k)
+			}
+		default:
+			return Policy{}, fmt.Errorf("rate limit %q: unknown option %q", s, key)
+		}
+	}
+
+	if len(p.KeyBy) == 0 {
+		p.KeyBy = []string{KeyIP}
+	}
+	return p, nil
+}
+
+// UnmarshalText parses the ParsePolicy format, so policies can be loaded
+// from configuration.
+func (p *Policy) UnmarshalText(text []byte) error {
+	parsed, err := ParsePolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
@@ -0,0 +1,27 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result is the state of the buckets after a Take.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is how long until the next token is available; zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets. Implementations must be safe for concurrent use;
+// a shared store (e.g. Redis) lets several replicas enforce one limit.
+type Store interface {
+	// Take removes one token from each of the buckets identified by keys if
+	// all of them have one, and none otherwise, so that a bucket denying the
+	// request does not drain the others. The result is that of the most
+	// restrictive bucket.
+	Take(ctx context.Context, keys []string, policy Policy) (Result, error)
+}
@@ -0,0 +1,32 @@
+package tracing
+
+import (
+	"github.com/exaring/otelpgx"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// GRPCServerOption traces incoming gRPC calls, continuing the trace of the caller.
+func GRPCServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// GRPCDialOption traces outgoing gRPC calls and propagates the trace context
+// in their metadata. The HTTP gateway uses it to link to the Gin span.
+func GRPCDialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// GinMiddleware starts a span for every HTTP request.
+func GinMiddleware(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName)
+}
+
+// PgxTracer traces the queries run through a pgx connection; set it as
+// pgx.ConnConfig.Tracer.
+func PgxTracer() pgx.QueryTracer {
+	return otelpgx.NewTracer()
+}
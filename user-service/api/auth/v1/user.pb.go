@@ -66,18 +66,175 @@ func (x *GetProfileRequest) GetUserId() string {
 	return ""
 }
 
+type BatchGetProfilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserIds       []string               `protobuf:"bytes,1,rep,name=user_ids,json=userIds,proto3" json:"user_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetProfilesRequest) Reset() {
+	*x = BatchGetProfilesRequest{}
+	mi := &file_user_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetProfilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetProfilesRequest) ProtoMessage() {}
+
+func (x *BatchGetProfilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetProfilesRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetProfilesRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BatchGetProfilesRequest) GetUserIds() []string {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+type BatchGetProfilesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One result per requested id, in the same order.
+	Results       []*ProfileResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetProfilesResponse) Reset() {
+	*x = BatchGetProfilesResponse{}
+	mi := &file_user_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetProfilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetProfilesResponse) ProtoMessage() {}
+
+func (x *BatchGetProfilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetProfilesResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetProfilesResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BatchGetProfilesResponse) GetResults() []*ProfileResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ProfileResult struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// False if the user does not exist or either of the caller and the user
+	// blocked the other; profile is then unset.
+	Found         bool         `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	Profile       *UserProfile `protobuf:"bytes,3,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProfileResult) Reset() {
+	*x = ProfileResult{}
+	mi := &file_user_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProfileResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProfileResult) ProtoMessage() {}
+
+func (x *ProfileResult) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProfileResult.ProtoReflect.Descriptor instead.
+func (*ProfileResult) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ProfileResult) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ProfileResult) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ProfileResult) GetProfile() *UserProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
 type UpdateProfileRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Username      string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
-	Avatar        string                 `protobuf:"bytes,3,opt,name=avatar,proto3" json:"avatar,omitempty"`
+	DisplayName   *string                `protobuf:"bytes,4,opt,name=display_name,json=displayName,proto3,oneof" json:"display_name,omitempty"`
+	Bio           *string                `protobuf:"bytes,5,opt,name=bio,proto3,oneof" json:"bio,omitempty"`
+	StatusMessage *string                `protobuf:"bytes,6,opt,name=status_message,json=statusMessage,proto3,oneof" json:"status_message,omitempty"`
+	// IANA time zone name, e.g. "Europe/Paris".
+	Timezone *string `protobuf:"bytes,7,opt,name=timezone,proto3,oneof" json:"timezone,omitempty"`
+	// BCP 47 language tag, e.g. "en-US".
+	Locale        *string          `protobuf:"bytes,8,opt,name=locale,proto3,oneof" json:"locale,omitempty"`
+	Privacy       *PrivacySettings `protobuf:"bytes,9,opt,name=privacy,proto3" json:"privacy,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdateProfileRequest) Reset() {
 	*x = UpdateProfileRequest{}
-	mi := &file_user_proto_msgTypes[1]
+	mi := &file_user_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -89,7 +246,7 @@ func (x *UpdateProfileRequest) String() string {
 func (*UpdateProfileRequest) ProtoMessage() {}
 
 func (x *UpdateProfileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[1]
+	mi := &file_user_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -102,7 +259,7 @@ func (x *UpdateProfileRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateProfileRequest.ProtoReflect.Descriptor instead.
 func (*UpdateProfileRequest) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{1}
+	return file_user_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *UpdateProfileRequest) GetUserId() string {
@@ -112,33 +269,137 @@ func (x *UpdateProfileRequest) GetUserId() string {
 	return ""
 }
 
-func (x *UpdateProfileRequest) GetUsername() string {
+func (x *UpdateProfileRequest) GetDisplayName() string {
+	if x != nil && x.DisplayName != nil {
+		return *x.DisplayName
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetBio() string {
+	if x != nil && x.Bio != nil {
+		return *x.Bio
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetStatusMessage() string {
+	if x != nil && x.StatusMessage != nil {
+		return *x.StatusMessage
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetTimezone() string {
+	if x != nil && x.Timezone != nil {
+		return *x.Timezone
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetLocale() string {
+	if x != nil && x.Locale != nil {
+		return *x.Locale
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetPrivacy() *PrivacySettings {
 	if x != nil {
-		return x.Username
+		return x.Privacy
+	}
+	return nil
+}
+
+type PrivacySettings struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Who can see the email: "everyone", "friends" or "nobody".
+	// Empty leaves it unchanged on update.
+	EmailVisibility string `protobuf:"bytes,1,opt,name=email_visibility,json=emailVisibility,proto3" json:"email_visibility,omitempty"`
+	// Who can send friend requests: "everyone", "friends_of_friends" or "nobody".
+	// Empty leaves it unchanged on update.
+	FriendRequestPolicy string `protobuf:"bytes,2,opt,name=friend_request_policy,json=friendRequestPolicy,proto3" json:"friend_request_policy,omitempty"`
+	// Whether non-friends can find the user with SearchUsers.
+	Searchable    *bool `protobuf:"varint,3,opt,name=searchable,proto3,oneof" json:"searchable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrivacySettings) Reset() {
+	*x = PrivacySettings{}
+	mi := &file_user_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrivacySettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrivacySettings) ProtoMessage() {}
+
+func (x *PrivacySettings) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrivacySettings.ProtoReflect.Descriptor instead.
+func (*PrivacySettings) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PrivacySettings) GetEmailVisibility() string {
+	if x != nil {
+		return x.EmailVisibility
 	}
 	return ""
 }
 
-func (x *UpdateProfileRequest) GetAvatar() string {
+func (x *PrivacySettings) GetFriendRequestPolicy() string {
 	if x != nil {
-		return x.Avatar
+		return x.FriendRequestPolicy
 	}
 	return ""
 }
 
+func (x *PrivacySettings) GetSearchable() bool {
+	if x != nil && x.Searchable != nil {
+		return *x.Searchable
+	}
+	return false
+}
+
 type UserProfile struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
-	Username      string                 `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
-	Avatar        string                 `protobuf:"bytes,4,opt,name=avatar,proto3" json:"avatar,omitempty"`
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Empty when the user does not share it with the caller.
+	Email    string `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Username string `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	// URL of the default avatar.
+	Avatar string `protobuf:"bytes,4,opt,name=avatar,proto3" json:"avatar,omitempty"`
+	// URLs of the uploaded avatar by variant name: large, medium, small.
+	AvatarVariants map[string]string `protobuf:"bytes,5,rep,name=avatar_variants,json=avatarVariants,proto3" json:"avatar_variants,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	DisplayName    string            `protobuf:"bytes,6,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Bio            string            `protobuf:"bytes,7,opt,name=bio,proto3" json:"bio,omitempty"`
+	StatusMessage  string            `protobuf:"bytes,8,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
+	Timezone       string            `protobuf:"bytes,9,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	Locale         string            `protobuf:"bytes,10,opt,name=locale,proto3" json:"locale,omitempty"`
+	// Only returned to the user themselves.
+	Privacy       *PrivacySettings `protobuf:"bytes,11,opt,name=privacy,proto3" json:"privacy,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UserProfile) Reset() {
 	*x = UserProfile{}
-	mi := &file_user_proto_msgTypes[2]
+	mi := &file_user_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -150,7 +411,7 @@ func (x *UserProfile) String() string {
 func (*UserProfile) ProtoMessage() {}
 
 func (x *UserProfile) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[2]
+	mi := &file_user_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -163,7 +424,7 @@ func (x *UserProfile) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UserProfile.ProtoReflect.Descriptor instead.
 func (*UserProfile) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{2}
+	return file_user_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *UserProfile) GetUserId() string {
@@ -194,29 +455,79 @@ func (x *UserProfile) GetAvatar() string {
 	return ""
 }
 
-// Search
-type SearchUsersRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Keyword       string                 `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
+func (x *UserProfile) GetAvatarVariants() map[string]string {
+	if x != nil {
+		return x.AvatarVariants
+	}
+	return nil
+}
+
+func (x *UserProfile) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *UserProfile) GetBio() string {
+	if x != nil {
+		return x.Bio
+	}
+	return ""
+}
+
+func (x *UserProfile) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+func (x *UserProfile) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *UserProfile) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *UserProfile) GetPrivacy() *PrivacySettings {
+	if x != nil {
+		return x.Privacy
+	}
+	return nil
+}
+
+type ChangeUsernameRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Must be the caller.
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username      string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SearchUsersRequest) Reset() {
-	*x = SearchUsersRequest{}
-	mi := &file_user_proto_msgTypes[3]
+func (x *ChangeUsernameRequest) Reset() {
+	*x = ChangeUsernameRequest{}
+	mi := &file_user_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SearchUsersRequest) String() string {
+func (x *ChangeUsernameRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SearchUsersRequest) ProtoMessage() {}
+func (*ChangeUsernameRequest) ProtoMessage() {}
 
-func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[3]
+func (x *ChangeUsernameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -227,40 +538,47 @@ func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SearchUsersRequest.ProtoReflect.Descriptor instead.
-func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use ChangeUsernameRequest.ProtoReflect.Descriptor instead.
+func (*ChangeUsernameRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *SearchUsersRequest) GetKeyword() string {
+func (x *ChangeUsernameRequest) GetUserId() string {
 	if x != nil {
-		return x.Keyword
+		return x.UserId
 	}
 	return ""
 }
 
-type SearchUsersResponse struct {
+func (x *ChangeUsernameRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+type CheckUsernameAvailableRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Results       []*UserProfile         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SearchUsersResponse) Reset() {
-	*x = SearchUsersResponse{}
-	mi := &file_user_proto_msgTypes[4]
+func (x *CheckUsernameAvailableRequest) Reset() {
+	*x = CheckUsernameAvailableRequest{}
+	mi := &file_user_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SearchUsersResponse) String() string {
+func (x *CheckUsernameAvailableRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SearchUsersResponse) ProtoMessage() {}
+func (*CheckUsernameAvailableRequest) ProtoMessage() {}
 
-func (x *SearchUsersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[4]
+func (x *CheckUsernameAvailableRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -271,42 +589,1030 @@ func (x *SearchUsersResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SearchUsersResponse.ProtoReflect.Descriptor instead.
-func (*SearchUsersResponse) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use CheckUsernameAvailableRequest.ProtoReflect.Descriptor instead.
+func (*CheckUsernameAvailableRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CheckUsernameAvailableRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+type CheckUsernameAvailableResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Available bool                   `protobuf:"varint,1,opt,name=available,proto3" json:"available,omitempty"`
+	// Why it is not available: "invalid", "reserved" or "taken".
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Human readable explanation of reason.
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckUsernameAvailableResponse) Reset() {
+	*x = CheckUsernameAvailableResponse{}
+	mi := &file_user_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckUsernameAvailableResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckUsernameAvailableResponse) ProtoMessage() {}
+
+func (x *CheckUsernameAvailableResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckUsernameAvailableResponse.ProtoReflect.Descriptor instead.
+func (*CheckUsernameAvailableResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CheckUsernameAvailableResponse) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *CheckUsernameAvailableResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CheckUsernameAvailableResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type UploadAvatarRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Must be the caller; only read from the first message.
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Chunk         []byte `protobuf:"bytes,2,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadAvatarRequest) Reset() {
+	*x = UploadAvatarRequest{}
+	mi := &file_user_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadAvatarRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadAvatarRequest) ProtoMessage() {}
+
+func (x *UploadAvatarRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadAvatarRequest.ProtoReflect.Descriptor instead.
+func (*UploadAvatarRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UploadAvatarRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UploadAvatarRequest) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+// Search
+type SearchUsersRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Keyword  string                 `protobuf:"bytes,1,opt,name=keyword,proto3" json:"keyword,omitempty"`
+	PageSize int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first page.
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersRequest) Reset() {
+	*x = SearchUsersRequest{}
+	mi := &file_user_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersRequest) ProtoMessage() {}
+
+func (x *SearchUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersRequest.ProtoReflect.Descriptor instead.
+func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchUsersRequest) GetKeyword() string {
+	if x != nil {
+		return x.Keyword
+	}
+	return ""
+}
+
+func (x *SearchUsersRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *SearchUsersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type SearchUsersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*UserProfile         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchUsersResponse) Reset() {
+	*x = SearchUsersResponse{}
+	mi := &file_user_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchUsersResponse) ProtoMessage() {}
+
+func (x *SearchUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchUsersResponse.ProtoReflect.Descriptor instead.
+func (*SearchUsersResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SearchUsersResponse) GetResults() []*UserProfile {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *SearchUsersResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Friend system
+type FriendRequestInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromUserId    string                 `protobuf:"bytes,1,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
+	ToUserId      string                 `protobuf:"bytes,2,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendRequestInput) Reset() {
+	*x = FriendRequestInput{}
+	mi := &file_user_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendRequestInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendRequestInput) ProtoMessage() {}
+
+func (x *FriendRequestInput) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendRequestInput.ProtoReflect.Descriptor instead.
+func (*FriendRequestInput) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FriendRequestInput) GetFromUserId() string {
+	if x != nil {
+		return x.FromUserId
+	}
+	return ""
+}
+
+func (x *FriendRequestInput) GetToUserId() string {
+	if x != nil {
+		return x.ToUserId
+	}
+	return ""
+}
+
+type FriendRespondInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"` // "accept", "reject"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendRespondInput) Reset() {
+	*x = FriendRespondInput{}
+	mi := &file_user_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendRespondInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendRespondInput) ProtoMessage() {}
+
+func (x *FriendRespondInput) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendRespondInput.ProtoReflect.Descriptor instead.
+func (*FriendRespondInput) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *FriendRespondInput) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *FriendRespondInput) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+type FriendActionResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Set by SendFriendRequest: the request sent, or the one it accepted.
+	RequestId     string `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendActionResponse) Reset() {
+	*x = FriendActionResponse{}
+	mi := &file_user_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendActionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendActionResponse) ProtoMessage() {}
+
+func (x *FriendActionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendActionResponse.ProtoReflect.Descriptor instead.
+func (*FriendActionResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FriendActionResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *FriendActionResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type CancelFriendRequestInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelFriendRequestInput) Reset() {
+	*x = CancelFriendRequestInput{}
+	mi := &file_user_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelFriendRequestInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelFriendRequestInput) ProtoMessage() {}
+
+func (x *CancelFriendRequestInput) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelFriendRequestInput.ProtoReflect.Descriptor instead.
+func (*CancelFriendRequestInput) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CancelFriendRequestInput) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type GetFriendRequestsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	UserId   string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PageSize int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first page.
+	PageToken     string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFriendRequestsRequest) Reset() {
+	*x = GetFriendRequestsRequest{}
+	mi := &file_user_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFriendRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFriendRequestsRequest) ProtoMessage() {}
+
+func (x *GetFriendRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFriendRequestsRequest.ProtoReflect.Descriptor instead.
+func (*GetFriendRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetFriendRequestsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetFriendRequestsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetFriendRequestsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListFriendRequestsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	PageSize int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first page.
+	PageToken     string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFriendRequestsRequest) Reset() {
+	*x = ListFriendRequestsRequest{}
+	mi := &file_user_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFriendRequestsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFriendRequestsRequest) ProtoMessage() {}
+
+func (x *ListFriendRequestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFriendRequestsRequest.ProtoReflect.Descriptor instead.
+func (*ListFriendRequestsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListFriendRequestsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListFriendRequestsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type FriendRequestsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*FriendRequestData   `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendRequestsResponse) Reset() {
+	*x = FriendRequestsResponse{}
+	mi := &file_user_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendRequestsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendRequestsResponse) ProtoMessage() {}
+
+func (x *FriendRequestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendRequestsResponse.ProtoReflect.Descriptor instead.
+func (*FriendRequestsResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *FriendRequestsResponse) GetRequests() []*FriendRequestData {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+func (x *FriendRequestsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type FriendRequestData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	FromUserId    string                 `protobuf:"bytes,2,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
+	ToUserId      string                 `protobuf:"bytes,3,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendRequestData) Reset() {
+	*x = FriendRequestData{}
+	mi := &file_user_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendRequestData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendRequestData) ProtoMessage() {}
+
+func (x *FriendRequestData) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendRequestData.ProtoReflect.Descriptor instead.
+func (*FriendRequestData) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *FriendRequestData) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *FriendRequestData) GetFromUserId() string {
+	if x != nil {
+		return x.FromUserId
+	}
+	return ""
+}
+
+func (x *FriendRequestData) GetToUserId() string {
+	if x != nil {
+		return x.ToUserId
+	}
+	return ""
+}
+
+func (x *FriendRequestData) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *FriendRequestData) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type GetFriendsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFriendsRequest) Reset() {
+	*x = GetFriendsRequest{}
+	mi := &file_user_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFriendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFriendsRequest) ProtoMessage() {}
+
+func (x *GetFriendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFriendsRequest.ProtoReflect.Descriptor instead.
+func (*GetFriendsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GetFriendsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type FriendsListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Friends       []*UserProfile         `protobuf:"bytes,1,rep,name=friends,proto3" json:"friends,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendsListResponse) Reset() {
+	*x = FriendsListResponse{}
+	mi := &file_user_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendsListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendsListResponse) ProtoMessage() {}
+
+func (x *FriendsListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendsListResponse.ProtoReflect.Descriptor instead.
+func (*FriendsListResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *FriendsListResponse) GetFriends() []*UserProfile {
+	if x != nil {
+		return x.Friends
+	}
+	return nil
+}
+
+type RemoveFriendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FriendId      string                 `protobuf:"bytes,2,opt,name=friend_id,json=friendId,proto3" json:"friend_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFriendRequest) Reset() {
+	*x = RemoveFriendRequest{}
+	mi := &file_user_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFriendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFriendRequest) ProtoMessage() {}
+
+func (x *RemoveFriendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFriendRequest.ProtoReflect.Descriptor instead.
+func (*RemoveFriendRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RemoveFriendRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RemoveFriendRequest) GetFriendId() string {
+	if x != nil {
+		return x.FriendId
+	}
+	return ""
+}
+
+// Discovery
+type GetMutualFriendsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of the two users must be the caller.
+	UserA    string `protobuf:"bytes,1,opt,name=user_a,json=userA,proto3" json:"user_a,omitempty"`
+	UserB    string `protobuf:"bytes,2,opt,name=user_b,json=userB,proto3" json:"user_b,omitempty"`
+	PageSize int32  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first page.
+	PageToken     string `protobuf:"bytes,4,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMutualFriendsRequest) Reset() {
+	*x = GetMutualFriendsRequest{}
+	mi := &file_user_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMutualFriendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMutualFriendsRequest) ProtoMessage() {}
+
+func (x *GetMutualFriendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMutualFriendsRequest.ProtoReflect.Descriptor instead.
+func (*GetMutualFriendsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *GetMutualFriendsRequest) GetUserA() string {
+	if x != nil {
+		return x.UserA
+	}
+	return ""
+}
+
+func (x *GetMutualFriendsRequest) GetUserB() string {
+	if x != nil {
+		return x.UserB
+	}
+	return ""
+}
+
+func (x *GetMutualFriendsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetMutualFriendsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type MutualFriendsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Friends       []*UserProfile         `protobuf:"bytes,1,rep,name=friends,proto3" json:"friends,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MutualFriendsResponse) Reset() {
+	*x = MutualFriendsResponse{}
+	mi := &file_user_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MutualFriendsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MutualFriendsResponse) ProtoMessage() {}
+
+func (x *MutualFriendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MutualFriendsResponse.ProtoReflect.Descriptor instead.
+func (*MutualFriendsResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *MutualFriendsResponse) GetFriends() []*UserProfile {
+	if x != nil {
+		return x.Friends
+	}
+	return nil
+}
+
+func (x *MutualFriendsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type SuggestFriendsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Must be the caller.
+	UserId        string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Limit         int32  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuggestFriendsRequest) Reset() {
+	*x = SuggestFriendsRequest{}
+	mi := &file_user_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuggestFriendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuggestFriendsRequest) ProtoMessage() {}
+
+func (x *SuggestFriendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuggestFriendsRequest.ProtoReflect.Descriptor instead.
+func (*SuggestFriendsRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *SuggestFriendsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
 }
 
-func (x *SearchUsersResponse) GetResults() []*UserProfile {
+func (x *SuggestFriendsRequest) GetLimit() int32 {
 	if x != nil {
-		return x.Results
+		return x.Limit
 	}
-	return nil
+	return 0
 }
 
-// Friend system
-type FriendRequestInput struct {
+type FriendSuggestion struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	FromUserId    string                 `protobuf:"bytes,1,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
-	ToUserId      string                 `protobuf:"bytes,2,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
+	User          *UserProfile           `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	MutualFriends int32                  `protobuf:"varint,2,opt,name=mutual_friends,json=mutualFriends,proto3" json:"mutual_friends,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendRequestInput) Reset() {
-	*x = FriendRequestInput{}
-	mi := &file_user_proto_msgTypes[5]
+func (x *FriendSuggestion) Reset() {
+	*x = FriendSuggestion{}
+	mi := &file_user_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendRequestInput) String() string {
+func (x *FriendSuggestion) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendRequestInput) ProtoMessage() {}
+func (*FriendSuggestion) ProtoMessage() {}
 
-func (x *FriendRequestInput) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[5]
+func (x *FriendSuggestion) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -317,48 +1623,47 @@ func (x *FriendRequestInput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendRequestInput.ProtoReflect.Descriptor instead.
-func (*FriendRequestInput) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use FriendSuggestion.ProtoReflect.Descriptor instead.
+func (*FriendSuggestion) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{27}
 }
 
-func (x *FriendRequestInput) GetFromUserId() string {
+func (x *FriendSuggestion) GetUser() *UserProfile {
 	if x != nil {
-		return x.FromUserId
+		return x.User
 	}
-	return ""
+	return nil
 }
 
-func (x *FriendRequestInput) GetToUserId() string {
+func (x *FriendSuggestion) GetMutualFriends() int32 {
 	if x != nil {
-		return x.ToUserId
+		return x.MutualFriends
 	}
-	return ""
+	return 0
 }
 
-type FriendRespondInput struct {
+type SuggestFriendsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
-	Action        string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"` // "accept", "reject"
+	Suggestions   []*FriendSuggestion    `protobuf:"bytes,1,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendRespondInput) Reset() {
-	*x = FriendRespondInput{}
-	mi := &file_user_proto_msgTypes[6]
+func (x *SuggestFriendsResponse) Reset() {
+	*x = SuggestFriendsResponse{}
+	mi := &file_user_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendRespondInput) String() string {
+func (x *SuggestFriendsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendRespondInput) ProtoMessage() {}
+func (*SuggestFriendsResponse) ProtoMessage() {}
 
-func (x *FriendRespondInput) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[6]
+func (x *SuggestFriendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -369,47 +1674,41 @@ func (x *FriendRespondInput) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendRespondInput.ProtoReflect.Descriptor instead.
-func (*FriendRespondInput) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{6}
-}
-
-func (x *FriendRespondInput) GetRequestId() string {
-	if x != nil {
-		return x.RequestId
-	}
-	return ""
+// Deprecated: Use SuggestFriendsResponse.ProtoReflect.Descriptor instead.
+func (*SuggestFriendsResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{28}
 }
 
-func (x *FriendRespondInput) GetAction() string {
+func (x *SuggestFriendsResponse) GetSuggestions() []*FriendSuggestion {
 	if x != nil {
-		return x.Action
+		return x.Suggestions
 	}
-	return ""
+	return nil
 }
 
-type FriendActionResponse struct {
+// Blocking
+type BlockUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendActionResponse) Reset() {
-	*x = FriendActionResponse{}
-	mi := &file_user_proto_msgTypes[7]
+func (x *BlockUserRequest) Reset() {
+	*x = BlockUserRequest{}
+	mi := &file_user_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendActionResponse) String() string {
+func (x *BlockUserRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendActionResponse) ProtoMessage() {}
+func (*BlockUserRequest) ProtoMessage() {}
 
-func (x *FriendActionResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[7]
+func (x *BlockUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -420,40 +1719,40 @@ func (x *FriendActionResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendActionResponse.ProtoReflect.Descriptor instead.
-func (*FriendActionResponse) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use BlockUserRequest.ProtoReflect.Descriptor instead.
+func (*BlockUserRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *FriendActionResponse) GetMessage() string {
+func (x *BlockUserRequest) GetUserId() string {
 	if x != nil {
-		return x.Message
+		return x.UserId
 	}
 	return ""
 }
 
-type GetFriendRequestsRequest struct {
+type UnblockUserRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetFriendRequestsRequest) Reset() {
-	*x = GetFriendRequestsRequest{}
-	mi := &file_user_proto_msgTypes[8]
+func (x *UnblockUserRequest) Reset() {
+	*x = UnblockUserRequest{}
+	mi := &file_user_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetFriendRequestsRequest) String() string {
+func (x *UnblockUserRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFriendRequestsRequest) ProtoMessage() {}
+func (*UnblockUserRequest) ProtoMessage() {}
 
-func (x *GetFriendRequestsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[8]
+func (x *UnblockUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -464,40 +1763,42 @@ func (x *GetFriendRequestsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFriendRequestsRequest.ProtoReflect.Descriptor instead.
-func (*GetFriendRequestsRequest) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use UnblockUserRequest.ProtoReflect.Descriptor instead.
+func (*UnblockUserRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{30}
 }
 
-func (x *GetFriendRequestsRequest) GetUserId() string {
+func (x *UnblockUserRequest) GetUserId() string {
 	if x != nil {
 		return x.UserId
 	}
 	return ""
 }
 
-type FriendRequestsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Requests      []*FriendRequestData   `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+type ListBlockedUsersRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	PageSize int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first page.
+	PageToken     string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendRequestsResponse) Reset() {
-	*x = FriendRequestsResponse{}
-	mi := &file_user_proto_msgTypes[9]
+func (x *ListBlockedUsersRequest) Reset() {
+	*x = ListBlockedUsersRequest{}
+	mi := &file_user_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendRequestsResponse) String() string {
+func (x *ListBlockedUsersRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendRequestsResponse) ProtoMessage() {}
+func (*ListBlockedUsersRequest) ProtoMessage() {}
 
-func (x *FriendRequestsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[9]
+func (x *ListBlockedUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -508,44 +1809,48 @@ func (x *FriendRequestsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendRequestsResponse.ProtoReflect.Descriptor instead.
-func (*FriendRequestsResponse) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use ListBlockedUsersRequest.ProtoReflect.Descriptor instead.
+func (*ListBlockedUsersRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *FriendRequestsResponse) GetRequests() []*FriendRequestData {
+func (x *ListBlockedUsersRequest) GetPageSize() int32 {
 	if x != nil {
-		return x.Requests
+		return x.PageSize
 	}
-	return nil
+	return 0
 }
 
-type FriendRequestData struct {
+func (x *ListBlockedUsersRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type BlockedUser struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	FromUserId    string                 `protobuf:"bytes,2,opt,name=from_user_id,json=fromUserId,proto3" json:"from_user_id,omitempty"`
-	ToUserId      string                 `protobuf:"bytes,3,opt,name=to_user_id,json=toUserId,proto3" json:"to_user_id,omitempty"`
-	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
-	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	User          *UserProfile           `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	BlockedAt     string                 `protobuf:"bytes,2,opt,name=blocked_at,json=blockedAt,proto3" json:"blocked_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendRequestData) Reset() {
-	*x = FriendRequestData{}
-	mi := &file_user_proto_msgTypes[10]
+func (x *BlockedUser) Reset() {
+	*x = BlockedUser{}
+	mi := &file_user_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendRequestData) String() string {
+func (x *BlockedUser) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendRequestData) ProtoMessage() {}
+func (*BlockedUser) ProtoMessage() {}
 
-func (x *FriendRequestData) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[10]
+func (x *BlockedUser) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -556,68 +1861,99 @@ func (x *FriendRequestData) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendRequestData.ProtoReflect.Descriptor instead.
-func (*FriendRequestData) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use BlockedUser.ProtoReflect.Descriptor instead.
+func (*BlockedUser) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *FriendRequestData) GetId() string {
+func (x *BlockedUser) GetUser() *UserProfile {
 	if x != nil {
-		return x.Id
+		return x.User
 	}
-	return ""
+	return nil
 }
 
-func (x *FriendRequestData) GetFromUserId() string {
+func (x *BlockedUser) GetBlockedAt() string {
 	if x != nil {
-		return x.FromUserId
+		return x.BlockedAt
 	}
 	return ""
 }
 
-func (x *FriendRequestData) GetToUserId() string {
+type ListBlockedUsersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*BlockedUser         `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlockedUsersResponse) Reset() {
+	*x = ListBlockedUsersResponse{}
+	mi := &file_user_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlockedUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlockedUsersResponse) ProtoMessage() {}
+
+func (x *ListBlockedUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[33]
 	if x != nil {
-		return x.ToUserId
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *FriendRequestData) GetStatus() string {
+// Deprecated: Use ListBlockedUsersResponse.ProtoReflect.Descriptor instead.
+func (*ListBlockedUsersResponse) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListBlockedUsersResponse) GetUsers() []*BlockedUser {
 	if x != nil {
-		return x.Status
+		return x.Users
 	}
-	return ""
+	return nil
 }
 
-func (x *FriendRequestData) GetCreatedAt() string {
+func (x *ListBlockedUsersResponse) GetNextPageToken() string {
 	if x != nil {
-		return x.CreatedAt
+		return x.NextPageToken
 	}
 	return ""
 }
 
-type GetFriendsRequest struct {
+// Data export
+type RequestDataExportRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetFriendsRequest) Reset() {
-	*x = GetFriendsRequest{}
-	mi := &file_user_proto_msgTypes[11]
+func (x *RequestDataExportRequest) Reset() {
+	*x = RequestDataExportRequest{}
+	mi := &file_user_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetFriendsRequest) String() string {
+func (x *RequestDataExportRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFriendsRequest) ProtoMessage() {}
+func (*RequestDataExportRequest) ProtoMessage() {}
 
-func (x *GetFriendsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[11]
+func (x *RequestDataExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -628,40 +1964,33 @@ func (x *GetFriendsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFriendsRequest.ProtoReflect.Descriptor instead.
-func (*GetFriendsRequest) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{11}
-}
-
-func (x *GetFriendsRequest) GetUserId() string {
-	if x != nil {
-		return x.UserId
-	}
-	return ""
+// Deprecated: Use RequestDataExportRequest.ProtoReflect.Descriptor instead.
+func (*RequestDataExportRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{34}
 }
 
-type FriendsListResponse struct {
+type GetExportStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Friends       []*UserProfile         `protobuf:"bytes,1,rep,name=friends,proto3" json:"friends,omitempty"`
+	ExportId      string                 `protobuf:"bytes,1,opt,name=export_id,json=exportId,proto3" json:"export_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FriendsListResponse) Reset() {
-	*x = FriendsListResponse{}
-	mi := &file_user_proto_msgTypes[12]
+func (x *GetExportStatusRequest) Reset() {
+	*x = GetExportStatusRequest{}
+	mi := &file_user_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FriendsListResponse) String() string {
+func (x *GetExportStatusRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FriendsListResponse) ProtoMessage() {}
+func (*GetExportStatusRequest) ProtoMessage() {}
 
-func (x *FriendsListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[12]
+func (x *GetExportStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -672,41 +2001,49 @@ func (x *FriendsListResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FriendsListResponse.ProtoReflect.Descriptor instead.
-func (*FriendsListResponse) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use GetExportStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetExportStatusRequest) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{35}
 }
 
-func (x *FriendsListResponse) GetFriends() []*UserProfile {
+func (x *GetExportStatusRequest) GetExportId() string {
 	if x != nil {
-		return x.Friends
+		return x.ExportId
 	}
-	return nil
+	return ""
 }
 
-type RemoveFriendRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	FriendId      string                 `protobuf:"bytes,2,opt,name=friend_id,json=friendId,proto3" json:"friend_id,omitempty"`
+type DataExport struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ExportId string                 `protobuf:"bytes,1,opt,name=export_id,json=exportId,proto3" json:"export_id,omitempty"`
+	// pending, ready, failed or expired.
+	Status      string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	RequestedAt string `protobuf:"bytes,3,opt,name=requested_at,json=requestedAt,proto3" json:"requested_at,omitempty"`
+	CompletedAt string `protobuf:"bytes,4,opt,name=completed_at,json=completedAt,proto3" json:"completed_at,omitempty"`
+	ExpiresAt   string `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Set while ready; valid for a short time only.
+	DownloadUrl   string `protobuf:"bytes,6,opt,name=download_url,json=downloadUrl,proto3" json:"download_url,omitempty"`
+	SizeBytes     int64  `protobuf:"varint,7,opt,name=size_bytes,json=sizeBytes,proto3" json:"size_bytes,omitempty"`
+	Error         string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RemoveFriendRequest) Reset() {
-	*x = RemoveFriendRequest{}
-	mi := &file_user_proto_msgTypes[13]
+func (x *DataExport) Reset() {
+	*x = DataExport{}
+	mi := &file_user_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RemoveFriendRequest) String() string {
+func (x *DataExport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RemoveFriendRequest) ProtoMessage() {}
+func (*DataExport) ProtoMessage() {}
 
-func (x *RemoveFriendRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_user_proto_msgTypes[13]
+func (x *DataExport) ProtoReflect() protoreflect.Message {
+	mi := &file_user_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -717,21 +2054,63 @@ func (x *RemoveFriendRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RemoveFriendRequest.ProtoReflect.Descriptor instead.
-func (*RemoveFriendRequest) Descriptor() ([]byte, []int) {
-	return file_user_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use DataExport.ProtoReflect.Descriptor instead.
+func (*DataExport) Descriptor() ([]byte, []int) {
+	return file_user_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *RemoveFriendRequest) GetUserId() string {
+func (x *DataExport) GetExportId() string {
 	if x != nil {
-		return x.UserId
+		return x.ExportId
 	}
 	return ""
 }
 
-func (x *RemoveFriendRequest) GetFriendId() string {
+func (x *DataExport) GetStatus() string {
 	if x != nil {
-		return x.FriendId
+		return x.Status
+	}
+	return ""
+}
+
+func (x *DataExport) GetRequestedAt() string {
+	if x != nil {
+		return x.RequestedAt
+	}
+	return ""
+}
+
+func (x *DataExport) GetCompletedAt() string {
+	if x != nil {
+		return x.CompletedAt
+	}
+	return ""
+}
+
+func (x *DataExport) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *DataExport) GetDownloadUrl() string {
+	if x != nil {
+		return x.DownloadUrl
+	}
+	return ""
+}
+
+func (x *DataExport) GetSizeBytes() int64 {
+	if x != nil {
+		return x.SizeBytes
+	}
+	return 0
+}
+
+func (x *DataExport) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
@@ -743,20 +2122,71 @@ const file_user_proto_rawDesc = "" +
 	"\n" +
 	"user.proto\x12\x04user\x1a\x1cgoogle/api/annotations.proto\",\n" +
 	"\x11GetProfileRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"c\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"4\n" +
+	"\x17BatchGetProfilesRequest\x12\x19\n" +
+	"\buser_ids\x18\x01 \x03(\tR\auserIds\"I\n" +
+	"\x18BatchGetProfilesResponse\x12-\n" +
+	"\aresults\x18\x01 \x03(\v2\x13.user.ProfileResultR\aresults\"k\n" +
+	"\rProfileResult\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12+\n" +
+	"\aprofile\x18\x03 \x01(\v2\x11.user.UserProfileR\aprofile\"\xeb\x02\n" +
 	"\x14UpdateProfileRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
-	"\busername\x18\x02 \x01(\tR\busername\x12\x16\n" +
-	"\x06avatar\x18\x03 \x01(\tR\x06avatar\"p\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12&\n" +
+	"\fdisplay_name\x18\x04 \x01(\tH\x00R\vdisplayName\x88\x01\x01\x12\x15\n" +
+	"\x03bio\x18\x05 \x01(\tH\x01R\x03bio\x88\x01\x01\x12*\n" +
+	"\x0estatus_message\x18\x06 \x01(\tH\x02R\rstatusMessage\x88\x01\x01\x12\x1f\n" +
+	"\btimezone\x18\a \x01(\tH\x03R\btimezone\x88\x01\x01\x12\x1b\n" +
+	"\x06locale\x18\b \x01(\tH\x04R\x06locale\x88\x01\x01\x12/\n" +
+	"\aprivacy\x18\t \x01(\v2\x15.user.PrivacySettingsR\aprivacyB\x0f\n" +
+	"\r_display_nameB\x06\n" +
+	"\x04_bioB\x11\n" +
+	"\x0f_status_messageB\v\n" +
+	"\t_timezoneB\t\n" +
+	"\a_localeJ\x04\b\x02\x10\x03J\x04\b\x03\x10\x04R\busernameR\x06avatar\"\xa4\x01\n" +
+	"\x0fPrivacySettings\x12)\n" +
+	"\x10email_visibility\x18\x01 \x01(\tR\x0femailVisibility\x122\n" +
+	"\x15friend_request_policy\x18\x02 \x01(\tR\x13friendRequestPolicy\x12#\n" +
+	"\n" +
+	"searchable\x18\x03 \x01(\bH\x00R\n" +
+	"searchable\x88\x01\x01B\r\n" +
+	"\v_searchable\"\xc4\x03\n" +
 	"\vUserProfile\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
 	"\x05email\x18\x02 \x01(\tR\x05email\x12\x1a\n" +
 	"\busername\x18\x03 \x01(\tR\busername\x12\x16\n" +
-	"\x06avatar\x18\x04 \x01(\tR\x06avatar\".\n" +
+	"\x06avatar\x18\x04 \x01(\tR\x06avatar\x12N\n" +
+	"\x0favatar_variants\x18\x05 \x03(\v2%.user.UserProfile.AvatarVariantsEntryR\x0eavatarVariants\x12!\n" +
+	"\fdisplay_name\x18\x06 \x01(\tR\vdisplayName\x12\x10\n" +
+	"\x03bio\x18\a \x01(\tR\x03bio\x12%\n" +
+	"\x0estatus_message\x18\b \x01(\tR\rstatusMessage\x12\x1a\n" +
+	"\btimezone\x18\t \x01(\tR\btimezone\x12\x16\n" +
+	"\x06locale\x18\n" +
+	" \x01(\tR\x06locale\x12/\n" +
+	"\aprivacy\x18\v \x01(\v2\x15.user.PrivacySettingsR\aprivacy\x1aA\n" +
+	"\x13AvatarVariantsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"L\n" +
+	"\x15ChangeUsernameRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\";\n" +
+	"\x1dCheckUsernameAvailableRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\"p\n" +
+	"\x1eCheckUsernameAvailableResponse\x12\x1c\n" +
+	"\tavailable\x18\x01 \x01(\bR\tavailable\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"D\n" +
+	"\x13UploadAvatarRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05chunk\x18\x02 \x01(\fR\x05chunk\"j\n" +
 	"\x12SearchUsersRequest\x12\x18\n" +
-	"\akeyword\x18\x01 \x01(\tR\akeyword\"B\n" +
+	"\akeyword\x18\x01 \x01(\tR\akeyword\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"j\n" +
 	"\x13SearchUsersResponse\x12+\n" +
-	"\aresults\x18\x01 \x03(\v2\x11.user.UserProfileR\aresults\"T\n" +
+	"\aresults\x18\x01 \x03(\v2\x11.user.UserProfileR\aresults\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"T\n" +
 	"\x12FriendRequestInput\x12 \n" +
 	"\ffrom_user_id\x18\x01 \x01(\tR\n" +
 	"fromUserId\x12\x1c\n" +
@@ -765,13 +2195,26 @@ const file_user_proto_rawDesc = "" +
 	"\x12FriendRespondInput\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x16\n" +
-	"\x06action\x18\x02 \x01(\tR\x06action\"0\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\"O\n" +
 	"\x14FriendActionResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"3\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\"9\n" +
+	"\x18CancelFriendRequestInput\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"o\n" +
 	"\x18GetFriendRequestsRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\"M\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\"W\n" +
+	"\x19ListFriendRequestsRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\"u\n" +
 	"\x16FriendRequestsResponse\x123\n" +
-	"\brequests\x18\x01 \x03(\v2\x17.user.FriendRequestDataR\brequests\"\x9a\x01\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.user.FriendRequestDataR\brequests\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\x9a\x01\n" +
 	"\x11FriendRequestData\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
 	"\ffrom_user_id\x18\x02 \x01(\tR\n" +
@@ -787,18 +2230,81 @@ const file_user_proto_rawDesc = "" +
 	"\afriends\x18\x01 \x03(\v2\x11.user.UserProfileR\afriends\"K\n" +
 	"\x13RemoveFriendRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
-	"\tfriend_id\x18\x02 \x01(\tR\bfriendId2\xcb\x06\n" +
+	"\tfriend_id\x18\x02 \x01(\tR\bfriendId\"\x83\x01\n" +
+	"\x17GetMutualFriendsRequest\x12\x15\n" +
+	"\x06user_a\x18\x01 \x01(\tR\x05userA\x12\x15\n" +
+	"\x06user_b\x18\x02 \x01(\tR\x05userB\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x04 \x01(\tR\tpageToken\"l\n" +
+	"\x15MutualFriendsResponse\x12+\n" +
+	"\afriends\x18\x01 \x03(\v2\x11.user.UserProfileR\afriends\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"F\n" +
+	"\x15SuggestFriendsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"`\n" +
+	"\x10FriendSuggestion\x12%\n" +
+	"\x04user\x18\x01 \x01(\v2\x11.user.UserProfileR\x04user\x12%\n" +
+	"\x0emutual_friends\x18\x02 \x01(\x05R\rmutualFriends\"R\n" +
+	"\x16SuggestFriendsResponse\x128\n" +
+	"\vsuggestions\x18\x01 \x03(\v2\x16.user.FriendSuggestionR\vsuggestions\"+\n" +
+	"\x10BlockUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"-\n" +
+	"\x12UnblockUserRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"U\n" +
+	"\x17ListBlockedUsersRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\"S\n" +
+	"\vBlockedUser\x12%\n" +
+	"\x04user\x18\x01 \x01(\v2\x11.user.UserProfileR\x04user\x12\x1d\n" +
+	"\n" +
+	"blocked_at\x18\x02 \x01(\tR\tblockedAt\"k\n" +
+	"\x18ListBlockedUsersResponse\x12'\n" +
+	"\x05users\x18\x01 \x03(\v2\x11.user.BlockedUserR\x05users\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\x1a\n" +
+	"\x18RequestDataExportRequest\"5\n" +
+	"\x16GetExportStatusRequest\x12\x1b\n" +
+	"\texport_id\x18\x01 \x01(\tR\bexportId\"\xfe\x01\n" +
+	"\n" +
+	"DataExport\x12\x1b\n" +
+	"\texport_id\x18\x01 \x01(\tR\bexportId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12!\n" +
+	"\frequested_at\x18\x03 \x01(\tR\vrequestedAt\x12!\n" +
+	"\fcompleted_at\x18\x04 \x01(\tR\vcompletedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\tR\texpiresAt\x12!\n" +
+	"\fdownload_url\x18\x06 \x01(\tR\vdownloadUrl\x12\x1d\n" +
+	"\n" +
+	"size_bytes\x18\a \x01(\x03R\tsizeBytes\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error2\x91\x13\n" +
 	"\vUserService\x12U\n" +
 	"\n" +
-	"GetProfile\x12\x17.user.GetProfileRequest\x1a\x11.user.UserProfile\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/users/{user_id}\x12^\n" +
-	"\rUpdateProfile\x12\x1a.user.UpdateProfileRequest\x1a\x11.user.UserProfile\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*\x1a\x13/v1/users/{user_id}\x12\\\n" +
+	"GetProfile\x12\x17.user.GetProfileRequest\x1a\x11.user.UserProfile\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/users/{user_id}\x12p\n" +
+	"\x10BatchGetProfiles\x12\x1d.user.BatchGetProfilesRequest\x1a\x1e.user.BatchGetProfilesResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/v1/users:batchGet\x12x\n" +
+	"\rUpdateProfile\x12\x1a.user.UpdateProfileRequest\x1a\x11.user.UserProfile\"8\x82\xd3\xe4\x93\x022:\x01*Z\x18:\x01*\x1a\x13/v1/users/{user_id}2\x13/v1/users/{user_id}\x12i\n" +
+	"\x0eChangeUsername\x12\x1b.user.ChangeUsernameRequest\x1a\x11.user.UserProfile\"'\x82\xd3\xe4\x93\x02!:\x01*\x1a\x1c/v1/users/{user_id}/username\x12\x92\x01\n" +
+	"\x16CheckUsernameAvailable\x12#.user.CheckUsernameAvailableRequest\x1a$.user.CheckUsernameAvailableResponse\"-\x82\xd3\xe4\x93\x02'\x12%/v1/usernames/{username}/availability\x12>\n" +
+	"\fUploadAvatar\x12\x19.user.UploadAvatarRequest\x1a\x11.user.UserProfile(\x01\x12\\\n" +
 	"\vSearchUsers\x12\x18.user.SearchUsersRequest\x1a\x19.user.SearchUsersResponse\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/v1/users/search\x12i\n" +
 	"\x11SendFriendRequest\x12\x18.user.FriendRequestInput\x1a\x1a.user.FriendActionResponse\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*\"\x13/v1/friends/request\x12n\n" +
-	"\x16RespondToFriendRequest\x12\x18.user.FriendRespondInput\x1a\x1a.user.FriendActionResponse\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*\"\x13/v1/friends/respond\x12y\n" +
-	"\x11GetFriendRequests\x12\x1e.user.GetFriendRequestsRequest\x1a\x1c.user.FriendRequestsResponse\"&\x82\xd3\xe4\x93\x02 \x12\x1e/v1/friends/requests/{user_id}\x12_\n" +
+	"\x16RespondToFriendRequest\x12\x18.user.FriendRespondInput\x1a\x1a.user.FriendActionResponse\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*\"\x13/v1/friends/respond\x12\x83\x01\n" +
+	"\x13CancelFriendRequest\x12\x1e.user.CancelFriendRequestInput\x1a\x1a.user.FriendActionResponse\"0\x82\xd3\xe4\x93\x02*\"(/v1/friends/requests/{request_id}/cancel\x12|\n" +
+	"\x11GetFriendRequests\x12\x1e.user.GetFriendRequestsRequest\x1a\x1c.user.FriendRequestsResponse\")\x82\xd3\xe4\x93\x02 \x12\x1e/v1/friends/requests/{user_id}\x88\x02\x01\x12\x82\x01\n" +
+	"\x1aListIncomingFriendRequests\x12\x1f.user.ListFriendRequestsRequest\x1a\x1c.user.FriendRequestsResponse\"%\x82\xd3\xe4\x93\x02\x1f\x12\x1d/v1/friends/requests/incoming\x12\x82\x01\n" +
+	"\x1aListOutgoingFriendRequests\x12\x1f.user.ListFriendRequestsRequest\x1a\x1c.user.FriendRequestsResponse\"%\x82\xd3\xe4\x93\x02\x1f\x12\x1d/v1/friends/requests/outgoing\x12_\n" +
 	"\n" +
 	"GetFriends\x12\x17.user.GetFriendsRequest\x1a\x19.user.FriendsListResponse\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/friends/{user_id}\x12p\n" +
-	"\fRemoveFriend\x12\x19.user.RemoveFriendRequest\x1a\x1a.user.FriendActionResponse\")\x82\xd3\xe4\x93\x02#*!/v1/friends/{user_id}/{friend_id}BAZ?github.com/Thanhbinh1905/realtime-chat-v2-go/api/user/v1;userpbb\x06proto3"
+	"\fRemoveFriend\x12\x19.user.RemoveFriendRequest\x1a\x1a.user.FriendActionResponse\")\x82\xd3\xe4\x93\x02#*!/v1/friends/{user_id}/{friend_id}\x12\x82\x01\n" +
+	"\x10GetMutualFriends\x12\x1d.user.GetMutualFriendsRequest\x1a\x1b.user.MutualFriendsResponse\"2\x82\xd3\xe4\x93\x02,\x12*/v1/users/{user_a}/mutual-friends/{user_b}\x12{\n" +
+	"\x0eSuggestFriends\x12\x1b.user.SuggestFriendsRequest\x1a\x1c.user.SuggestFriendsResponse\".\x82\xd3\xe4\x93\x02(\x12&/v1/users/{user_id}/friend-suggestions\x12V\n" +
+	"\tBlockUser\x12\x16.user.BlockUserRequest\x1a\x1a.user.FriendActionResponse\"\x15\x82\xd3\xe4\x93\x02\x0f:\x01*\"\n" +
+	"/v1/blocks\x12a\n" +
+	"\vUnblockUser\x12\x18.user.UnblockUserRequest\x1a\x1a.user.FriendActionResponse\"\x1c\x82\xd3\xe4\x93\x02\x16*\x14/v1/blocks/{user_id}\x12e\n" +
+	"\x10ListBlockedUsers\x12\x1d.user.ListBlockedUsersRequest\x1a\x1e.user.ListBlockedUsersResponse\"\x12\x82\xd3\xe4\x93\x02\f\x12\n" +
+	"/v1/blocks\x12]\n" +
+	"\x11RequestDataExport\x12\x1e.user.RequestDataExportRequest\x1a\x10.user.DataExport\"\x16\x82\xd3\xe4\x93\x02\x10:\x01*\"\v/v1/exports\x12b\n" +
+	"\x0fGetExportStatus\x12\x1c.user.GetExportStatusRequest\x1a\x10.user.DataExport\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/v1/exports/{export_id}BAZ?github.com/Thanhbinh1905/realtime-chat-v2-go/api/user/v1;userpbb\x06proto3"
 
 var (
 	file_user_proto_rawDescOnce sync.Once
@@ -812,48 +2318,110 @@ func file_user_proto_rawDescGZIP() []byte {
 	return file_user_proto_rawDescData
 }
 
-var file_user_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_user_proto_msgTypes = make([]protoimpl.MessageInfo, 38)
 var file_user_proto_goTypes = []any{
-	(*GetProfileRequest)(nil),        // 0: user.GetProfileRequest
-	(*UpdateProfileRequest)(nil),     // 1: user.UpdateProfileRequest
-	(*UserProfile)(nil),              // 2: user.UserProfile
-	(*SearchUsersRequest)(nil),       // 3: user.SearchUsersRequest
-	(*SearchUsersResponse)(nil),      // 4: user.SearchUsersResponse
-	(*FriendRequestInput)(nil),       // 5: user.FriendRequestInput
-	(*FriendRespondInput)(nil),       // 6: user.FriendRespondInput
-	(*FriendActionResponse)(nil),     // 7: user.FriendActionResponse
-	(*GetFriendRequestsRequest)(nil), // 8: user.GetFriendRequestsRequest
-	(*FriendRequestsResponse)(nil),   // 9: user.FriendRequestsResponse
-	(*FriendRequestData)(nil),        // 10: user.FriendRequestData
-	(*GetFriendsRequest)(nil),        // 11: user.GetFriendsRequest
-	(*FriendsListResponse)(nil),      // 12: user.FriendsListResponse
-	(*RemoveFriendRequest)(nil),      // 13: user.RemoveFriendRequest
+	(*GetProfileRequest)(nil),              // 0: user.GetProfileRequest
+	(*BatchGetProfilesRequest)(nil),        // 1: user.BatchGetProfilesRequest
+	(*BatchGetProfilesResponse)(nil),       // 2: user.BatchGetProfilesResponse
+	(*ProfileResult)(nil),                  // 3: user.ProfileResult
+	(*UpdateProfileRequest)(nil),           // 4: user.UpdateProfileRequest
+	(*PrivacySettings)(nil),                // 5: user.PrivacySettings
+	(*UserProfile)(nil),                    // 6: user.UserProfile
+	(*ChangeUsernameRequest)(nil),          // 7: user.ChangeUsernameRequest
+	(*CheckUsernameAvailableRequest)(nil),  // 8: user.CheckUsernameAvailableRequest
+	(*CheckUsernameAvailableResponse)(nil), // 9: user.CheckUsernameAvailableResponse
+	(*UploadAvatarRequest)(nil),            // 10: user.UploadAvatarRequest
+	(*SearchUsersRequest)(nil),             // 11: user.SearchUsersRequest
+	(*SearchUsersResponse)(nil),            // 12: user.SearchUsersResponse
+	(*FriendRequestInput)(nil),             // 13: user.FriendRequestInput
+	(*FriendRespondInput)(nil),             // 14: user.FriendRespondInput
+	(*FriendActionResponse)(nil),           // 15: user.FriendActionResponse
+	(*CancelFriendRequestInput)(nil),       // 16: user.CancelFriendRequestInput
+	(*GetFriendRequestsRequest)(nil),       // 17: user.GetFriendRequestsRequest
+	(*ListFriendRequestsRequest)(nil),      // 18: user.ListFriendRequestsRequest
+	(*FriendRequestsResponse)(nil),         // 19: user.FriendRequestsResponse
+	(*FriendRequestData)(nil),              // 20: user.FriendRequestData
+	(*GetFriendsRequest)(nil),              // 21: user.GetFriendsRequest
+	(*FriendsListResponse)(nil),            // 22: user.FriendsListResponse
+	(*RemoveFriendRequest)(nil),            // 23: user.RemoveFriendRequest
+	(*GetMutualFriendsRequest)(nil),        // 24: user.GetMutualFriendsRequest
+	(*MutualFriendsResponse)(nil),          // 25: user.MutualFriendsResponse
+	(*SuggestFriendsRequest)(nil),          // 26: user.SuggestFriendsRequest
+	(*FriendSuggestion)(nil),               // 27: user.FriendSuggestion
+	(*SuggestFriendsResponse)(nil),         // 28: user.SuggestFriendsResponse
+	(*BlockUserRequest)(nil),               // 29: user.BlockUserRequest
+	(*UnblockUserRequest)(nil),             // 30: user.UnblockUserRequest
+	(*ListBlockedUsersRequest)(nil),        // 31: user.ListBlockedUsersRequest
+	(*BlockedUser)(nil),                    // 32: user.BlockedUser
+	(*ListBlockedUsersResponse)(nil),       // 33: user.ListBlockedUsersResponse
+	(*RequestDataExportRequest)(nil),       // 34: user.RequestDataExportRequest
+	(*GetExportStatusRequest)(nil),         // 35: user.GetExportStatusRequest
+	(*DataExport)(nil),                     // 36: user.DataExport
+	nil,                                    // 37: user.UserProfile.AvatarVariantsEntry
 }
 var file_user_proto_depIdxs = []int32{
-	2,  // 0: user.SearchUsersResponse.results:type_name -> user.UserProfile
-	10, // 1: user.FriendRequestsResponse.requests:type_name -> user.FriendRequestData
-	2,  // 2: user.FriendsListResponse.friends:type_name -> user.UserProfile
-	0,  // 3: user.UserService.GetProfile:input_type -> user.GetProfileRequest
-	1,  // 4: user.UserService.UpdateProfile:input_type -> user.UpdateProfileRequest
-	3,  // 5: user.UserService.SearchUsers:input_type -> user.SearchUsersRequest
-	5,  // 6: user.UserService.SendFriendRequest:input_type -> user.FriendRequestInput
-	6,  // 7: user.UserService.RespondToFriendRequest:input_type -> user.FriendRespondInput
-	8,  // 8: user.UserService.GetFriendRequests:input_type -> user.GetFriendRequestsRequest
-	11, // 9: user.UserService.GetFriends:input_type -> user.GetFriendsRequest
-	13, // 10: user.UserService.RemoveFriend:input_type -> user.RemoveFriendRequest
-	2,  // 11: user.UserService.GetProfile:output_type -> user.UserProfile
-	2,  // 12: user.UserService.UpdateProfile:output_type -> user.UserProfile
-	4,  // 13: user.UserService.SearchUsers:output_type -> user.SearchUsersResponse
-	7,  // 14: user.UserService.SendFriendRequest:output_type -> user.FriendActionResponse
-	7,  // 15: user.UserService.RespondToFriendRequest:output_type -> user.FriendActionResponse
-	9,  // 16: user.UserService.GetFriendRequests:output_type -> user.FriendRequestsResponse
-	12, // 17: user.UserService.GetFriends:output_type -> user.FriendsListResponse
-	7,  // 18: user.UserService.RemoveFriend:output_type -> user.FriendActionResponse
-	11, // [11:19] is the sub-list for method output_type
-	3,  // [3:11] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	3,  // 0: user.BatchGetProfilesResponse.results:type_name -> user.ProfileResult
+	6,  // 1: user.ProfileResult.profile:type_name -> user.UserProfile
+	5,  // 2: user.UpdateProfileRequest.privacy:type_name -> user.PrivacySettings
+	37, // 3: user.UserProfile.avatar_variants:type_name -> user.UserProfile.AvatarVariantsEntry
+	5,  // 4: user.UserProfile.privacy:type_name -> user.PrivacySettings
+	6,  // 5: user.SearchUsersResponse.results:type_name -> user.UserProfile
+	20, // 6: user.FriendRequestsResponse.requests:type_name -> user.FriendRequestData
+	6,  // 7: user.FriendsListResponse.friends:type_name -> user.UserProfile
+	6,  // 8: user.MutualFriendsResponse.friends:type_name -> user.UserProfile
+	6,  // 9: user.FriendSuggestion.user:type_name -> user.UserProfile
+	27, // 10: user.SuggestFriendsResponse.suggestions:type_name -> user.FriendSuggestion
+	6,  // 11: user.BlockedUser.user:type_name -> user.UserProfile
+	32, // 12: user.ListBlockedUsersResponse.users:type_name -> user.BlockedUser
+	0,  // 13: user.UserService.GetProfile:input_type -> user.GetProfileRequest
+	1,  // 14: user.UserService.BatchGetProfiles:input_type -> user.BatchGetProfilesRequest
+	4,  // 15: user.UserService.UpdateProfile:input_type -> user.UpdateProfileRequest
+	7,  // 16: user.UserService.ChangeUsername:input_type -> user.ChangeUsernameRequest
+	8,  // 17: user.UserService.CheckUsernameAvailable:input_type -> user.CheckUsernameAvailableRequest
+	10, // 18: user.UserService.UploadAvatar:input_type -> user.UploadAvatarRequest
+	11, // 19: user.UserService.SearchUsers:input_type -> user.SearchUsersRequest
+	13, // 20: user.UserService.SendFriendRequest:input_type -> user.FriendRequestInput
+	14, // 21: user.UserService.RespondToFriendRequest:input_type -> user.FriendRespondInput
+	16, // 22: user.UserService.CancelFriendRequest:input_type -> user.CancelFriendRequestInput
+	17, // 23: user.UserService.GetFriendRequests:input_type -> user.GetFriendRequestsRequest
+	18, // 24: user.UserService.ListIncomingFriendRequests:input_type -> user.ListFriendRequestsRequest
+	18, // 25: user.UserService.ListOutgoingFriendRequests:input_type -> user.ListFriendRequestsRequest
+	21, // 26: user.UserService.GetFriends:input_type -> user.GetFriendsRequest
+	23, // 27: user.UserService.RemoveFriend:input_type -> user.RemoveFriendRequest
+	24, // 28: user.UserService.GetMutualFriends:input_type -> user.GetMutualFriendsRequest
+	26, // 29: user.UserService.SuggestFriends:input_type -> user.SuggestFriendsRequest
+	29, // 30: user.UserService.BlockUser:input_type -> user.BlockUserRequest
+	30, // 31: user.UserService.UnblockUser:input_type -> user.UnblockUserRequest
+	31, // 32: user.UserService.ListBlockedUsers:input_type -> user.ListBlockedUsersRequest
+	34, // 33: user.UserService.RequestDataExport:input_type -> user.RequestDataExportRequest
+	35, // 34: user.UserService.GetExportStatus:input_type -> user.GetExportStatusRequest
+	6,  // 35: user.UserService.GetProfile:output_type -> user.UserProfile
+	2,  // 36: user.UserService.BatchGetProfiles:output_type -> user.BatchGetProfilesResponse
+	6,  // 37: user.UserService.UpdateProfile:output_type -> user.UserProfile
+	6,  // 38: user.UserService.ChangeUsername:output_type -> user.UserProfile
+	9,  // 39: user.UserService.CheckUsernameAvailable:output_type -> user.CheckUsernameAvailableResponse
+	6,  // 40: user.UserService.UploadAvatar:output_type -> user.UserProfile
+	12, // 41: user.UserService.SearchUsers:output_type -> user.SearchUsersResponse
+	15, // 42: user.UserService.SendFriendRequest:output_type -> user.FriendActionResponse
+	15, // 43: user.UserService.RespondToFriendRequest:output_type -> user.FriendActionResponse
+	15, // 44: user.UserService.CancelFriendRequest:output_type -> user.FriendActionResponse
+	19, // 45: user.UserService.GetFriendRequests:output_type -> user.FriendRequestsResponse
+	19, // 46: user.UserService.ListIncomingFriendRequests:output_type -> user.FriendRequestsResponse
+	19, // 47: user.UserService.ListOutgoingFriendRequests:output_type -> user.FriendRequestsResponse
+	22, // 48: user.UserService.GetFriends:output_type -> user.FriendsListResponse
+	15, // 49: user.UserService.RemoveFriend:output_type -> user.FriendActionResponse
+	25, // 50: user.UserService.GetMutualFriends:output_type -> user.MutualFriendsResponse
+	28, // 51: user.UserService.SuggestFriends:output_type -> user.SuggestFriendsResponse
+	15, // 52: user.UserService.BlockUser:output_type -> user.FriendActionResponse
+	15, // 53: user.UserService.UnblockUser:output_type -> user.FriendActionResponse
+	33, // 54: user.UserService.ListBlockedUsers:output_type -> user.ListBlockedUsersResponse
+	36, // 55: user.UserService.RequestDataExport:output_type -> user.DataExport
+	36, // 56: user.UserService.GetExportStatus:output_type -> user.DataExport
+	35, // [35:57] is the sub-list for method output_type
+	13, // [13:35] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_user_proto_init() }
@@ -861,13 +2429,15 @@ func file_user_proto_init() {
 	if File_user_proto != nil {
 		return
 	}
+	file_user_proto_msgTypes[4].OneofWrappers = []any{}
+	file_user_proto_msgTypes[5].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_user_proto_rawDesc), len(file_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   14,
+			NumMessages:   38,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
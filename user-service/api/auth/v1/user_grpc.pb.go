@@ -19,14 +19,28 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	UserService_GetProfile_FullMethodName             = "/user.UserService/GetProfile"
-	UserService_UpdateProfile_FullMethodName          = "/user.UserService/UpdateProfile"
-	UserService_SearchUsers_FullMethodName            = "/user.UserService/SearchUsers"
-	UserService_SendFriendRequest_FullMethodName      = "/user.UserService/SendFriendRequest"
-	UserService_RespondToFriendRequest_FullMethodName = "/user.UserService/RespondToFriendRequest"
-	UserService_GetFriendRequests_FullMethodName      = "/user.UserService/GetFriendRequests"
-	UserService_GetFriends_FullMethodName             = "/user.UserService/GetFriends"
-	UserService_RemoveFriend_FullMethodName           = "/user.UserService/RemoveFriend"
+	UserService_GetProfile_FullMethodName                 = "/user.UserService/GetProfile"
+	UserService_BatchGetProfiles_FullMethodName           = "/user.UserService/BatchGetProfiles"
+	UserService_UpdateProfile_FullMethodName              = "/user.UserService/UpdateProfile"
+	UserService_ChangeUsername_FullMethodName             = "/user.UserService/ChangeUsername"
+	UserService_CheckUsernameAvailable_FullMethodName     = "/user.UserService/CheckUsernameAvailable"
+	UserService_UploadAvatar_FullMethodName               = "/user.UserService/UploadAvatar"
+	UserService_SearchUsers_FullMethodName                = "/user.UserService/SearchUsers"
+	UserService_SendFriendRequest_FullMethodName          = "/user.UserService/SendFriendRequest"
+	UserService_RespondToFriendRequest_FullMethodName     = "/user.UserService/RespondToFriendRequest"
+	UserService_CancelFriendRequest_FullMethodName        = "/user.UserService/CancelFriendRequest"
+	UserService_GetFriendRequests_FullMethodName          = "/user.UserService/GetFriendRequests"
+	UserService_ListIncomingFriendRequests_FullMethodName = "/user.UserService/ListIncomingFriendRequests"
+	UserService_ListOutgoingFriendRequests_FullMethodName = "/user.UserService/ListOutgoingFriendRequests"
+	UserService_GetFriends_FullMethodName                 = "/user.UserService/GetFriends"
+	UserService_RemoveFriend_FullMethodName               = "/user.UserService/RemoveFriend"
+	UserService_GetMutualFriends_FullMethodName           = "/user.UserService/GetMutualFriends"
+	UserService_SuggestFriends_FullMethodName             = "/user.UserService/SuggestFriends"
+	UserService_BlockUser_FullMethodName                  = "/user.UserService/BlockUser"
+	UserService_UnblockUser_FullMethodName                = "/user.UserService/UnblockUser"
+	UserService_ListBlockedUsers_FullMethodName           = "/user.UserService/ListBlockedUsers"
+	UserService_RequestDataExport_FullMethodName          = "/user.UserService/RequestDataExport"
+	UserService_GetExportStatus_FullMethodName            = "/user.UserService/GetExportStatus"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -34,14 +48,52 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type UserServiceClient interface {
 	GetProfile(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*UserProfile, error)
+	// Profiles of up to BATCH_GET_PROFILES_MAX users at once, in request order.
+	// Requires the profiles:read permission. It is the only RPC that accepts
+	// tokens exchanged for an API key, whose scopes must include it.
+	BatchGetProfiles(ctx context.Context, in *BatchGetProfilesRequest, opts ...grpc.CallOption) (*BatchGetProfilesResponse, error)
+	// Updates the fields that are set. Only the user can update their profile.
+	//
+	// PUT /v1/users/{user_id} is deprecated and kept for existing clients. It
+	// behaves as PATCH: fields left out are unchanged. The username and avatar
+	// it used to take are no longer accepted and are ignored, see
+	// UpdateProfileRequest.
 	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserProfile, error)
+	// Renames the user. Usernames can only be changed every so often, and the
+	// old one stays unavailable to others for a while.
+	ChangeUsername(ctx context.Context, in *ChangeUsernameRequest, opts ...grpc.CallOption) (*UserProfile, error)
+	// Tells whether a username can be taken. Does not require an account.
+	CheckUsernameAvailable(ctx context.Context, in *CheckUsernameAvailableRequest, opts ...grpc.CallOption) (*CheckUsernameAvailableResponse, error)
+	// Uploads a new avatar as a stream of chunks. Over HTTP it is served as a
+	// multipart upload ("avatar" file field) at POST /v1/users/{user_id}/avatar.
+	UploadAvatar(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadAvatarRequest, UserProfile], error)
 	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error)
 	// Friend System
 	SendFriendRequest(ctx context.Context, in *FriendRequestInput, opts ...grpc.CallOption) (*FriendActionResponse, error)
 	RespondToFriendRequest(ctx context.Context, in *FriendRespondInput, opts ...grpc.CallOption) (*FriendActionResponse, error)
+	CancelFriendRequest(ctx context.Context, in *CancelFriendRequestInput, opts ...grpc.CallOption) (*FriendActionResponse, error)
+	// Deprecated: Do not use.
+	// Deprecated: use ListIncomingFriendRequests, which this is an alias of.
+	// user_id must be the caller. It comes before the list RPCs so that their
+	// literal paths take precedence over {user_id}.
 	GetFriendRequests(ctx context.Context, in *GetFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error)
+	// Pending requests addressed to the caller.
+	ListIncomingFriendRequests(ctx context.Context, in *ListFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error)
+	// Pending requests sent by the caller.
+	ListOutgoingFriendRequests(ctx context.Context, in *ListFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error)
 	GetFriends(ctx context.Context, in *GetFriendsRequest, opts ...grpc.CallOption) (*FriendsListResponse, error)
 	RemoveFriend(ctx context.Context, in *RemoveFriendRequest, opts ...grpc.CallOption) (*FriendActionResponse, error)
+	// Discovery
+	GetMutualFriends(ctx context.Context, in *GetMutualFriendsRequest, opts ...grpc.CallOption) (*MutualFriendsResponse, error)
+	SuggestFriends(ctx context.Context, in *SuggestFriendsRequest, opts ...grpc.CallOption) (*SuggestFriendsResponse, error)
+	// Blocking
+	BlockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*FriendActionResponse, error)
+	UnblockUser(ctx context.Context, in *UnblockUserRequest, opts ...grpc.CallOption) (*FriendActionResponse, error)
+	ListBlockedUsers(ctx context.Context, in *ListBlockedUsersRequest, opts ...grpc.CallOption) (*ListBlockedUsersResponse, error)
+	// Personal data export. The archive is assembled asynchronously; poll
+	// GetExportStatus until it is ready to get an expiring download link.
+	RequestDataExport(ctx context.Context, in *RequestDataExportRequest, opts ...grpc.CallOption) (*DataExport, error)
+	GetExportStatus(ctx context.Context, in *GetExportStatusRequest, opts ...grpc.CallOption) (*DataExport, error)
 }
 
 type userServiceClient struct {
@@ -62,6 +114,16 @@ func (c *userServiceClient) GetProfile(ctx context.Context, in *GetProfileReques
 	return out, nil
 }
 
+func (c *userServiceClient) BatchGetProfiles(ctx context.Context, in *BatchGetProfilesRequest, opts ...grpc.CallOption) (*BatchGetProfilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetProfilesResponse)
+	err := c.cc.Invoke(ctx, UserService_BatchGetProfiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *userServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserProfile, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UserProfile)
@@ -72,6 +134,39 @@ func (c *userServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfile
 	return out, nil
 }
 
+func (c *userServiceClient) ChangeUsername(ctx context.Context, in *ChangeUsernameRequest, opts ...grpc.CallOption) (*UserProfile, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserProfile)
+	err := c.cc.Invoke(ctx, UserService_ChangeUsername_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) CheckUsernameAvailable(ctx context.Context, in *CheckUsernameAvailableRequest, opts ...grpc.CallOption) (*CheckUsernameAvailableResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckUsernameAvailableResponse)
+	err := c.cc.Invoke(ctx, UserService_CheckUsernameAvailable_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UploadAvatar(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadAvatarRequest, UserProfile], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &UserService_ServiceDesc.Streams[0], UserService_UploadAvatar_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadAvatarRequest, UserProfile]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_UploadAvatarClient = grpc.ClientStreamingClient[UploadAvatarRequest, UserProfile]
+
 func (c *userServiceClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*SearchUsersResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(SearchUsersResponse)
@@ -102,6 +197,17 @@ func (c *userServiceClient) RespondToFriendRequest(ctx context.Context, in *Frie
 	return out, nil
 }
 
+func (c *userServiceClient) CancelFriendRequest(ctx context.Context, in *CancelFriendRequestInput, opts ...grpc.CallOption) (*FriendActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FriendActionResponse)
+	err := c.cc.Invoke(ctx, UserService_CancelFriendRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Deprecated: Do not use.
 func (c *userServiceClient) GetFriendRequests(ctx context.Context, in *GetFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(FriendRequestsResponse)
@@ -112,6 +218,26 @@ func (c *userServiceClient) GetFriendRequests(ctx context.Context, in *GetFriend
 	return out, nil
 }
 
+func (c *userServiceClient) ListIncomingFriendRequests(ctx context.Context, in *ListFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FriendRequestsResponse)
+	err := c.cc.Invoke(ctx, UserService_ListIncomingFriendRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListOutgoingFriendRequests(ctx context.Context, in *ListFriendRequestsRequest, opts ...grpc.CallOption) (*FriendRequestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FriendRequestsResponse)
+	err := c.cc.Invoke(ctx, UserService_ListOutgoingFriendRequests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *userServiceClient) GetFriends(ctx context.Context, in *GetFriendsRequest, opts ...grpc.CallOption) (*FriendsListResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(FriendsListResponse)
@@ -132,19 +258,127 @@ func (c *userServiceClient) RemoveFriend(ctx context.Context, in *RemoveFriendRe
 	return out, nil
 }
 
+func (c *userServiceClient) GetMutualFriends(ctx context.Context, in *GetMutualFriendsRequest, opts ...grpc.CallOption) (*MutualFriendsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MutualFriendsResponse)
+	err := c.cc.Invoke(ctx, UserService_GetMutualFriends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) SuggestFriends(ctx context.Context, in *SuggestFriendsRequest, opts ...grpc.CallOption) (*SuggestFriendsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SuggestFriendsResponse)
+	err := c.cc.Invoke(ctx, UserService_SuggestFriends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) BlockUser(ctx context.Context, in *BlockUserRequest, opts ...grpc.CallOption) (*FriendActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FriendActionResponse)
+	err := c.cc.Invoke(ctx, UserService_BlockUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UnblockUser(ctx context.Context, in *UnblockUserRequest, opts ...grpc.CallOption) (*FriendActionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FriendActionResponse)
+	err := c.cc.Invoke(ctx, UserService_UnblockUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListBlockedUsers(ctx context.Context, in *ListBlockedUsersRequest, opts ...grpc.CallOption) (*ListBlockedUsersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBlockedUsersResponse)
+	err := c.cc.Invoke(ctx, UserService_ListBlockedUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) RequestDataExport(ctx context.Context, in *RequestDataExportRequest, opts ...grpc.CallOption) (*DataExport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataExport)
+	err := c.cc.Invoke(ctx, UserService_RequestDataExport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetExportStatus(ctx context.Context, in *GetExportStatusRequest, opts ...grpc.CallOption) (*DataExport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataExport)
+	err := c.cc.Invoke(ctx, UserService_GetExportStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility.
 type UserServiceServer interface {
 	GetProfile(context.Context, *GetProfileRequest) (*UserProfile, error)
+	// Profiles of up to BATCH_GET_PROFILES_MAX users at once, in request order.
+	// Requires the profiles:read permission. It is the only RPC that accepts
+	// tokens exchanged for an API key, whose scopes must include it.
+	BatchGetProfiles(context.Context, *BatchGetProfilesRequest) (*BatchGetProfilesResponse, error)
+	// Updates the fields that are set. Only the user can update their profile.
+	//
+	// PUT /v1/users/{user_id} is deprecated and kept for existing clients. It
+	// behaves as PATCH: fields left out are unchanged. The username and avatar
+	// it used to take are no longer accepted and are ignored, see
+	// UpdateProfileRequest.
 	UpdateProfile(context.Context, *UpdateProfileRequest) (*UserProfile, error)
+	// Renames the user. Usernames can only be changed every so often, and the
+	// old one stays unavailable to others for a while.
+	ChangeUsername(context.Context, *ChangeUsernameRequest) (*UserProfile, error)
+	// Tells whether a username can be taken. Does not require an account.
+	CheckUsernameAvailable(context.Context, *CheckUsernameAvailableRequest) (*CheckUsernameAvailableResponse, error)
+	// Uploads a new avatar as a stream of chunks. Over HTTP it is served as a
+	// multipart upload ("avatar" file field) at POST /v1/users/{user_id}/avatar.
+	UploadAvatar(grpc.ClientStreamingServer[UploadAvatarRequest, UserProfile]) error
 	SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error)
 	// Friend System
 	SendFriendRequest(context.Context, *FriendRequestInput) (*FriendActionResponse, error)
 	RespondToFriendRequest(context.Context, *FriendRespondInput) (*FriendActionResponse, error)
+	CancelFriendRequest(context.Context, *CancelFriendRequestInput) (*FriendActionResponse, error)
+	// Deprecated: Do not use.
+	// Deprecated: use ListIncomingFriendRequests, which this is an alias of.
+	// user_id must be the caller. It comes before the list RPCs so that their
+	// literal paths take precedence over {user_id}.
 	GetFriendRequests(context.Context, *GetFriendRequestsRequest) (*FriendRequestsResponse, error)
+	// Pending requests addressed to the caller.
+	ListIncomingFriendRequests(context.Context, *ListFriendRequestsRequest) (*FriendRequestsResponse, error)
+	// Pending requests sent by the caller.
+	ListOutgoingFriendRequests(context.Context, *ListFriendRequestsRequest) (*FriendRequestsResponse, error)
 	GetFriends(context.Context, *GetFriendsRequest) (*FriendsListResponse, error)
 	RemoveFriend(context.Context, *RemoveFriendRequest) (*FriendActionResponse, error)
+	// Discovery
+	GetMutualFriends(context.Context, *GetMutualFriendsRequest) (*MutualFriendsResponse, error)
+	SuggestFriends(context.Context, *SuggestFriendsRequest) (*SuggestFriendsResponse, error)
+	// Blocking
+	BlockUser(context.Context, *BlockUserRequest) (*FriendActionResponse, error)
+	UnblockUser(context.Context, *UnblockUserRequest) (*FriendActionResponse, error)
+	ListBlockedUsers(context.Context, *ListBlockedUsersRequest) (*ListBlockedUsersResponse, error)
+	// Personal data export. The archive is assembled asynchronously; poll
+	// GetExportStatus until it is ready to get an expiring download link.
+	RequestDataExport(context.Context, *RequestDataExportRequest) (*DataExport, error)
+	GetExportStatus(context.Context, *GetExportStatusRequest) (*DataExport, error)
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -158,9 +392,21 @@ type UnimplementedUserServiceServer struct{}
 func (UnimplementedUserServiceServer) GetProfile(context.Context, *GetProfileRequest) (*UserProfile, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
 }
+func (UnimplementedUserServiceServer) BatchGetProfiles(context.Context, *BatchGetProfilesRequest) (*BatchGetProfilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetProfiles not implemented")
+}
 func (UnimplementedUserServiceServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*UserProfile, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
 }
+func (UnimplementedUserServiceServer) ChangeUsername(context.Context, *ChangeUsernameRequest) (*UserProfile, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeUsername not implemented")
+}
+func (UnimplementedUserServiceServer) CheckUsernameAvailable(context.Context, *CheckUsernameAvailableRequest) (*CheckUsernameAvailableResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckUsernameAvailable not implemented")
+}
+func (UnimplementedUserServiceServer) UploadAvatar(grpc.ClientStreamingServer[UploadAvatarRequest, UserProfile]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAvatar not implemented")
+}
 func (UnimplementedUserServiceServer) SearchUsers(context.Context, *SearchUsersRequest) (*SearchUsersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
 }
@@ -170,15 +416,45 @@ func (UnimplementedUserServiceServer) SendFriendRequest(context.Context, *Friend
 func (UnimplementedUserServiceServer) RespondToFriendRequest(context.Context, *FriendRespondInput) (*FriendActionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RespondToFriendRequest not implemented")
 }
+func (UnimplementedUserServiceServer) CancelFriendRequest(context.Context, *CancelFriendRequestInput) (*FriendActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelFriendRequest not implemented")
+}
 func (UnimplementedUserServiceServer) GetFriendRequests(context.Context, *GetFriendRequestsRequest) (*FriendRequestsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetFriendRequests not implemented")
 }
+func (UnimplementedUserServiceServer) ListIncomingFriendRequests(context.Context, *ListFriendRequestsRequest) (*FriendRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIncomingFriendRequests not implemented")
+}
+func (UnimplementedUserServiceServer) ListOutgoingFriendRequests(context.Context, *ListFriendRequestsRequest) (*FriendRequestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOutgoingFriendRequests not implemented")
+}
 func (UnimplementedUserServiceServer) GetFriends(context.Context, *GetFriendsRequest) (*FriendsListResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetFriends not implemented")
 }
 func (UnimplementedUserServiceServer) RemoveFriend(context.Context, *RemoveFriendRequest) (*FriendActionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveFriend not implemented")
 }
+func (UnimplementedUserServiceServer) GetMutualFriends(context.Context, *GetMutualFriendsRequest) (*MutualFriendsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMutualFriends not implemented")
+}
+func (UnimplementedUserServiceServer) SuggestFriends(context.Context, *SuggestFriendsRequest) (*SuggestFriendsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SuggestFriends not implemented")
+}
+func (UnimplementedUserServiceServer) BlockUser(context.Context, *BlockUserRequest) (*FriendActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BlockUser not implemented")
+}
+func (UnimplementedUserServiceServer) UnblockUser(context.Context, *UnblockUserRequest) (*FriendActionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnblockUser not implemented")
+}
+func (UnimplementedUserServiceServer) ListBlockedUsers(context.Context, *ListBlockedUsersRequest) (*ListBlockedUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBlockedUsers not implemented")
+}
+func (UnimplementedUserServiceServer) RequestDataExport(context.Context, *RequestDataExportRequest) (*DataExport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestDataExport not implemented")
+}
+func (UnimplementedUserServiceServer) GetExportStatus(context.Context, *GetExportStatusRequest) (*DataExport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExportStatus not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
 
@@ -218,6 +494,24 @@ func _UserService_GetProfile_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_BatchGetProfiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetProfilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).BatchGetProfiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_BatchGetProfiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).BatchGetProfiles(ctx, req.(*BatchGetProfilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UpdateProfileRequest)
 	if err := dec(in); err != nil {
@@ -236,6 +530,49 @@ func _UserService_UpdateProfile_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_ChangeUsername_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeUsernameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangeUsername(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ChangeUsername_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangeUsername(ctx, req.(*ChangeUsernameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_CheckUsernameAvailable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckUsernameAvailableRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CheckUsernameAvailable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CheckUsernameAvailable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CheckUsernameAvailable(ctx, req.(*CheckUsernameAvailableRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UploadAvatar_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UserServiceServer).UploadAvatar(&grpc.GenericServerStream[UploadAvatarRequest, UserProfile]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_UploadAvatarServer = grpc.ClientStreamingServer[UploadAvatarRequest, UserProfile]
+
 func _UserService_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SearchUsersRequest)
 	if err := dec(in); err != nil {
@@ -290,6 +627,24 @@ func _UserService_RespondToFriendRequest_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_CancelFriendRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelFriendRequestInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CancelFriendRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CancelFriendRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CancelFriendRequest(ctx, req.(*CancelFriendRequestInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserService_GetFriendRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetFriendRequestsRequest)
 	if err := dec(in); err != nil {
@@ -308,6 +663,42 @@ func _UserService_GetFriendRequests_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_ListIncomingFriendRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFriendRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListIncomingFriendRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListIncomingFriendRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListIncomingFriendRequests(ctx, req.(*ListFriendRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListOutgoingFriendRequests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFriendRequestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListOutgoingFriendRequests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListOutgoingFriendRequests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListOutgoingFriendRequests(ctx, req.(*ListFriendRequestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _UserService_GetFriends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetFriendsRequest)
 	if err := dec(in); err != nil {
@@ -344,6 +735,132 @@ func _UserService_RemoveFriend_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_GetMutualFriends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMutualFriendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetMutualFriends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetMutualFriends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetMutualFriends(ctx, req.(*GetMutualFriendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_SuggestFriends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuggestFriendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).SuggestFriends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_SuggestFriends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).SuggestFriends(ctx, req.(*SuggestFriendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_BlockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).BlockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_BlockUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).BlockUser(ctx, req.(*BlockUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UnblockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnblockUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UnblockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UnblockUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UnblockUser(ctx, req.(*UnblockUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListBlockedUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBlockedUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListBlockedUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_ListBlockedUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListBlockedUsers(ctx, req.(*ListBlockedUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_RequestDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestDataExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).RequestDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_RequestDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).RequestDataExport(ctx, req.(*RequestDataExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetExportStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExportStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetExportStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetExportStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetExportStatus(ctx, req.(*GetExportStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -355,10 +872,22 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetProfile",
 			Handler:    _UserService_GetProfile_Handler,
 		},
+		{
+			MethodName: "BatchGetProfiles",
+			Handler:    _UserService_BatchGetProfiles_Handler,
+		},
 		{
 			MethodName: "UpdateProfile",
 			Handler:    _UserService_UpdateProfile_Handler,
 		},
+		{
+			MethodName: "ChangeUsername",
+			Handler:    _UserService_ChangeUsername_Handler,
+		},
+		{
+			MethodName: "CheckUsernameAvailable",
+			Handler:    _UserService_CheckUsernameAvailable_Handler,
+		},
 		{
 			MethodName: "SearchUsers",
 			Handler:    _UserService_SearchUsers_Handler,
@@ -371,10 +900,22 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RespondToFriendRequest",
 			Handler:    _UserService_RespondToFriendRequest_Handler,
 		},
+		{
+			MethodName: "CancelFriendRequest",
+			Handler:    _UserService_CancelFriendRequest_Handler,
+		},
 		{
 			MethodName: "GetFriendRequests",
 			Handler:    _UserService_GetFriendRequests_Handler,
 		},
+		{
+			MethodName: "ListIncomingFriendRequests",
+			Handler:    _UserService_ListIncomingFriendRequests_Handler,
+		},
+		{
+			MethodName: "ListOutgoingFriendRequests",
+			Handler:    _UserService_ListOutgoingFriendRequests_Handler,
+		},
 		{
 			MethodName: "GetFriends",
 			Handler:    _UserService_GetFriends_Handler,
@@ -383,7 +924,41 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveFriend",
 			Handler:    _UserService_RemoveFriend_Handler,
 		},
+		{
+			MethodName: "GetMutualFriends",
+			Handler:    _UserService_GetMutualFriends_Handler,
+		},
+		{
+			MethodName: "SuggestFriends",
+			Handler:    _UserService_SuggestFriends_Handler,
+		},
+		{
+			MethodName: "BlockUser",
+			Handler:    _UserService_BlockUser_Handler,
+		},
+		{
+			MethodName: "UnblockUser",
+			Handler:    _UserService_UnblockUser_Handler,
+		},
+		{
+			MethodName: "ListBlockedUsers",
+			Handler:    _UserService_ListBlockedUsers_Handler,
+		},
+		{
+			MethodName: "RequestDataExport",
+			Handler:    _UserService_RequestDataExport_Handler,
+		},
+		{
+			MethodName: "GetExportStatus",
+			Handler:    _UserService_GetExportStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadAvatar",
+			Handler:       _UserService_UploadAvatar_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "user.proto",
 }
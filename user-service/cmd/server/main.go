@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/gateway"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/health"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	userpb "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/api/auth/v1"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/config"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/migrations"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/handler"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/job"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	// Init logger
+	logger.InitLogger(true)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// Load config
+	cfg := config.LoadConfig()
+
+	// Tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service", cfg.Tracing)
+	if err != nil {
+		logger.Log.Fatal("failed to init tracing", zap.Error(err))
+	}
+
+	// Schema
+	if err := migrate.Prepare(context.Background(), cfg.DatabaseURL, migrations.FS, cfg.AutoMigrate); err != nil {
+		logger.Log.Fatal("database schema is not ready", zap.Error(err))
+	}
+
+	// Connect DB
+	if err := db.Connect(cfg.DatabaseURL); err != nil {
+		logger.Log.Fatal("failed to connect database", zap.Error(err))
+	}
+	defer db.Close()
+	if err := metrics.RegisterPgxPool(db.Pool, "user"); err != nil {
+		logger.Log.Fatal("failed to register pool metrics", zap.Error(err))
+	}
+
+	// RabbitMQ publisher
+	publisher, err := mq.NewRabbitPublisher(cfg.RabbitMQURL, cfg.RabbitMQConnectRetries, cfg.RabbitMQRetryDelay)
+	if err != nil {
+		logger.Log.Fatal("failed to create RabbitMQ publisher", zap.Error(err))
+	}
+	defer publisher.Close()
+
+	// Blob storage
+	blobs, err := storage.NewLocalStore(cfg.BlobStorageDir, cfg.MediaBaseURL)
+	if err != nil {
+		logger.Log.Fatal("failed to open blob storage", zap.Error(err))
+	}
+	exports, err := storage.NewPrivateLocalStore(cfg.ExportStorageDir, cfg.ExportBaseURL, []byte(cfg.ExportSigningKey))
+	if err != nil {
+		logger.Log.Fatal("failed to open export storage", zap.Error(err))
+	}
+
+	// Repo, service, handler
+	repo := repository.NewRepository(db.Pool)
+	svc := service.NewSercice(repo, blobs, exports, service.Options{
+		FriendRequestCooldown:  cfg.FriendRequestCooldown,
+		AvatarMaxBytes:         cfg.AvatarMaxBytes,
+		UsernameChangeInterval: cfg.UsernameChangeInterval,
+		UsernameQuarantine:     cfg.UsernameQuarantine,
+		ExportLinkTTL:          cfg.ExportLinkTTL,
+		SuggestFriendsSample:   cfg.SuggestFriendsSample,
+		SuggestFriendsFanOut:   cfg.SuggestFriendsFanOut,
+		BatchGetProfilesMax:    cfg.BatchGetProfilesMax,
+		ProfileCacheSize:       cfg.ProfileCacheSize,
+		ProfileCacheTTL:        cfg.ProfileCacheTTL,
+	})
+	h := handler.NewUserServiceServer(svc, blobs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// RabbitMQ consumers
+	consumer, err := mq.NewRabbitConsumer(cfg.RabbitMQURL, cfg.RabbitMQConnectRetries, cfg.RabbitMQRetryDelay)
+	if err != nil {
+		logger.Log.Fatal("failed to create RabbitMQ consumer", zap.Error(err))
+	}
+	defer consumer.Close()
+
+	err = consumer.ConsumeUserDeleted(ctx, func(ctx context.Context, event mq.UserDeletedEvent) error {
+		return svc.EraseUser(ctx, event.UserID)
+	})
+	if err != nil {
+		logger.Log.Fatal("failed to consume user.deleted events", zap.Error(err))
+	}
+	if err := consumer.ConsumeExportSections(ctx, svc.SaveExportSection); err != nil {
+		logger.Log.Fatal("failed to consume user.export.section events", zap.Error(err))
+	}
+	if err := consumer.ConsumeProfileChanges(ctx, svc.InvalidateProfile); err != nil {
+		logger.Log.Fatal("failed to consume profile changes", zap.Error(err))
+	}
+	logger.Log.Info("🚀 user-service consuming user.events")
+
+	// Health checks
+	checks := health.NewRegistry(cfg.HealthCheckTimeout)
+	checks.Register("postgres", health.PingChecker(db.Pool))
+	checks.Register("rabbitmq", consumer)
+	checks.Register("rabbitmq_publisher", publisher)
+	go checks.Run(ctx, cfg.HealthCheckInterval)
+
+	// Background jobs
+	relay := job.NewOutboxRelay(repo, publisher, cfg.OutboxRelayInterval, cfg.OutboxRetention)
+	go relay.Run(ctx)
+	assembler := job.NewDataExportAssembler(repo, svc, exports, job.DataExportOptions{
+		Sections:  cfg.ExportSections,
+		Interval:  cfg.ExportInterval,
+		Timeout:   cfg.ExportTimeout,
+		Lease:     cfg.ExportAssemblyLease,
+		Retention: cfg.ExportRetention,
+	})
+	go assembler.Run(ctx)
+
+	// gRPC server
+	grpcAddr := fmt.Sprintf(":%d", cfg.GRPCPort)
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.Log.Fatal("failed to listen for gRPC", zap.String("addr", grpcAddr), zap.Error(err))
+	}
+	grpcServer := grpc.NewServer(
+		tracing.GRPCServerOption(),
+		grpc.ChainUnaryInterceptor(
+			metrics.UnaryServerInterceptor(),
+			logger.UnaryServerInterceptor(),
+			authz.UnaryServerInterceptor(cfg.JWTSecret, handler.MethodPermissions),
+		),
+		grpc.ChainStreamInterceptor(
+			metrics.StreamServerInterceptor(),
+			logger.StreamServerInterceptor(),
+			authz.StreamServerInterceptor(cfg.JWTSecret, handler.MethodPermissions),
+		),
+	)
+	userpb.RegisterUserServiceServer(grpcServer, h)
+	grpc_health_v1.RegisterHealthServer(grpcServer, checks.GRPCServer())
+
+	go func() {
+		logger.Log.Info("🚀 gRPC server started", zap.String("addr", grpcAddr))
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.Log.Fatal("failed to serve gRPC", zap.Error(err))
+		}
+	}()
+
+	// gRPC gateway (mux)
+	gwMux := runtime.NewServeMux(
+		runtime.WithErrorHandler(gateway.ErrorHandler),
+		runtime.WithMiddlewares(metrics.GatewayMiddleware()),
+	)
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		tracing.GRPCDialOption(),
+		grpc.WithChainUnaryInterceptor(logger.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(logger.StreamClientInterceptor()),
+	}
+
+	err = userpb.RegisterUserServiceHandlerFromEndpoint(ctx, gwMux, fmt.Sprintf("localhost:%d", cfg.GRPCPort), dialOpts)
+	if err != nil {
+		logger.Log.Fatal("failed to register grpc-gateway handler", zap.Error(err))
+	}
+
+	// Multipart avatar uploads are streamed to the UploadAvatar RPC.
+	gwConn, err := grpc.NewClient(fmt.Sprintf("localhost:%d", cfg.GRPCPort), dialOpts...)
+	if err != nil {
+		logger.Log.Fatal("failed to dial gRPC server", zap.Error(err))
+	}
+	defer gwConn.Close()
+	if err := handler.RegisterAvatarUploadHandler(gwMux, userpb.NewUserServiceClient(gwConn), cfg.AvatarMaxBytes); err != nil {
+		logger.Log.Fatal("failed to register avatar upload handler", zap.Error(err))
+	}
+
+	// GIN
+	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Log.Fatal("invalid trusted proxies", zap.Error(err))
+	}
+	r.Use(tracing.GinMiddleware("user-service"), logger.GinMiddleware(), metrics.GinMiddleware())
+
+	// Liveness and readiness
+	r.GET("/livez", checks.LivezHandler())
+	r.GET("/readyz", checks.ReadyzHandler())
+	r.GET("/health", checks.ReadyzHandler())
+	r.GET("/media/*key", gin.WrapH(http.StripPrefix("/media", blobs)))
+	r.GET("/exports/*key", gin.WrapH(http.StripPrefix("/exports", exports)))
+
+	// Mount grpc-gateway under /v1/*
+	r.Any("/v1/*any", gin.WrapH(gwMux))
+
+	httpServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
+		Handler:      r,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	go func() {
+		logger.Log.Info("🚀 Gin HTTP gateway started, prefix /v1", zap.String("addr", httpServer.Addr))
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Fatal("Gin HTTP server error", zap.Error(err))
+		}
+	}()
+
+	// Metrics get their own listener, kept off the public port.
+	metricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:      metrics.Handler(),
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	go func() {
+		logger.Log.Info("🚀 metrics server started", zap.String("addr", metricsServer.Addr))
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Fatal("metrics server error", zap.Error(err))
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Log.Info("🔥 Shutting down user-service...")
+	checks.Shutdown()
+	// Keep serving until load balancers have seen the service is not ready.
+	time.Sleep(cfg.ShutdownDrainDelay)
+	grpcServer.GracefulStop()
+	cancel()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("Gin HTTP shutdown error", zap.Error(err))
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("metrics server shutdown error", zap.Error(err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Log.Error("tracing shutdown error", zap.Error(err))
+	}
+	logger.Log.Info("✅ user-service stopped cleanly")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/migrate"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/config"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/migrations"
+)
+
+// runMigrate runs the migrate subcommand, "user-service migrate [flags] <command>".
+func runMigrate(args []string) {
+	cfg, args := config.LoadMigrateConfig(args)
+	if err := migrate.Command(context.Background(), cfg.DatabaseURL, migrations.FS, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
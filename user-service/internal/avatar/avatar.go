@@ -0,0 +1,136 @@
+// Package avatar validates uploaded profile pictures and renders the square
+// variants served to clients. Re-encoding drops all metadata, EXIF included.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const maxDimension = 8192
+
+var (
+	ErrUnsupportedFormat  = errors.New("unsupported image format")
+	ErrInvalidImage       = errors.New("invalid image")
+	ErrDimensionsTooLarge = errors.New("image dimensions too large")
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWebP Format = "webp"
+)
+
+// DefaultVariant is the variant shown where a single avatar is expected.
+const DefaultVariant = "medium"
+
+// Sizes are the edge lengths of the rendered variants, by name.
+var Sizes = []struct {
+	Name string
+	Size int
+}{
+	{"large", 512},
+	{"medium", 256},
+	{"small", 64},
+}
+
+type Variant struct {
+	Name        string
+	ContentType string
+	Ext         string
+	Data        []byte
+}
+
+// Detect identifies the image format from its magic bytes, whatever the client
+// claimed.
+func Detect(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF, nil
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return FormatWebP, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Process renders every variant of Sizes from data: the image is turned
+// upright according to its EXIF orientation, center-cropped to a square and
+// scaled down, never up. JPEG sources give JPEG variants, others PNG so
+// transparency is kept.
+func Process(data []byte) ([]Variant, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return nil, ErrDimensionsTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	orientation := 1
+	if format == FormatJPEG {
+		orientation = jpegOrientation(data)
+	}
+
+	crop := squareCrop(src.Bounds())
+	variants := make([]Variant, 0, len(Sizes))
+	for _, s := range Sizes {
+		size := min(s.Size, crop.Dx())
+		dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+
+		v, err := encode(s.Name, orient(dst, orientation), format)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+func squareCrop(b image.Rectangle) image.Rectangle {
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+func encode(name string, img image.Image, format Format) (Variant, error) {
+	var buf bytes.Buffer
+	if format == FormatJPEG {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return Variant{}, err
+		}
+		return Variant{Name: name, ContentType: "image/jpeg", Ext: "jpg", Data: buf.Bytes()}, nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return Variant{}, err
+	}
+	return Variant{Name: name, ContentType: "image/png", Ext: "png", Data: buf.Bytes()}, nil
+}
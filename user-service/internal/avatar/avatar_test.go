@@ -0,0 +1,120 @@
+package avatar_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/avatar"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 255})
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withPNGSize returns data, a PNG, with its header claiming w×h pixels.
+func withPNGSize(data []byte, w, h uint32) []byte {
+	data = bytes.Clone(data)
+	// The IHDR chunk follows the signature: length, type, width, height...
+	binary.BigEndian.PutUint32(data[16:], w)
+	binary.BigEndian.PutUint32(data[20:], h)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    avatar.Format
+		wantErr error
+	}{
+		{name: "png", data: encodePNG(t, 1, 1), want: avatar.FormatPNG},
+		{name: "jpeg", data: encodeJPEG(t, 1, 1), want: avatar.FormatJPEG},
+		{name: "gif", data: []byte("GIF89a..."), want: avatar.FormatGIF},
+		{name: "webp", data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), want: avatar.FormatWebP},
+		{name: "html", data: []byte("<html><script>alert(1)</script></html>"), wantErr: avatar.ErrUnsupportedFormat},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), wantErr: avatar.ErrUnsupportedFormat},
+		{name: "riff but not webp", data: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), wantErr: avatar.ErrUnsupportedFormat},
+		{name: "truncated webp", data: []byte("RIFF\x00\x00"), wantErr: avatar.ErrUnsupportedFormat},
+		{name: "empty", wantErr: avatar.ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := avatar.Detect(tt.data)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Detect = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		wantErr         error
+		wantContentType string
+		// wantSizes are the edge lengths of the variants, in Sizes order.
+		wantSizes []int
+	}{
+		{name: "png", data: encodePNG(t, 600, 300), wantContentType: "image/png", wantSizes: []int{300, 256, 64}},
+		{name: "jpeg", data: encodeJPEG(t, 40, 50), wantContentType: "image/jpeg", wantSizes: []int{40, 40, 40}},
+		{name: "html", data: []byte("<html></html>"), wantErr: avatar.ErrUnsupportedFormat},
+		{name: "png magic before html", data: append([]byte("\x89PNG\r\n\x1a\n"), "<html></html>"...), wantErr: avatar.ErrInvalidImage},
+		{name: "jpeg magic before script", data: append([]byte{0xFF, 0xD8, 0xFF}, "<script>alert(1)</script>"...), wantErr: avatar.ErrInvalidImage},
+		{name: "too wide", data: withPNGSize(encodePNG(t, 1, 1), 8193, 1), wantErr: avatar.ErrDimensionsTooLarge},
+		{name: "too tall", data: withPNGSize(encodePNG(t, 1, 1), 1, 100000), wantErr: avatar.ErrDimensionsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := avatar.Process(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Process error = %v, want %v", err, tt.wantErr)
+			}
+			if len(variants) != len(tt.wantSizes) {
+				t.Fatalf("Process returned %d variants, want %d", len(variants), len(tt.wantSizes))
+			}
+			for i, v := range variants {
+				if v.Name != avatar.Sizes[i].Name || v.ContentType != tt.wantContentType {
+					t.Errorf("variant %d = %s %s, want %s %s", i, v.Name, v.ContentType, avatar.Sizes[i].Name, tt.wantContentType)
+				}
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(v.Data))
+				if err != nil {
+					t.Fatalf("variant %s: %v", v.Name, err)
+				}
+				if cfg.Width != tt.wantSizes[i] || cfg.Height != tt.wantSizes[i] {
+					t.Errorf("variant %s is %dx%d, want %dx%[4]d", v.Name, cfg.Width, cfg.Height, tt.wantSizes[i])
+				}
+			}
+		})
+	}
+}
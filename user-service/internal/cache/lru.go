@@ -0,0 +1,103 @@
+// Package cache holds in-process caches.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded cache evicting the least recently used entries, whose
+// entries also expire after a TTL. It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is the most recently used
+	entries map[K]*list.Element
+	// version changes on every invalidation, see Version.
+	version uint64
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// NewLRU returns a cache of at most size entries, each kept for at most ttl.
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+// Get returns the value cached for key, if any.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if time.Now().After(e.expires) {
+		c.remove(el)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Version returns a token to pass to Add. Read it before loading the values to
+// cache.
+func (c *LRU[K, V]) Version() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+// Add caches values loaded since Version returned version. They are dropped if
+// anything was invalidated in the meantime, as they may predate that change.
+func (c *LRU[K, V]) Add(version uint64, values map[K]V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		return
+	}
+	expires := time.Now().Add(c.ttl)
+	for key, value := range values {
+		if el, ok := c.entries[key]; ok {
+			e := el.Value.(*entry[K, V])
+			e.value, e.expires = value, expires
+			c.order.MoveToFront(el)
+			continue
+		}
+		c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+		if c.order.Len() > c.size {
+			c.remove(c.order.Back())
+		}
+	}
+}
+
+// Invalidate drops key from the cache.
+func (c *LRU[K, V]) Invalidate(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+func (c *LRU[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	sharedconfig "github.com/Thanhbinh1905/realtime-chat-v2-go/shared/config"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	sharedconfig.Server
+	Tracing tracing.Config `mapstructure:",squash"`
+
+	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+	JWTSecret   string `mapstructure:"JWT_SECRET" validate:"required"`
+	// AutoMigrate applies pending migrations at startup. Otherwise the service
+	// refuses to start until they are applied with the migrate subcommand.
+	AutoMigrate bool `mapstructure:"AUTO_MIGRATE" default:"false"`
+
+	RabbitMQURL            string        `mapstructure:"RABBITMQ_URL" validate:"required"`
+	RabbitMQConnectRetries int           `mapstructure:"RABBITMQ_CONNECT_RETRIES" default:"5" validate:"min=1"`
+	RabbitMQRetryDelay     time.Duration `mapstructure:"RABBITMQ_RETRY_DELAY" default:"5s"`
+
+	// FriendRequestCooldown is how long a user must wait to ask again after
+	// their friend request was rejected.
+	FriendRequestCooldown time.Duration `mapstructure:"FRIEND_REQUEST_COOLDOWN" default:"72h"`
+
+	// UsernameChangeInterval is how long a user must wait between username changes.
+	UsernameChangeInterval time.Duration `mapstructure:"USERNAME_CHANGE_INTERVAL" default:"720h"`
+	// UsernameQuarantine is how long a released username stays unavailable to others.
+	UsernameQuarantine time.Duration `mapstructure:"USERNAME_QUARANTINE" default:"2160h"`
+
+	// SuggestFriendsSample is how many of the user's friends, picked at random,
+	// SuggestFriends looks at, and SuggestFriendsFanOut how many friends of each.
+	SuggestFriendsSample int `mapstructure:"SUGGEST_FRIENDS_SAMPLE" default:"100" validate:"min=1"`
+	SuggestFriendsFanOut int `mapstructure:"SUGGEST_FRIENDS_FAN_OUT" default:"100" validate:"min=1"`
+
+	// BatchGetProfilesMax is the most users one BatchGetProfiles call can ask for.
+	BatchGetProfilesMax int `mapstructure:"BATCH_GET_PROFILES_MAX" default:"100" validate:"min=1"`
+	// ProfileCacheSize is how many profiles BatchGetProfiles keeps in memory.
+	ProfileCacheSize int `mapstructure:"PROFILE_CACHE_SIZE" default:"10000" validate:"min=1"`
+	// ProfileCacheTTL caps how long a profile stays cached, should an
+	// invalidation event be missed.
+	ProfileCacheTTL time.Duration `mapstructure:"PROFILE_CACHE_TTL" default:"5m" validate:"gt=0"`
+
+	// BlobStorageDir is where uploaded files are kept, served at /media.
+	BlobStorageDir string `mapstructure:"BLOB_STORAGE_DIR" default:"data/blobs" validate:"required"`
+	// MediaBaseURL prefixes the blob URLs given to clients, e.g. a CDN in front of /media.
+	MediaBaseURL   string `mapstructure:"MEDIA_BASE_URL" default:"/media"`
+	AvatarMaxBytes int64  `mapstructure:"AVATAR_MAX_BYTES" default:"5242880" validate:"min=1"`
+
+	// ExportStorageDir is where data export archives are kept, served at /exports.
+	ExportStorageDir string `mapstructure:"EXPORT_STORAGE_DIR" default:"data/exports" validate:"required"`
+	ExportBaseURL    string `mapstructure:"EXPORT_BASE_URL" default:"/exports"`
+	// ExportSigningKey signs the expiring download links of export archives.
+	ExportSigningKey string `mapstructure:"EXPORT_SIGNING_KEY" validate:"required"`
+	// ExportSections are the other services that contribute to data exports.
+	ExportSections []string      `mapstructure:"EXPORT_SECTIONS" default:"auth-service"`
+	ExportInterval time.Duration `mapstructure:"EXPORT_INTERVAL" default:"5s" validate:"gt=0"`
+	// ExportTimeout fails exports still missing a section after that long.
+	ExportTimeout time.Duration `mapstructure:"EXPORT_TIMEOUT" default:"1h" validate:"gt=0"`
+	// ExportAssemblyLease is how long an assembler may take over an archive
+	// before another one takes the export over.
+	ExportAssemblyLease time.Duration `mapstructure:"EXPORT_ASSEMBLY_LEASE" default:"10m" validate:"gt=0"`
+	// ExportRetention is how long a ready archive can be downloaded.
+	ExportRetention time.Duration `mapstructure:"EXPORT_RETENTION" default:"168h" validate:"gt=0"`
+	// ExportLinkTTL is how long a download link stays valid.
+	ExportLinkTTL time.Duration `mapstructure:"EXPORT_LINK_TTL" default:"15m" validate:"gt=0"`
+
+	OutboxRelayInterval time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL" default:"1s" validate:"gt=0"`
+	// OutboxRetention is how long published events are kept for inspection.
+	OutboxRetention time.Duration `mapstructure:"OUTBOX_RETENTION" default:"168h"`
+}
+
+func LoadConfig() *Config {
+	cfg := &Config{}
+	if err := sharedconfig.Load(cfg, os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		logger.Log.Fatal("failed to load configuration", zap.Error(err))
+	}
+	return cfg
+}
+
+// MigrateConfig is the configuration of the migrate subcommand.
+type MigrateConfig struct {
+	DatabaseURL string `mapstructure:"DATABASE_URL" validate:"required"`
+}
+
+// LoadMigrateConfig loads the migrate subcommand configuration from args and
+// returns the arguments after the flags.
+func LoadMigrateConfig(args []string) (*MigrateConfig, []string) {
+	cfg := &MigrateConfig{}
+	rest, err := sharedconfig.LoadArgs(cfg, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		logger.Log.Fatal("failed to load configuration", zap.Error(err))
+	}
+	return cfg, rest
+}
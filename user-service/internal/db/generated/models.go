@@ -5,23 +5,74 @@
 package db
 
 import (
-	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type DataExport struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Status      string             `json:"status"`
+	RequestedAt time.Time          `json:"requested_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	BlobKey     pgtype.Text        `json:"blob_key"`
+	SizeBytes   pgtype.Int8        `json:"size_bytes"`
+	Error       pgtype.Text        `json:"error"`
+	ClaimedAt   pgtype.Timestamptz `json:"claimed_at"`
+}
+
+type DataExportSection struct {
+	ExportID   uuid.UUID   `json:"export_id"`
+	Service    string      `json:"service"`
+	Files      []byte      `json:"files"`
+	Error      pgtype.Text `json:"error"`
+	ReceivedAt time.Time   `json:"received_at"`
+	Part       int32       `json:"part"`
+	Parts      int32       `json:"parts"`
+}
+
 type Friendship struct {
-	ID          uuid.UUID    `json:"id"`
-	RequesterID uuid.UUID    `json:"requester_id"`
-	AddresseeID uuid.UUID    `json:"addressee_id"`
-	Status      string       `json:"status"`
-	CreatedAt   sql.NullTime `json:"created_at"`
+	ID          uuid.UUID          `json:"id"`
+	RequesterID uuid.UUID          `json:"requester_id"`
+	AddresseeID uuid.UUID          `json:"addressee_id"`
+	Status      string             `json:"status"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	RespondedAt pgtype.Timestamptz `json:"responded_at"`
+}
+
+type OutboxEvent struct {
+	ID          int64              `json:"id"`
+	Exchange    string             `json:"exchange"`
+	RoutingKey  string             `json:"routing_key"`
+	Payload     []byte             `json:"payload"`
+	CreatedAt   time.Time          `json:"created_at"`
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
 }
 
 type User struct {
-	ID        uuid.UUID      `json:"id"`
-	Email     string         `json:"email"`
-	Username  string         `json:"username"`
-	Avatar    sql.NullString `json:"avatar"`
-	CreatedAt sql.NullTime   `json:"created_at"`
+	ID                  uuid.UUID          `json:"id"`
+	Email               string             `json:"email"`
+	Username            string             `json:"username"`
+	Avatar              pgtype.Text        `json:"avatar"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	AvatarKeys          []byte             `json:"avatar_keys"`
+	DisplayName         string             `json:"display_name"`
+	Bio                 string             `json:"bio"`
+	StatusMessage       string             `json:"status_message"`
+	Timezone            string             `json:"timezone"`
+	Locale              string             `json:"locale"`
+	EmailVisibility     string             `json:"email_visibility"`
+	FriendRequestPolicy string             `json:"friend_request_policy"`
+	Searchable          bool               `json:"searchable"`
+	UsernameChangedAt   pgtype.Timestamptz `json:"username_changed_at"`
+}
+
+type UsernameHistory struct {
+	ID         int64     `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username"`
+	ReleasedAt time.Time `json:"released_at"`
 }
@@ -7,42 +7,246 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const acceptFriendRequest = `-- name: AcceptFriendRequest :exec
+const acceptFriendRequest = `-- name: AcceptFriendRequest :one
 UPDATE friendships
-SET status = 'accepted'
-WHERE requester_id = $1 AND addressee_id = $2
+SET status = 'accepted', responded_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
 `
 
-type AcceptFriendRequestParams struct {
+func (q *Queries) AcceptFriendRequest(ctx context.Context, id uuid.UUID) (Friendship, error) {
+	row := q.db.QueryRow(ctx, acceptFriendRequest, id)
+	var i Friendship
+	err := row.Scan(
+		&i.ID,
+		&i.RequesterID,
+		&i.AddresseeID,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const areFriends = `-- name: AreFriends :one
+SELECT EXISTS (
+  SELECT 1 FROM friendships
+  WHERE status = 'accepted'
+    AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+)
+`
+
+type AreFriendsParams struct {
 	RequesterID uuid.UUID `json:"requester_id"`
 	AddresseeID uuid.UUID `json:"addressee_id"`
 }
 
-func (q *Queries) AcceptFriendRequest(ctx context.Context, arg AcceptFriendRequestParams) error {
-	_, err := q.db.ExecContext(ctx, acceptFriendRequest, arg.RequesterID, arg.AddresseeID)
-	return err
+func (q *Queries) AreFriends(ctx context.Context, arg AreFriendsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, areFriends, arg.RequesterID, arg.AddresseeID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const blockUser = `-- name: BlockUser :execrows
+INSERT INTO friendships (id, requester_id, addressee_id, status)
+VALUES ($1, $2, $3, 'blocked')
+ON CONFLICT (requester_id, addressee_id) DO NOTHING
+`
+
+type BlockUserParams struct {
+	ID          uuid.UUID `json:"id"`
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+// Blocks are friendships with status 'blocked', the requester being the blocker.
+func (q *Queries) BlockUser(ctx context.Context, arg BlockUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, blockUser, arg.ID, arg.RequesterID, arg.AddresseeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cancelFriendRequest = `-- name: CancelFriendRequest :execrows
+DELETE FROM friendships
+WHERE id = $1 AND requester_id = $2 AND status = 'pending'
+`
+
+type CancelFriendRequestParams struct {
+	ID          uuid.UUID `json:"id"`
+	RequesterID uuid.UUID `json:"requester_id"`
+}
+
+func (q *Queries) CancelFriendRequest(ctx context.Context, arg CancelFriendRequestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelFriendRequest, arg.ID, arg.RequesterID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const changeUsername = `-- name: ChangeUsername :one
+UPDATE users u
+SET username = $1::text, username_changed_at = now()
+FROM users old
+WHERE u.id = old.id
+  AND u.id = $2::uuid
+  AND (u.username_changed_at IS NULL OR u.username_changed_at < $3::timestamptz)
+RETURNING old.username
+`
+
+type ChangeUsernameParams struct {
+	Username      string    `json:"username"`
+	UserID        uuid.UUID `json:"user_id"`
+	ChangedBefore time.Time `json:"changed_before"`
+}
+
+// Renames the user unless they already did after changed_before. Returns their
+// previous username.
+func (q *Queries) ChangeUsername(ctx context.Context, arg ChangeUsernameParams) (string, error) {
+	row := q.db.QueryRow(ctx, changeUsername, arg.Username, arg.UserID, arg.ChangedBefore)
+	var username string
+	err := row.Scan(&username)
+	return username, err
+}
+
+const claimCompleteDataExport = `-- name: ClaimCompleteDataExport :one
+UPDATE data_exports
+SET status = 'assembling', claimed_at = now()
+WHERE id = (
+  SELECT e.id FROM data_exports e
+  WHERE NOT e.id = ANY(coalesce($1::uuid[], '{}'))
+    AND (
+      (
+        e.status = 'pending'
+        AND (
+          SELECT count(DISTINCT s.service) FROM data_export_sections s
+          WHERE s.export_id = e.id
+            AND s.service = ANY($2::text[])
+            AND s.parts = (
+              SELECT count(*) FROM data_export_sections p
+              WHERE p.export_id = s.export_id AND p.service = s.service
+            )
+        ) = cardinality($2::text[])
+      )
+      OR (e.status = 'assembling' AND e.claimed_at < $3)
+    )
+  ORDER BY e.requested_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, status, requested_at, completed_at, expires_at, blob_key, size_bytes, error, claimed_at
+`
+
+type ClaimCompleteDataExportParams struct {
+	Skipped       []uuid.UUID        `json:"skipped"`
+	Services      []string           `json:"services"`
+	ClaimedBefore pgtype.Timestamptz `json:"claimed_before"`
+}
+
+// Claims for assembly the oldest pending export that has every part of a
+// section from every service, or an export whose claim is older than
+// claimed_before, except the skipped ones. Failed sections are handled by
+// FailDataExportsWithSectionErrors first.
+func (q *Queries) ClaimCompleteDataExport(ctx context.Context, arg ClaimCompleteDataExportParams) (DataExport, error) {
+	row := q.db.QueryRow(ctx, claimCompleteDataExport, arg.Skipped, arg.Services, arg.ClaimedBefore)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.BlobKey,
+		&i.SizeBytes,
+		&i.Error,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const completeDataExport = `-- name: CompleteDataExport :execrows
+UPDATE data_exports
+SET status = 'ready', completed_at = now(), expires_at = $2, blob_key = $3, size_bytes = $4
+WHERE id = $1 AND status = 'assembling' AND claimed_at = $5
+`
+
+type CompleteDataExportParams struct {
+	ID        uuid.UUID          `json:"id"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	BlobKey   pgtype.Text        `json:"blob_key"`
+	SizeBytes pgtype.Int8        `json:"size_bytes"`
+	ClaimedAt pgtype.Timestamptz `json:"claimed_at"`
+}
+
+// Completes an export claimed at claimed_at. It does nothing if the export was
+// erased or its claim taken over meanwhile.
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) (int64, error) {
+	result, err := q.db.Exec(ctx, completeDataExport,
+		arg.ID,
+		arg.ExpiresAt,
+		arg.BlobKey,
+		arg.SizeBytes,
+		arg.ClaimedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id) VALUES ($1, $2)
+RETURNING id, user_id, status, requested_at, completed_at, expires_at, blob_key, size_bytes, error, claimed_at
+`
+
+type CreateDataExportParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) CreateDataExport(ctx context.Context, arg CreateDataExportParams) (DataExport, error) {
+	row := q.db.QueryRow(ctx, createDataExport, arg.ID, arg.UserID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.BlobKey,
+		&i.SizeBytes,
+		&i.Error,
+		&i.ClaimedAt,
+	)
+	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email, username, avatar)
 VALUES ($1, $2, $3, $4)
-RETURNING id, email, username, avatar, created_at
+RETURNING id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at
 `
 
 type CreateUserParams struct {
-	ID       uuid.UUID      `json:"id"`
-	Email    string         `json:"email"`
-	Username string         `json:"username"`
-	Avatar   sql.NullString `json:"avatar"`
+	ID       uuid.UUID   `json:"id"`
+	Email    string      `json:"email"`
+	Username string      `json:"username"`
+	Avatar   pgtype.Text `json:"avatar"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser,
+	row := q.db.QueryRow(ctx, createUser,
 		arg.ID,
 		arg.Email,
 		arg.Username,
@@ -55,93 +259,330 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Username,
 		&i.Avatar,
 		&i.CreatedAt,
+		&i.AvatarKeys,
+		&i.DisplayName,
+		&i.Bio,
+		&i.StatusMessage,
+		&i.Timezone,
+		&i.Locale,
+		&i.EmailVisibility,
+		&i.FriendRequestPolicy,
+		&i.Searchable,
+		&i.UsernameChangedAt,
 	)
 	return i, err
 }
 
-const getFriends = `-- name: GetFriends :many
-SELECT u.id, u.email, u.username, u.avatar, u.created_at
-FROM users u
-JOIN friendships f ON (f.addressee_id = u.id OR f.requester_id = u.id)
-WHERE (f.requester_id = $1 OR f.addressee_id = $1)
-  AND f.status = 'accepted'
-  AND u.id != $1
+const deleteFriendship = `-- name: DeleteFriendship :exec
+DELETE FROM friendships WHERE id = $1
 `
 
-func (q *Queries) GetFriends(ctx context.Context, requesterID uuid.UUID) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, getFriends, requesterID)
+func (q *Queries) DeleteFriendship(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteFriendship, id)
+	return err
+}
+
+const deleteFriendshipBetween = `-- name: DeleteFriendshipBetween :many
+DELETE FROM friendships
+WHERE status <> 'blocked'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+`
+
+type DeleteFriendshipBetweenParams struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+// Removes any friendship or pending request between two users, leaving blocks alone.
+func (q *Queries) DeleteFriendshipBetween(ctx context.Context, arg DeleteFriendshipBetweenParams) ([]Friendship, error) {
+	rows, err := q.db.Query(ctx, deleteFriendshipBetween, arg.RequesterID, arg.AddresseeID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []User
+	var items []Friendship
 	for rows.Next() {
-		var i User
+		var i Friendship
 		if err := rows.Scan(
 			&i.ID,
-			&i.Email,
-			&i.Username,
-			&i.Avatar,
+			&i.RequesterID,
+			&i.AddresseeID,
+			&i.Status,
 			&i.CreatedAt,
+			&i.RespondedAt,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteFriendshipsByUser = `-- name: DeleteFriendshipsByUser :many
+DELETE FROM friendships
+WHERE requester_id = $1 OR addressee_id = $1
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+`
+
+func (q *Queries) DeleteFriendshipsByUser(ctx context.Context, requesterID uuid.UUID) ([]Friendship, error) {
+	rows, err := q.db.Query(ctx, deleteFriendshipsByUser, requesterID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var items []Friendship
+	for rows.Next() {
+		var i Friendship
+		if err := rows.Scan(
+			&i.ID,
+			&i.RequesterID,
+			&i.AddresseeID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, username, avatar, created_at FROM users WHERE email = $1
+const deletePublishedOutboxEvents = `-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events WHERE published_at < $1
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
-	var i User
+func (q *Queries) DeletePublishedOutboxEvents(ctx context.Context, publishedAt pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deletePublishedOutboxEvents, publishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const expireDataExport = `-- name: ExpireDataExport :exec
+UPDATE data_exports SET status = 'expired', blob_key = NULL WHERE id = $1
+`
+
+func (q *Queries) ExpireDataExport(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, expireDataExport, id)
+	return err
+}
+
+const exportBlocks = `-- name: ExportBlocks :many
+SELECT u.id, u.username, f.created_at AS blocked_at
+FROM friendships f
+JOIN users u ON u.id = f.addressee_id
+WHERE f.requester_id = $1::uuid AND f.status = 'blocked'
+ORDER BY f.created_at, f.id
+`
+
+type ExportBlocksRow struct {
+	ID        uuid.UUID          `json:"id"`
+	Username  string             `json:"username"`
+	BlockedAt pgtype.Timestamptz `json:"blocked_at"`
+}
+
+// Users blocked by blocker_id. Blocks against them are not theirs to see.
+func (q *Queries) ExportBlocks(ctx context.Context, blockerID uuid.UUID) ([]ExportBlocksRow, error) {
+	rows, err := q.db.Query(ctx, exportBlocks, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportBlocksRow
+	for rows.Next() {
+		var i ExportBlocksRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.BlockedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportFriendships = `-- name: ExportFriendships :many
+SELECT
+  f.id,
+  f.status,
+  f.created_at,
+  f.responded_at,
+  f.requester_id = $1::uuid AS sent,
+  o.id AS other_user_id,
+  o.username AS other_username
+FROM friendships f
+JOIN users o
+  ON o.id = CASE WHEN f.requester_id = $1::uuid THEN f.addressee_id ELSE f.requester_id END
+WHERE (f.requester_id = $1::uuid OR f.addressee_id = $1::uuid)
+  AND f.status <> 'blocked'
+ORDER BY f.created_at, f.id
+`
+
+type ExportFriendshipsRow struct {
+	ID            uuid.UUID          `json:"id"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	RespondedAt   pgtype.Timestamptz `json:"responded_at"`
+	Sent          bool               `json:"sent"`
+	OtherUserID   uuid.UUID          `json:"other_user_id"`
+	OtherUsername string             `json:"other_username"`
+}
+
+// Friendships and requests of user_id, blocks aside, with the other user.
+func (q *Queries) ExportFriendships(ctx context.Context, userID uuid.UUID) ([]ExportFriendshipsRow, error) {
+	rows, err := q.db.Query(ctx, exportFriendships, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportFriendshipsRow
+	for rows.Next() {
+		var i ExportFriendshipsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+			&i.Sent,
+			&i.OtherUserID,
+			&i.OtherUsername,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const failDataExport = `-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed', completed_at = now(), error = $2
+WHERE id = $1 AND status = 'assembling' AND claimed_at = $3
+`
+
+type FailDataExportParams struct {
+	ID        uuid.UUID          `json:"id"`
+	Error     pgtype.Text        `json:"error"`
+	ClaimedAt pgtype.Timestamptz `json:"claimed_at"`
+}
+
+// Fails an export claimed at claimed_at that cannot be assembled.
+func (q *Queries) FailDataExport(ctx context.Context, arg FailDataExportParams) error {
+	_, err := q.db.Exec(ctx, failDataExport, arg.ID, arg.Error, arg.ClaimedAt)
+	return err
+}
+
+const failDataExportsWithSectionErrors = `-- name: FailDataExportsWithSectionErrors :execrows
+UPDATE data_exports e
+SET status = 'failed', completed_at = now(), error = s.errors
+FROM (
+  SELECT export_id, string_agg(service || ': ' || error, '; ' ORDER BY service) AS errors
+  FROM data_export_sections
+  WHERE error IS NOT NULL
+  GROUP BY export_id
+) s
+WHERE e.id = s.export_id AND e.status = 'pending'
+`
+
+// Fails pending exports for which a service reported an error.
+func (q *Queries) FailDataExportsWithSectionErrors(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, failDataExportsWithSectionErrors)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const failStaleDataExports = `-- name: FailStaleDataExports :execrows
+UPDATE data_exports
+SET status = 'failed', completed_at = now(), error = 'timed out waiting for all services'
+WHERE status = 'pending' AND requested_at < $1
+`
+
+func (q *Queries) FailStaleDataExports(ctx context.Context, requestedAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, failStaleDataExports, requestedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const getDataExport = `-- name: GetDataExport :one
+SELECT id, user_id, status, requested_at, completed_at, expires_at, blob_key, size_bytes, error, claimed_at FROM data_exports WHERE id = $1
+`
+
+func (q *Queries) GetDataExport(ctx context.Context, id uuid.UUID) (DataExport, error) {
+	row := q.db.QueryRow(ctx, getDataExport, id)
+	var i DataExport
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
-		&i.Username,
-		&i.Avatar,
-		&i.CreatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.BlobKey,
+		&i.SizeBytes,
+		&i.Error,
+		&i.ClaimedAt,
 	)
 	return i, err
 }
 
-const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, username, avatar, created_at FROM users WHERE id = $1
+const getFriendRequestByID = `-- name: GetFriendRequestByID :one
+SELECT id, requester_id, addressee_id, status, created_at, responded_at FROM friendships WHERE id = $1 AND status <> 'blocked'
 `
 
-func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByID, id)
-	var i User
+func (q *Queries) GetFriendRequestByID(ctx context.Context, id uuid.UUID) (Friendship, error) {
+	row := q.db.QueryRow(ctx, getFriendRequestByID, id)
+	var i Friendship
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
-		&i.Username,
-		&i.Avatar,
+		&i.RequesterID,
+		&i.AddresseeID,
+		&i.Status,
 		&i.CreatedAt,
+		&i.RespondedAt,
 	)
 	return i, err
 }
 
-const listUsers = `-- name: ListUsers :many
-SELECT id, email, username, avatar, created_at FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
+const getFriends = `-- name: GetFriends :many
+SELECT u.id, u.email, u.username, u.avatar, u.created_at, u.avatar_keys, u.display_name, u.bio, u.status_message, u.timezone, u.locale, u.email_visibility, u.friend_request_policy, u.searchable, u.username_changed_at
+FROM users u
+JOIN friendships f ON (f.addressee_id = u.id OR f.requester_id = u.id)
+WHERE (f.requester_id = $1 OR f.addressee_id = $1)
+  AND f.status = 'accepted'
+  AND u.id != $1
 `
 
-type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
-}
-
-func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+func (q *Queries) GetFriends(ctx context.Context, requesterID uuid.UUID) ([]User, error) {
+	rows, err := q.db.Query(ctx, getFriends, requesterID)
 	if err != nil {
 		return nil, err
 	}
@@ -155,50 +596,41 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Username,
 			&i.Avatar,
 			&i.CreatedAt,
+			&i.AvatarKeys,
+			&i.DisplayName,
+			&i.Bio,
+			&i.StatusMessage,
+			&i.Timezone,
+			&i.Locale,
+			&i.EmailVisibility,
+			&i.FriendRequestPolicy,
+			&i.Searchable,
+			&i.UsernameChangedAt,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
 	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-const rejectFriendRequest = `-- name: RejectFriendRequest :exec
-UPDATE friendships
-SET status = 'rejected'
-WHERE requester_id = $1 AND addressee_id = $2
-`
-
-type RejectFriendRequestParams struct {
-	RequesterID uuid.UUID `json:"requester_id"`
-	AddresseeID uuid.UUID `json:"addressee_id"`
-}
-
-func (q *Queries) RejectFriendRequest(ctx context.Context, arg RejectFriendRequestParams) error {
-	_, err := q.db.ExecContext(ctx, rejectFriendRequest, arg.RequesterID, arg.AddresseeID)
-	return err
-}
-
-const sendFriendRequest = `-- name: SendFriendRequest :one
-INSERT INTO friendships (id, requester_id, addressee_id, status)
-VALUES ($1, $2, $3, 'pending')
-RETURNING id, requester_id, addressee_id, status, created_at
+const getFriendshipBetween = `-- name: GetFriendshipBetween :one
+SELECT id, requester_id, addressee_id, status, created_at, responded_at FROM friendships
+WHERE status <> 'blocked'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
 `
 
-type SendFriendRequestParams struct {
-	ID          uuid.UUID `json:"id"`
+type GetFriendshipBetweenParams struct {
 	RequesterID uuid.UUID `json:"requester_id"`
 	AddresseeID uuid.UUID `json:"addressee_id"`
 }
 
-func (q *Queries) SendFriendRequest(ctx context.Context, arg SendFriendRequestParams) (Friendship, error) {
-	row := q.db.QueryRowContext(ctx, sendFriendRequest, arg.ID, arg.RequesterID, arg.AddresseeID)
+// Returns the request or friendship between two users in either direction.
+func (q *Queries) GetFriendshipBetween(ctx context.Context, arg GetFriendshipBetweenParams) (Friendship, error) {
+	row := q.db.QueryRow(ctx, getFriendshipBetween, arg.RequesterID, arg.AddresseeID)
 	var i Friendship
 	err := row.Scan(
 		&i.ID,
@@ -206,6 +638,1252 @@ func (q *Queries) SendFriendRequest(ctx context.Context, arg SendFriendRequestPa
 		&i.AddresseeID,
 		&i.Status,
 		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const getMutualFriends = `-- name: GetMutualFriends :many
+WITH friends_a AS (
+  SELECT addressee_id AS id FROM friendships WHERE requester_id = $5::uuid AND status = 'accepted'
+  UNION ALL
+  SELECT requester_id FROM friendships WHERE addressee_id = $5::uuid AND status = 'accepted'
+), friends_b AS (
+  SELECT addressee_id AS id FROM friendships WHERE requester_id = $6::uuid AND status = 'accepted'
+  UNION ALL
+  SELECT requester_id FROM friendships WHERE addressee_id = $6::uuid AND status = 'accepted'
+), mutual AS (
+  SELECT id FROM friends_a
+  INTERSECT
+  SELECT id FROM friends_b
+)
+SELECT u.id, u.email, u.username, u.avatar, u.created_at, u.avatar_keys, u.display_name, u.bio, u.status_message, u.timezone, u.locale, u.email_visibility, u.friend_request_policy, u.searchable, u.username_changed_at
+FROM mutual m
+JOIN users u ON u.id = m.id
+WHERE NOT EXISTS (
+    SELECT 1 FROM friendships b
+    WHERE b.status = 'blocked'
+      AND (
+        (b.requester_id = u.id AND b.addressee_id = $1::uuid)
+        OR (b.requester_id = $1::uuid AND b.addressee_id = u.id)
+      )
+  )
+  AND (
+    $2::text IS NULL
+    OR (u.username, u.id) > ($2::text, $3::uuid)
+  )
+ORDER BY u.username, u.id
+LIMIT $4::int
+`
+
+type GetMutualFriendsParams struct {
+	CallerID       uuid.UUID     `json:"caller_id"`
+	CursorUsername pgtype.Text   `json:"cursor_username"`
+	CursorID       uuid.NullUUID `json:"cursor_id"`
+	PageSize       int32         `json:"page_size"`
+	UserA          uuid.UUID     `json:"user_a"`
+	UserB          uuid.UUID     `json:"user_b"`
+}
+
+// Friends of both user_a and user_b, excluding users blocked by or blocking
+// caller_id, by username. Paged by (username, id) keyset.
+func (q *Queries) GetMutualFriends(ctx context.Context, arg GetMutualFriendsParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, getMutualFriends,
+		arg.CallerID,
+		arg.CursorUsername,
+		arg.CursorID,
+		arg.PageSize,
+		arg.UserA,
+		arg.UserB,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.Avatar,
+			&i.CreatedAt,
+			&i.AvatarKeys,
+			&i.DisplayName,
+			&i.Bio,
+			&i.StatusMessage,
+			&i.Timezone,
+			&i.Locale,
+			&i.EmailVisibility,
+			&i.FriendRequestPolicy,
+			&i.Searchable,
+			&i.UsernameChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPendingDataExport = `-- name: GetPendingDataExport :one
+SELECT id, user_id, status, requested_at, completed_at, expires_at, blob_key, size_bytes, error, claimed_at FROM data_exports WHERE user_id = $1 AND status IN ('pending', 'assembling')
+`
+
+// The export of the user still in progress, if any.
+func (q *Queries) GetPendingDataExport(ctx context.Context, userID uuid.UUID) (DataExport, error) {
+	row := q.db.QueryRow(ctx, getPendingDataExport, userID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.BlobKey,
+		&i.SizeBytes,
+		&i.Error,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at FROM users WHERE email = $1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Avatar,
+		&i.CreatedAt,
+		&i.AvatarKeys,
+		&i.DisplayName,
+		&i.Bio,
+		&i.StatusMessage,
+		&i.Timezone,
+		&i.Locale,
+		&i.EmailVisibility,
+		&i.FriendRequestPolicy,
+		&i.Searchable,
+		&i.UsernameChangedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Avatar,
+		&i.CreatedAt,
+		&i.AvatarKeys,
+		&i.DisplayName,
+		&i.Bio,
+		&i.StatusMessage,
+		&i.Timezone,
+		&i.Locale,
+		&i.EmailVisibility,
+		&i.FriendRequestPolicy,
+		&i.Searchable,
+		&i.UsernameChangedAt,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at FROM users WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.Avatar,
+			&i.CreatedAt,
+			&i.AvatarKeys,
+			&i.DisplayName,
+			&i.Bio,
+			&i.StatusMessage,
+			&i.Timezone,
+			&i.Locale,
+			&i.EmailVisibility,
+			&i.FriendRequestPolicy,
+			&i.Searchable,
+			&i.UsernameChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const haveMutualFriend = `-- name: HaveMutualFriend :one
+SELECT EXISTS (
+  SELECT 1
+  FROM friendships a
+  JOIN friendships b
+    ON CASE WHEN a.requester_id = $1::uuid THEN a.addressee_id ELSE a.requester_id END
+     = CASE WHEN b.requester_id = $2::uuid THEN b.addressee_id ELSE b.requester_id END
+  WHERE a.status = 'accepted'
+    AND (a.requester_id = $1::uuid OR a.addressee_id = $1::uuid)
+    AND b.status = 'accepted'
+    AND (b.requester_id = $2::uuid OR b.addressee_id = $2::uuid)
+)
+`
+
+type HaveMutualFriendParams struct {
+	UserA uuid.UUID `json:"user_a"`
+	UserB uuid.UUID `json:"user_b"`
+}
+
+func (q *Queries) HaveMutualFriend(ctx context.Context, arg HaveMutualFriendParams) (bool, error) {
+	row := q.db.QueryRow(ctx, haveMutualFriend, arg.UserA, arg.UserB)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const insertOutboxEvent = `-- name: InsertOutboxEvent :exec
+INSERT INTO outbox_events (exchange, routing_key, payload)
+VALUES ($1, $2, $3)
+`
+
+type InsertOutboxEventParams struct {
+	Exchange   string `json:"exchange"`
+	RoutingKey string `json:"routing_key"`
+	Payload    []byte `json:"payload"`
+}
+
+func (q *Queries) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, insertOutboxEvent, arg.Exchange, arg.RoutingKey, arg.Payload)
+	return err
+}
+
+const insertUsernameHistory = `-- name: InsertUsernameHistory :exec
+INSERT INTO username_history (user_id, username) VALUES ($1, $2)
+`
+
+type InsertUsernameHistoryParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+func (q *Queries) InsertUsernameHistory(ctx context.Context, arg InsertUsernameHistoryParams) error {
+	_, err := q.db.Exec(ctx, insertUsernameHistory, arg.UserID, arg.Username)
+	return err
+}
+
+const isBlockedEitherWay = `-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+  SELECT 1 FROM friendships
+  WHERE status = 'blocked'
+    AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+)
+`
+
+type IsBlockedEitherWayParams struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+func (q *Queries) IsBlockedEitherWay(ctx context.Context, arg IsBlockedEitherWayParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isBlockedEitherWay, arg.RequesterID, arg.AddresseeID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isUsernameTaken = `-- name: IsUsernameTaken :one
+SELECT EXISTS (
+  SELECT 1 FROM users u
+  WHERE lower(u.username) = lower($1::text)
+    AND u.id <> $2::uuid
+  UNION ALL
+  SELECT 1 FROM username_history h
+  WHERE lower(h.username) = lower($1::text)
+    AND h.released_at > $3::timestamptz
+    AND h.user_id <> $2::uuid
+)
+`
+
+type IsUsernameTakenParams struct {
+	Username  string    `json:"username"`
+	UserID    uuid.UUID `json:"user_id"`
+	HeldSince time.Time `json:"held_since"`
+}
+
+// Reports whether username, ignoring case, belongs to someone other than
+// user_id or was released by someone else after held_since.
+func (q *Queries) IsUsernameTaken(ctx context.Context, arg IsUsernameTakenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isUsernameTaken, arg.Username, arg.UserID, arg.HeldSince)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listBlockedEitherWayAmong = `-- name: ListBlockedEitherWayAmong :many
+SELECT (CASE WHEN requester_id = $1::uuid THEN addressee_id ELSE requester_id END)::uuid AS id
+FROM friendships
+WHERE status = 'blocked'
+  AND (
+    (requester_id = $1::uuid AND addressee_id = ANY($2::uuid[]))
+    OR (addressee_id = $1::uuid AND requester_id = ANY($2::uuid[]))
+  )
+`
+
+type ListBlockedEitherWayAmongParams struct {
+	UserID uuid.UUID   `json:"user_id"`
+	Ids    []uuid.UUID `json:"ids"`
+}
+
+// The users among ids that user_id blocked or was blocked by.
+func (q *Queries) ListBlockedEitherWayAmong(ctx context.Context, arg ListBlockedEitherWayAmongParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listBlockedEitherWayAmong, arg.UserID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBlockedUsers = `-- name: ListBlockedUsers :many
+SELECT u.id, u.email, u.username, u.avatar, u.created_at, u.avatar_keys, u.display_name, u.bio, u.status_message, u.timezone, u.locale, u.email_visibility, u.friend_request_policy, u.searchable, u.username_changed_at, f.created_at AS blocked_at
+FROM friendships f
+JOIN users u ON u.id = f.addressee_id
+WHERE f.requester_id = $1::uuid
+  AND f.status = 'blocked'
+  AND (
+    $2::timestamptz IS NULL
+    OR (f.created_at, u.id) < ($2::timestamptz, $3::uuid)
+  )
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT $4::int
+`
+
+type ListBlockedUsersParams struct {
+	BlockerID       uuid.UUID          `json:"blocker_id"`
+	CursorBlockedAt pgtype.Timestamptz `json:"cursor_blocked_at"`
+	CursorID        uuid.NullUUID      `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+type ListBlockedUsersRow struct {
+	User      User               `json:"user"`
+	BlockedAt pgtype.Timestamptz `json:"blocked_at"`
+}
+
+// Users blocked by blocker_id, most recently blocked first, paged by
+// (blocked_at, id) keyset.
+func (q *Queries) ListBlockedUsers(ctx context.Context, arg ListBlockedUsersParams) ([]ListBlockedUsersRow, error) {
+	rows, err := q.db.Query(ctx, listBlockedUsers,
+		arg.BlockerID,
+		arg.CursorBlockedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBlockedUsersRow
+	for rows.Next() {
+		var i ListBlockedUsersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Email,
+			&i.User.Username,
+			&i.User.Avatar,
+			&i.User.CreatedAt,
+			&i.User.AvatarKeys,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.StatusMessage,
+			&i.User.Timezone,
+			&i.User.Locale,
+			&i.User.EmailVisibility,
+			&i.User.FriendRequestPolicy,
+			&i.User.Searchable,
+			&i.User.UsernameChangedAt,
+			&i.BlockedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDataExportBlobKeys = `-- name: ListDataExportBlobKeys :many
+SELECT blob_key::text FROM data_exports WHERE user_id = $1 AND blob_key IS NOT NULL
+`
+
+func (q *Queries) ListDataExportBlobKeys(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listDataExportBlobKeys, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var blob_key string
+		if err := rows.Scan(&blob_key); err != nil {
+			return nil, err
+		}
+		items = append(items, blob_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDataExportSections = `-- name: ListDataExportSections :many
+SELECT export_id, service, files, error, received_at, part, parts FROM data_export_sections WHERE export_id = $1 ORDER BY service, part
+`
+
+func (q *Queries) ListDataExportSections(ctx context.Context, exportID uuid.UUID) ([]DataExportSection, error) {
+	rows, err := q.db.Query(ctx, listDataExportSections, exportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DataExportSection
+	for rows.Next() {
+		var i DataExportSection
+		if err := rows.Scan(
+			&i.ExportID,
+			&i.Service,
+			&i.Files,
+			&i.Error,
+			&i.ReceivedAt,
+			&i.Part,
+			&i.Parts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredDataExports = `-- name: ListExpiredDataExports :many
+SELECT id, user_id, status, requested_at, completed_at, expires_at, blob_key, size_bytes, error, claimed_at FROM data_exports
+WHERE status = 'ready' AND expires_at < $1
+ORDER BY expires_at
+LIMIT $2
+`
+
+type ListExpiredDataExportsParams struct {
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	Limit     int32              `json:"limit"`
+}
+
+func (q *Queries) ListExpiredDataExports(ctx context.Context, arg ListExpiredDataExportsParams) ([]DataExport, error) {
+	rows, err := q.db.Query(ctx, listExpiredDataExports, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DataExport
+	for rows.Next() {
+		var i DataExport
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Status,
+			&i.RequestedAt,
+			&i.CompletedAt,
+			&i.ExpiresAt,
+			&i.BlobKey,
+			&i.SizeBytes,
+			&i.Error,
+			&i.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIncomingFriendRequests = `-- name: ListIncomingFriendRequests :many
+SELECT id, requester_id, addressee_id, status, created_at, responded_at FROM friendships
+WHERE addressee_id = $1::uuid
+  AND status = 'pending'
+  AND (
+    $2::timestamptz IS NULL
+    OR (created_at, id) < ($2::timestamptz, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4::int
+`
+
+type ListIncomingFriendRequestsParams struct {
+	UserID          uuid.UUID          `json:"user_id"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID      `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+// Pending requests addressed to user_id, newest first, paged by (created_at, id) keyset.
+func (q *Queries) ListIncomingFriendRequests(ctx context.Context, arg ListIncomingFriendRequestsParams) ([]Friendship, error) {
+	rows, err := q.db.Query(ctx, listIncomingFriendRequests,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Friendship
+	for rows.Next() {
+		var i Friendship
+		if err := rows.Scan(
+			&i.ID,
+			&i.RequesterID,
+			&i.AddresseeID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOutgoingFriendRequests = `-- name: ListOutgoingFriendRequests :many
+SELECT id, requester_id, addressee_id, status, created_at, responded_at FROM friendships
+WHERE requester_id = $1::uuid
+  AND status = 'pending'
+  AND (
+    $2::timestamptz IS NULL
+    OR (created_at, id) < ($2::timestamptz, $3::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $4::int
+`
+
+type ListOutgoingFriendRequestsParams struct {
+	UserID          uuid.UUID          `json:"user_id"`
+	CursorCreatedAt pgtype.Timestamptz `json:"cursor_created_at"`
+	CursorID        uuid.NullUUID      `json:"cursor_id"`
+	PageSize        int32              `json:"page_size"`
+}
+
+// Pending requests sent by user_id, newest first, paged by (created_at, id) keyset.
+func (q *Queries) ListOutgoingFriendRequests(ctx context.Context, arg ListOutgoingFriendRequestsParams) ([]Friendship, error) {
+	rows, err := q.db.Query(ctx, listOutgoingFriendRequests,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Friendship
+	for rows.Next() {
+		var i Friendship
+		if err := rows.Scan(
+			&i.ID,
+			&i.RequesterID,
+			&i.AddresseeID,
+			&i.Status,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpublishedOutboxEvents = `-- name: ListUnpublishedOutboxEvents :many
+SELECT id, exchange, routing_key, payload, created_at, published_at FROM outbox_events
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1
+`
+
+func (q *Queries) ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]OutboxEvent, error) {
+	rows, err := q.db.Query(ctx, listUnpublishedOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OutboxEvent
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Exchange,
+			&i.RoutingKey,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsernameHistory = `-- name: ListUsernameHistory :many
+SELECT id, user_id, username, released_at FROM username_history
+WHERE user_id = $1
+ORDER BY released_at DESC, id DESC
+`
+
+func (q *Queries) ListUsernameHistory(ctx context.Context, userID uuid.UUID) ([]UsernameHistory, error) {
+	rows, err := q.db.Query(ctx, listUsernameHistory, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UsernameHistory
+	for rows.Next() {
+		var i UsernameHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Username,
+			&i.ReleasedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Username,
+			&i.Avatar,
+			&i.CreatedAt,
+			&i.AvatarKeys,
+			&i.DisplayName,
+			&i.Bio,
+			&i.StatusMessage,
+			&i.Timezone,
+			&i.Locale,
+			&i.EmailVisibility,
+			&i.FriendRequestPolicy,
+			&i.Searchable,
+			&i.UsernameChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventPublished = `-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events SET published_at = now() WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markOutboxEventPublished, id)
+	return err
+}
+
+const rejectFriendRequest = `-- name: RejectFriendRequest :one
+UPDATE friendships
+SET status = 'rejected', responded_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+`
+
+func (q *Queries) RejectFriendRequest(ctx context.Context, id uuid.UUID) (Friendship, error) {
+	row := q.db.QueryRow(ctx, rejectFriendRequest, id)
+	var i Friendship
+	err := row.Scan(
+		&i.ID,
+		&i.RequesterID,
+		&i.AddresseeID,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const releaseDataExport = `-- name: ReleaseDataExport :exec
+UPDATE data_exports
+SET status = 'pending', claimed_at = NULL
+WHERE id = $1 AND status = 'assembling' AND claimed_at = $2
+`
+
+type ReleaseDataExportParams struct {
+	ID        uuid.UUID          `json:"id"`
+	ClaimedAt pgtype.Timestamptz `json:"claimed_at"`
+}
+
+// Puts back an export whose assembly failed, to be retried.
+func (q *Queries) ReleaseDataExport(ctx context.Context, arg ReleaseDataExportParams) error {
+	_, err := q.db.Exec(ctx, releaseDataExport, arg.ID, arg.ClaimedAt)
+	return err
+}
+
+const removeFriend = `-- name: RemoveFriend :one
+DELETE FROM friendships
+WHERE status = 'accepted'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+`
+
+type RemoveFriendParams struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+func (q *Queries) RemoveFriend(ctx context.Context, arg RemoveFriendParams) (Friendship, error) {
+	row := q.db.QueryRow(ctx, removeFriend, arg.RequesterID, arg.AddresseeID)
+	var i Friendship
+	err := row.Scan(
+		&i.ID,
+		&i.RequesterID,
+		&i.AddresseeID,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const saveDataExportSection = `-- name: SaveDataExportSection :execrows
+INSERT INTO data_export_sections (export_id, service, part, parts, files, error)
+SELECT e.id, $1, $2, $3, $4, $5
+FROM data_exports e
+WHERE e.id = $6 AND e.user_id = $7 AND e.status = 'pending'
+ON CONFLICT (export_id, service, part) DO UPDATE
+SET parts = EXCLUDED.parts, files = EXCLUDED.files, error = EXCLUDED.error, received_at = now()
+`
+
+type SaveDataExportSectionParams struct {
+	Service  string      `json:"service"`
+	Part     int32       `json:"part"`
+	Parts    int32       `json:"parts"`
+	Files    []byte      `json:"files"`
+	Error    pgtype.Text `json:"error"`
+	ExportID uuid.UUID   `json:"export_id"`
+	UserID   uuid.UUID   `json:"user_id"`
+}
+
+// Stores a part of a section of a pending export of user_id; a redelivered
+// part replaces the previous copy. Parts for exports no longer pending or of
+// another user are ignored.
+func (q *Queries) SaveDataExportSection(ctx context.Context, arg SaveDataExportSectionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, saveDataExportSection,
+		arg.Service,
+		arg.Part,
+		arg.Parts,
+		arg.Files,
+		arg.Error,
+		arg.ExportID,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const searchUsers = `-- name: SearchUsers :many
+WITH friends AS (
+  SELECT CASE WHEN f.requester_id = $4::uuid THEN f.addressee_id ELSE f.requester_id END AS id
+  FROM friendships f
+  WHERE f.status = 'accepted'
+    AND (f.requester_id = $4::uuid OR f.addressee_id = $4::uuid)
+), friends_of_friends AS (
+  SELECT CASE WHEN f.requester_id = fr.id THEN f.addressee_id ELSE f.requester_id END AS id
+  FROM friendships f
+  JOIN friends fr ON f.requester_id = fr.id OR f.addressee_id = fr.id
+  WHERE f.status = 'accepted'
+), candidates AS (
+  SELECT
+    u.id,
+    u.id IN (SELECT id FROM friends) AS is_friend,
+    u.id IN (SELECT id FROM friends_of_friends) AS is_friend_of_friend,
+    (
+      u.email_visibility = 'everyone'
+      OR (u.email_visibility = 'friends' AND u.id IN (SELECT id FROM friends))
+    ) AS email_visible
+  FROM users u
+  WHERE u.id <> $4::uuid
+    AND (
+      u.username % $5::text
+      OR u.display_name % $5::text
+      OR u.username ILIKE $6::text
+      OR u.display_name ILIKE $6::text
+      OR lower(u.email) = lower($5::text)
+    )
+    AND NOT EXISTS (
+      SELECT 1 FROM friendships b
+      WHERE b.status = 'blocked'
+        AND (
+          (b.requester_id = u.id AND b.addressee_id = $4::uuid)
+          OR (b.requester_id = $4::uuid AND b.addressee_id = u.id)
+        )
+    )
+), ranked AS (
+  SELECT
+    c.id,
+    c.is_friend,
+    (
+      GREATEST(
+        similarity(u.username, $5::text),
+        similarity(u.display_name, $5::text),
+        CASE WHEN c.email_visible AND lower(u.email) = lower($5::text) THEN 1 ELSE 0 END
+      )
+      + CASE
+          WHEN u.username ILIKE $6::text THEN 0.5
+          WHEN u.display_name ILIKE $6::text THEN 0.4
+          ELSE 0
+        END
+      + CASE
+          WHEN c.is_friend THEN 0.4
+          WHEN c.is_friend_of_friend THEN 0.2
+          ELSE 0
+        END
+    )::float8 AS score
+  FROM candidates c
+  JOIN users u ON u.id = c.id
+  WHERE (u.searchable OR c.is_friend)
+    AND (
+      u.username % $5::text
+      OR u.display_name % $5::text
+      OR u.username ILIKE $6::text
+      OR u.display_name ILIKE $6::text
+      OR (c.email_visible AND lower(u.email) = lower($5::text))
+    )
+)
+SELECT u.id, u.email, u.username, u.avatar, u.created_at, u.avatar_keys, u.display_name, u.bio, u.status_message, u.timezone, u.locale, u.email_visibility, u.friend_request_policy, u.searchable, u.username_changed_at, r.score, r.is_friend::bool AS is_friend
+FROM ranked r
+JOIN users u ON u.id = r.id
+WHERE $1::float8 IS NULL
+   OR (r.score, r.id) < ($1::float8, $2::uuid)
+ORDER BY r.score DESC, r.id DESC
+LIMIT $3::int
+`
+
+type SearchUsersParams struct {
+	CursorScore   pgtype.Float8 `json:"cursor_score"`
+	CursorID      uuid.NullUUID `json:"cursor_id"`
+	PageSize      int32         `json:"page_size"`
+	CallerID      uuid.UUID     `json:"caller_id"`
+	Keyword       string        `json:"keyword"`
+	PrefixPattern string        `json:"prefix_pattern"`
+}
+
+type SearchUsersRow struct {
+	User     User    `json:"user"`
+	Score    float64 `json:"score"`
+	IsFriend bool    `json:"is_friend"`
+}
+
+// Ranks users by trigram similarity of their username or display name to the
+// keyword, boosted for prefix matches, friends and friends of friends. Emails
+// are never fuzzy-matched: a keyword equal to an email the caller may see finds
+// its user, ranked as an exact match. Users blocked by or blocking
+// the caller are never returned, nor unsearchable users who are not friends.
+// Paged by (score, id) keyset.
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
+		arg.CursorScore,
+		arg.CursorID,
+		arg.PageSize,
+		arg.CallerID,
+		arg.Keyword,
+		arg.PrefixPattern,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersRow
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Email,
+			&i.User.Username,
+			&i.User.Avatar,
+			&i.User.CreatedAt,
+			&i.User.AvatarKeys,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.StatusMessage,
+			&i.User.Timezone,
+			&i.User.Locale,
+			&i.User.EmailVisibility,
+			&i.User.FriendRequestPolicy,
+			&i.User.Searchable,
+			&i.User.UsernameChangedAt,
+			&i.Score,
+			&i.IsFriend,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sendFriendRequest = `-- name: SendFriendRequest :one
+INSERT INTO friendships (id, requester_id, addressee_id, status)
+VALUES ($1, $2, $3, 'pending')
+RETURNING id, requester_id, addressee_id, status, created_at, responded_at
+`
+
+type SendFriendRequestParams struct {
+	ID          uuid.UUID `json:"id"`
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+func (q *Queries) SendFriendRequest(ctx context.Context, arg SendFriendRequestParams) (Friendship, error) {
+	row := q.db.QueryRow(ctx, sendFriendRequest, arg.ID, arg.RequesterID, arg.AddresseeID)
+	var i Friendship
+	err := row.Scan(
+		&i.ID,
+		&i.RequesterID,
+		&i.AddresseeID,
+		&i.Status,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const setAvatarKeys = `-- name: SetAvatarKeys :one
+UPDATE users SET avatar_keys = $2, avatar = NULL
+WHERE id = $1
+RETURNING id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at
+`
+
+type SetAvatarKeysParams struct {
+	ID         uuid.UUID `json:"id"`
+	AvatarKeys []byte    `json:"avatar_keys"`
+}
+
+// Replaces the avatar with uploaded variants; any external avatar URL is dropped.
+func (q *Queries) SetAvatarKeys(ctx context.Context, arg SetAvatarKeysParams) (User, error) {
+	row := q.db.QueryRow(ctx, setAvatarKeys, arg.ID, arg.AvatarKeys)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Avatar,
+		&i.CreatedAt,
+		&i.AvatarKeys,
+		&i.DisplayName,
+		&i.Bio,
+		&i.StatusMessage,
+		&i.Timezone,
+		&i.Locale,
+		&i.EmailVisibility,
+		&i.FriendRequestPolicy,
+		&i.Searchable,
+		&i.UsernameChangedAt,
+	)
+	return i, err
+}
+
+const suggestFriends = `-- name: SuggestFriends :many
+WITH friends AS (
+  SELECT f.id FROM (
+    SELECT addressee_id AS id FROM friendships WHERE requester_id = $1::uuid AND status = 'accepted'
+    UNION ALL
+    SELECT requester_id FROM friendships WHERE addressee_id = $1::uuid AND status = 'accepted'
+  ) f
+  ORDER BY random()
+  LIMIT $3::int
+), candidates AS (
+  SELECT c.id
+  FROM friends fr
+  CROSS JOIN LATERAL (
+    SELECT CASE WHEN f.requester_id = fr.id THEN f.addressee_id ELSE f.requester_id END AS id
+    FROM friendships f
+    WHERE (f.requester_id = fr.id OR f.addressee_id = fr.id) AND f.status = 'accepted'
+    LIMIT $4::int
+  ) c
+), ranked AS (
+  SELECT id, COUNT(*) AS mutual_friends
+  FROM candidates
+  WHERE id <> $1::uuid
+  GROUP BY id
+)
+SELECT u.id, u.email, u.username, u.avatar, u.created_at, u.avatar_keys, u.display_name, u.bio, u.status_message, u.timezone, u.locale, u.email_visibility, u.friend_request_policy, u.searchable, u.username_changed_at, r.mutual_friends
+FROM ranked r
+JOIN users u ON u.id = r.id
+WHERE u.searchable
+  AND u.friend_request_policy <> 'nobody'
+  AND NOT EXISTS (
+    SELECT 1 FROM friendships x
+    WHERE (x.requester_id = $1::uuid AND x.addressee_id = r.id)
+       OR (x.requester_id = r.id AND x.addressee_id = $1::uuid)
+  )
+ORDER BY r.mutual_friends DESC, u.id
+LIMIT $2::int
+`
+
+type SuggestFriendsParams struct {
+	UserID       uuid.UUID `json:"user_id"`
+	PageSize     int32     `json:"page_size"`
+	FriendSample int32     `json:"friend_sample"`
+	FanOut       int32     `json:"fan_out"`
+}
+
+type SuggestFriendsRow struct {
+	User          User  `json:"user"`
+	MutualFriends int64 `json:"mutual_friends"`
+}
+
+// Friends of user_id's friends ranked by how many friends they share with
+// user_id. Anyone user_id already has a friendship, request or block with, in
+// either direction, is left out, and so is anyone who is not searchable or
+// takes no friend requests. To bound the work for well-connected users,
+// only a random sample of friend_sample friends is looked at, each
+// contributing at most fan_out of their own friends, so mutual_friends is a
+// lower bound.
+func (q *Queries) SuggestFriends(ctx context.Context, arg SuggestFriendsParams) ([]SuggestFriendsRow, error) {
+	rows, err := q.db.Query(ctx, suggestFriends,
+		arg.UserID,
+		arg.PageSize,
+		arg.FriendSample,
+		arg.FanOut,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SuggestFriendsRow
+	for rows.Next() {
+		var i SuggestFriendsRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.Email,
+			&i.User.Username,
+			&i.User.Avatar,
+			&i.User.CreatedAt,
+			&i.User.AvatarKeys,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.StatusMessage,
+			&i.User.Timezone,
+			&i.User.Locale,
+			&i.User.EmailVisibility,
+			&i.User.FriendRequestPolicy,
+			&i.User.Searchable,
+			&i.User.UsernameChangedAt,
+			&i.MutualFriends,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tryLockOutbox = `-- name: TryLockOutbox :one
+SELECT pg_try_advisory_lock(122550254464888)
+`
+
+// Session lock held by the one relay publishing at a time, so events go out
+// in order without a transaction staying open across the publishes. The key
+// spells "outbox".
+func (q *Queries) TryLockOutbox(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, tryLockOutbox)
+	var pg_try_advisory_lock bool
+	err := row.Scan(&pg_try_advisory_lock)
+	return pg_try_advisory_lock, err
+}
+
+const unblockUser = `-- name: UnblockUser :execrows
+DELETE FROM friendships
+WHERE requester_id = $1 AND addressee_id = $2 AND status = 'blocked'
+`
+
+type UnblockUserParams struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	AddresseeID uuid.UUID `json:"addressee_id"`
+}
+
+func (q *Queries) UnblockUser(ctx context.Context, arg UnblockUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, unblockUser, arg.RequesterID, arg.AddresseeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const unlockOutbox = `-- name: UnlockOutbox :one
+SELECT pg_advisory_unlock(122550254464888)
+`
+
+func (q *Queries) UnlockOutbox(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, unlockOutbox)
+	var pg_advisory_unlock bool
+	err := row.Scan(&pg_advisory_unlock)
+	return pg_advisory_unlock, err
+}
+
+const updateProfile = `-- name: UpdateProfile :one
+UPDATE users
+SET display_name = COALESCE($1, display_name),
+    bio = COALESCE($2, bio),
+    status_message = COALESCE($3, status_message),
+    timezone = COALESCE($4, timezone),
+    locale = COALESCE($5, locale),
+    email_visibility = COALESCE($6, email_visibility),
+    friend_request_policy = COALESCE($7, friend_request_policy),
+    searchable = COALESCE($8, searchable)
+WHERE id = $9
+RETURNING id, email, username, avatar, created_at, avatar_keys, display_name, bio, status_message, timezone, locale, email_visibility, friend_request_policy, searchable, username_changed_at
+`
+
+type UpdateProfileParams struct {
+	DisplayName         pgtype.Text `json:"display_name"`
+	Bio                 pgtype.Text `json:"bio"`
+	StatusMessage       pgtype.Text `json:"status_message"`
+	Timezone            pgtype.Text `json:"timezone"`
+	Locale              pgtype.Text `json:"locale"`
+	EmailVisibility     pgtype.Text `json:"email_visibility"`
+	FriendRequestPolicy pgtype.Text `json:"friend_request_policy"`
+	Searchable          pgtype.Bool `json:"searchable"`
+	ID                  uuid.UUID   `json:"id"`
+}
+
+// Sets the fields that are not null.
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateProfile,
+		arg.DisplayName,
+		arg.Bio,
+		arg.StatusMessage,
+		arg.Timezone,
+		arg.Locale,
+		arg.EmailVisibility,
+		arg.FriendRequestPolicy,
+		arg.Searchable,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Avatar,
+		&i.CreatedAt,
+		&i.AvatarKeys,
+		&i.DisplayName,
+		&i.Bio,
+		&i.StatusMessage,
+		&i.Timezone,
+		&i.Locale,
+		&i.EmailVisibility,
+		&i.FriendRequestPolicy,
+		&i.Searchable,
+		&i.UsernameChangedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS friendships;
+DROP TABLE IF EXISTS users;
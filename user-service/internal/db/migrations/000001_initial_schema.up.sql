@@ -1,3 +1,8 @@
+-- Schema of the original schema.sql. Databases created from that file, or from
+-- any later version of it, already have these tables: record that with
+-- "migrate force 1", then run "migrate up". The later migrations only add what
+-- is missing, so they are safe to apply to any of those databases.
+
 CREATE TABLE users (
     id UUID PRIMARY KEY,
     email TEXT UNIQUE NOT NULL,
@@ -13,4 +18,4 @@ CREATE TABLE friendships (
     status TEXT NOT NULL CHECK (status IN ('pending', 'accepted', 'rejected', 'blocked')),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
     UNIQUE (requester_id, addressee_id)
-);
\ No newline at end of file
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS friendships_addressee_id_idx;
+DROP INDEX IF EXISTS users_email_trgm_idx;
+DROP INDEX IF EXISTS users_username_trgm_idx;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS friendships_pair_idx;
+
+ALTER TABLE friendships DROP COLUMN IF EXISTS responded_at;
@@ -0,0 +1,20 @@
+ALTER TABLE friendships ADD COLUMN IF NOT EXISTS responded_at TIMESTAMP WITH TIME ZONE;
+
+-- Requests used to be allowed in both directions between the same two users.
+-- Keep one row per pair, preferring a friendship over a request and then the
+-- oldest, so that the index below can be built.
+DELETE FROM friendships f
+USING friendships g
+WHERE f.status <> 'blocked'
+  AND g.status <> 'blocked'
+  AND f.id <> g.id
+  AND LEAST(f.requester_id, f.addressee_id) = LEAST(g.requester_id, g.addressee_id)
+  AND GREATEST(f.requester_id, f.addressee_id) = GREATEST(g.requester_id, g.addressee_id)
+  AND (g.status = 'accepted', f.created_at, f.id) > (f.status = 'accepted', g.created_at, g.id);
+
+-- At most one request or friendship per pair of users, whatever its direction.
+-- Blocks are per direction so both users may block each other.
+CREATE UNIQUE INDEX IF NOT EXISTS friendships_pair_idx ON friendships (
+    LEAST(requester_id, addressee_id),
+    GREATEST(requester_id, addressee_id)
+) WHERE status <> 'blocked';
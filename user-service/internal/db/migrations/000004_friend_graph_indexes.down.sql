@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS friendships_accepted_addressee_idx;
+DROP INDEX IF EXISTS friendships_accepted_requester_idx;
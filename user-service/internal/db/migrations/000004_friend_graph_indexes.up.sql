@@ -0,0 +1,3 @@
+-- Friend graph lookups from either side of an accepted friendship.
+CREATE INDEX IF NOT EXISTS friendships_accepted_requester_idx ON friendships (requester_id, addressee_id) WHERE status = 'accepted';
+CREATE INDEX IF NOT EXISTS friendships_accepted_addressee_idx ON friendships (addressee_id, requester_id) WHERE status = 'accepted';
@@ -0,0 +1,2 @@
+-- Blob keys of the uploaded avatar variants, by variant name.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_keys JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS users_display_name_trgm_idx;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS searchable,
+    DROP COLUMN IF EXISTS friend_request_policy,
+    DROP COLUMN IF EXISTS email_visibility,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone,
+    DROP COLUMN IF EXISTS status_message,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS display_name;
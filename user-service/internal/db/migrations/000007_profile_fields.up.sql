@@ -0,0 +1,12 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS status_message TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'en',
+    -- Privacy settings.
+    ADD COLUMN IF NOT EXISTS email_visibility TEXT NOT NULL DEFAULT 'friends' CHECK (email_visibility IN ('everyone', 'friends', 'nobody')),
+    ADD COLUMN IF NOT EXISTS friend_request_policy TEXT NOT NULL DEFAULT 'everyone' CHECK (friend_request_policy IN ('everyone', 'friends_of_friends', 'nobody')),
+    ADD COLUMN IF NOT EXISTS searchable BOOLEAN NOT NULL DEFAULT true;
+
+CREATE INDEX IF NOT EXISTS users_display_name_trgm_idx ON users USING gin (display_name gin_trgm_ops);
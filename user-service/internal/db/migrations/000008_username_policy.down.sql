@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS username_history;
+
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+DROP INDEX IF EXISTS users_username_lower_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS username_changed_at;
@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMP WITH TIME ZONE;
+
+-- Usernames become unique regardless of case. Existing usernames differing
+-- only in case make this fail and must be renamed first.
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(username));
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+
+-- Usernames given up by their owners. They stay held for everyone else until
+-- the quarantine period after released_at has passed.
+CREATE TABLE IF NOT EXISTS username_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    released_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS username_history_username_idx ON username_history (lower(username), released_at);
+CREATE INDEX IF NOT EXISTS username_history_user_id_idx ON username_history (user_id, released_at);
@@ -0,0 +1,28 @@
+-- Personal data exports. Other services contribute their part through
+-- data_export_sections; the archive is assembled once all have arrived.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'ready', 'failed', 'expired')),
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    -- When the archive is deleted, for ready exports.
+    expires_at TIMESTAMP WITH TIME ZONE,
+    blob_key TEXT,
+    size_bytes BIGINT,
+    error TEXT
+);
+
+-- At most one export in progress per user.
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS data_exports_expires_at_idx ON data_exports (expires_at) WHERE status = 'ready';
+
+CREATE TABLE IF NOT EXISTS data_export_sections (
+    export_id UUID NOT NULL REFERENCES data_exports(id) ON DELETE CASCADE,
+    service TEXT NOT NULL,
+    -- JSON documents by file name.
+    files JSONB NOT NULL DEFAULT '{}',
+    error TEXT,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (export_id, service)
+);
@@ -0,0 +1,14 @@
+ALTER TABLE data_export_sections DROP CONSTRAINT IF EXISTS data_export_sections_pkey;
+DELETE FROM data_export_sections WHERE part > 0;
+ALTER TABLE data_export_sections ADD PRIMARY KEY (export_id, service);
+ALTER TABLE data_export_sections DROP CONSTRAINT IF EXISTS data_export_sections_part_check;
+ALTER TABLE data_export_sections DROP COLUMN IF EXISTS parts;
+ALTER TABLE data_export_sections DROP COLUMN IF EXISTS part;
+
+UPDATE data_exports SET status = 'pending' WHERE status = 'assembling';
+DROP INDEX IF EXISTS data_exports_pending_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (user_id) WHERE status = 'pending';
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_status_check;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_status_check
+    CHECK (status IN ('pending', 'ready', 'failed', 'expired'));
+ALTER TABLE data_exports DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,21 @@
+-- Exports are claimed for assembly with status 'assembling' and assembled
+-- outside any transaction. A claim older than the assembly lease is taken over
+-- by another assembler.
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE data_exports DROP CONSTRAINT IF EXISTS data_exports_status_check;
+ALTER TABLE data_exports ADD CONSTRAINT data_exports_status_check
+    CHECK (status IN ('pending', 'assembling', 'ready', 'failed', 'expired'));
+
+-- An export being assembled is still in progress.
+DROP INDEX IF EXISTS data_exports_pending_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (user_id) WHERE status IN ('pending', 'assembling');
+
+-- Sections arrive in parts, each with some of the files, so that no message
+-- has to carry a whole section. A section is complete once all its parts have
+-- arrived.
+ALTER TABLE data_export_sections ADD COLUMN IF NOT EXISTS part INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE data_export_sections ADD COLUMN IF NOT EXISTS parts INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE data_export_sections DROP CONSTRAINT IF EXISTS data_export_sections_part_check;
+ALTER TABLE data_export_sections ADD CONSTRAINT data_export_sections_part_check CHECK (part >= 0 AND part < parts);
+ALTER TABLE data_export_sections DROP CONSTRAINT IF EXISTS data_export_sections_pkey;
+ALTER TABLE data_export_sections ADD PRIMARY KEY (export_id, service, part);
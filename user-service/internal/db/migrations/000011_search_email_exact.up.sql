@@ -0,0 +1,3 @@
+-- Search no longer fuzzy-matches emails, only looks them up exactly.
+DROP INDEX IF EXISTS users_email_trgm_idx;
+CREATE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
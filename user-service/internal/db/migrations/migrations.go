@@ -0,0 +1,8 @@
+// Package migrations embeds the versioned SQL migrations of the service,
+// applied with the shared migrate package.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -6,6 +6,9 @@ RETURNING *;
 -- name: GetUserByID :one
 SELECT * FROM users WHERE id = $1;
 
+-- name: GetUsersByIDs :many
+SELECT * FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
@@ -17,15 +20,63 @@ INSERT INTO friendships (id, requester_id, addressee_id, status)
 VALUES ($1, $2, $3, 'pending')
 RETURNING *;
 
--- name: AcceptFriendRequest :exec
+-- name: GetFriendRequestByID :one
+SELECT * FROM friendships WHERE id = $1 AND status <> 'blocked';
+
+-- name: GetFriendshipBetween :one
+-- Returns the request or friendship between two users in either direction.
+SELECT * FROM friendships
+WHERE status <> 'blocked'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1));
+
+-- name: AcceptFriendRequest :one
 UPDATE friendships
-SET status = 'accepted'
-WHERE requester_id = $1 AND addressee_id = $2;
+SET status = 'accepted', responded_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
 
--- name: RejectFriendRequest :exec
+-- name: RejectFriendRequest :one
 UPDATE friendships
-SET status = 'rejected'
-WHERE requester_id = $1 AND addressee_id = $2;
+SET status = 'rejected', responded_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: CancelFriendRequest :execrows
+DELETE FROM friendships
+WHERE id = $1 AND requester_id = $2 AND status = 'pending';
+
+-- name: RemoveFriend :one
+DELETE FROM friendships
+WHERE status = 'accepted'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+RETURNING *;
+
+-- name: DeleteFriendship :exec
+DELETE FROM friendships WHERE id = $1;
+
+-- name: ListIncomingFriendRequests :many
+-- Pending requests addressed to user_id, newest first, paged by (created_at, id) keyset.
+SELECT * FROM friendships
+WHERE addressee_id = sqlc.arg(user_id)::uuid
+  AND status = 'pending'
+  AND (
+    sqlc.narg(cursor_created_at)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: ListOutgoingFriendRequests :many
+-- Pending requests sent by user_id, newest first, paged by (created_at, id) keyset.
+SELECT * FROM friendships
+WHERE requester_id = sqlc.arg(user_id)::uuid
+  AND status = 'pending'
+  AND (
+    sqlc.narg(cursor_created_at)::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)::int;
 
 -- name: GetFriends :many
 SELECT u.*
@@ -33,4 +84,449 @@ FROM users u
 JOIN friendships f ON (f.addressee_id = u.id OR f.requester_id = u.id)
 WHERE (f.requester_id = $1 OR f.addressee_id = $1)
   AND f.status = 'accepted'
-  AND u.id != $1;
\ No newline at end of file
+  AND u.id != $1;
+
+-- name: DeleteFriendshipsByUser :many
+DELETE FROM friendships
+WHERE requester_id = $1 OR addressee_id = $1
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: SetAvatarKeys :one
+-- Replaces the avatar with uploaded variants; any external avatar URL is dropped.
+UPDATE users SET avatar_keys = $2, avatar = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: IsUsernameTaken :one
+-- Reports whether username, ignoring case, belongs to someone other than
+-- user_id or was released by someone else after held_since.
+SELECT EXISTS (
+  SELECT 1 FROM users u
+  WHERE lower(u.username) = lower(sqlc.arg(username)::text)
+    AND u.id <> sqlc.arg(user_id)::uuid
+  UNION ALL
+  SELECT 1 FROM username_history h
+  WHERE lower(h.username) = lower(sqlc.arg(username)::text)
+    AND h.released_at > sqlc.arg(held_since)::timestamptz
+    AND h.user_id <> sqlc.arg(user_id)::uuid
+);
+
+-- name: ChangeUsername :one
+-- Renames the user unless they already did after changed_before. Returns their
+-- previous username.
+UPDATE users u
+SET username = sqlc.arg(username)::text, username_changed_at = now()
+FROM users old
+WHERE u.id = old.id
+  AND u.id = sqlc.arg(user_id)::uuid
+  AND (u.username_changed_at IS NULL OR u.username_changed_at < sqlc.arg(changed_before)::timestamptz)
+RETURNING old.username;
+
+-- name: InsertUsernameHistory :exec
+INSERT INTO username_history (user_id, username) VALUES ($1, $2);
+
+-- name: UpdateProfile :one
+-- Sets the fields that are not null.
+UPDATE users
+SET display_name = COALESCE(sqlc.narg(display_name), display_name),
+    bio = COALESCE(sqlc.narg(bio), bio),
+    status_message = COALESCE(sqlc.narg(status_message), status_message),
+    timezone = COALESCE(sqlc.narg(timezone), timezone),
+    locale = COALESCE(sqlc.narg(locale), locale),
+    email_visibility = COALESCE(sqlc.narg(email_visibility), email_visibility),
+    friend_request_policy = COALESCE(sqlc.narg(friend_request_policy), friend_request_policy),
+    searchable = COALESCE(sqlc.narg(searchable), searchable)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SearchUsers :many
+-- Ranks users by trigram similarity of their username or display name to the
+-- keyword, boosted for prefix matches, friends and friends of friends. Emails
+-- are never fuzzy-matched: a keyword equal to an email the caller may see finds
+-- its user, ranked as an exact match. Users blocked by or blocking
+-- the caller are never returned, nor unsearchable users who are not friends.
+-- Paged by (score, id) keyset.
+WITH friends AS (
+  SELECT CASE WHEN f.requester_id = sqlc.arg(caller_id)::uuid THEN f.addressee_id ELSE f.requester_id END AS id
+  FROM friendships f
+  WHERE f.status = 'accepted'
+    AND (f.requester_id = sqlc.arg(caller_id)::uuid OR f.addressee_id = sqlc.arg(caller_id)::uuid)
+), friends_of_friends AS (
+  SELECT CASE WHEN f.requester_id = fr.id THEN f.addressee_id ELSE f.requester_id END AS id
+  FROM friendships f
+  JOIN friends fr ON f.requester_id = fr.id OR f.addressee_id = fr.id
+  WHERE f.status = 'accepted'
+), candidates AS (
+  SELECT
+    u.id,
+    u.id IN (SELECT id FROM friends) AS is_friend,
+    u.id IN (SELECT id FROM friends_of_friends) AS is_friend_of_friend,
+    (
+      u.email_visibility = 'everyone'
+      OR (u.email_visibility = 'friends' AND u.id IN (SELECT id FROM friends))
+    ) AS email_visible
+  FROM users u
+  WHERE u.id <> sqlc.arg(caller_id)::uuid
+    AND (
+      u.username % sqlc.arg(keyword)::text
+      OR u.display_name % sqlc.arg(keyword)::text
+      OR u.username ILIKE sqlc.arg(prefix_pattern)::text
+      OR u.display_name ILIKE sqlc.arg(prefix_pattern)::text
+      OR lower(u.email) = lower(sqlc.arg(keyword)::text)
+    )
+    AND NOT EXISTS (
+      SELECT 1 FROM friendships b
+      WHERE b.status = 'blocked'
+        AND (
+          (b.requester_id = u.id AND b.addressee_id = sqlc.arg(caller_id)::uuid)
+          OR (b.requester_id = sqlc.arg(caller_id)::uuid AND b.addressee_id = u.id)
+        )
+    )
+), ranked AS (
+  SELECT
+    c.id,
+    c.is_friend,
+    (
+      GREATEST(
+        similarity(u.username, sqlc.arg(keyword)::text),
+        similarity(u.display_name, sqlc.arg(keyword)::text),
+        CASE WHEN c.email_visible AND lower(u.email) = lower(sqlc.arg(keyword)::text) THEN 1 ELSE 0 END
+      )
+      + CASE
+          WHEN u.username ILIKE sqlc.arg(prefix_pattern)::text THEN 0.5
+          WHEN u.display_name ILIKE sqlc.arg(prefix_pattern)::text THEN 0.4
+          ELSE 0
+        END
+      + CASE
+          WHEN c.is_friend THEN 0.4
+          WHEN c.is_friend_of_friend THEN 0.2
+          ELSE 0
+        END
+    )::float8 AS score
+  FROM candidates c
+  JOIN users u ON u.id = c.id
+  WHERE (u.searchable OR c.is_friend)
+    AND (
+      u.username % sqlc.arg(keyword)::text
+      OR u.display_name % sqlc.arg(keyword)::text
+      OR u.username ILIKE sqlc.arg(prefix_pattern)::text
+      OR u.display_name ILIKE sqlc.arg(prefix_pattern)::text
+      OR (c.email_visible AND lower(u.email) = lower(sqlc.arg(keyword)::text))
+    )
+)
+SELECT sqlc.embed(u), r.score, r.is_friend::bool AS is_friend
+FROM ranked r
+JOIN users u ON u.id = r.id
+WHERE sqlc.narg(cursor_score)::float8 IS NULL
+   OR (r.score, r.id) < (sqlc.narg(cursor_score)::float8, sqlc.narg(cursor_id)::uuid)
+ORDER BY r.score DESC, r.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: DeleteFriendshipBetween :many
+-- Removes any friendship or pending request between two users, leaving blocks alone.
+DELETE FROM friendships
+WHERE status <> 'blocked'
+  AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+RETURNING *;
+
+-- name: BlockUser :execrows
+-- Blocks are friendships with status 'blocked', the requester being the blocker.
+INSERT INTO friendships (id, requester_id, addressee_id, status)
+VALUES ($1, $2, $3, 'blocked')
+ON CONFLICT (requester_id, addressee_id) DO NOTHING;
+
+-- name: UnblockUser :execrows
+DELETE FROM friendships
+WHERE requester_id = $1 AND addressee_id = $2 AND status = 'blocked';
+
+-- name: AreFriends :one
+SELECT EXISTS (
+  SELECT 1 FROM friendships
+  WHERE status = 'accepted'
+    AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+);
+
+-- name: HaveMutualFriend :one
+SELECT EXISTS (
+  SELECT 1
+  FROM friendships a
+  JOIN friendships b
+    ON CASE WHEN a.requester_id = sqlc.arg(user_a)::uuid THEN a.addressee_id ELSE a.requester_id END
+     = CASE WHEN b.requester_id = sqlc.arg(user_b)::uuid THEN b.addressee_id ELSE b.requester_id END
+  WHERE a.status = 'accepted'
+    AND (a.requester_id = sqlc.arg(user_a)::uuid OR a.addressee_id = sqlc.arg(user_a)::uuid)
+    AND b.status = 'accepted'
+    AND (b.requester_id = sqlc.arg(user_b)::uuid OR b.addressee_id = sqlc.arg(user_b)::uuid)
+);
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+  SELECT 1 FROM friendships
+  WHERE status = 'blocked'
+    AND ((requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1))
+);
+
+-- name: ListBlockedEitherWayAmong :many
+-- The users among ids that user_id blocked or was blocked by.
+SELECT (CASE WHEN requester_id = sqlc.arg(user_id)::uuid THEN addressee_id ELSE requester_id END)::uuid AS id
+FROM friendships
+WHERE status = 'blocked'
+  AND (
+    (requester_id = sqlc.arg(user_id)::uuid AND addressee_id = ANY(sqlc.arg(ids)::uuid[]))
+    OR (addressee_id = sqlc.arg(user_id)::uuid AND requester_id = ANY(sqlc.arg(ids)::uuid[]))
+  );
+
+-- name: ListBlockedUsers :many
+-- Users blocked by blocker_id, most recently blocked first, paged by
+-- (blocked_at, id) keyset.
+SELECT sqlc.embed(u), f.created_at AS blocked_at
+FROM friendships f
+JOIN users u ON u.id = f.addressee_id
+WHERE f.requester_id = sqlc.arg(blocker_id)::uuid
+  AND f.status = 'blocked'
+  AND (
+    sqlc.narg(cursor_blocked_at)::timestamptz IS NULL
+    OR (f.created_at, u.id) < (sqlc.narg(cursor_blocked_at)::timestamptz, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: GetMutualFriends :many
+-- Friends of both user_a and user_b, excluding users blocked by or blocking
+-- caller_id, by username. Paged by (username, id) keyset.
+WITH friends_a AS (
+  SELECT addressee_id AS id FROM friendships WHERE requester_id = sqlc.arg(user_a)::uuid AND status = 'accepted'
+  UNION ALL
+  SELECT requester_id FROM friendships WHERE addressee_id = sqlc.arg(user_a)::uuid AND status = 'accepted'
+), friends_b AS (
+  SELECT addressee_id AS id FROM friendships WHERE requester_id = sqlc.arg(user_b)::uuid AND status = 'accepted'
+  UNION ALL
+  SELECT requester_id FROM friendships WHERE addressee_id = sqlc.arg(user_b)::uuid AND status = 'accepted'
+), mutual AS (
+  SELECT id FROM friends_a
+  INTERSECT
+  SELECT id FROM friends_b
+)
+SELECT u.*
+FROM mutual m
+JOIN users u ON u.id = m.id
+WHERE NOT EXISTS (
+    SELECT 1 FROM friendships b
+    WHERE b.status = 'blocked'
+      AND (
+        (b.requester_id = u.id AND b.addressee_id = sqlc.arg(caller_id)::uuid)
+        OR (b.requester_id = sqlc.arg(caller_id)::uuid AND b.addressee_id = u.id)
+      )
+  )
+  AND (
+    sqlc.narg(cursor_username)::text IS NULL
+    OR (u.username, u.id) > (sqlc.narg(cursor_username)::text, sqlc.narg(cursor_id)::uuid)
+  )
+ORDER BY u.username, u.id
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: SuggestFriends :many
+-- Friends of user_id's friends ranked by how many friends they share with
+-- user_id. Anyone user_id already has a friendship, request or block with, in
+-- either direction, is left out, and so is anyone who is not searchable or
+-- takes no friend requests. To bound the work for well-connected users,
+-- only a random sample of friend_sample friends is looked at, each
+-- contributing at most fan_out of their own friends, so mutual_friends is a
+-- lower bound.
+WITH friends AS (
+  SELECT f.id FROM (
+    SELECT addressee_id AS id FROM friendships WHERE requester_id = sqlc.arg(user_id)::uuid AND status = 'accepted'
+    UNION ALL
+    SELECT requester_id FROM friendships WHERE addressee_id = sqlc.arg(user_id)::uuid AND status = 'accepted'
+  ) f
+  ORDER BY random()
+  LIMIT sqlc.arg(friend_sample)::int
+), candidates AS (
+  SELECT c.id
+  FROM friends fr
+  CROSS JOIN LATERAL (
+    SELECT CASE WHEN f.requester_id = fr.id THEN f.addressee_id ELSE f.requester_id END AS id
+    FROM friendships f
+    WHERE (f.requester_id = fr.id OR f.addressee_id = fr.id) AND f.status = 'accepted'
+    LIMIT sqlc.arg(fan_out)::int
+  ) c
+), ranked AS (
+  SELECT id, COUNT(*) AS mutual_friends
+  FROM candidates
+  WHERE id <> sqlc.arg(user_id)::uuid
+  GROUP BY id
+)
+SELECT sqlc.embed(u), r.mutual_friends
+FROM ranked r
+JOIN users u ON u.id = r.id
+WHERE u.searchable
+  AND u.friend_request_policy <> 'nobody'
+  AND NOT EXISTS (
+    SELECT 1 FROM friendships x
+    WHERE (x.requester_id = sqlc.arg(user_id)::uuid AND x.addressee_id = r.id)
+       OR (x.requester_id = r.id AND x.addressee_id = sqlc.arg(user_id)::uuid)
+  )
+ORDER BY r.mutual_friends DESC, u.id
+LIMIT sqlc.arg(page_size)::int;
+
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox_events (exchange, routing_key, payload)
+VALUES ($1, $2, $3);
+
+-- name: TryLockOutbox :one
+-- Session lock held by the one relay publishing at a time, so events go out
+-- in order without a transaction staying open across the publishes. The key
+-- spells "outbox".
+SELECT pg_try_advisory_lock(122550254464888);
+
+-- name: UnlockOutbox :one
+SELECT pg_advisory_unlock(122550254464888);
+
+-- name: ListUnpublishedOutboxEvents :many
+SELECT * FROM outbox_events
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events SET published_at = now() WHERE id = $1;
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events WHERE published_at < $1;
+
+-- name: ListUsernameHistory :many
+SELECT * FROM username_history
+WHERE user_id = $1
+ORDER BY released_at DESC, id DESC;
+
+-- name: ExportFriendships :many
+-- Friendships and requests of user_id, blocks aside, with the other user.
+SELECT
+  f.id,
+  f.status,
+  f.created_at,
+  f.responded_at,
+  f.requester_id = sqlc.arg(user_id)::uuid AS sent,
+  o.id AS other_user_id,
+  o.username AS other_username
+FROM friendships f
+JOIN users o
+  ON o.id = CASE WHEN f.requester_id = sqlc.arg(user_id)::uuid THEN f.addressee_id ELSE f.requester_id END
+WHERE (f.requester_id = sqlc.arg(user_id)::uuid OR f.addressee_id = sqlc.arg(user_id)::uuid)
+  AND f.status <> 'blocked'
+ORDER BY f.created_at, f.id;
+
+-- name: ExportBlocks :many
+-- Users blocked by blocker_id. Blocks against them are not theirs to see.
+SELECT u.id, u.username, f.created_at AS blocked_at
+FROM friendships f
+JOIN users u ON u.id = f.addressee_id
+WHERE f.requester_id = sqlc.arg(blocker_id)::uuid AND f.status = 'blocked'
+ORDER BY f.created_at, f.id;
+
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id) VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports WHERE id = $1;
+
+-- name: GetPendingDataExport :one
+-- The export of the user still in progress, if any.
+SELECT * FROM data_exports WHERE user_id = $1 AND status IN ('pending', 'assembling');
+
+-- name: SaveDataExportSection :execrows
+-- Stores a part of a section of a pending export of user_id; a redelivered
+-- part replaces the previous copy. Parts for exports no longer pending or of
+-- another user are ignored.
+INSERT INTO data_export_sections (export_id, service, part, parts, files, error)
+SELECT e.id, sqlc.arg(service), sqlc.arg(part), sqlc.arg(parts), sqlc.arg(files), sqlc.narg(error)
+FROM data_exports e
+WHERE e.id = sqlc.arg(export_id) AND e.user_id = sqlc.arg(user_id) AND e.status = 'pending'
+ON CONFLICT (export_id, service, part) DO UPDATE
+SET parts = EXCLUDED.parts, files = EXCLUDED.files, error = EXCLUDED.error, received_at = now();
+
+-- name: ClaimCompleteDataExport :one
+-- Claims for assembly the oldest pending export that has every part of a
+-- section from every service, or an export whose claim is older than
+-- claimed_before, except the skipped ones. Failed sections are handled by
+-- FailDataExportsWithSectionErrors first.
+UPDATE data_exports
+SET status = 'assembling', claimed_at = now()
+WHERE id = (
+  SELECT e.id FROM data_exports e
+  WHERE NOT e.id = ANY(coalesce(sqlc.arg(skipped)::uuid[], '{}'))
+    AND (
+      (
+        e.status = 'pending'
+        AND (
+          SELECT count(DISTINCT s.service) FROM data_export_sections s
+          WHERE s.export_id = e.id
+            AND s.service = ANY(sqlc.arg(services)::text[])
+            AND s.parts = (
+              SELECT count(*) FROM data_export_sections p
+              WHERE p.export_id = s.export_id AND p.service = s.service
+            )
+        ) = cardinality(sqlc.arg(services)::text[])
+      )
+      OR (e.status = 'assembling' AND e.claimed_at < sqlc.arg(claimed_before))
+    )
+  ORDER BY e.requested_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ListDataExportSections :many
+SELECT * FROM data_export_sections WHERE export_id = $1 ORDER BY service, part;
+
+-- name: CompleteDataExport :execrows
+-- Completes an export claimed at claimed_at. It does nothing if the export was
+-- erased or its claim taken over meanwhile.
+UPDATE data_exports
+SET status = 'ready', completed_at = now(), expires_at = $2, blob_key = $3, size_bytes = $4
+WHERE id = $1 AND status = 'assembling' AND claimed_at = sqlc.arg(claimed_at);
+
+-- name: ReleaseDataExport :exec
+-- Puts back an export whose assembly failed, to be retried.
+UPDATE data_exports
+SET status = 'pending', claimed_at = NULL
+WHERE id = $1 AND status = 'assembling' AND claimed_at = sqlc.arg(claimed_at);
+
+-- name: FailDataExport :exec
+-- Fails an export claimed at claimed_at that cannot be assembled.
+UPDATE data_exports
+SET status = 'failed', completed_at = now(), error = $2
+WHERE id = $1 AND status = 'assembling' AND claimed_at = sqlc.arg(claimed_at);
+
+-- name: FailDataExportsWithSectionErrors :execrows
+-- Fails pending exports for which a service reported an error.
+UPDATE data_exports e
+SET status = 'failed', completed_at = now(), error = s.errors
+FROM (
+  SELECT export_id, string_agg(service || ': ' || error, '; ' ORDER BY service) AS errors
+  FROM data_export_sections
+  WHERE error IS NOT NULL
+  GROUP BY export_id
+) s
+WHERE e.id = s.export_id AND e.status = 'pending';
+
+-- name: FailStaleDataExports :execrows
+UPDATE data_exports
+SET status = 'failed', completed_at = now(), error = 'timed out waiting for all services'
+WHERE status = 'pending' AND requested_at < $1;
+
+-- name: ListExpiredDataExports :many
+SELECT * FROM data_exports
+WHERE status = 'ready' AND expires_at < $1
+ORDER BY expires_at
+LIMIT $2;
+
+-- name: ExpireDataExport :exec
+UPDATE data_exports SET status = 'expired', blob_key = NULL WHERE id = $1;
+
+-- name: ListDataExportBlobKeys :many
+SELECT blob_key::text FROM data_exports WHERE user_id = $1 AND blob_key IS NOT NULL;
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	userpb "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/api/auth/v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	avatarUploadPattern = "/v1/users/{user_id}/avatar"
+	avatarFormField     = "avatar"
+	uploadChunkSize     = 32 << 10
+	// multipartOverhead allows for the part headers and boundaries around the file.
+	multipartOverhead = 64 << 10
+)
+
+// RegisterAvatarUploadHandler serves UploadAvatar on mux as a multipart upload
+// of the "avatar" form field. The file is streamed to the RPC through client,
+// so it goes through the same interceptors as every other call.
+func RegisterAvatarUploadHandler(mux *runtime.ServeMux, client userpb.UserServiceClient, maxBytes int64) error {
+	return mux.HandlePath(http.MethodPost, avatarUploadPattern, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		fail := func(err error) {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+		}
+
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, userpb.UserService_UploadAvatar_FullMethodName, runtime.WithHTTPPathPattern(avatarUploadPattern))
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		// Cancelling aborts the RPC if the upload fails half way.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+		file, err := avatarPart(r)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		stream, err := client.UploadAvatar(ctx)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		msg := &userpb.UploadAvatarRequest{UserId: params["user_id"]}
+		buf := make([]byte, uploadChunkSize)
+		for {
+			n, readErr := file.Read(buf)
+			if n > 0 {
+				msg.Chunk = buf[:n]
+				if err := stream.Send(msg); err != nil {
+					// The server gave up; its status is returned by CloseAndRecv.
+					break
+				}
+				msg = &userpb.UploadAvatarRequest{}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				fail(uploadError(readErr))
+				return
+			}
+		}
+
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			fail(err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, resp)
+	})
+}
+
+// avatarPart returns the avatar file of a multipart request without buffering it.
+func avatarPart(r *http.Request) (io.Reader, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "expected a multipart/form-data body")
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, status.Errorf(codes.InvalidArgument, "missing %q file", avatarFormField)
+		}
+		if err != nil {
+			return nil, uploadError(err)
+		}
+		if part.FormName() == avatarFormField {
+			return part, nil
+		}
+	}
+}
+
+func uploadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return status.Error(codes.InvalidArgument, "avatar is too large")
+	}
+	return status.Error(codes.InvalidArgument, "malformed multipart body")
+}
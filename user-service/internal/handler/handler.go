@@ -0,0 +1,713 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/apperror"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	userpb "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/api/auth/v1"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/avatar"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+	apperrors "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/utils/errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type userHandler struct {
+	service service.Service
+	blobs   storage.BlobStore
+	userpb.UnimplementedUserServiceServer
+}
+
+func NewUserServiceServer(s service.Service, blobs storage.BlobStore) userpb.UserServiceServer {
+	return &userHandler{service: s, blobs: blobs}
+}
+
+// permissionProfilesRead is granted by auth-service to every role, so that
+// accounts can give it to the API keys of other services.
+const permissionProfilesRead = "profiles:read"
+
+// MethodPermissions lists the RPCs that require an authenticated caller.
+// Tokens exchanged for an API key are refused by all of them but
+// BatchGetProfiles, see callerUserID.
+var MethodPermissions = authz.MethodPermissions{
+	userpb.UserService_GetProfile_FullMethodName:                 {},
+	userpb.UserService_BatchGetProfiles_FullMethodName:           {permissionProfilesRead},
+	userpb.UserService_UpdateProfile_FullMethodName:              {},
+	userpb.UserService_ChangeUsername_FullMethodName:             {},
+	userpb.UserService_UploadAvatar_FullMethodName:               {},
+	userpb.UserService_SearchUsers_FullMethodName:                {},
+	userpb.UserService_SendFriendRequest_FullMethodName:          {},
+	userpb.UserService_RespondToFriendRequest_FullMethodName:     {},
+	userpb.UserService_RemoveFriend_FullMethodName:               {},
+	userpb.UserService_CancelFriendRequest_FullMethodName:        {},
+	userpb.UserService_GetFriendRequests_FullMethodName:          {},
+	userpb.UserService_ListIncomingFriendRequests_FullMethodName: {},
+	userpb.UserService_ListOutgoingFriendRequests_FullMethodName: {},
+	userpb.UserService_GetMutualFriends_FullMethodName:           {},
+	userpb.UserService_SuggestFriends_FullMethodName:             {},
+	userpb.UserService_BlockUser_FullMethodName:                  {},
+	userpb.UserService_UnblockUser_FullMethodName:                {},
+	userpb.UserService_ListBlockedUsers_FullMethodName:           {},
+	userpb.UserService_RequestDataExport_FullMethodName:          {},
+	userpb.UserService_GetExportStatus_FullMethodName:            {},
+}
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+func (h *userHandler) GetProfile(ctx context.Context, req *userpb.GetProfileRequest) (*userpb.UserProfile, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("user_id", "must be a valid UUID"))
+	}
+
+	user, rel, err := h.service.GetProfile(ctx, callerID, userID)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return h.userProfile(*user, rel), nil
+}
+
+// BatchGetProfiles renders every profile as a stranger sees it, except the
+// caller's own: relations are not looked up per user.
+func (h *userHandler) BatchGetProfiles(ctx context.Context, req *userpb.BatchGetProfilesRequest) (*userpb.BatchGetProfilesResponse, error) {
+	callerID, err := callerOrKeyOwnerID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	ids := make([]uuid.UUID, len(req.GetUserIds()))
+	for i, raw := range req.GetUserIds() {
+		if ids[i], err = uuid.Parse(raw); err != nil {
+			return nil, apperrors.ToGRPC(apperrors.InvalidField(fmt.Sprintf("user_ids[%d]", i), "must be a valid UUID"))
+		}
+	}
+
+	users, err := h.service.BatchGetProfiles(ctx, callerID, ids)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.BatchGetProfilesResponse{Results: make([]*userpb.ProfileResult, len(ids))}
+	for i, user := range users {
+		result := &userpb.ProfileResult{UserId: ids[i].String()}
+		if user != nil {
+			rel := model.RelationStranger
+			if user.ID == callerID {
+				rel = model.RelationSelf
+			}
+			result.Found = true
+			result.Profile = h.userProfile(*user, rel)
+		}
+		resp.Results[i] = result
+	}
+	return resp, nil
+}
+
+func (h *userHandler) UpdateProfile(ctx context.Context, req *userpb.UpdateProfileRequest) (*userpb.UserProfile, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+
+	input := model.UpdateProfileInput{
+		UserID:        callerID,
+		DisplayName:   req.DisplayName,
+		Bio:           req.Bio,
+		StatusMessage: req.StatusMessage,
+		Timezone:      req.Timezone,
+		Locale:        req.Locale,
+	}
+	if p := req.GetPrivacy(); p != nil {
+		if p.GetEmailVisibility() != "" {
+			input.EmailVisibility = &p.EmailVisibility
+		}
+		if p.GetFriendRequestPolicy() != "" {
+			input.FriendRequestPolicy = &p.FriendRequestPolicy
+		}
+		input.Searchable = p.Searchable
+	}
+
+	user, err := h.service.UpdateProfile(ctx, input)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	logger.FromContext(ctx).Info("profile updated")
+	return h.userProfile(*user, model.RelationSelf), nil
+}
+
+func (h *userHandler) ChangeUsername(ctx context.Context, req *userpb.ChangeUsernameRequest) (*userpb.UserProfile, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+
+	user, err := h.service.ChangeUsername(ctx, callerID, req.GetUsername())
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return h.userProfile(*user, model.RelationSelf), nil
+}
+
+// CheckUsernameAvailable is public; a signed-in caller may reclaim their own
+// current or released usernames.
+func (h *userHandler) CheckUsernameAvailable(ctx context.Context, req *userpb.CheckUsernameAvailableRequest) (*userpb.CheckUsernameAvailableResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		callerID = uuid.Nil
+	}
+
+	err = h.service.CheckUsernameAvailable(ctx, callerID, req.GetUsername())
+	var invalid *apperror.ValidationError
+	switch {
+	case err == nil:
+		return &userpb.CheckUsernameAvailableResponse{Available: true}, nil
+	case errors.As(err, &invalid):
+		return &userpb.CheckUsernameAvailableResponse{Reason: "invalid", Message: invalid.Violations[0].Description}, nil
+	case errors.Is(err, apperrors.ErrUsernameReserved):
+		return &userpb.CheckUsernameAvailableResponse{Reason: "reserved", Message: err.Error()}, nil
+	case errors.Is(err, apperrors.ErrUsernameTaken):
+		return &userpb.CheckUsernameAvailableResponse{Reason: "taken", Message: err.Error()}, nil
+	default:
+		return nil, apperrors.ToGRPC(err)
+	}
+}
+
+func (h *userHandler) UploadAvatar(stream userpb.UserService_UploadAvatarServer) error {
+	ctx := stream.Context()
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return apperrors.ToGRPC(err)
+	}
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return apperrors.ToGRPC(apperrors.InvalidField("chunk", "is required"))
+	}
+	if err != nil {
+		return err
+	}
+	if first.GetUserId() != callerID.String() {
+		return apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+
+	user, err := h.service.UploadAvatar(ctx, callerID, &chunkReader{stream: stream, buf: first.GetChunk()})
+	if err != nil {
+		logger.FromContext(ctx).Warn("avatar upload failed", zap.Error(err))
+		return apperrors.ToGRPC(err)
+	}
+	return stream.SendAndClose(h.userProfile(*user, model.RelationSelf))
+}
+
+// chunkReader reads the chunks of an UploadAvatar stream as one byte stream.
+type chunkReader struct {
+	stream userpb.UserService_UploadAvatarServer
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = msg.GetChunk()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (h *userHandler) SendFriendRequest(ctx context.Context, req *userpb.FriendRequestInput) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetFromUserId() != "" && req.GetFromUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+	toID, err := uuid.Parse(req.GetToUserId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("to_user_id", "must be a valid UUID"))
+	}
+
+	request, err := h.service.SendFriendRequest(ctx, callerID, toID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("send friend request failed", zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	message := "friend request sent"
+	if request.Status == "accepted" {
+		message = "friend request accepted"
+	}
+	return &userpb.FriendActionResponse{Message: message, RequestId: request.ID.String()}, nil
+}
+
+func (h *userHandler) RespondToFriendRequest(ctx context.Context, req *userpb.FriendRespondInput) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	requestID, err := uuid.Parse(req.GetRequestId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("request_id", "must be a valid UUID"))
+	}
+
+	var accept bool
+	switch req.GetAction() {
+	case "accept":
+		accept = true
+	case "reject":
+	default:
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("action", "must be one of: accept reject"))
+	}
+
+	request, err := h.service.RespondToFriendRequest(ctx, callerID, requestID, accept)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return &userpb.FriendActionResponse{Message: "friend request " + request.Status, RequestId: request.ID.String()}, nil
+}
+
+func (h *userHandler) RemoveFriend(ctx context.Context, req *userpb.RemoveFriendRequest) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+	friendID, err := uuid.Parse(req.GetFriendId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("friend_id", "must be a valid UUID"))
+	}
+
+	if err := h.service.RemoveFriend(ctx, callerID, friendID); err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return &userpb.FriendActionResponse{Message: "friend removed"}, nil
+}
+
+func (h *userHandler) CancelFriendRequest(ctx context.Context, req *userpb.CancelFriendRequestInput) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	requestID, err := uuid.Parse(req.GetRequestId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("request_id", "must be a valid UUID"))
+	}
+
+	if err := h.service.CancelFriendRequest(ctx, callerID, requestID); err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return &userpb.FriendActionResponse{Message: "friend request cancelled", RequestId: requestID.String()}, nil
+}
+
+// GetFriendRequests is the deprecated alias of ListIncomingFriendRequests.
+func (h *userHandler) GetFriendRequests(ctx context.Context, req *userpb.GetFriendRequestsRequest) (*userpb.FriendRequestsResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+	return h.ListIncomingFriendRequests(ctx, &userpb.ListFriendRequestsRequest{
+		PageSize:  req.GetPageSize(),
+		PageToken: req.GetPageToken(),
+	})
+}
+
+func (h *userHandler) ListIncomingFriendRequests(ctx context.Context, req *userpb.ListFriendRequestsRequest) (*userpb.FriendRequestsResponse, error) {
+	return h.listFriendRequests(ctx, model.IncomingFriendRequests, req)
+}
+
+func (h *userHandler) ListOutgoingFriendRequests(ctx context.Context, req *userpb.ListFriendRequestsRequest) (*userpb.FriendRequestsResponse, error) {
+	return h.listFriendRequests(ctx, model.OutgoingFriendRequests, req)
+}
+
+func (h *userHandler) listFriendRequests(ctx context.Context, direction model.FriendRequestDirection, req *userpb.ListFriendRequestsRequest) (*userpb.FriendRequestsResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	input := model.ListFriendRequestsInput{
+		UserID:    callerID,
+		Direction: direction,
+		PageSize:  int(req.GetPageSize()),
+	}
+	if input.Cursor, err = decodeTimeCursor(req.GetPageToken()); err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("page_token", "is invalid"))
+	}
+
+	requests, next, err := h.service.ListFriendRequests(ctx, input)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.FriendRequestsResponse{}
+	for _, r := range requests {
+		resp.Requests = append(resp.Requests, &userpb.FriendRequestData{
+			Id:         r.ID.String(),
+			FromUserId: r.RequesterID.String(),
+			ToUserId:   r.AddresseeID.String(),
+			Status:     r.Status,
+			CreatedAt:  r.CreatedAt.Time.Format(time.RFC3339),
+		})
+	}
+	if next != nil {
+		resp.NextPageToken = encodeTimeCursor(next)
+	}
+	return resp, nil
+}
+
+func (h *userHandler) SearchUsers(ctx context.Context, req *userpb.SearchUsersRequest) (*userpb.SearchUsersResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	input := model.SearchUsersInput{
+		CallerID: callerID,
+		Keyword:  req.GetKeyword(),
+		PageSize: int(req.GetPageSize()),
+	}
+	if req.GetPageToken() != "" {
+		key, id, err := decodePageToken(req.GetPageToken())
+		score, parseErr := strconv.ParseFloat(key, 64)
+		if err != nil || parseErr != nil {
+			return nil, apperrors.ToGRPC(apperrors.InvalidField("page_token", "is invalid"))
+		}
+		input.Cursor = &model.SearchCursor{Score: score, ID: id}
+	}
+
+	users, next, err := h.service.SearchUsers(ctx, input)
+	if err != nil {
+		logger.FromContext(ctx).Error("search users failed", zap.Error(err))
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.SearchUsersResponse{}
+	for _, u := range users {
+		rel := model.RelationStranger
+		if u.IsFriend {
+			rel = model.RelationFriend
+		}
+		resp.Results = append(resp.Results, h.userProfile(u.User, rel))
+	}
+	if next != nil {
+		resp.NextPageToken = encodePageToken(strconv.FormatFloat(next.Score, 'g', -1, 64), next.ID)
+	}
+	return resp, nil
+}
+
+func (h *userHandler) GetMutualFriends(ctx context.Context, req *userpb.GetMutualFriendsRequest) (*userpb.MutualFriendsResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	input := model.MutualFriendsInput{
+		CallerID: callerID,
+		PageSize: int(req.GetPageSize()),
+	}
+	if input.UserA, err = uuid.Parse(req.GetUserA()); err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("user_a", "must be a valid UUID"))
+	}
+	if input.UserB, err = uuid.Parse(req.GetUserB()); err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("user_b", "must be a valid UUID"))
+	}
+	if req.GetPageToken() != "" {
+		username, id, err := decodePageToken(req.GetPageToken())
+		if err != nil {
+			return nil, apperrors.ToGRPC(apperrors.InvalidField("page_token", "is invalid"))
+		}
+		input.Cursor = &model.NameCursor{Username: username, ID: id}
+	}
+
+	users, next, err := h.service.GetMutualFriends(ctx, input)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.MutualFriendsResponse{}
+	for _, u := range users {
+		// Mutual friends are friends of the caller too.
+		resp.Friends = append(resp.Friends, h.userProfile(u, model.RelationFriend))
+	}
+	if next != nil {
+		resp.NextPageToken = encodePageToken(next.Username, next.ID)
+	}
+	return resp, nil
+}
+
+func (h *userHandler) SuggestFriends(ctx context.Context, req *userpb.SuggestFriendsRequest) (*userpb.SuggestFriendsResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	if req.GetUserId() != callerID.String() {
+		return nil, apperrors.ToGRPC(apperrors.ErrPermissionDenied)
+	}
+
+	suggestions, err := h.service.SuggestFriends(ctx, callerID, int(req.GetLimit()))
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.SuggestFriendsResponse{}
+	for _, s := range suggestions {
+		resp.Suggestions = append(resp.Suggestions, &userpb.FriendSuggestion{
+			User:          h.userProfile(s.User, model.RelationStranger),
+			MutualFriends: int32(s.MutualFriends),
+		})
+	}
+	return resp, nil
+}
+
+func (h *userHandler) BlockUser(ctx context.Context, req *userpb.BlockUserRequest) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("user_id", "must be a valid UUID"))
+	}
+
+	if err := h.service.BlockUser(ctx, callerID, userID); err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	logger.FromContext(ctx).Info("user blocked", zap.String("blocked_id", userID.String()))
+	return &userpb.FriendActionResponse{Message: "user blocked"}, nil
+}
+
+func (h *userHandler) UnblockUser(ctx context.Context, req *userpb.UnblockUserRequest) (*userpb.FriendActionResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	userID, err := uuid.Parse(req.GetUserId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("user_id", "must be a valid UUID"))
+	}
+
+	if err := h.service.UnblockUser(ctx, callerID, userID); err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	logger.FromContext(ctx).Info("user unblocked", zap.String("blocked_id", userID.String()))
+	return &userpb.FriendActionResponse{Message: "user unblocked"}, nil
+}
+
+func (h *userHandler) ListBlockedUsers(ctx context.Context, req *userpb.ListBlockedUsersRequest) (*userpb.ListBlockedUsersResponse, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	input := model.ListBlockedUsersInput{
+		BlockerID: callerID,
+		PageSize:  int(req.GetPageSize()),
+	}
+	if input.Cursor, err = decodeTimeCursor(req.GetPageToken()); err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("page_token", "is invalid"))
+	}
+
+	rows, next, err := h.service.ListBlockedUsers(ctx, input)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	resp := &userpb.ListBlockedUsersResponse{}
+	for _, u := range rows {
+		resp.Users = append(resp.Users, &userpb.BlockedUser{
+			User:      h.userProfile(u.User, model.RelationStranger),
+			BlockedAt: u.BlockedAt.Time.Format(time.RFC3339),
+		})
+	}
+	if next != nil {
+		resp.NextPageToken = encodeTimeCursor(next)
+	}
+	return resp, nil
+}
+
+func (h *userHandler) RequestDataExport(ctx context.Context, req *userpb.RequestDataExportRequest) (*userpb.DataExport, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+
+	export, err := h.service.RequestDataExport(ctx, callerID)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return dataExport(export, ""), nil
+}
+
+func (h *userHandler) GetExportStatus(ctx context.Context, req *userpb.GetExportStatusRequest) (*userpb.DataExport, error) {
+	callerID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	exportID, err := uuid.Parse(req.GetExportId())
+	if err != nil {
+		return nil, apperrors.ToGRPC(apperrors.InvalidField("export_id", "must be a valid UUID"))
+	}
+
+	export, url, err := h.service.GetDataExport(ctx, callerID, exportID)
+	if err != nil {
+		return nil, apperrors.ToGRPC(err)
+	}
+	return dataExport(export, url), nil
+}
+
+func dataExport(e *db.DataExport, url string) *userpb.DataExport {
+	resp := &userpb.DataExport{
+		ExportId:    e.ID.String(),
+		Status:      e.Status,
+		RequestedAt: e.RequestedAt.Format(time.RFC3339),
+		DownloadUrl: url,
+		SizeBytes:   e.SizeBytes.Int64,
+		Error:       e.Error.String,
+	}
+	if e.CompletedAt.Valid {
+		resp.CompletedAt = e.CompletedAt.Time.Format(time.RFC3339)
+	}
+	if e.ExpiresAt.Valid {
+		resp.ExpiresAt = e.ExpiresAt.Time.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// userProfile renders u for a caller of relation rel, leaving out what u does
+// not share with them.
+func (h *userHandler) userProfile(u db.User, rel model.Relation) *userpb.UserProfile {
+	profile := &userpb.UserProfile{
+		UserId:        u.ID.String(),
+		Username:      u.Username,
+		Avatar:        u.Avatar.String,
+		DisplayName:   u.DisplayName,
+		Bio:           u.Bio,
+		StatusMessage: u.StatusMessage,
+		Timezone:      u.Timezone,
+		Locale:        u.Locale,
+	}
+	if model.EmailVisible(u.EmailVisibility, rel) {
+		profile.Email = u.Email
+	}
+	if rel == model.RelationSelf {
+		searchable := u.Searchable
+		profile.Privacy = &userpb.PrivacySettings{
+			EmailVisibility:     u.EmailVisibility,
+			FriendRequestPolicy: u.FriendRequestPolicy,
+			Searchable:          &searchable,
+		}
+	}
+
+	var keys map[string]string
+	if json.Unmarshal(u.AvatarKeys, &keys) == nil && len(keys) > 0 {
+		profile.AvatarVariants = make(map[string]string, len(keys))
+		for name, key := range keys {
+			profile.AvatarVariants[name] = h.blobs.URL(key)
+		}
+		profile.Avatar = profile.AvatarVariants[avatar.DefaultVariant]
+	}
+	return profile
+}
+
+// callerUserID returns the id of the authenticated user.
+// callerUserID returns the authenticated user. Tokens obtained from an API key
+// are rejected: their scopes grant nothing on behalf of the key owner here.
+func callerUserID(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := authz.FromContext(ctx)
+	if !ok || claims.IsServiceAccount() {
+		return uuid.Nil, apperrors.ErrUnauthorized
+	}
+	return parseCallerID(claims)
+}
+
+// callerOrKeyOwnerID is callerUserID that also accepts tokens obtained from an
+// API key, for which it returns the key owner. Only use it in RPCs whose
+// MethodPermissions require a scope.
+func callerOrKeyOwnerID(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := authz.FromContext(ctx)
+	if !ok {
+		return uuid.Nil, apperrors.ErrUnauthorized
+	}
+	return parseCallerID(claims)
+}
+
+func parseCallerID(claims *authz.Claims) (uuid.UUID, error) {
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, apperrors.ErrUnauthorized
+	}
+	return userID, nil
+}
+
+// encodePageToken returns an opaque token for a keyset position made of a sort
+// key and the id of the last row.
+func encodePageToken(key string, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + "|" + id.String()))
+}
+
+// decodePageToken returns the sort key and id of a token made by encodePageToken.
+func decodePageToken(token string) (string, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", uuid.Nil, errInvalidPageToken
+	}
+
+	key, rawID, _ := strings.Cut(string(raw), "|")
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", uuid.Nil, errInvalidPageToken
+	}
+	return key, id, nil
+}
+
+func encodeTimeCursor(c *model.TimeCursor) string {
+	return encodePageToken(c.Time.Format(time.RFC3339Nano), c.ID)
+}
+
+// decodeTimeCursor returns nil for an empty token.
+func decodeTimeCursor(token string) (*model.TimeCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	key, id, err := decodePageToken(token)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, key)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+	return &model.TimeCursor{Time: t, ID: id}, nil
+}
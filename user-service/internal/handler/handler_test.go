@@ -0,0 +1,91 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/authz"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	userpb "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/api/auth/v1"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/handler"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository/memory"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func newServer(t *testing.T) (userpb.UserServiceServer, service.Service) {
+	t.Helper()
+	repo := memory.NewRepository(time.Now)
+	svc := service.NewSercice(repo, nil, nil, service.Options{
+		FriendRequestCooldown:  time.Hour,
+		UsernameChangeInterval: time.Hour,
+		UsernameQuarantine:     time.Hour,
+		SuggestFriendsSample:   100,
+		SuggestFriendsFanOut:   100,
+		BatchGetProfilesMax:    100,
+		ProfileCacheSize:       100,
+		ProfileCacheTTL:        time.Minute,
+	})
+	return handler.NewUserServiceServer(svc, nil), svc
+}
+
+// callerContext returns a context authenticated as userID, with a token of
+// subjectType holding permissions.
+func callerContext(t *testing.T, userID, subjectType string, permissions ...string) context.Context {
+	ctx := logger.NewContext(context.Background(), zaptest.NewLogger(t))
+	return authz.NewContext(ctx, &authz.Claims{
+		UserID:      userID,
+		Permissions: permissions,
+		TokenType:   authz.TokenTypeAccess,
+		SubjectType: subjectType,
+	})
+}
+
+func TestServiceAccountTokens(t *testing.T) {
+	server, svc := newServer(t)
+	ctx := logger.NewContext(context.Background(), zaptest.NewLogger(t))
+	alice, err := svc.CreateUser(ctx, model.CreateUserInput{Email: "alice@example.com", Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	aliceID := alice.ID.String()
+	update := &userpb.UpdateProfileRequest{UserId: aliceID, DisplayName: proto.String("Alice")}
+
+	serviceCtx := callerContext(t, aliceID, authz.SubjectTypeServiceAccount, "profiles:read", "audit:read")
+	if _, err := server.UpdateProfile(serviceCtx, update); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("UpdateProfile with a service account token = %v, want Unauthenticated", err)
+	}
+	if _, err := server.RequestDataExport(serviceCtx, &userpb.RequestDataExportRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("RequestDataExport with a service account token = %v, want Unauthenticated", err)
+	}
+
+	resp, err := server.BatchGetProfiles(serviceCtx, &userpb.BatchGetProfilesRequest{UserIds: []string{aliceID}})
+	if err != nil {
+		t.Fatalf("BatchGetProfiles with a service account token: %v", err)
+	}
+	if len(resp.GetResults()) != 1 || !resp.GetResults()[0].GetFound() {
+		t.Errorf("BatchGetProfiles = %v, want alice found", resp.GetResults())
+	}
+
+	userCtx := callerContext(t, aliceID, authz.SubjectTypeUser)
+	if _, err := server.UpdateProfile(userCtx, update); err != nil {
+		t.Errorf("UpdateProfile with a user token: %v", err)
+	}
+}
+
+func TestBatchGetProfilesRequiresScope(t *testing.T) {
+	required := handler.MethodPermissions[userpb.UserService_BatchGetProfiles_FullMethodName]
+	if len(required) == 0 {
+		t.Fatal("BatchGetProfiles requires no permission, so any API key may call it")
+	}
+	for method, perms := range handler.MethodPermissions {
+		if method != userpb.UserService_BatchGetProfiles_FullMethodName && len(perms) != 0 {
+			t.Errorf("%s requires %v; service account tokens are only meant for BatchGetProfiles", method, perms)
+		}
+	}
+}
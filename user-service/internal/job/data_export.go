@@ -0,0 +1,252 @@
+package job
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const (
+	expireBatchSize = 100
+	// exportService is the folder of user-service's own section in archives.
+	exportService = "user-service"
+)
+
+type DataExportOptions struct {
+	// Sections are the services that must contribute before an export is assembled.
+	Sections []string
+	Interval time.Duration
+	// Timeout fails exports still missing sections after that long.
+	Timeout time.Duration
+	// Lease is how long an export stays claimed by the assembler working on it.
+	Lease time.Duration
+	// Retention is how long archives can be downloaded.
+	Retention time.Duration
+}
+
+// DataExportAssembler zips the sections of complete data exports into the
+// export store, fails the ones that cannot complete and deletes expired archives.
+type DataExportAssembler struct {
+	repo    repository.Repository
+	svc     service.Service
+	exports storage.BlobStore
+	opts    DataExportOptions
+}
+
+func NewDataExportAssembler(repo repository.Repository, svc service.Service, exports storage.BlobStore, opts DataExportOptions) *DataExportAssembler {
+	return &DataExportAssembler{
+		repo:    repo,
+		svc:     svc,
+		exports: exports,
+		opts:    opts,
+	}
+}
+
+// Run assembles on every tick until ctx is cancelled.
+func (a *DataExportAssembler) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *DataExportAssembler) RunOnce(ctx context.Context) {
+	if n, err := a.repo.FailDataExportsWithSectionErrors(ctx); err != nil {
+		logger.FromContext(ctx).Error("failed to fail data exports with section errors", zap.Error(err))
+	} else if n > 0 {
+		logger.FromContext(ctx).Warn("data exports failed by a service", zap.Int64("count", n))
+	}
+
+	// Each export is tried once per run, so that one failing for a reason that
+	// may pass does not hold back the others.
+	var tried []uuid.UUID
+	for {
+		var claimed uuid.UUID
+		var written string
+		found, err := a.repo.AssembleDataExport(ctx, a.opts.Sections, time.Now().Add(-a.opts.Lease), tried, func(export db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+			claimed = export.ID
+			arg, err := a.assemble(ctx, export, sections)
+			written = arg.BlobKey.String
+			return arg, err
+		})
+		if !found {
+			if err != nil {
+				logger.FromContext(ctx).Error("failed to claim data export", zap.Error(err))
+			}
+			break
+		}
+		tried = append(tried, claimed)
+
+		var invalid *repository.InvalidDataExportError
+		switch {
+		case stdErrors.Is(err, pgx.ErrNoRows):
+			if written == "" {
+				continue
+			}
+			// Erased or taken over while the archive was written.
+			logger.FromContext(ctx).Warn("data export gone before completion, deleting its archive", zap.String("key", written))
+			if err := a.exports.Delete(ctx, written); err != nil {
+				logger.FromContext(ctx).Warn("failed to delete data export", zap.String("key", written), zap.Error(err))
+			}
+		case stdErrors.As(err, &invalid):
+			logger.FromContext(ctx).Error("data export cannot be assembled, failing it", zap.String("export_id", claimed.String()), zap.Error(err))
+		case err != nil:
+			logger.FromContext(ctx).Error("failed to assemble data export, will retry", zap.String("export_id", claimed.String()), zap.Error(err))
+		}
+	}
+
+	if n, err := a.repo.FailStaleDataExports(ctx, time.Now().Add(-a.opts.Timeout)); err != nil {
+		logger.FromContext(ctx).Error("failed to time out data exports", zap.Error(err))
+	} else if n > 0 {
+		logger.FromContext(ctx).Warn("data exports timed out", zap.Int64("count", n))
+	}
+
+	a.expire(ctx)
+}
+
+type exportManifest struct {
+	ExportID    string    `json:"export_id"`
+	UserID      string    `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Sections    []string  `json:"sections"`
+}
+
+// assemble writes the archive of export and returns how to complete it.
+func (a *DataExportAssembler) assemble(ctx context.Context, export db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+	own, err := a.svc.ExportUserData(ctx, export.UserID)
+	if err != nil {
+		return db.CompleteDataExportParams{}, err
+	}
+
+	files := make(map[string][]byte, len(own))
+	for name, data := range own {
+		files[exportService+"/"+name] = data
+	}
+	manifest := exportManifest{
+		ExportID:    export.ID.String(),
+		UserID:      export.UserID.String(),
+		RequestedAt: export.RequestedAt.UTC(),
+		GeneratedAt: time.Now().UTC(),
+		Sections:    []string{exportService},
+	}
+
+	for _, section := range sections {
+		var docs map[string]json.RawMessage
+		if err := json.Unmarshal(section.Files, &docs); err != nil {
+			return db.CompleteDataExportParams{}, &repository.InvalidDataExportError{Err: fmt.Errorf("section %s: %w", section.Service, err)}
+		}
+		for name, doc := range docs {
+			name = section.Service + "/" + name
+			if !safeArchivePath(name) {
+				return db.CompleteDataExportParams{}, &repository.InvalidDataExportError{Err: fmt.Errorf("%q: unsafe file name", name)}
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, doc, "", "  "); err != nil {
+				return db.CompleteDataExportParams{}, &repository.InvalidDataExportError{Err: fmt.Errorf("%s: %w", name, err)}
+			}
+			files[name] = indented.Bytes()
+		}
+		// Sections come in parts, ordered by service.
+		if manifest.Sections[len(manifest.Sections)-1] != section.Service {
+			manifest.Sections = append(manifest.Sections, section.Service)
+		}
+	}
+
+	if files["manifest.json"], err = json.MarshalIndent(manifest, "", "  "); err != nil {
+		return db.CompleteDataExportParams{}, err
+	}
+	archive, err := zipFiles(files)
+	if err != nil {
+		return db.CompleteDataExportParams{}, err
+	}
+
+	// Each claim writes its own archive, so that an assembler whose claim was
+	// taken over does not delete the archive of the one that took it over.
+	key := fmt.Sprintf("exports/%s/%s-%d.zip", export.UserID, export.ID, export.ClaimedAt.Time.UnixMicro())
+	if err := a.exports.Put(ctx, key, bytes.NewReader(archive)); err != nil {
+		return db.CompleteDataExportParams{}, err
+	}
+
+	logger.FromContext(ctx).Info("data export ready", zap.String("export_id", export.ID.String()), zap.Int("bytes", len(archive)))
+	return db.CompleteDataExportParams{
+		ID:        export.ID,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(a.opts.Retention), Valid: true},
+		BlobKey:   pgtype.Text{String: key, Valid: true},
+		SizeBytes: pgtype.Int8{Int64: int64(len(archive)), Valid: true},
+	}, nil
+}
+
+// zipFiles returns an archive of files, in name order.
+func zipFiles(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// safeArchivePath rejects names from other services that would escape their
+// folder once extracted.
+func safeArchivePath(name string) bool {
+	return path.Clean(name) == name && !path.IsAbs(name) && !strings.HasPrefix(name, "../") && !strings.Contains(name, "\\")
+}
+
+func (a *DataExportAssembler) expire(ctx context.Context) {
+	exports, err := a.repo.ListExpiredDataExports(ctx, time.Now(), expireBatchSize)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list expired data exports", zap.Error(err))
+		return
+	}
+
+	for _, export := range exports {
+		if err := a.exports.Delete(ctx, export.BlobKey.String); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete expired data export", zap.String("export_id", export.ID.String()), zap.Error(err))
+			continue
+		}
+		if err := a.repo.ExpireDataExport(ctx, export.ID); err != nil {
+			logger.FromContext(ctx).Error("failed to expire data export", zap.String("export_id", export.ID.String()), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package job_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/job"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository/memory"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap/zaptest"
+)
+
+// flakyStore fails to store the archives of one user.
+type flakyStore struct {
+	storage.BlobStore
+	down uuid.UUID
+}
+
+func (s *flakyStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if strings.HasPrefix(key, "exports/"+s.down.String()+"/") {
+		return io.ErrUnexpectedEOF
+	}
+	return s.BlobStore.Put(ctx, key, r)
+}
+
+func TestDataExportAssemblerFailureIsolation(t *testing.T) {
+	ctx := logger.NewContext(context.Background(), zaptest.NewLogger(t))
+	repo := memory.NewRepository(time.Now)
+	svc := service.NewSercice(repo, nil, nil, service.Options{ProfileCacheSize: 100, ProfileCacheTTL: time.Minute})
+	blobs, err := storage.NewLocalStore(t.TempDir(), "/exports")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	// Oldest first: bob's archive cannot be stored for now, carol's auth
+	// section can never be archived, and alice's export is fine.
+	exports := make(map[string]uuid.UUID)
+	var bob uuid.UUID
+	for _, u := range []struct{ name, authFiles string }{
+		{"bob", `{"account.json": {}}`},
+		{"carol", `{"../account.json": {}}`},
+		{"alice", `{"account.json": {}}`},
+	} {
+		user, err := svc.CreateUser(ctx, model.CreateUserInput{Email: u.name + "@example.com", Username: u.name})
+		if err != nil {
+			t.Fatalf("CreateUser(%s): %v", u.name, err)
+		}
+		if u.name == "bob" {
+			bob = user.ID
+		}
+		export, err := repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: uuid.New(), UserID: user.ID}, db.InsertOutboxEventParams{})
+		if err != nil {
+			t.Fatalf("CreateDataExport(%s): %v", u.name, err)
+		}
+		saved, err := repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+			ExportID: export.ID, UserID: user.ID, Service: "auth-service", Parts: 1, Files: []byte(u.authFiles),
+		})
+		if err != nil || !saved {
+			t.Fatalf("SaveDataExportSection(%s) = %v, %v, want true", u.name, saved, err)
+		}
+		exports[u.name] = export.ID
+	}
+
+	assembler := job.NewDataExportAssembler(repo, svc, &flakyStore{BlobStore: blobs, down: bob}, job.DataExportOptions{
+		Sections:  []string{"auth-service"},
+		Timeout:   time.Hour,
+		Lease:     time.Minute,
+		Retention: time.Hour,
+	})
+	assembler.RunOnce(ctx)
+
+	for name, want := range map[string]string{"bob": "pending", "carol": "failed", "alice": "ready"} {
+		got, err := repo.GetDataExport(ctx, exports[name])
+		if err != nil {
+			t.Fatalf("GetDataExport(%s): %v", name, err)
+		}
+		if got.Status != want {
+			t.Errorf("export of %s = %q (%s), want %q", name, got.Status, got.Error.String, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package job
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	relayBatchSize  = 100
+	cleanupInterval = time.Hour
+)
+
+type OutboxPublisher interface {
+	PublishConfirmed(ctx context.Context, exchange, routingKey, messageID string, body []byte) error
+}
+
+// OutboxRelay publishes the events stored in the outbox, in order, and deletes
+// them once they have been published for longer than the retention period.
+type OutboxRelay struct {
+	repo      repository.Repository
+	publisher OutboxPublisher
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewOutboxRelay(repo repository.Repository, publisher OutboxPublisher, interval, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:      repo,
+		publisher: publisher,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run relays on every tick until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		r.RelayOnce(ctx)
+
+		if time.Since(lastCleanup) >= cleanupInterval {
+			r.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes pending events until the outbox is drained or the broker
+// fails, in which case the remaining events wait for the next run.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) {
+	for {
+		published, err := r.repo.PublishOutbox(ctx, relayBatchSize, func(event db.OutboxEvent) error {
+			return r.publisher.PublishConfirmed(ctx, event.Exchange, event.RoutingKey, strconv.FormatInt(event.ID, 10), event.Payload)
+		})
+		if err != nil {
+			logger.FromContext(ctx).Warn("failed to relay outbox events, will retry", zap.Int("published", published), zap.Error(err))
+			return
+		}
+		if published < relayBatchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) cleanup(ctx context.Context) {
+	deleted, err := r.repo.DeletePublishedOutboxEvents(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to delete published outbox events", zap.Error(err))
+		return
+	}
+	if deleted > 0 {
+		logger.FromContext(ctx).Info("deleted published outbox events", zap.Int64("count", deleted))
+	}
+}
@@ -1,7 +1,115 @@
 package model
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type CreateUserInput struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
 	Avatar   string `json:"avatar"`
 }
+
+// SearchCursor is the position of the last result of a search page.
+type SearchCursor struct {
+	Score float64
+	ID    uuid.UUID
+}
+
+type SearchUsersInput struct {
+	CallerID uuid.UUID
+	Keyword  string `validate:"required,max=100"`
+	PageSize int
+	Cursor   *SearchCursor
+}
+
+// NameCursor is the position of the last entry of a page ordered by username.
+type NameCursor struct {
+	Username string
+	ID       uuid.UUID
+}
+
+type MutualFriendsInput struct {
+	CallerID uuid.UUID
+	UserA    uuid.UUID
+	UserB    uuid.UUID
+	PageSize int
+	Cursor   *NameCursor
+}
+
+// TimeCursor is the position of the last entry of a page ordered by time.
+type TimeCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+type ListBlockedUsersInput struct {
+	BlockerID uuid.UUID
+	PageSize  int
+	Cursor    *TimeCursor
+}
+
+type FriendRequestDirection int
+
+const (
+	IncomingFriendRequests FriendRequestDirection = iota
+	OutgoingFriendRequests
+)
+
+type ListFriendRequestsInput struct {
+	UserID    uuid.UUID
+	Direction FriendRequestDirection
+	PageSize  int
+	Cursor    *TimeCursor
+}
+
+// Values of users.email_visibility.
+const (
+	EmailVisibleToEveryone = "everyone"
+	EmailVisibleToFriends  = "friends"
+	EmailVisibleToNobody   = "nobody"
+)
+
+// Values of users.friend_request_policy.
+const (
+	FriendRequestsFromEveryone         = "everyone"
+	FriendRequestsFromFriendsOfFriends = "friends_of_friends"
+	FriendRequestsFromNobody           = "nobody"
+)
+
+// Relation is how the caller relates to a user whose profile they are shown.
+type Relation int
+
+const (
+	RelationStranger Relation = iota
+	RelationFriend
+	RelationSelf
+)
+
+// EmailVisible reports whether a user with the given email_visibility shares
+// their email with someone of relation rel.
+func EmailVisible(visibility string, rel Relation) bool {
+	switch rel {
+	case RelationSelf:
+		return true
+	case RelationFriend:
+		return visibility == EmailVisibleToEveryone || visibility == EmailVisibleToFriends
+	default:
+		return visibility == EmailVisibleToEveryone
+	}
+}
+
+// UpdateProfileInput holds the profile fields to change; nil fields are left as is.
+type UpdateProfileInput struct {
+	UserID              uuid.UUID
+	DisplayName         *string `validate:"omitempty,max=64"`
+	Bio                 *string `validate:"omitempty,max=500"`
+	StatusMessage       *string `validate:"omitempty,max=140"`
+	Timezone            *string `validate:"omitempty,timezone"`
+	Locale              *string `validate:"omitempty,bcp47_language_tag"`
+	EmailVisibility     *string `validate:"omitempty,oneof=everyone friends nobody"`
+	FriendRequestPolicy *string `validate:"omitempty,oneof=everyone friends_of_friends nobody"`
+	Searchable          *bool
+}
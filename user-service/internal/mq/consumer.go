@@ -0,0 +1,196 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+)
+
+var errConnectionClosed = errors.New("rabbitmq connection closed")
+
+const (
+	userDeletedQueue   = "user-service.user.deleted"
+	exportSectionQueue = "user-service.user.export.section"
+)
+
+type RabbitConsumer struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewRabbitConsumer(rabbitURL string, maxRetries int, retryDelay time.Duration) (*RabbitConsumer, error) {
+	var conn *amqp.Connection
+	var err error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		logger.Log.Info("connecting to RabbitMQ", zap.Int("attempt", attempt))
+
+		conn, err = amqp.Dial(rabbitURL)
+		if err == nil {
+			break
+		}
+
+		logger.Log.Warn("failed to connect to RabbitMQ, will retry", zap.Error(err))
+		time.Sleep(retryDelay)
+	}
+
+	if err != nil {
+		logger.Log.Error("giving up connecting to RabbitMQ", zap.Error(err))
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		logger.Log.Error("failed to open a channel", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	err = ch.ExchangeDeclare(
+		UserEventsExchange, // name
+		"topic",            // type
+		true,               // durable
+		false,              // auto-deleted
+		false,              // internal
+		false,              // no-wait
+		nil,                // args
+	)
+	if err != nil {
+		logger.Log.Error("failed to declare exchange", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	if err := ch.Qos(10, 0, false); err != nil {
+		logger.Log.Error("failed to set channel QoS", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	logger.Log.Info("RabbitMQ consumer connected successfully")
+	return &RabbitConsumer{conn: conn, channel: ch}, nil
+}
+
+// ConsumeUserDeleted delivers user.deleted events to handle until ctx is
+// cancelled. Messages are acknowledged only after handle succeeds; failures are
+// requeued, malformed messages are dropped.
+func (c *RabbitConsumer) ConsumeUserDeleted(ctx context.Context, handle func(context.Context, UserDeletedEvent) error) error {
+	return consume(ctx, c, userDeletedQueue, []string{UserDeleted}, handle)
+}
+
+// ConsumeExportSections delivers the data export sections sent by other
+// services, with the same delivery guarantees as ConsumeUserDeleted.
+func (c *RabbitConsumer) ConsumeExportSections(ctx context.Context, handle func(context.Context, ExportSectionEvent) error) error {
+	return consume(ctx, c, exportSectionQueue, []string{ExportSection}, handle)
+}
+
+// ConsumeProfileChanges delivers the profile updates and deletions of every
+// user to this instance, so that it can drop what it cached about them. Unlike
+// the other consumers each instance gets its own copy, from a queue that lives
+// as long as the connection; changes made while it is down are not replayed.
+func (c *RabbitConsumer) ConsumeProfileChanges(ctx context.Context, handle func(context.Context, ProfileUpdatedEvent) error) error {
+	return consume(ctx, c, "", []string{ProfileUpdated, UserDeleted}, handle)
+}
+
+// consume binds queue to routingKeys on user.events and hands the decoded
+// messages to handle from a new goroutine. An empty queue name declares a
+// private queue deleted with the connection.
+func consume[T any](ctx context.Context, c *RabbitConsumer, queue string, routingKeys []string, handle func(context.Context, T) error) error {
+	private := queue == ""
+	q, err := c.channel.QueueDeclare(
+		queue,    // name
+		!private, // durable
+		private,  // auto-deleted
+		private,  // exclusive
+		false,    // no-wait
+		nil,      // args
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range routingKeys {
+		if err := c.channel.QueueBind(q.Name, key, UserEventsExchange, false, nil); err != nil {
+			return err
+		}
+	}
+
+	deliveries, err := c.channel.Consume(
+		q.Name, // queue
+		"",     // consumer
+		false,  // auto-ack
+		false,  // exclusive
+		false,  // no-local
+		false,  // no-wait
+		nil,    // args
+	)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					logger.Log.Warn("delivery channel closed", zap.String("queue", q.Name))
+					return
+				}
+
+				msgCtx, span := tracing.StartConsume(ctx, q.Name, d)
+				msgCtx = logger.ExtractAMQP(msgCtx, d)
+
+				var event T
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					logger.FromContext(msgCtx).Error("invalid message", zap.String("routing_key", d.RoutingKey), zap.Error(err))
+					d.Nack(false, false)
+					metrics.ObserveConsume(q.Name, metrics.ConsumeDrop)
+					tracing.EndSpan(span, err)
+					continue
+				}
+
+				if err := handle(msgCtx, event); err != nil {
+					logger.FromContext(msgCtx).Error("failed to handle message", zap.String("routing_key", d.RoutingKey), zap.Error(err))
+					d.Nack(false, true)
+					metrics.ObserveConsume(q.Name, metrics.ConsumeRequeue)
+					tracing.EndSpan(span, err)
+					continue
+				}
+				d.Ack(false)
+				metrics.ObserveConsume(q.Name, metrics.ConsumeAck)
+				tracing.EndSpan(span, nil)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Check reports whether the broker connection is still open.
+func (c *RabbitConsumer) Check(ctx context.Context) error {
+	if c.conn.IsClosed() {
+		return errConnectionClosed
+	}
+	return nil
+}
+
+func (c *RabbitConsumer) Close() {
+	if err := c.channel.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ channel: %v", err)
+	}
+	if err := c.conn.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ connection: %v", err)
+	} else {
+		log.Println("RabbitMQ connection closed successfully")
+	}
+}
@@ -0,0 +1,103 @@
+package mq
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserEventsExchange is the topic exchange of user lifecycle events, shared
+// with auth-service.
+const UserEventsExchange = "user.events"
+
+// UserDeleted is the routing key on user.events of UserDeletedEvent.
+const UserDeleted = "user.deleted"
+
+// UserDeletedEvent is published by auth-service on user.events once an account
+// has been permanently erased.
+type UserDeletedEvent struct {
+	UserID    uuid.UUID `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ProfileUpdated is the routing key on user.events of ProfileUpdatedEvent.
+const ProfileUpdated = "user.profile.updated"
+
+// ProfileUpdatedEvent is published on user.events when the profile, username
+// or avatar of a user changes. It shares the id field of UserDeletedEvent, so
+// either decodes as the other.
+type ProfileUpdatedEvent struct {
+	UserID    uuid.UUID `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserBlocked and UserUnblocked are the routing keys on user.events of
+// UserBlockedEvent and UserUnblockedEvent.
+const (
+	UserBlocked   = "user.blocked"
+	UserUnblocked = "user.unblocked"
+)
+
+// UserBlockedEvent is published on user.events when a user blocks another.
+type UserBlockedEvent struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// UserUnblockedEvent is published on user.events when a block is lifted.
+type UserUnblockedEvent struct {
+	BlockerID   uuid.UUID `json:"blocker_id"`
+	BlockedID   uuid.UUID `json:"blocked_id"`
+	UnblockedAt time.Time `json:"unblocked_at"`
+}
+
+// FriendEventsExchange is the topic exchange friendship changes are published
+// to, with one of the Friend* routing keys.
+const FriendEventsExchange = "friend.events"
+
+const (
+	FriendRequested = "friend.requested"
+	FriendAccepted  = "friend.accepted"
+	FriendRejected  = "friend.rejected"
+	FriendRemoved   = "friend.removed"
+)
+
+// FriendshipEvent describes a change to the friendship between RequesterID,
+// who sent the original request, and AddresseeID.
+type FriendshipEvent struct {
+	FriendshipID uuid.UUID `json:"friendship_id"`
+	RequesterID  uuid.UUID `json:"requester_id"`
+	AddresseeID  uuid.UUID `json:"addressee_id"`
+	// ActorID is the user whose action caused the event.
+	ActorID    uuid.UUID `json:"actor_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Routing keys on user.events of the personal data export flow.
+const (
+	ExportRequested = "user.export.requested"
+	ExportSection   = "user.export.section"
+)
+
+// ExportRequestedEvent asks every service holding data about the user for
+// their section of export ExportID.
+type ExportRequestedEvent struct {
+	ExportID    uuid.UUID `json:"export_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// ExportSectionEvent carries part Part of Parts of the data one service holds
+// about the user, as JSON documents by file name, or why it could not be
+// produced. Parts defaults to 1 when unset.
+type ExportSectionEvent struct {
+	ExportID uuid.UUID                  `json:"export_id"`
+	UserID   uuid.UUID                  `json:"user_id"`
+	Service  string                     `json:"service"`
+	Part     int32                      `json:"part,omitempty"`
+	Parts    int32                      `json:"parts,omitempty"`
+	Files    map[string]json.RawMessage `json:"files,omitempty"`
+	Error    string                     `json:"error,omitempty"`
+}
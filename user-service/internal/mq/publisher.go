@@ -0,0 +1,115 @@
+package mq
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/metrics"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/rabbitmq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/tracing"
+	"github.com/streadway/amqp"
+	"go.uber.org/zap"
+)
+
+type RabbitPublisher struct {
+	conn *amqp.Connection
+	// confirm is in confirm mode, see PublishConfirmed.
+	confirm *rabbitmq.ConfirmChannel
+}
+
+func NewRabbitPublisher(rabbitURL string, maxRetries int, retryDelay time.Duration) (*RabbitPublisher, error) {
+	var conn *amqp.Connection
+	var err error
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		logger.Log.Info("connecting to RabbitMQ", zap.Int("attempt", attempt))
+
+		conn, err = amqp.Dial(rabbitURL)
+		if err == nil {
+			break
+		}
+
+		logger.Log.Warn("failed to connect to RabbitMQ, will retry", zap.Error(err))
+		time.Sleep(retryDelay)
+	}
+
+	if err != nil {
+		logger.Log.Error("giving up connecting to RabbitMQ", zap.Error(err))
+		return nil, err
+	}
+
+	confirm, err := rabbitmq.NewConfirmChannel(conn)
+	if err != nil {
+		logger.Log.Error("failed to open a confirm channel", zap.Error(err))
+		conn.Close()
+		return nil, err
+	}
+
+	for _, exchange := range []string{UserEventsExchange, FriendEventsExchange} {
+		err = confirm.Channel().ExchangeDeclare(
+			exchange, // name
+			"topic",  // type
+			true,     // durable
+			false,    // auto-deleted
+			false,    // internal
+			false,    // no-wait
+			nil,      // args
+		)
+		if err != nil {
+			logger.Log.Error("failed to declare exchange", zap.String("exchange", exchange), zap.Error(err))
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	logger.Log.Info("RabbitMQ publisher connected successfully")
+	return &RabbitPublisher{conn: conn, confirm: confirm}, nil
+}
+
+// PublishConfirmed publishes a persistent JSON message and waits until the
+// broker has taken responsibility for it. messageID lets consumers drop
+// duplicates, since a message may be sent again if the confirmation is lost.
+// The message is not mandatory: events nobody has bound a queue for are
+// dropped by the broker, not retried.
+func (p *RabbitPublisher) PublishConfirmed(ctx context.Context, exchange, routingKey, messageID string, body []byte) error {
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+		Body:         body,
+	}
+	return p.send(ctx, exchange, routingKey, msg, func(msg amqp.Publishing) error {
+		return p.confirm.Publish(ctx, exchange, routingKey, false, msg)
+	})
+}
+
+// send hands msg to publish with the trace context and request id of ctx in
+// its headers and counts it.
+func (p *RabbitPublisher) send(ctx context.Context, exchange, routingKey string, msg amqp.Publishing, publish func(amqp.Publishing) error) error {
+	_, span := tracing.StartPublish(ctx, exchange, routingKey, &msg)
+	logger.InjectAMQP(ctx, &msg)
+	err := publish(msg)
+	metrics.ObservePublish(exchange, routingKey, err)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+// Check reports whether the broker connection is still open.
+func (p *RabbitPublisher) Check(ctx context.Context) error {
+	if p.conn.IsClosed() {
+		return errConnectionClosed
+	}
+	return nil
+}
+
+func (p *RabbitPublisher) Close() {
+	if err := p.confirm.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ confirm channel: %v", err)
+	}
+	if err := p.conn.Close(); err != nil {
+		log.Printf("failed to close RabbitMQ connection: %v", err)
+	}
+}
@@ -0,0 +1,1235 @@
+// Package memory is an in-memory Repository for tests. It enforces the unique
+// and foreign key constraints of the schema and reports violations and missing
+// rows with the errors pgx would return, so callers handle them as they do in
+// production. Each method is atomic, as the transactions of the Postgres
+// implementation are.
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	statusPending = "pending"
+	// statusAssembling is the status of data exports being assembled.
+	statusAssembling = "assembling"
+	statusAccepted   = "accepted"
+	statusRejected   = "rejected"
+	statusBlocked    = "blocked"
+)
+
+type sectionPart struct {
+	service string
+	part    int32
+}
+
+type repo struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	users           map[uuid.UUID]db.User
+	usernameHistory []db.UsernameHistory
+	friendships     map[uuid.UUID]db.Friendship
+	exports         map[uuid.UUID]db.DataExport
+	// sections holds the section parts of each export.
+	sections map[uuid.UUID]map[sectionPart]db.DataExportSection
+	outbox   []db.OutboxEvent
+	lastID   int64
+}
+
+// NewRepository returns an empty repository. now stands in for now(); nil
+// means time.Now.
+func NewRepository(now func() time.Time) repository.Repository {
+	if now == nil {
+		now = time.Now
+	}
+	return &repo{
+		now:         now,
+		users:       make(map[uuid.UUID]db.User),
+		friendships: make(map[uuid.UUID]db.Friendship),
+		exports:     make(map[uuid.UUID]db.DataExport),
+		sections:    make(map[uuid.UUID]map[sectionPart]db.DataExportSection),
+	}
+}
+
+func pgError(code, constraint, format string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           code,
+		Message:        fmt.Sprintf(format, constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func uniqueViolation(constraint string) error {
+	return pgError("23505", constraint, "duplicate key value violates unique constraint %q")
+}
+
+func foreignKeyViolation(constraint string) error {
+	return pgError("23503", constraint, "insert or update violates foreign key constraint %q")
+}
+
+func checkViolation(constraint string) error {
+	return pgError("23514", constraint, "new row violates check constraint %q")
+}
+
+func (r *repo) timestamp() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: r.now(), Valid: true}
+}
+
+func (r *repo) nextID() int64 {
+	r.lastID++
+	return r.lastID
+}
+
+// compareIDs orders uuids as Postgres does.
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// before reports whether (t, id) sorts before the keyset cursor.
+func before(t pgtype.Timestamptz, id uuid.UUID, cursorTime pgtype.Timestamptz, cursorID uuid.NullUUID) bool {
+	if !cursorTime.Valid {
+		return true
+	}
+	if c := t.Time.Compare(cursorTime.Time); c != 0 {
+		return c < 0
+	}
+	return compareIDs(id, cursorID.UUID) < 0
+}
+
+// newestFirst orders rows by (created_at, id) descending.
+func newestFirst(a, b db.Friendship) int {
+	if c := b.CreatedAt.Time.Compare(a.CreatedAt.Time); c != 0 {
+		return c
+	}
+	return compareIDs(b.ID, a.ID)
+}
+
+func oldestFirst(a, b db.Friendship) int {
+	return newestFirst(b, a)
+}
+
+func limit[T any](rows []T, n int32) []T {
+	return rows[:min(max(int(n), 0), len(rows))]
+}
+
+// Users
+
+// checkUser enforces the constraints of the users table on u, which replaces
+// the row with the same id if there is one.
+func (r *repo) checkUser(u db.User) error {
+	for _, other := range r.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if other.Email == u.Email {
+			return uniqueViolation("users_email_key")
+		}
+		if strings.EqualFold(other.Username, u.Username) {
+			return uniqueViolation("users_username_lower_idx")
+		}
+	}
+	if !slices.Contains([]string{"everyone", "friends", "nobody"}, u.EmailVisibility) {
+		return checkViolation("users_email_visibility_check")
+	}
+	if !slices.Contains([]string{"everyone", "friends_of_friends", "nobody"}, u.FriendRequestPolicy) {
+		return checkViolation("users_friend_request_policy_check")
+	}
+	return nil
+}
+
+func (r *repo) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[arg.ID]; ok {
+		return db.User{}, uniqueViolation("users_pkey")
+	}
+	user := db.User{
+		ID:                  arg.ID,
+		Email:               arg.Email,
+		Username:            arg.Username,
+		Avatar:              arg.Avatar,
+		CreatedAt:           r.timestamp(),
+		AvatarKeys:          []byte("{}"),
+		Timezone:            "UTC",
+		Locale:              "en",
+		EmailVisibility:     "friends",
+		FriendRequestPolicy: "everyone",
+		Searchable:          true,
+	}
+	if err := r.checkUser(user); err != nil {
+		return db.User{}, err
+	}
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *repo) GetUserByID(ctx context.Context, id uuid.UUID) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return db.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *repo) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return db.User{}, pgx.ErrNoRows
+}
+
+func (r *repo) ListUsers(ctx context.Context, arg db.ListUsersParams) ([]db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := r.allUsers()
+	slices.SortFunc(users, func(a, b db.User) int { return b.CreatedAt.Time.Compare(a.CreatedAt.Time) })
+	users = users[min(max(int(arg.Offset), 0), len(users)):]
+	return limit(users, arg.Limit), nil
+}
+
+func (r *repo) allUsers() []db.User {
+	users := make([]db.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	return users
+}
+
+// SearchUsers matches by case-insensitive substring rather than trigram
+// similarity, so scores only approximate those of Postgres. Filtering and
+// boosts follow the query.
+func (r *repo) SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keyword := strings.ToLower(arg.Keyword)
+	prefix := strings.ToLower(strings.TrimSuffix(arg.PrefixPattern, "%"))
+	prefix = strings.NewReplacer(`\\`, `\`, `\%`, "%", `\_`, "_").Replace(prefix)
+	friends := r.friendsOf(arg.CallerID)
+	friendsOfFriends := make(map[uuid.UUID]bool)
+	for id := range friends {
+		for fof := range r.friendsOf(id) {
+			friendsOfFriends[fof] = true
+		}
+	}
+
+	var rows []db.SearchUsersRow
+	for _, u := range r.users {
+		if u.ID == arg.CallerID || r.blockedEitherWay(u.ID, arg.CallerID) {
+			continue
+		}
+		isFriend := friends[u.ID]
+		if !u.Searchable && !isFriend {
+			continue
+		}
+		emailVisible := u.EmailVisibility == "everyone" || (u.EmailVisibility == "friends" && isFriend)
+
+		score := max(similarity(u.Username, keyword), similarity(u.DisplayName, keyword))
+		if emailVisible && strings.EqualFold(u.Email, keyword) {
+			score = 1
+		}
+		switch {
+		case strings.HasPrefix(strings.ToLower(u.Username), prefix):
+			score += 0.5
+		case strings.HasPrefix(strings.ToLower(u.DisplayName), prefix):
+			score += 0.4
+		}
+		if score == 0 {
+			continue
+		}
+		switch {
+		case isFriend:
+			score += 0.4
+		case friendsOfFriends[u.ID]:
+			score += 0.2
+		}
+
+		if arg.CursorScore.Valid && (score > arg.CursorScore.Float64 ||
+			(score == arg.CursorScore.Float64 && compareIDs(u.ID, arg.CursorID.UUID) >= 0)) {
+			continue
+		}
+		rows = append(rows, db.SearchUsersRow{User: u, Score: score, IsFriend: isFriend})
+	}
+
+	slices.SortFunc(rows, func(a, b db.SearchUsersRow) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return compareIDs(b.User.ID, a.User.ID)
+	})
+	return limit(rows, arg.PageSize), nil
+}
+
+// similarity stands in for pg_trgm's similarity: the share of s that keyword
+// covers if it occurs in s, ignoring case.
+func similarity(s, keyword string) float64 {
+	if keyword == "" || !strings.Contains(strings.ToLower(s), keyword) {
+		return 0
+	}
+	return float64(len(keyword)) / float64(len(s))
+}
+
+func (r *repo) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []db.User
+	for id, user := range r.users {
+		if slices.Contains(ids, id) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *repo) SetAvatarKeys(ctx context.Context, arg db.SetAvatarKeysParams, event db.InsertOutboxEventParams) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[arg.ID]
+	if !ok {
+		return db.User{}, pgx.ErrNoRows
+	}
+	user.AvatarKeys = bytes.Clone(arg.AvatarKeys)
+	user.Avatar = pgtype.Text{}
+	r.users[user.ID] = user
+	r.insertOutboxEvent(event)
+	return user, nil
+}
+
+func (r *repo) UpdateProfile(ctx context.Context, arg db.UpdateProfileParams, event db.InsertOutboxEventParams) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[arg.ID]
+	if !ok {
+		return db.User{}, pgx.ErrNoRows
+	}
+	for _, field := range []struct {
+		value pgtype.Text
+		dst   *string
+	}{
+		{arg.DisplayName, &user.DisplayName},
+		{arg.Bio, &user.Bio},
+		{arg.StatusMessage, &user.StatusMessage},
+		{arg.Timezone, &user.Timezone},
+		{arg.Locale, &user.Locale},
+		{arg.EmailVisibility, &user.EmailVisibility},
+		{arg.FriendRequestPolicy, &user.FriendRequestPolicy},
+	} {
+		if field.value.Valid {
+			*field.dst = field.value.String
+		}
+	}
+	if arg.Searchable.Valid {
+		user.Searchable = arg.Searchable.Bool
+	}
+	if err := r.checkUser(user); err != nil {
+		return db.User{}, err
+	}
+
+	r.users[user.ID] = user
+	r.insertOutboxEvent(event)
+	return user, nil
+}
+
+func (r *repo) IsUsernameTaken(ctx context.Context, arg db.IsUsernameTakenParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.ID != arg.UserID && strings.EqualFold(u.Username, arg.Username) {
+			return true, nil
+		}
+	}
+	for _, h := range r.usernameHistory {
+		if h.UserID != arg.UserID && strings.EqualFold(h.Username, arg.Username) && h.ReleasedAt.After(arg.HeldSince) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *repo) ChangeUsername(ctx context.Context, arg db.ChangeUsernameParams, event db.InsertOutboxEventParams) (db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[arg.UserID]
+	if !ok || (user.UsernameChangedAt.Valid && !user.UsernameChangedAt.Time.Before(arg.ChangedBefore)) {
+		return db.User{}, pgx.ErrNoRows
+	}
+
+	previous := user.Username
+	user.Username = arg.Username
+	user.UsernameChangedAt = r.timestamp()
+	if err := r.checkUser(user); err != nil {
+		return db.User{}, err
+	}
+
+	r.users[user.ID] = user
+	r.usernameHistory = append(r.usernameHistory, db.UsernameHistory{
+		ID:         r.nextID(),
+		UserID:     user.ID,
+		Username:   previous,
+		ReleasedAt: r.now(),
+	})
+	r.insertOutboxEvent(event)
+	return user, nil
+}
+
+// Friendships
+
+// insertFriendship enforces the constraints of the friendships table.
+func (r *repo) insertFriendship(f db.Friendship) error {
+	if _, ok := r.friendships[f.ID]; ok {
+		return uniqueViolation("friendships_pkey")
+	}
+	if _, ok := r.users[f.RequesterID]; !ok {
+		return foreignKeyViolation("friendships_requester_id_fkey")
+	}
+	if _, ok := r.users[f.AddresseeID]; !ok {
+		return foreignKeyViolation("friendships_addressee_id_fkey")
+	}
+	for _, other := range r.friendships {
+		if other.RequesterID == f.RequesterID && other.AddresseeID == f.AddresseeID {
+			return uniqueViolation("friendships_requester_id_addressee_id_key")
+		}
+		if f.Status != statusBlocked && other.Status != statusBlocked && samePair(f, other) {
+			return uniqueViolation("friendships_pair_idx")
+		}
+	}
+	r.friendships[f.ID] = f
+	return nil
+}
+
+func samePair(a, b db.Friendship) bool {
+	return (a.RequesterID == b.RequesterID && a.AddresseeID == b.AddresseeID) ||
+		(a.RequesterID == b.AddresseeID && a.AddresseeID == b.RequesterID)
+}
+
+func between(f db.Friendship, a, b uuid.UUID) bool {
+	return samePair(f, db.Friendship{RequesterID: a, AddresseeID: b})
+}
+
+// changeFriendship applies change to a copy of the friendships and keeps it
+// only if change and event succeed, as a rolled back transaction would.
+func (r *repo) changeFriendship(event repository.FriendshipEvent, change func() (db.Friendship, error)) (db.Friendship, error) {
+	saved := make(map[uuid.UUID]db.Friendship, len(r.friendships))
+	for id, f := range r.friendships {
+		saved[id] = f
+	}
+
+	friendship, err := change()
+	if err == nil {
+		var arg db.InsertOutboxEventParams
+		if arg, err = event(friendship); err == nil {
+			r.insertOutboxEvent(arg)
+			return friendship, nil
+		}
+	}
+	r.friendships = saved
+	return db.Friendship{}, err
+}
+
+func (r *repo) newFriendRequest(arg db.SendFriendRequestParams) (db.Friendship, error) {
+	f := db.Friendship{
+		ID:          arg.ID,
+		RequesterID: arg.RequesterID,
+		AddresseeID: arg.AddresseeID,
+		Status:      statusPending,
+		CreatedAt:   r.timestamp(),
+	}
+	return f, r.insertFriendship(f)
+}
+
+func (r *repo) SendFriendRequest(ctx context.Context, arg db.SendFriendRequestParams, event repository.FriendshipEvent) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changeFriendship(event, func() (db.Friendship, error) {
+		return r.newFriendRequest(arg)
+	})
+}
+
+func (r *repo) ResendFriendRequest(ctx context.Context, staleID uuid.UUID, arg db.SendFriendRequestParams, event repository.FriendshipEvent) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changeFriendship(event, func() (db.Friendship, error) {
+		delete(r.friendships, staleID)
+		return r.newFriendRequest(arg)
+	})
+}
+
+func (r *repo) GetFriendRequestByID(ctx context.Context, id uuid.UUID) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.friendships[id]
+	if !ok || f.Status == statusBlocked {
+		return db.Friendship{}, pgx.ErrNoRows
+	}
+	return f, nil
+}
+
+func (r *repo) GetFriendshipBetween(ctx context.Context, arg db.GetFriendshipBetweenParams) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, f := range r.friendships {
+		if f.Status != statusBlocked && between(f, arg.RequesterID, arg.AddresseeID) {
+			return f, nil
+		}
+	}
+	return db.Friendship{}, pgx.ErrNoRows
+}
+
+func (r *repo) AcceptFriendRequest(ctx context.Context, id uuid.UUID, event repository.FriendshipEvent) (db.Friendship, error) {
+	return r.answerFriendRequest(id, statusAccepted, event)
+}
+
+func (r *repo) RejectFriendRequest(ctx context.Context, id uuid.UUID, event repository.FriendshipEvent) (db.Friendship, error) {
+	return r.answerFriendRequest(id, statusRejected, event)
+}
+
+func (r *repo) answerFriendRequest(id uuid.UUID, status string, event repository.FriendshipEvent) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changeFriendship(event, func() (db.Friendship, error) {
+		f, ok := r.friendships[id]
+		if !ok || f.Status != statusPending {
+			return db.Friendship{}, pgx.ErrNoRows
+		}
+		f.Status = status
+		f.RespondedAt = r.timestamp()
+		r.friendships[id] = f
+		return f, nil
+	})
+}
+
+func (r *repo) RemoveFriend(ctx context.Context, arg db.RemoveFriendParams, event repository.FriendshipEvent) (db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.changeFriendship(event, func() (db.Friendship, error) {
+		for id, f := range r.friendships {
+			if f.Status == statusAccepted && between(f, arg.RequesterID, arg.AddresseeID) {
+				delete(r.friendships, id)
+				return f, nil
+			}
+		}
+		return db.Friendship{}, pgx.ErrNoRows
+	})
+}
+
+func (r *repo) CancelFriendRequest(ctx context.Context, arg db.CancelFriendRequestParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.friendships[arg.ID]
+	if !ok || f.RequesterID != arg.RequesterID || f.Status != statusPending {
+		return false, nil
+	}
+	delete(r.friendships, arg.ID)
+	return true, nil
+}
+
+func (r *repo) ListIncomingFriendRequests(ctx context.Context, arg db.ListIncomingFriendRequestsParams) ([]db.Friendship, error) {
+	return r.listFriendRequests(func(f db.Friendship) bool { return f.AddresseeID == arg.UserID },
+		arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+}
+
+func (r *repo) ListOutgoingFriendRequests(ctx context.Context, arg db.ListOutgoingFriendRequestsParams) ([]db.Friendship, error) {
+	return r.listFriendRequests(func(f db.Friendship) bool { return f.RequesterID == arg.UserID },
+		arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+}
+
+func (r *repo) listFriendRequests(match func(db.Friendship) bool, cursorCreatedAt pgtype.Timestamptz, cursorID uuid.NullUUID, pageSize int32) ([]db.Friendship, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requests []db.Friendship
+	for _, f := range r.friendships {
+		if f.Status == statusPending && match(f) && before(f.CreatedAt, f.ID, cursorCreatedAt, cursorID) {
+			requests = append(requests, f)
+		}
+	}
+	slices.SortFunc(requests, newestFirst)
+	return limit(requests, pageSize), nil
+}
+
+// friendsOf returns the ids of the friends of id.
+func (r *repo) friendsOf(id uuid.UUID) map[uuid.UUID]bool {
+	friends := make(map[uuid.UUID]bool)
+	for _, f := range r.friendships {
+		switch {
+		case f.Status != statusAccepted:
+		case f.RequesterID == id:
+			friends[f.AddresseeID] = true
+		case f.AddresseeID == id:
+			friends[f.RequesterID] = true
+		}
+	}
+	return friends
+}
+
+func (r *repo) blockedEitherWay(a, b uuid.UUID) bool {
+	for _, f := range r.friendships {
+		if f.Status == statusBlocked && between(f, a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *repo) GetFriends(ctx context.Context, requesterID uuid.UUID) ([]db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var friends []db.User
+	for id := range r.friendsOf(requesterID) {
+		if user, ok := r.users[id]; ok {
+			friends = append(friends, user)
+		}
+	}
+	return friends, nil
+}
+
+func (r *repo) GetMutualFriends(ctx context.Context, arg db.GetMutualFriendsParams) ([]db.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	friendsB := r.friendsOf(arg.UserB)
+	var mutual []db.User
+	for id := range r.friendsOf(arg.UserA) {
+		user, ok := r.users[id]
+		if !ok || !friendsB[id] || r.blockedEitherWay(id, arg.CallerID) {
+			continue
+		}
+		if arg.CursorUsername.Valid {
+			c := cmp.Compare(user.Username, arg.CursorUsername.String)
+			if c < 0 || (c == 0 && compareIDs(user.ID, arg.CursorID.UUID) <= 0) {
+				continue
+			}
+		}
+		mutual = append(mutual, user)
+	}
+	slices.SortFunc(mutual, func(a, b db.User) int {
+		if c := cmp.Compare(a.Username, b.Username); c != 0 {
+			return c
+		}
+		return compareIDs(a.ID, b.ID)
+	})
+	return limit(mutual, arg.PageSize), nil
+}
+
+func (r *repo) SuggestFriends(ctx context.Context, arg db.SuggestFriendsParams) ([]db.SuggestFriendsRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The first friends by id stand in for the random sample.
+	mutualFriends := make(map[uuid.UUID]int64)
+	for _, friend := range limit(r.sortedFriendsOf(arg.UserID), arg.FriendSample) {
+		for _, candidate := range limit(r.sortedFriendsOf(friend), arg.FanOut) {
+			if candidate != arg.UserID {
+				mutualFriends[candidate]++
+			}
+		}
+	}
+
+	var rows []db.SuggestFriendsRow
+	for id, n := range mutualFriends {
+		user, ok := r.users[id]
+		if !ok || !user.Searchable || user.FriendRequestPolicy == "nobody" || r.related(arg.UserID, id) {
+			continue
+		}
+		rows = append(rows, db.SuggestFriendsRow{User: user, MutualFriends: n})
+	}
+	slices.SortFunc(rows, func(a, b db.SuggestFriendsRow) int {
+		if c := cmp.Compare(b.MutualFriends, a.MutualFriends); c != 0 {
+			return c
+		}
+		return compareIDs(a.User.ID, b.User.ID)
+	})
+	return limit(rows, arg.PageSize), nil
+}
+
+func (r *repo) sortedFriendsOf(id uuid.UUID) []uuid.UUID {
+	friends := slices.Collect(maps.Keys(r.friendsOf(id)))
+	slices.SortFunc(friends, compareIDs)
+	return friends
+}
+
+// related reports whether a and b have a friendship, request or block.
+func (r *repo) related(a, b uuid.UUID) bool {
+	for _, f := range r.friendships {
+		if between(f, a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *repo) AreFriends(ctx context.Context, arg db.AreFriendsParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.friendsOf(arg.RequesterID)[arg.AddresseeID], nil
+}
+
+func (r *repo) HaveMutualFriend(ctx context.Context, arg db.HaveMutualFriendParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	friendsB := r.friendsOf(arg.UserB)
+	for id := range r.friendsOf(arg.UserA) {
+		if friendsB[id] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Blocks
+
+// removeFriendships deletes the friendships matching drop and stores removed
+// for each accepted one. Nothing changes if removed fails.
+func (r *repo) removeFriendships(drop func(db.Friendship) bool, removed repository.FriendshipEvent) error {
+	var ids []uuid.UUID
+	var events []db.InsertOutboxEventParams
+	for id, f := range r.friendships {
+		if !drop(f) {
+			continue
+		}
+		ids = append(ids, id)
+		if f.Status != statusAccepted {
+			continue
+		}
+		arg, err := removed(f)
+		if err != nil {
+			return err
+		}
+		events = append(events, arg)
+	}
+
+	for _, id := range ids {
+		delete(r.friendships, id)
+	}
+	for _, arg := range events {
+		r.insertOutboxEvent(arg)
+	}
+	return nil
+}
+
+func (r *repo) BlockUser(ctx context.Context, arg db.BlockUserParams, event db.InsertOutboxEventParams, removed repository.FriendshipEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.removeFriendships(func(f db.Friendship) bool {
+		return f.Status != statusBlocked && between(f, arg.RequesterID, arg.AddresseeID)
+	}, removed)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range r.friendships {
+		if f.RequesterID == arg.RequesterID && f.AddresseeID == arg.AddresseeID {
+			// ON CONFLICT DO NOTHING.
+			return false, nil
+		}
+	}
+
+	err = r.insertFriendship(db.Friendship{
+		ID:          arg.ID,
+		RequesterID: arg.RequesterID,
+		AddresseeID: arg.AddresseeID,
+		Status:      statusBlocked,
+		CreatedAt:   r.timestamp(),
+	})
+	if err != nil {
+		return false, err
+	}
+	r.insertOutboxEvent(event)
+	return true, nil
+}
+
+func (r *repo) UnblockUser(ctx context.Context, arg db.UnblockUserParams, event db.InsertOutboxEventParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, f := range r.friendships {
+		if f.Status == statusBlocked && f.RequesterID == arg.RequesterID && f.AddresseeID == arg.AddresseeID {
+			delete(r.friendships, id)
+			r.insertOutboxEvent(event)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *repo) IsBlockedEitherWay(ctx context.Context, arg db.IsBlockedEitherWayParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.blockedEitherWay(arg.RequesterID, arg.AddresseeID), nil
+}
+
+func (r *repo) ListBlockedEitherWayAmong(ctx context.Context, arg db.ListBlockedEitherWayAmongParams) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var blocked []uuid.UUID
+	for _, id := range arg.Ids {
+		if !slices.Contains(blocked, id) && r.blockedEitherWay(arg.UserID, id) {
+			blocked = append(blocked, id)
+		}
+	}
+	return blocked, nil
+}
+
+func (r *repo) ListBlockedUsers(ctx context.Context, arg db.ListBlockedUsersParams) ([]db.ListBlockedUsersRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rows []db.ListBlockedUsersRow
+	for _, f := range r.blocksBy(arg.BlockerID) {
+		user := r.users[f.AddresseeID]
+		if before(f.CreatedAt, user.ID, arg.CursorBlockedAt, arg.CursorID) {
+			rows = append(rows, db.ListBlockedUsersRow{User: user, BlockedAt: f.CreatedAt})
+		}
+	}
+	slices.SortFunc(rows, func(a, b db.ListBlockedUsersRow) int {
+		if c := b.BlockedAt.Time.Compare(a.BlockedAt.Time); c != 0 {
+			return c
+		}
+		return compareIDs(b.User.ID, a.User.ID)
+	})
+	return limit(rows, arg.PageSize), nil
+}
+
+// blocksBy returns the blocks made by blockerID, oldest first.
+func (r *repo) blocksBy(blockerID uuid.UUID) []db.Friendship {
+	var blocks []db.Friendship
+	for _, f := range r.friendships {
+		if f.Status == statusBlocked && f.RequesterID == blockerID {
+			blocks = append(blocks, f)
+		}
+	}
+	slices.SortFunc(blocks, oldestFirst)
+	return blocks
+}
+
+func (r *repo) EraseUser(ctx context.Context, id uuid.UUID, removed repository.FriendshipEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.removeFriendships(func(f db.Friendship) bool {
+		return f.RequesterID == id || f.AddresseeID == id
+	}, removed)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := r.users[id]; !ok {
+		return false, nil
+	}
+
+	// ON DELETE CASCADE.
+	delete(r.users, id)
+	r.usernameHistory = slices.DeleteFunc(r.usernameHistory, func(h db.UsernameHistory) bool { return h.UserID == id })
+	for eid, e := range r.exports {
+		if e.UserID == id {
+			delete(r.exports, eid)
+			delete(r.sections, eid)
+		}
+	}
+	return true, nil
+}
+
+// Data exports
+
+func (r *repo) ListUsernameHistory(ctx context.Context, userID uuid.UUID) ([]db.UsernameHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var history []db.UsernameHistory
+	for _, h := range r.usernameHistory {
+		if h.UserID == userID {
+			history = append(history, h)
+		}
+	}
+	slices.SortFunc(history, func(a, b db.UsernameHistory) int {
+		if c := b.ReleasedAt.Compare(a.ReleasedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return history, nil
+}
+
+func (r *repo) ExportFriendships(ctx context.Context, userID uuid.UUID) ([]db.ExportFriendshipsRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var friendships []db.Friendship
+	for _, f := range r.friendships {
+		if f.Status != statusBlocked && (f.RequesterID == userID || f.AddresseeID == userID) {
+			friendships = append(friendships, f)
+		}
+	}
+	slices.SortFunc(friendships, oldestFirst)
+
+	var rows []db.ExportFriendshipsRow
+	for _, f := range friendships {
+		sent := f.RequesterID == userID
+		other := r.users[f.RequesterID]
+		if sent {
+			other = r.users[f.AddresseeID]
+		}
+		rows = append(rows, db.ExportFriendshipsRow{
+			ID:            f.ID,
+			Status:        f.Status,
+			CreatedAt:     f.CreatedAt,
+			RespondedAt:   f.RespondedAt,
+			Sent:          sent,
+			OtherUserID:   other.ID,
+			OtherUsername: other.Username,
+		})
+	}
+	return rows, nil
+}
+
+func (r *repo) ExportBlocks(ctx context.Context, blockerID uuid.UUID) ([]db.ExportBlocksRow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rows []db.ExportBlocksRow
+	for _, f := range r.blocksBy(blockerID) {
+		user := r.users[f.AddresseeID]
+		rows = append(rows, db.ExportBlocksRow{ID: user.ID, Username: user.Username, BlockedAt: f.CreatedAt})
+	}
+	return rows, nil
+}
+
+func (r *repo) CreateDataExport(ctx context.Context, arg db.CreateDataExportParams, event db.InsertOutboxEventParams) (db.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.exports[arg.ID]; ok {
+		return db.DataExport{}, uniqueViolation("data_exports_pkey")
+	}
+	if _, ok := r.users[arg.UserID]; !ok {
+		return db.DataExport{}, foreignKeyViolation("data_exports_user_id_fkey")
+	}
+	for _, e := range r.exports {
+		if e.UserID == arg.UserID && e.Status == statusPending {
+			return db.DataExport{}, uniqueViolation("data_exports_pending_idx")
+		}
+	}
+
+	export := db.DataExport{ID: arg.ID, UserID: arg.UserID, Status: statusPending, RequestedAt: r.now()}
+	r.exports[export.ID] = export
+	r.insertOutboxEvent(event)
+	return export, nil
+}
+
+func (r *repo) GetDataExport(ctx context.Context, id uuid.UUID) (db.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	export, ok := r.exports[id]
+	if !ok {
+		return db.DataExport{}, pgx.ErrNoRows
+	}
+	return export, nil
+}
+
+func (r *repo) GetPendingDataExport(ctx context.Context, userID uuid.UUID) (db.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.exports {
+		if e.UserID == userID && (e.Status == statusPending || e.Status == statusAssembling) {
+			return e, nil
+		}
+	}
+	return db.DataExport{}, pgx.ErrNoRows
+}
+
+func (r *repo) SaveDataExportSection(ctx context.Context, arg db.SaveDataExportSectionParams) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	export, ok := r.exports[arg.ExportID]
+	if !ok || export.Status != statusPending || export.UserID != arg.UserID {
+		return false, nil
+	}
+	if arg.Part < 0 || arg.Part >= arg.Parts {
+		return false, &pgconn.PgError{Code: "23514", ConstraintName: "data_export_sections_part_check"}
+	}
+	if r.sections[arg.ExportID] == nil {
+		r.sections[arg.ExportID] = make(map[sectionPart]db.DataExportSection)
+	}
+	r.sections[arg.ExportID][sectionPart{arg.Service, arg.Part}] = db.DataExportSection{
+		ExportID:   arg.ExportID,
+		Service:    arg.Service,
+		Part:       arg.Part,
+		Parts:      arg.Parts,
+		Files:      bytes.Clone(arg.Files),
+		Error:      arg.Error,
+		ReceivedAt: r.now(),
+	}
+	return true, nil
+}
+
+// pendingExports returns the pending exports, oldest first.
+func (r *repo) pendingExports() []db.DataExport {
+	var exports []db.DataExport
+	for _, e := range r.exports {
+		if e.Status == statusPending {
+			exports = append(exports, e)
+		}
+	}
+	slices.SortFunc(exports, func(a, b db.DataExport) int { return a.RequestedAt.Compare(b.RequestedAt) })
+	return exports
+}
+
+// hasAllSections reports whether export has every part of a section from each
+// of services.
+func (r *repo) hasAllSections(export db.DataExport, services []string) bool {
+	for _, service := range services {
+		var received, parts int32
+		for key, s := range r.sections[export.ID] {
+			if key.service == service {
+				received++
+				parts = s.Parts
+			}
+		}
+		if received == 0 || received != parts {
+			return false
+		}
+	}
+	return true
+}
+
+// claimDataExport claims the export AssembleDataExport assembles and returns
+// its sections.
+func (r *repo) claimDataExport(services []string, claimedBefore time.Time, skipped []uuid.UUID) (db.DataExport, []db.DataExportSection, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var candidates []db.DataExport
+	for _, e := range r.exports {
+		if slices.Contains(skipped, e.ID) {
+			continue
+		}
+		stale := e.Status == statusAssembling && e.ClaimedAt.Time.Before(claimedBefore)
+		if stale || (e.Status == statusPending && r.hasAllSections(e, services)) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return db.DataExport{}, nil, false
+	}
+	export := slices.MinFunc(candidates, func(a, b db.DataExport) int { return a.RequestedAt.Compare(b.RequestedAt) })
+	export.Status = statusAssembling
+	export.ClaimedAt = r.timestamp()
+	r.exports[export.ID] = export
+
+	var sections []db.DataExportSection
+	for _, s := range r.sections[export.ID] {
+		sections = append(sections, s)
+	}
+	slices.SortFunc(sections, func(a, b db.DataExportSection) int {
+		return cmp.Or(cmp.Compare(a.Service, b.Service), cmp.Compare(a.Part, b.Part))
+	})
+	return export, sections, true
+}
+
+func (r *repo) AssembleDataExport(ctx context.Context, services []string, claimedBefore time.Time, skipped []uuid.UUID, assemble func(db.DataExport, []db.DataExportSection) (db.CompleteDataExportParams, error)) (bool, error) {
+	claimed, sections, ok := r.claimDataExport(services, claimedBefore, skipped)
+	if !ok {
+		return false, nil
+	}
+
+	arg, err := assemble(claimed, sections)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	export, ok := r.exports[claimed.ID]
+	if !ok || export.Status != statusAssembling || !export.ClaimedAt.Time.Equal(claimed.ClaimedAt.Time) {
+		if err != nil {
+			return true, err
+		}
+		return true, pgx.ErrNoRows
+	}
+	var invalid *repository.InvalidDataExportError
+	if errors.As(err, &invalid) {
+		r.fail(export, invalid.Error())
+		return true, err
+	}
+	if err != nil {
+		export.Status, export.ClaimedAt = statusPending, pgtype.Timestamptz{}
+		r.exports[export.ID] = export
+		return true, err
+	}
+	export.Status = "ready"
+	export.CompletedAt = r.timestamp()
+	export.ExpiresAt, export.BlobKey, export.SizeBytes = arg.ExpiresAt, arg.BlobKey, arg.SizeBytes
+	r.exports[export.ID] = export
+	return true, nil
+}
+
+func (r *repo) FailDataExportsWithSectionErrors(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failed int64
+	for _, export := range r.pendingExports() {
+		var errs []string
+		for _, s := range r.sections[export.ID] {
+			if s.Error.Valid {
+				errs = append(errs, s.Service+": "+s.Error.String)
+			}
+		}
+		if len(errs) == 0 {
+			continue
+		}
+		slices.Sort(errs)
+		r.fail(export, strings.Join(errs, "; "))
+		failed++
+	}
+	return failed, nil
+}
+
+func (r *repo) FailStaleDataExports(ctx context.Context, requestedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failed int64
+	for _, export := range r.pendingExports() {
+		if export.RequestedAt.Before(requestedBefore) {
+			r.fail(export, "timed out waiting for all services")
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (r *repo) fail(export db.DataExport, reason string) {
+	export.Status = "failed"
+	export.CompletedAt = r.timestamp()
+	export.Error = pgtype.Text{String: reason, Valid: true}
+	r.exports[export.ID] = export
+}
+
+func (r *repo) ListExpiredDataExports(ctx context.Context, now time.Time, n int32) ([]db.DataExport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var exports []db.DataExport
+	for _, e := range r.exports {
+		if e.Status == "ready" && e.ExpiresAt.Valid && e.ExpiresAt.Time.Before(now) {
+			exports = append(exports, e)
+		}
+	}
+	slices.SortFunc(exports, func(a, b db.DataExport) int { return a.ExpiresAt.Time.Compare(b.ExpiresAt.Time) })
+	return limit(exports, n), nil
+}
+
+func (r *repo) ExpireDataExport(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if export, ok := r.exports[id]; ok {
+		export.Status = "expired"
+		export.BlobKey = pgtype.Text{}
+		r.exports[id] = export
+	}
+	return nil
+}
+
+func (r *repo) ListDataExportBlobKeys(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []string
+	for _, e := range r.exports {
+		if e.UserID == userID && e.BlobKey.Valid {
+			keys = append(keys, e.BlobKey.String)
+		}
+	}
+	return keys, nil
+}
+
+// Outbox
+
+func (r *repo) insertOutboxEvent(arg db.InsertOutboxEventParams) {
+	r.outbox = append(r.outbox, db.OutboxEvent{
+		ID:         r.nextID(),
+		Exchange:   arg.Exchange,
+		RoutingKey: arg.RoutingKey,
+		Payload:    bytes.Clone(arg.Payload),
+		CreatedAt:  r.now(),
+	})
+}
+
+func (r *repo) PublishOutbox(ctx context.Context, n int32, publish func(db.OutboxEvent) error) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	published := 0
+	for i := range r.outbox {
+		if published == int(n) {
+			break
+		}
+		if r.outbox[i].PublishedAt.Valid {
+			continue
+		}
+		if err := publish(r.outbox[i]); err != nil {
+			return published, err
+		}
+		r.outbox[i].PublishedAt = r.timestamp()
+		published++
+	}
+	return published, nil
+}
+
+func (r *repo) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.outbox)
+	r.outbox = slices.DeleteFunc(r.outbox, func(e db.OutboxEvent) bool {
+		return e.PublishedAt.Valid && e.PublishedAt.Time.Before(before)
+	})
+	return int64(n - len(r.outbox)), nil
+}
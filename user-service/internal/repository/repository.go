@@ -2,31 +2,132 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// FriendshipEvent builds the outbox event announcing a change, from the
+// friendship as it is after the change.
+type FriendshipEvent func(db.Friendship) (db.InsertOutboxEventParams, error)
+
+// InvalidDataExportError is returned by the assemble function of
+// AssembleDataExport for an export that no retry can assemble.
+type InvalidDataExportError struct {
+	Err error
+}
+
+func (e *InvalidDataExportError) Error() string { return e.Err.Error() }
+
+func (e *InvalidDataExportError) Unwrap() error { return e.Err }
+
 type Repository interface {
 	// Users
 	CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (db.User, error)
 	GetUserByEmail(ctx context.Context, email string) (db.User, error)
 	ListUsers(ctx context.Context, arg db.ListUsersParams) ([]db.User, error)
+	SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error)
+	// GetUsersByIDs returns the users among ids that exist, in no particular order.
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]db.User, error)
+	// Profile changes store event in the outbox in the same transaction.
+	SetAvatarKeys(ctx context.Context, arg db.SetAvatarKeysParams, event db.InsertOutboxEventParams) (db.User, error)
+	UpdateProfile(ctx context.Context, arg db.UpdateProfileParams, event db.InsertOutboxEventParams) (db.User, error)
+	IsUsernameTaken(ctx context.Context, arg db.IsUsernameTakenParams) (bool, error)
+	// ChangeUsername renames the user and records the old username in the
+	// history. It returns pgx.ErrNoRows if they changed it after ChangedBefore.
+	ChangeUsername(ctx context.Context, arg db.ChangeUsernameParams, event db.InsertOutboxEventParams) (db.User, error)
 
 	// Friendships
-	SendFriendRequest(ctx context.Context, arg db.SendFriendRequestParams) (db.Friendship, error)
-	AcceptFriendRequest(ctx context.Context, arg db.AcceptFriendRequestParams) error
+	// Changes that take a FriendshipEvent store it in the outbox in the same
+	// transaction.
+	SendFriendRequest(ctx context.Context, arg db.SendFriendRequestParams, event FriendshipEvent) (db.Friendship, error)
+	// ResendFriendRequest replaces the answered request staleID with a new one.
+	ResendFriendRequest(ctx context.Context, staleID uuid.UUID, arg db.SendFriendRequestParams, event FriendshipEvent) (db.Friendship, error)
+	GetFriendRequestByID(ctx context.Context, id uuid.UUID) (db.Friendship, error)
+	GetFriendshipBetween(ctx context.Context, arg db.GetFriendshipBetweenParams) (db.Friendship, error)
+	AcceptFriendRequest(ctx context.Context, id uuid.UUID, event FriendshipEvent) (db.Friendship, error)
+	RejectFriendRequest(ctx context.Context, id uuid.UUID, event FriendshipEvent) (db.Friendship, error)
+	RemoveFriend(ctx context.Context, arg db.RemoveFriendParams, event FriendshipEvent) (db.Friendship, error)
+	CancelFriendRequest(ctx context.Context, arg db.CancelFriendRequestParams) (bool, error)
+	ListIncomingFriendRequests(ctx context.Context, arg db.ListIncomingFriendRequestsParams) ([]db.Friendship, error)
+	ListOutgoingFriendRequests(ctx context.Context, arg db.ListOutgoingFriendRequestsParams) ([]db.Friendship, error)
 	GetFriends(ctx context.Context, requesterID uuid.UUID) ([]db.User, error)
-	RejectFriendRequest(ctx context.Context, arg db.RejectFriendRequestParams) error
+	GetMutualFriends(ctx context.Context, arg db.GetMutualFriendsParams) ([]db.User, error)
+	SuggestFriends(ctx context.Context, arg db.SuggestFriendsParams) ([]db.SuggestFriendsRow, error)
+	AreFriends(ctx context.Context, arg db.AreFriendsParams) (bool, error)
+	HaveMutualFriend(ctx context.Context, arg db.HaveMutualFriendParams) (bool, error)
+
+	// Blocks
+	// BlockUser removes any friendship or pending request between the two users
+	// and records the block. It stores event, and removed for a friendship it
+	// ends, in the outbox in the same transaction. It reports false, storing
+	// nothing, if the block already existed.
+	BlockUser(ctx context.Context, arg db.BlockUserParams, event db.InsertOutboxEventParams, removed FriendshipEvent) (bool, error)
+	// UnblockUser lifts the block and stores event in the outbox in the same
+	// transaction. It reports false, storing nothing, if there was no block.
+	UnblockUser(ctx context.Context, arg db.UnblockUserParams, event db.InsertOutboxEventParams) (bool, error)
+	IsBlockedEitherWay(ctx context.Context, arg db.IsBlockedEitherWayParams) (bool, error)
+	// ListBlockedEitherWayAmong returns the users among Ids that UserID
+	// blocked or was blocked by, in no particular order.
+	ListBlockedEitherWayAmong(ctx context.Context, arg db.ListBlockedEitherWayAmongParams) ([]uuid.UUID, error)
+	ListBlockedUsers(ctx context.Context, arg db.ListBlockedUsersParams) ([]db.ListBlockedUsersRow, error)
+
+	// EraseUser deletes the user and every friendship they are part of, and
+	// announces the end of each friendship with removed in the same transaction.
+	EraseUser(ctx context.Context, id uuid.UUID, removed FriendshipEvent) (bool, error)
+
+	// Data exports
+	// Export* return what the user's data export includes.
+	ListUsernameHistory(ctx context.Context, userID uuid.UUID) ([]db.UsernameHistory, error)
+	ExportFriendships(ctx context.Context, userID uuid.UUID) ([]db.ExportFriendshipsRow, error)
+	ExportBlocks(ctx context.Context, blockerID uuid.UUID) ([]db.ExportBlocksRow, error)
+	// CreateDataExport stores the export and, in the same transaction, the
+	// outbox entry asking other services for their sections.
+	CreateDataExport(ctx context.Context, arg db.CreateDataExportParams, event db.InsertOutboxEventParams) (db.DataExport, error)
+	GetDataExport(ctx context.Context, id uuid.UUID) (db.DataExport, error)
+	GetPendingDataExport(ctx context.Context, userID uuid.UUID) (db.DataExport, error)
+	// SaveDataExportSection reports false if the export is no longer pending
+	// or belongs to another user than arg.UserID.
+	SaveDataExportSection(ctx context.Context, arg db.SaveDataExportSectionParams) (bool, error)
+	// AssembleDataExport claims the oldest pending export that has every part
+	// of a section from every service in services, or one whose claim is older
+	// than claimedBefore, and completes it as assemble says. assemble runs
+	// outside any transaction; the export goes back to pending if it fails, or
+	// is failed if it returns an *InvalidDataExportError. Exports in skipped are
+	// left alone. It reports false if no export was ready, and returns
+	// pgx.ErrNoRows if the export was erased or taken over while being assembled.
+	AssembleDataExport(ctx context.Context, services []string, claimedBefore time.Time, skipped []uuid.UUID, assemble func(db.DataExport, []db.DataExportSection) (db.CompleteDataExportParams, error)) (bool, error)
+	FailDataExportsWithSectionErrors(ctx context.Context) (int64, error)
+	FailStaleDataExports(ctx context.Context, requestedBefore time.Time) (int64, error)
+	ListExpiredDataExports(ctx context.Context, now time.Time, limit int32) ([]db.DataExport, error)
+	ExpireDataExport(ctx context.Context, id uuid.UUID) error
+	ListDataExportBlobKeys(ctx context.Context, userID uuid.UUID) ([]string, error)
+
+	// Outbox
+	// PublishOutbox hands up to limit pending events, oldest first, to publish
+	// and marks those it accepts as published. It stops at the first failure
+	// and returns how many were published. Only one caller publishes at a
+	// time, across replicas; the others return 0 straight away.
+	PublishOutbox(ctx context.Context, limit int32, publish func(db.OutboxEvent) error) (int, error)
+	DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
 }
 
 type repository struct {
-	q *db.Queries
+	pool *pgxpool.Pool
+	q    *db.Queries
 }
 
-func NewRepository(q *db.Queries) Repository {
-	return &repository{q: q}
+func NewRepository(pool *pgxpool.Pool) Repository {
+	return &repository{
+		pool: pool,
+		q:    db.New(pool),
+	}
 }
 
 func (r *repository) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
@@ -45,19 +146,403 @@ func (r *repository) ListUsers(ctx context.Context, arg db.ListUsersParams) ([]d
 	return r.q.ListUsers(ctx, arg)
 }
 
+func (r *repository) SearchUsers(ctx context.Context, arg db.SearchUsersParams) ([]db.SearchUsersRow, error) {
+	return r.q.SearchUsers(ctx, arg)
+}
+
+func (r *repository) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]db.User, error) {
+	return r.q.GetUsersByIDs(ctx, ids)
+}
+
+func (r *repository) SetAvatarKeys(ctx context.Context, arg db.SetAvatarKeysParams, event db.InsertOutboxEventParams) (db.User, error) {
+	var user db.User
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		var err error
+		if user, err = q.SetAvatarKeys(ctx, arg); err != nil {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, event)
+	})
+	return user, err
+}
+
+func (r *repository) UpdateProfile(ctx context.Context, arg db.UpdateProfileParams, event db.InsertOutboxEventParams) (db.User, error) {
+	var user db.User
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		var err error
+		if user, err = q.UpdateProfile(ctx, arg); err != nil {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, event)
+	})
+	return user, err
+}
+
+func (r *repository) IsUsernameTaken(ctx context.Context, arg db.IsUsernameTakenParams) (bool, error) {
+	return r.q.IsUsernameTaken(ctx, arg)
+}
+
+func (r *repository) ChangeUsername(ctx context.Context, arg db.ChangeUsernameParams, event db.InsertOutboxEventParams) (db.User, error) {
+	var user db.User
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		previous, err := q.ChangeUsername(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		err = q.InsertUsernameHistory(ctx, db.InsertUsernameHistoryParams{UserID: arg.UserID, Username: previous})
+		if err != nil {
+			return err
+		}
+		if err := q.InsertOutboxEvent(ctx, event); err != nil {
+			return err
+		}
+
+		user, err = q.GetUserByID(ctx, arg.UserID)
+		return err
+	})
+	return user, err
+}
+
 // Friendships
-func (r *repository) SendFriendRequest(ctx context.Context, arg db.SendFriendRequestParams) (db.Friendship, error) {
-	return r.q.SendFriendRequest(ctx, arg)
+func (r *repository) SendFriendRequest(ctx context.Context, arg db.SendFriendRequestParams, event FriendshipEvent) (db.Friendship, error) {
+	return r.changeFriendship(ctx, event, func(q *db.Queries) (db.Friendship, error) {
+		return q.SendFriendRequest(ctx, arg)
+	})
+}
+
+func (r *repository) ResendFriendRequest(ctx context.Context, staleID uuid.UUID, arg db.SendFriendRequestParams, event FriendshipEvent) (db.Friendship, error) {
+	return r.changeFriendship(ctx, event, func(q *db.Queries) (db.Friendship, error) {
+		if err := q.DeleteFriendship(ctx, staleID); err != nil {
+			return db.Friendship{}, err
+		}
+		return q.SendFriendRequest(ctx, arg)
+	})
 }
 
-func (r *repository) AcceptFriendRequest(ctx context.Context, arg db.AcceptFriendRequestParams) error {
-	return r.q.AcceptFriendRequest(ctx, arg)
+func (r *repository) GetFriendRequestByID(ctx context.Context, id uuid.UUID) (db.Friendship, error) {
+	return r.q.GetFriendRequestByID(ctx, id)
 }
 
-func (r *repository) RejectFriendRequest(ctx context.Context, arg db.RejectFriendRequestParams) error {
-	return r.q.RejectFriendRequest(ctx, arg)
+func (r *repository) GetFriendshipBetween(ctx context.Context, arg db.GetFriendshipBetweenParams) (db.Friendship, error) {
+	return r.q.GetFriendshipBetween(ctx, arg)
+}
+
+func (r *repository) AcceptFriendRequest(ctx context.Context, id uuid.UUID, event FriendshipEvent) (db.Friendship, error) {
+	return r.changeFriendship(ctx, event, func(q *db.Queries) (db.Friendship, error) {
+		return q.AcceptFriendRequest(ctx, id)
+	})
+}
+
+func (r *repository) RejectFriendRequest(ctx context.Context, id uuid.UUID, event FriendshipEvent) (db.Friendship, error) {
+	return r.changeFriendship(ctx, event, func(q *db.Queries) (db.Friendship, error) {
+		return q.RejectFriendRequest(ctx, id)
+	})
+}
+
+func (r *repository) RemoveFriend(ctx context.Context, arg db.RemoveFriendParams, event FriendshipEvent) (db.Friendship, error) {
+	return r.changeFriendship(ctx, event, func(q *db.Queries) (db.Friendship, error) {
+		return q.RemoveFriend(ctx, arg)
+	})
+}
+
+// changeFriendship applies change and stores the event describing it in one
+// transaction.
+func (r *repository) changeFriendship(ctx context.Context, event FriendshipEvent, change func(q *db.Queries) (db.Friendship, error)) (db.Friendship, error) {
+	var friendship db.Friendship
+	err := r.withTxOptions(ctx, serializable, func(q *db.Queries) error {
+		var err error
+		if friendship, err = change(q); err != nil {
+			return err
+		}
+
+		arg, err := event(friendship)
+		if err != nil {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, arg)
+	})
+	return friendship, err
+}
+
+func (r *repository) CancelFriendRequest(ctx context.Context, arg db.CancelFriendRequestParams) (bool, error) {
+	deleted, err := r.q.CancelFriendRequest(ctx, arg)
+	return deleted > 0, err
+}
+
+func (r *repository) ListIncomingFriendRequests(ctx context.Context, arg db.ListIncomingFriendRequestsParams) ([]db.Friendship, error) {
+	return r.q.ListIncomingFriendRequests(ctx, arg)
+}
+
+func (r *repository) ListOutgoingFriendRequests(ctx context.Context, arg db.ListOutgoingFriendRequestsParams) ([]db.Friendship, error) {
+	return r.q.ListOutgoingFriendRequests(ctx, arg)
 }
 
 func (r *repository) GetFriends(ctx context.Context, requesterID uuid.UUID) ([]db.User, error) {
 	return r.q.GetFriends(ctx, requesterID)
 }
+
+func (r *repository) GetMutualFriends(ctx context.Context, arg db.GetMutualFriendsParams) ([]db.User, error) {
+	return r.q.GetMutualFriends(ctx, arg)
+}
+
+func (r *repository) SuggestFriends(ctx context.Context, arg db.SuggestFriendsParams) ([]db.SuggestFriendsRow, error) {
+	return r.q.SuggestFriends(ctx, arg)
+}
+
+// insertRemovedEvents stores removed for each accepted friendship among
+// deleted. Pending requests dropped along the way are not announced, as when
+// they are cancelled.
+func insertRemovedEvents(ctx context.Context, q *db.Queries, deleted []db.Friendship, removed FriendshipEvent) error {
+	for _, f := range deleted {
+		if f.Status != "accepted" {
+			continue
+		}
+		arg, err := removed(f)
+		if err != nil {
+			return err
+		}
+		if err := q.InsertOutboxEvent(ctx, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Blocks
+func (r *repository) BlockUser(ctx context.Context, arg db.BlockUserParams, event db.InsertOutboxEventParams, removed FriendshipEvent) (bool, error) {
+	var inserted int64
+	err := r.withTxOptions(ctx, serializable, func(q *db.Queries) error {
+		deleted, err := q.DeleteFriendshipBetween(ctx, db.DeleteFriendshipBetweenParams{
+			RequesterID: arg.RequesterID,
+			AddresseeID: arg.AddresseeID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := insertRemovedEvents(ctx, q, deleted, removed); err != nil {
+			return err
+		}
+
+		inserted, err = q.BlockUser(ctx, arg)
+		if err != nil || inserted == 0 {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, event)
+	})
+	return inserted > 0, err
+}
+
+func (r *repository) UnblockUser(ctx context.Context, arg db.UnblockUserParams, event db.InsertOutboxEventParams) (bool, error) {
+	var deleted int64
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		var err error
+		deleted, err = q.UnblockUser(ctx, arg)
+		if err != nil || deleted == 0 {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, event)
+	})
+	return deleted > 0, err
+}
+
+func (r *repository) AreFriends(ctx context.Context, arg db.AreFriendsParams) (bool, error) {
+	return r.q.AreFriends(ctx, arg)
+}
+
+func (r *repository) HaveMutualFriend(ctx context.Context, arg db.HaveMutualFriendParams) (bool, error) {
+	return r.q.HaveMutualFriend(ctx, arg)
+}
+
+func (r *repository) IsBlockedEitherWay(ctx context.Context, arg db.IsBlockedEitherWayParams) (bool, error) {
+	return r.q.IsBlockedEitherWay(ctx, arg)
+}
+
+func (r *repository) ListBlockedEitherWayAmong(ctx context.Context, arg db.ListBlockedEitherWayAmongParams) ([]uuid.UUID, error) {
+	return r.q.ListBlockedEitherWayAmong(ctx, arg)
+}
+
+func (r *repository) ListBlockedUsers(ctx context.Context, arg db.ListBlockedUsersParams) ([]db.ListBlockedUsersRow, error) {
+	return r.q.ListBlockedUsers(ctx, arg)
+}
+
+func (r *repository) EraseUser(ctx context.Context, id uuid.UUID, removed FriendshipEvent) (bool, error) {
+	var deleted int64
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		friendships, err := q.DeleteFriendshipsByUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := insertRemovedEvents(ctx, q, friendships, removed); err != nil {
+			return err
+		}
+
+		deleted, err = q.DeleteUser(ctx, id)
+		return err
+	})
+	return deleted > 0, err
+}
+
+// Data exports
+func (r *repository) ListUsernameHistory(ctx context.Context, userID uuid.UUID) ([]db.UsernameHistory, error) {
+	return r.q.ListUsernameHistory(ctx, userID)
+}
+
+func (r *repository) ExportFriendships(ctx context.Context, userID uuid.UUID) ([]db.ExportFriendshipsRow, error) {
+	return r.q.ExportFriendships(ctx, userID)
+}
+
+func (r *repository) ExportBlocks(ctx context.Context, blockerID uuid.UUID) ([]db.ExportBlocksRow, error) {
+	return r.q.ExportBlocks(ctx, blockerID)
+}
+
+func (r *repository) CreateDataExport(ctx context.Context, arg db.CreateDataExportParams, event db.InsertOutboxEventParams) (db.DataExport, error) {
+	var export db.DataExport
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		var err error
+		if export, err = q.CreateDataExport(ctx, arg); err != nil {
+			return err
+		}
+		return q.InsertOutboxEvent(ctx, event)
+	})
+	return export, err
+}
+
+func (r *repository) GetDataExport(ctx context.Context, id uuid.UUID) (db.DataExport, error) {
+	return r.q.GetDataExport(ctx, id)
+}
+
+func (r *repository) GetPendingDataExport(ctx context.Context, userID uuid.UUID) (db.DataExport, error) {
+	return r.q.GetPendingDataExport(ctx, userID)
+}
+
+func (r *repository) SaveDataExportSection(ctx context.Context, arg db.SaveDataExportSectionParams) (bool, error) {
+	saved, err := r.q.SaveDataExportSection(ctx, arg)
+	return saved > 0, err
+}
+
+func (r *repository) AssembleDataExport(ctx context.Context, services []string, claimedBefore time.Time, skipped []uuid.UUID, assemble func(db.DataExport, []db.DataExportSection) (db.CompleteDataExportParams, error)) (bool, error) {
+	var export db.DataExport
+	var sections []db.DataExportSection
+	err := r.withTx(ctx, func(q *db.Queries) error {
+		var err error
+		export, err = q.ClaimCompleteDataExport(ctx, db.ClaimCompleteDataExportParams{
+			Skipped:       skipped,
+			Services:      services,
+			ClaimedBefore: pgtype.Timestamptz{Time: claimedBefore, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		sections, err = q.ListDataExportSections(ctx, export.ID)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	arg, err := assemble(export, sections)
+	var invalid *InvalidDataExportError
+	if errors.As(err, &invalid) {
+		fail := db.FailDataExportParams{
+			ID:        export.ID,
+			Error:     pgtype.Text{String: invalid.Error(), Valid: true},
+			ClaimedAt: export.ClaimedAt,
+		}
+		if failErr := r.q.FailDataExport(context.WithoutCancel(ctx), fail); failErr != nil {
+			// The claim expires and the export is retried instead.
+			return true, errors.Join(err, failErr)
+		}
+		return true, err
+	}
+	if err != nil {
+		release := db.ReleaseDataExportParams{ID: export.ID, ClaimedAt: export.ClaimedAt}
+		if releaseErr := r.q.ReleaseDataExport(context.WithoutCancel(ctx), release); releaseErr != nil {
+			// The claim expires instead.
+			return true, errors.Join(err, releaseErr)
+		}
+		return true, err
+	}
+
+	arg.ID, arg.ClaimedAt = export.ID, export.ClaimedAt
+	completed, err := r.q.CompleteDataExport(ctx, arg)
+	if err == nil && completed == 0 {
+		err = pgx.ErrNoRows
+	}
+	return true, err
+}
+
+func (r *repository) FailDataExportsWithSectionErrors(ctx context.Context) (int64, error) {
+	return r.q.FailDataExportsWithSectionErrors(ctx)
+}
+
+func (r *repository) FailStaleDataExports(ctx context.Context, requestedBefore time.Time) (int64, error) {
+	return r.q.FailStaleDataExports(ctx, requestedBefore)
+}
+
+func (r *repository) ListExpiredDataExports(ctx context.Context, now time.Time, limit int32) ([]db.DataExport, error) {
+	return r.q.ListExpiredDataExports(ctx, db.ListExpiredDataExportsParams{
+		ExpiresAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Limit:     limit,
+	})
+}
+
+func (r *repository) ExpireDataExport(ctx context.Context, id uuid.UUID) error {
+	return r.q.ExpireDataExport(ctx, id)
+}
+
+func (r *repository) ListDataExportBlobKeys(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return r.q.ListDataExportBlobKeys(ctx, userID)
+}
+
+// Outbox
+
+// PublishOutbox runs outside any transaction, so that none stays open while
+// the broker is slow: order is kept by a session advisory lock held on a
+// connection of its own. An event published but not marked because of a crash
+// is published again, which consumers tolerate thanks to the message id.
+func (r *repository) PublishOutbox(ctx context.Context, limit int32, publish func(db.OutboxEvent) error) (int, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+	q := db.New(conn)
+
+	locked, err := q.TryLockOutbox(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+	defer func() {
+		if _, err := q.UnlockOutbox(context.WithoutCancel(ctx)); err != nil {
+			// Closing the connection is the only other way to release the
+			// lock; the pool then drops it.
+			conn.Conn().Close(context.WithoutCancel(ctx))
+		}
+	}()
+
+	events, err := q.ListUnpublishedOutboxEvents(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		if err := publish(event); err != nil {
+			// The rest is retried later.
+			return published, err
+		}
+		if err := q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+func (r *repository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeletePublishedOutboxEvents(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
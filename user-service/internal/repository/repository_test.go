@@ -0,0 +1,694 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/pgtest"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/migrations"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository/memory"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Run(m)) }
+
+// forEachRepository runs test against the sqlc repository and against the
+// in-memory fake the service tests use, so that the two stay in agreement.
+// The Postgres run is skipped when no server is available.
+func forEachRepository(t *testing.T, test func(t *testing.T, ctx context.Context, repo repository.Repository)) {
+	t.Run("postgres", func(t *testing.T) {
+		test(t, context.Background(), repository.NewRepository(pgtest.New(t, migrations.FS)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, context.Background(), memory.NewRepository(nil))
+	})
+}
+
+func createUser(t *testing.T, ctx context.Context, repo repository.Repository, username string) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	_, err := repo.CreateUser(ctx, db.CreateUserParams{ID: id, Email: username + "@example.com", Username: username})
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", username, err)
+	}
+	return id
+}
+
+// event is the outbox entry stored with each change; payloads are JSONB.
+var event = db.InsertOutboxEventParams{Exchange: "user_events", RoutingKey: "test", Payload: []byte(`{}`)}
+
+func friendshipEvent(f db.Friendship) (db.InsertOutboxEventParams, error) {
+	payload, err := json.Marshal(map[string]string{"id": f.ID.String(), "status": f.Status})
+	if err != nil {
+		return db.InsertOutboxEventParams{}, err
+	}
+	return db.InsertOutboxEventParams{Exchange: "user_events", RoutingKey: "friendship." + f.Status, Payload: payload}, nil
+}
+
+// removedEvent announces a friendship ended by a block or an erasure.
+func removedEvent(f db.Friendship) (db.InsertOutboxEventParams, error) {
+	arg, err := friendshipEvent(f)
+	arg.RoutingKey = "friendship.removed"
+	return arg, err
+}
+
+// publishedKeys publishes the pending outbox events and returns their routing
+// keys.
+func publishedKeys(t *testing.T, ctx context.Context, repo repository.Repository) []string {
+	t.Helper()
+	var keys []string
+	_, err := repo.PublishOutbox(ctx, 100, func(e db.OutboxEvent) error {
+		keys = append(keys, e.RoutingKey)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	return keys
+}
+
+func sendRequest(t *testing.T, ctx context.Context, repo repository.Repository, from, to uuid.UUID) db.Friendship {
+	t.Helper()
+	f, err := repo.SendFriendRequest(ctx, db.SendFriendRequestParams{ID: uuid.New(), RequesterID: from, AddresseeID: to}, friendshipEvent)
+	if err != nil {
+		t.Fatalf("SendFriendRequest: %v", err)
+	}
+	return f
+}
+
+func befriend(t *testing.T, ctx context.Context, repo repository.Repository, a, b uuid.UUID) {
+	t.Helper()
+	f := sendRequest(t, ctx, repo, a, b)
+	if _, err := repo.AcceptFriendRequest(ctx, f.ID, friendshipEvent); err != nil {
+		t.Fatalf("AcceptFriendRequest: %v", err)
+	}
+}
+
+func areFriends(t *testing.T, ctx context.Context, repo repository.Repository, a, b uuid.UUID) bool {
+	t.Helper()
+	friends, err := repo.AreFriends(ctx, db.AreFriendsParams{RequesterID: a, AddresseeID: b})
+	if err != nil {
+		t.Fatalf("AreFriends: %v", err)
+	}
+	return friends
+}
+
+func wantUniqueViolation(t *testing.T, err error, constraint string) {
+	t.Helper()
+	var pgErr *pgconn.PgError
+	if !stdErrors.As(err, &pgErr) || pgErr.Code != "23505" || pgErr.ConstraintName != constraint {
+		t.Fatalf("error = %v, want a unique violation of %s", err, constraint)
+	}
+}
+
+func userIDs(users []db.User) []uuid.UUID {
+	ids := make([]uuid.UUID, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func TestUsers(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "Alice")
+		bob := createUser(t, ctx, repo, "bob")
+
+		user, err := repo.GetUserByEmail(ctx, "Alice@example.com")
+		if err != nil {
+			t.Fatalf("GetUserByEmail: %v", err)
+		}
+		if user.ID != alice || user.FriendRequestPolicy != "everyone" || user.EmailVisibility != "friends" || !user.Searchable {
+			t.Errorf("user = %+v, want %s with the default settings", user, alice)
+		}
+		if _, err := repo.GetUserByID(ctx, uuid.New()); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetUserByID of unknown id: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+
+		users, err := repo.GetUsersByIDs(ctx, []uuid.UUID{alice, bob, uuid.New()})
+		if err != nil {
+			t.Fatalf("GetUsersByIDs: %v", err)
+		}
+		if ids := userIDs(users); len(ids) != 2 || !slices.Contains(ids, alice) || !slices.Contains(ids, bob) {
+			t.Errorf("GetUsersByIDs = %v, want %s and %s", ids, alice, bob)
+		}
+
+		_, err = repo.CreateUser(ctx, db.CreateUserParams{ID: uuid.New(), Email: "other@example.com", Username: "ALICE"})
+		wantUniqueViolation(t, err, "users_username_lower_idx")
+		_, err = repo.CreateUser(ctx, db.CreateUserParams{ID: uuid.New(), Email: "bob@example.com", Username: "robert"})
+		wantUniqueViolation(t, err, "users_email_key")
+	})
+}
+
+func TestChangeUsername(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		start := time.Now().Add(-time.Hour)
+
+		user, err := repo.ChangeUsername(ctx, db.ChangeUsernameParams{Username: "alicia", UserID: alice, ChangedBefore: time.Now()}, event)
+		if err != nil {
+			t.Fatalf("ChangeUsername: %v", err)
+		}
+		if user.Username != "alicia" || !user.UsernameChangedAt.Valid {
+			t.Errorf("user after rename = %+v, want alicia with the change time", user)
+		}
+		_, err = repo.ChangeUsername(ctx, db.ChangeUsernameParams{Username: "ally", UserID: alice, ChangedBefore: start}, event)
+		if !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("second ChangeUsername within the cooldown: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+
+		history, err := repo.ListUsernameHistory(ctx, alice)
+		if err != nil {
+			t.Fatalf("ListUsernameHistory: %v", err)
+		}
+		if len(history) != 1 || history[0].Username != "alice" {
+			t.Errorf("username history = %+v, want alice", history)
+		}
+
+		tests := []struct {
+			name string
+			arg  db.IsUsernameTakenParams
+			want bool
+		}{
+			{name: "held by another user", arg: db.IsUsernameTakenParams{Username: "ALICIA", UserID: bob, HeldSince: start}, want: true},
+			{name: "held by the user", arg: db.IsUsernameTakenParams{Username: "alicia", UserID: alice, HeldSince: start}},
+			{name: "recently released", arg: db.IsUsernameTakenParams{Username: "Alice", UserID: bob, HeldSince: start}, want: true},
+			{name: "released long enough ago", arg: db.IsUsernameTakenParams{Username: "alice", UserID: bob, HeldSince: time.Now().Add(time.Hour)}},
+			{name: "released by the user", arg: db.IsUsernameTakenParams{Username: "alice", UserID: alice, HeldSince: start}},
+			{name: "free", arg: db.IsUsernameTakenParams{Username: "carol", UserID: bob, HeldSince: start}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				taken, err := repo.IsUsernameTaken(ctx, tt.arg)
+				if err != nil {
+					t.Fatalf("IsUsernameTaken: %v", err)
+				}
+				if taken != tt.want {
+					t.Errorf("IsUsernameTaken = %v, want %v", taken, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestFriendRequests(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		carol := createUser(t, ctx, repo, "carol")
+
+		request := sendRequest(t, ctx, repo, alice, bob)
+		if request.Status != "pending" || !request.CreatedAt.Valid {
+			t.Errorf("new request = %+v, want pending with a creation time", request)
+		}
+		_, err := repo.SendFriendRequest(ctx, db.SendFriendRequestParams{ID: uuid.New(), RequesterID: bob, AddresseeID: alice}, friendshipEvent)
+		wantUniqueViolation(t, err, "friendships_pair_idx")
+		_, err = repo.SendFriendRequest(ctx, db.SendFriendRequestParams{ID: uuid.New(), RequesterID: alice, AddresseeID: bob}, friendshipEvent)
+		wantUniqueViolation(t, err, "friendships_requester_id_addressee_id_key")
+
+		between, err := repo.GetFriendshipBetween(ctx, db.GetFriendshipBetweenParams{RequesterID: bob, AddresseeID: alice})
+		if err != nil || between.ID != request.ID {
+			t.Errorf("GetFriendshipBetween = %+v, %v, want %s", between, err, request.ID)
+		}
+
+		sendRequest(t, ctx, repo, carol, bob)
+		incoming, err := repo.ListIncomingFriendRequests(ctx, db.ListIncomingFriendRequestsParams{UserID: bob, PageSize: 10})
+		if err != nil {
+			t.Fatalf("ListIncomingFriendRequests: %v", err)
+		}
+		if len(incoming) != 2 {
+			t.Errorf("ListIncomingFriendRequests returned %d requests, want 2", len(incoming))
+		}
+
+		accepted, err := repo.AcceptFriendRequest(ctx, request.ID, friendshipEvent)
+		if err != nil {
+			t.Fatalf("AcceptFriendRequest: %v", err)
+		}
+		if accepted.Status != "accepted" || !accepted.RespondedAt.Valid {
+			t.Errorf("accepted request = %+v, want accepted with a response time", accepted)
+		}
+		if _, err := repo.AcceptFriendRequest(ctx, request.ID, friendshipEvent); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("second AcceptFriendRequest: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if !areFriends(t, ctx, repo, bob, alice) {
+			t.Error("AreFriends = false after accepting")
+		}
+
+		friends, err := repo.GetFriends(ctx, alice)
+		if err != nil || !slices.Equal(userIDs(friends), []uuid.UUID{bob}) {
+			t.Errorf("GetFriends = %v, %v, want %s", userIDs(friends), err, bob)
+		}
+
+		if _, err := repo.RemoveFriend(ctx, db.RemoveFriendParams{RequesterID: bob, AddresseeID: alice}, friendshipEvent); err != nil {
+			t.Fatalf("RemoveFriend: %v", err)
+		}
+		if areFriends(t, ctx, repo, alice, bob) {
+			t.Error("AreFriends = true after removing")
+		}
+		if _, err := repo.RemoveFriend(ctx, db.RemoveFriendParams{RequesterID: bob, AddresseeID: alice}, friendshipEvent); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("second RemoveFriend: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+
+		pending, err := repo.GetFriendshipBetween(ctx, db.GetFriendshipBetweenParams{RequesterID: carol, AddresseeID: bob})
+		if err != nil {
+			t.Fatalf("GetFriendshipBetween: %v", err)
+		}
+		if cancelled, err := repo.CancelFriendRequest(ctx, db.CancelFriendRequestParams{ID: pending.ID, RequesterID: bob}); err != nil || cancelled {
+			t.Errorf("CancelFriendRequest by the addressee = %v, %v, want false", cancelled, err)
+		}
+		if cancelled, err := repo.CancelFriendRequest(ctx, db.CancelFriendRequestParams{ID: pending.ID, RequesterID: carol}); err != nil || !cancelled {
+			t.Errorf("CancelFriendRequest = %v, %v, want true", cancelled, err)
+		}
+	})
+}
+
+func TestFriendshipEventRollsBack(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		failing := func(db.Friendship) (db.InsertOutboxEventParams, error) {
+			return db.InsertOutboxEventParams{}, stdErrors.New("no event")
+		}
+
+		_, err := repo.SendFriendRequest(ctx, db.SendFriendRequestParams{ID: uuid.New(), RequesterID: alice, AddresseeID: bob}, failing)
+		if err == nil {
+			t.Fatal("SendFriendRequest succeeded though its event failed")
+		}
+		if _, err := repo.GetFriendshipBetween(ctx, db.GetFriendshipBetweenParams{RequesterID: alice, AddresseeID: bob}); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetFriendshipBetween after the failed request: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+		if published, err := repo.PublishOutbox(ctx, 10, func(db.OutboxEvent) error { return nil }); err != nil || published != 0 {
+			t.Errorf("PublishOutbox = %d, %v, want nothing to publish", published, err)
+		}
+	})
+}
+
+func TestBlocks(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		befriend(t, ctx, repo, alice, bob)
+		publishedKeys(t, ctx, repo)
+		blockEvent := db.InsertOutboxEventParams{Exchange: "user_events", RoutingKey: "user.blocked", Payload: []byte(`{}`)}
+		unblockEvent := db.InsertOutboxEventParams{Exchange: "user_events", RoutingKey: "user.unblocked", Payload: []byte(`{}`)}
+
+		if blocked, err := repo.BlockUser(ctx, db.BlockUserParams{ID: uuid.New(), RequesterID: bob, AddresseeID: alice}, blockEvent, removedEvent); err != nil || !blocked {
+			t.Fatalf("BlockUser = %v, %v, want true", blocked, err)
+		}
+		if keys, want := publishedKeys(t, ctx, repo), []string{"friendship.removed", "user.blocked"}; !slices.Equal(keys, want) {
+			t.Errorf("events after blocking a friend = %v, want %v", keys, want)
+		}
+		if blocked, err := repo.BlockUser(ctx, db.BlockUserParams{ID: uuid.New(), RequesterID: bob, AddresseeID: alice}, blockEvent, removedEvent); err != nil || blocked {
+			t.Errorf("second BlockUser = %v, %v, want false", blocked, err)
+		}
+		if keys := publishedKeys(t, ctx, repo); len(keys) != 0 {
+			t.Errorf("events after blocking again = %v, want none", keys)
+		}
+		if areFriends(t, ctx, repo, alice, bob) {
+			t.Error("AreFriends = true after blocking")
+		}
+		if blocked, err := repo.IsBlockedEitherWay(ctx, db.IsBlockedEitherWayParams{RequesterID: alice, AddresseeID: bob}); err != nil || !blocked {
+			t.Errorf("IsBlockedEitherWay = %v, %v, want true", blocked, err)
+		}
+		if _, err := repo.GetFriendshipBetween(ctx, db.GetFriendshipBetweenParams{RequesterID: alice, AddresseeID: bob}); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetFriendshipBetween ignores blocks: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+
+		// The block does not take the pair's place in friendships_pair_idx.
+		request := sendRequest(t, ctx, repo, alice, bob)
+		if cancelled, err := repo.CancelFriendRequest(ctx, db.CancelFriendRequestParams{ID: request.ID, RequesterID: alice}); err != nil || !cancelled {
+			t.Fatalf("CancelFriendRequest = %v, %v, want true", cancelled, err)
+		}
+		publishedKeys(t, ctx, repo)
+
+		if unblocked, err := repo.UnblockUser(ctx, db.UnblockUserParams{RequesterID: alice, AddresseeID: bob}, unblockEvent); err != nil || unblocked {
+			t.Errorf("UnblockUser by the blocked user = %v, %v, want false", unblocked, err)
+		}
+		if unblocked, err := repo.UnblockUser(ctx, db.UnblockUserParams{RequesterID: bob, AddresseeID: alice}, unblockEvent); err != nil || !unblocked {
+			t.Errorf("UnblockUser = %v, %v, want true", unblocked, err)
+		}
+		if keys, want := publishedKeys(t, ctx, repo), []string{"user.unblocked"}; !slices.Equal(keys, want) {
+			t.Errorf("events after unblocking = %v, want %v", keys, want)
+		}
+		if blocked, err := repo.IsBlockedEitherWay(ctx, db.IsBlockedEitherWayParams{RequesterID: bob, AddresseeID: alice}); err != nil || blocked {
+			t.Errorf("IsBlockedEitherWay after unblock = %v, %v, want false", blocked, err)
+		}
+	})
+}
+
+func TestSocialGraph(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		carol := createUser(t, ctx, repo, "carol")
+		dave := createUser(t, ctx, repo, "dave")
+		erin := createUser(t, ctx, repo, "erin")
+		// alice and dave share bob and carol; erin is only a friend of bob.
+		befriend(t, ctx, repo, alice, bob)
+		befriend(t, ctx, repo, alice, carol)
+		befriend(t, ctx, repo, bob, dave)
+		befriend(t, ctx, repo, carol, dave)
+		befriend(t, ctx, repo, erin, bob)
+
+		mutual, err := repo.GetMutualFriends(ctx, db.GetMutualFriendsParams{CallerID: alice, UserA: alice, UserB: dave, PageSize: 10})
+		if err != nil {
+			t.Fatalf("GetMutualFriends: %v", err)
+		}
+		if !slices.Equal(userIDs(mutual), []uuid.UUID{bob, carol}) {
+			t.Errorf("GetMutualFriends = %v, want bob and carol by username", userIDs(mutual))
+		}
+		page, err := repo.GetMutualFriends(ctx, db.GetMutualFriendsParams{
+			CallerID: alice, UserA: alice, UserB: dave, PageSize: 10,
+			CursorUsername: pgtype.Text{String: "bob", Valid: true}, CursorID: uuid.NullUUID{UUID: bob, Valid: true},
+		})
+		if err != nil || !slices.Equal(userIDs(page), []uuid.UUID{carol}) {
+			t.Errorf("GetMutualFriends after bob = %v, %v, want carol", userIDs(page), err)
+		}
+		if have, err := repo.HaveMutualFriend(ctx, db.HaveMutualFriendParams{UserA: alice, UserB: erin}); err != nil || !have {
+			t.Errorf("HaveMutualFriend = %v, %v, want true", have, err)
+		}
+
+		suggestions, err := repo.SuggestFriends(ctx, db.SuggestFriendsParams{UserID: alice, PageSize: 10, FriendSample: 10, FanOut: 10})
+		if err != nil {
+			t.Fatalf("SuggestFriends: %v", err)
+		}
+		if len(suggestions) != 2 || suggestions[0].User.ID != dave || suggestions[0].MutualFriends != 2 ||
+			suggestions[1].User.ID != erin || suggestions[1].MutualFriends != 1 {
+			t.Errorf("SuggestFriends = %+v, want dave with 2 mutual friends then erin with 1", suggestions)
+		}
+
+		sampled, err := repo.SuggestFriends(ctx, db.SuggestFriendsParams{UserID: alice, PageSize: 10, FriendSample: 1, FanOut: 10})
+		if err != nil {
+			t.Fatalf("SuggestFriends sampling one friend: %v", err)
+		}
+		for _, s := range sampled {
+			if s.MutualFriends != 1 {
+				t.Errorf("SuggestFriends sampling one friend counted %d mutual friends for %s, want 1", s.MutualFriends, s.User.Username)
+			}
+		}
+
+		for _, arg := range []db.UpdateProfileParams{
+			{ID: dave, Searchable: pgtype.Bool{Bool: false, Valid: true}},
+			{ID: erin, FriendRequestPolicy: pgtype.Text{String: "nobody", Valid: true}},
+		} {
+			if _, err := repo.UpdateProfile(ctx, arg, event); err != nil {
+				t.Fatalf("UpdateProfile: %v", err)
+			}
+			hidden, err := repo.SuggestFriends(ctx, db.SuggestFriendsParams{UserID: alice, PageSize: 10, FriendSample: 10, FanOut: 10})
+			if err != nil {
+				t.Fatalf("SuggestFriends: %v", err)
+			}
+			for _, s := range hidden {
+				if s.User.ID == arg.ID {
+					t.Errorf("SuggestFriends suggested %s after UpdateProfile(%+v)", s.User.Username, arg)
+				}
+			}
+			restore := db.UpdateProfileParams{ID: arg.ID, Searchable: pgtype.Bool{Bool: true, Valid: true}, FriendRequestPolicy: pgtype.Text{String: "everyone", Valid: true}}
+			if _, err := repo.UpdateProfile(ctx, restore, event); err != nil {
+				t.Fatalf("UpdateProfile: %v", err)
+			}
+		}
+
+		sendRequest(t, ctx, repo, dave, alice)
+		suggestions, err = repo.SuggestFriends(ctx, db.SuggestFriendsParams{UserID: alice, PageSize: 10, FriendSample: 10, FanOut: 10})
+		if err != nil || len(suggestions) != 1 || suggestions[0].User.ID != erin {
+			t.Errorf("SuggestFriends with a pending request from dave = %+v, %v, want only erin", suggestions, err)
+		}
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		friend := createUser(t, ctx, repo, "samantha")
+		stranger := createUser(t, ctx, repo, "samuel")
+		blocker := createUser(t, ctx, repo, "sammy")
+		createUser(t, ctx, repo, "zed")
+		befriend(t, ctx, repo, alice, friend)
+		if _, err := repo.BlockUser(ctx, db.BlockUserParams{ID: uuid.New(), RequesterID: blocker, AddresseeID: alice}, event, removedEvent); err != nil {
+			t.Fatalf("BlockUser: %v", err)
+		}
+
+		rows, err := repo.SearchUsers(ctx, db.SearchUsersParams{CallerID: alice, Keyword: "sam", PrefixPattern: "sam%", PageSize: 10})
+		if err != nil {
+			t.Fatalf("SearchUsers: %v", err)
+		}
+		var ids []uuid.UUID
+		for _, row := range rows {
+			ids = append(ids, row.User.ID)
+		}
+		// The friend is boosted above the stranger; the blocker is left out.
+		if !slices.Equal(ids, []uuid.UUID{friend, stranger}) {
+			t.Fatalf("SearchUsers = %v, want %s then %s", ids, friend, stranger)
+		}
+		if !rows[0].IsFriend || rows[1].IsFriend || rows[0].Score <= rows[1].Score {
+			t.Errorf("SearchUsers rows = %+v, want the friend first and flagged", rows)
+		}
+
+		page, err := repo.SearchUsers(ctx, db.SearchUsersParams{
+			CallerID: alice, Keyword: "sam", PrefixPattern: "sam%", PageSize: 10,
+			CursorScore: pgtype.Float8{Float64: rows[0].Score, Valid: true}, CursorID: uuid.NullUUID{UUID: friend, Valid: true},
+		})
+		if err != nil || len(page) != 1 || page[0].User.ID != stranger {
+			t.Errorf("SearchUsers after the first row = %+v, %v, want only %s", page, err, stranger)
+		}
+
+		// Emails are only found by an exact match, and only when visible.
+		public, private := uuid.New(), uuid.New()
+		for _, arg := range []db.CreateUserParams{
+			{ID: public, Email: "quincy.public@example.com", Username: "zoe"},
+			{ID: private, Email: "quincy.private@example.com", Username: "yan"},
+		} {
+			if _, err := repo.CreateUser(ctx, arg); err != nil {
+				t.Fatalf("CreateUser(%q): %v", arg.Username, err)
+			}
+		}
+		visible := db.UpdateProfileParams{ID: public, EmailVisibility: pgtype.Text{String: "everyone", Valid: true}}
+		if _, err := repo.UpdateProfile(ctx, visible, event); err != nil {
+			t.Fatalf("UpdateProfile: %v", err)
+		}
+		for keyword, want := range map[string][]uuid.UUID{
+			"Quincy.Public@example.com":  {public},
+			"quincy.private@example.com": nil,
+			"quincy":                     nil,
+		} {
+			rows, err := repo.SearchUsers(ctx, db.SearchUsersParams{CallerID: alice, Keyword: keyword, PrefixPattern: keyword + "%", PageSize: 10})
+			if err != nil {
+				t.Fatalf("SearchUsers(%q): %v", keyword, err)
+			}
+			var ids []uuid.UUID
+			for _, row := range rows {
+				ids = append(ids, row.User.ID)
+			}
+			if !slices.Equal(ids, want) {
+				t.Errorf("SearchUsers(%q) = %v, want %v", keyword, ids, want)
+			}
+		}
+	})
+}
+
+func TestOutbox(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+		bob := createUser(t, ctx, repo, "bob")
+		befriend(t, ctx, repo, alice, bob)
+
+		var keys []string
+		publish := func(e db.OutboxEvent) error {
+			if len(keys) == 1 {
+				return stdErrors.New("broker unavailable")
+			}
+			keys = append(keys, e.RoutingKey)
+			return nil
+		}
+		if published, err := repo.PublishOutbox(ctx, 10, publish); published != 1 || err == nil {
+			t.Errorf("PublishOutbox with a failing broker = %d, %v, want 1 and the error", published, err)
+		}
+
+		publish = func(e db.OutboxEvent) error {
+			keys = append(keys, e.RoutingKey)
+			return nil
+		}
+		if published, err := repo.PublishOutbox(ctx, 10, publish); err != nil || published != 1 {
+			t.Errorf("PublishOutbox = %d, %v, want the remaining event", published, err)
+		}
+		if want := []string{"friendship.pending", "friendship.accepted"}; !slices.Equal(keys, want) {
+			t.Errorf("published routing keys = %v, want %v", keys, want)
+		}
+
+		if deleted, err := repo.DeletePublishedOutboxEvents(ctx, time.Now().Add(time.Minute)); err != nil || deleted != 2 {
+			t.Errorf("DeletePublishedOutboxEvents = %d, %v, want 2", deleted, err)
+		}
+	})
+}
+
+func TestDataExports(t *testing.T) {
+	services := []string{"auth", "user"}
+
+	forEachRepository(t, func(t *testing.T, ctx context.Context, repo repository.Repository) {
+		alice := createUser(t, ctx, repo, "alice")
+
+		export, err := repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: uuid.New(), UserID: alice}, event)
+		if err != nil {
+			t.Fatalf("CreateDataExport: %v", err)
+		}
+		if export.Status != "pending" {
+			t.Errorf("new export status = %q, want pending", export.Status)
+		}
+		_, err = repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: uuid.New(), UserID: alice}, event)
+		wantUniqueViolation(t, err, "data_exports_pending_idx")
+		if pending, err := repo.GetPendingDataExport(ctx, alice); err != nil || pending.ID != export.ID {
+			t.Errorf("GetPendingDataExport = %+v, %v, want %s", pending, err, export.ID)
+		}
+
+		// auth sends its section in two parts.
+		const wantSections = 3
+		assemble := func(e db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+			if len(sections) != wantSections {
+				t.Errorf("assemble got %d sections, want %d", len(sections), wantSections)
+			}
+			return db.CompleteDataExportParams{
+				ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+				BlobKey:   pgtype.Text{String: "exports/" + e.ID.String(), Valid: true},
+				SizeBytes: pgtype.Int8{Int64: 42, Valid: true},
+			}, nil
+		}
+		fresh := time.Now().Add(-time.Hour)
+		save := func(service string, part, parts int32) {
+			t.Helper()
+			saved, err := repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+				ExportID: export.ID, UserID: alice, Service: service, Part: part, Parts: parts, Files: []byte(`{"account.json":"x"}`),
+			})
+			if err != nil || !saved {
+				t.Fatalf("SaveDataExportSection(%s, %d/%d) = %v, %v, want true", service, part, parts, saved, err)
+			}
+		}
+
+		save("user", 0, 1)
+		save("auth", 0, 2)
+		if assembled, err := repo.AssembleDataExport(ctx, services, fresh, nil, assemble); err != nil || assembled {
+			t.Errorf("AssembleDataExport with a part missing = %v, %v, want false", assembled, err)
+		}
+		saved, err := repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+			ExportID: export.ID, UserID: uuid.New(), Service: "auth", Part: 1, Parts: 2, Files: []byte(`{}`),
+		})
+		if err != nil || saved {
+			t.Errorf("SaveDataExportSection for another user = %v, %v, want false", saved, err)
+		}
+		save("auth", 1, 2)
+
+		boom := stdErrors.New("boom")
+		failing := func(e db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+			if pending, err := repo.GetPendingDataExport(ctx, alice); err != nil || pending.Status != "assembling" {
+				t.Errorf("GetPendingDataExport while assembling = %+v, %v, want assembling", pending, err)
+			}
+			if assembled, err := repo.AssembleDataExport(ctx, services, fresh, nil, assemble); err != nil || assembled {
+				t.Errorf("AssembleDataExport of a claimed export = %v, %v, want false", assembled, err)
+			}
+			return db.CompleteDataExportParams{}, boom
+		}
+		if assembled, err := repo.AssembleDataExport(ctx, services, fresh, nil, failing); !stdErrors.Is(err, boom) || !assembled {
+			t.Errorf("failed AssembleDataExport = %v, %v, want true, %v", assembled, err, boom)
+		}
+		if pending, err := repo.GetPendingDataExport(ctx, alice); err != nil || pending.Status != "pending" {
+			t.Errorf("export after a failed assembly = %+v, %v, want pending", pending, err)
+		}
+		if assembled, err := repo.AssembleDataExport(ctx, services, fresh, []uuid.UUID{export.ID}, assemble); err != nil || assembled {
+			t.Errorf("AssembleDataExport of a skipped export = %v, %v, want false", assembled, err)
+		}
+
+		// An assembler whose claim was taken over does not complete the export.
+		takenOver := func(e db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+			if assembled, err := repo.AssembleDataExport(ctx, services, time.Now().Add(time.Hour), nil, assemble); err != nil || !assembled {
+				t.Errorf("AssembleDataExport of a stale claim = %v, %v, want true", assembled, err)
+			}
+			return assemble(e, sections)
+		}
+		if assembled, err := repo.AssembleDataExport(ctx, services, fresh, nil, takenOver); !stdErrors.Is(err, pgx.ErrNoRows) || !assembled {
+			t.Fatalf("AssembleDataExport taken over = %v, %v, want true, %v", assembled, err, pgx.ErrNoRows)
+		}
+
+		ready, err := repo.GetDataExport(ctx, export.ID)
+		if err != nil {
+			t.Fatalf("GetDataExport: %v", err)
+		}
+		if ready.Status != "ready" || !ready.CompletedAt.Valid || ready.SizeBytes.Int64 != 42 {
+			t.Errorf("assembled export = %+v, want ready", ready)
+		}
+		if saved, err := repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{ExportID: export.ID, UserID: alice, Service: "auth", Parts: 1, Files: []byte(`{}`)}); err != nil || saved {
+			t.Errorf("SaveDataExportSection for a ready export = %v, %v, want false", saved, err)
+		}
+		if keys, err := repo.ListDataExportBlobKeys(ctx, alice); err != nil || !slices.Equal(keys, []string{"exports/" + export.ID.String()}) {
+			t.Errorf("ListDataExportBlobKeys = %v, %v", keys, err)
+		}
+
+		expired, err := repo.ListExpiredDataExports(ctx, time.Now(), 10)
+		if err != nil || len(expired) != 1 || expired[0].ID != export.ID {
+			t.Fatalf("ListExpiredDataExports = %+v, %v, want %s", expired, err, export.ID)
+		}
+		if err := repo.ExpireDataExport(ctx, export.ID); err != nil {
+			t.Fatalf("ExpireDataExport: %v", err)
+		}
+		if keys, err := repo.ListDataExportBlobKeys(ctx, alice); err != nil || len(keys) != 0 {
+			t.Errorf("ListDataExportBlobKeys after expiry = %v, %v, want none", keys, err)
+		}
+
+		failed, err := repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: uuid.New(), UserID: alice}, event)
+		if err != nil {
+			t.Fatalf("CreateDataExport after the first completed: %v", err)
+		}
+		_, err = repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+			ExportID: failed.ID, UserID: alice, Service: "auth", Parts: 1, Files: []byte(`{}`), Error: pgtype.Text{String: "boom", Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("SaveDataExportSection: %v", err)
+		}
+		if n, err := repo.FailDataExportsWithSectionErrors(ctx); err != nil || n != 1 {
+			t.Errorf("FailDataExportsWithSectionErrors = %d, %v, want 1", n, err)
+		}
+		if got, err := repo.GetDataExport(ctx, failed.ID); err != nil || got.Status != "failed" || got.Error.String != "auth: boom" {
+			t.Errorf("failed export = %+v, %v, want failed with the section error", got, err)
+		}
+
+		invalid, err := repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: uuid.New(), UserID: alice}, event)
+		if err != nil {
+			t.Fatalf("CreateDataExport after the second failed: %v", err)
+		}
+		for _, service := range services {
+			saved, err := repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+				ExportID: invalid.ID, UserID: alice, Service: service, Parts: 1, Files: []byte(`{}`),
+			})
+			if err != nil || !saved {
+				t.Fatalf("SaveDataExportSection(%s) = %v, %v, want true", service, saved, err)
+			}
+		}
+		bad := &repository.InvalidDataExportError{Err: stdErrors.New("auth/account.json: bad JSON")}
+		rejecting := func(e db.DataExport, sections []db.DataExportSection) (db.CompleteDataExportParams, error) {
+			return db.CompleteDataExportParams{}, bad
+		}
+		if assembled, err := repo.AssembleDataExport(ctx, services, fresh, nil, rejecting); !stdErrors.Is(err, bad) || !assembled {
+			t.Errorf("AssembleDataExport of an invalid export = %v, %v, want true, %v", assembled, err, bad)
+		}
+		if got, err := repo.GetDataExport(ctx, invalid.ID); err != nil || got.Status != "failed" || got.Error.String != bad.Error() {
+			t.Errorf("invalid export = %+v, %v, want failed with %q", got, err, bad.Error())
+		}
+
+		if erased, err := repo.EraseUser(ctx, alice, removedEvent); err != nil || !erased {
+			t.Errorf("EraseUser = %v, %v, want true", erased, err)
+		}
+		if _, err := repo.GetDataExport(ctx, export.ID); !stdErrors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("GetDataExport after erasing the user: error = %v, want %v", err, pgx.ErrNoRows)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// maxTxAttempts bounds how many times a transaction is run when it keeps
+// conflicting with concurrent ones.
+const maxTxAttempts = 5
+
+// serializable is for transactions that read what they are about to change.
+var serializable = pgx.TxOptions{IsoLevel: pgx.Serializable}
+
+// withTx runs fn as a unit of work: its queries share one read committed
+// transaction, committed if fn succeeds and rolled back otherwise.
+func (r *repository) withTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	return r.withTxOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// withTxOptions is withTx with the given transaction options. When Postgres
+// aborts the transaction on a serialization failure or deadlock, fn is run
+// again from the start in a new one, so it must only have effects through q
+// and must reset whatever it records for its caller.
+func (r *repository) withTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(q *db.Queries) error) error {
+	for attempt := 1; ; attempt++ {
+		err := pgx.BeginTxFunc(ctx, r.pool, opts, func(tx pgx.Tx) error {
+			return fn(r.q.WithTx(tx))
+		})
+		if err == nil || !retryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		logger.FromContext(ctx).Warn("transaction conflicted, retrying", zap.Int("attempt", attempt), zap.Error(err))
+		backoff := time.Duration(attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// retryable reports whether err aborted a transaction that may succeed if run again.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/utils/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+func (s *service) RequestDataExport(ctx context.Context, userID uuid.UUID) (*db.DataExport, error) {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	export, err := s.repo.GetPendingDataExport(ctx, userID)
+	if err == nil {
+		return &export, nil
+	}
+	if !stdErrors.Is(err, pgx.ErrNoRows) {
+		logger.FromContext(ctx).Error("error loading pending data export", zap.Error(err))
+		return nil, err
+	}
+
+	id := uuid.New()
+	payload, err := json.Marshal(mq.ExportRequestedEvent{ExportID: id, UserID: userID, RequestedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	export, err = s.repo.CreateDataExport(ctx, db.CreateDataExportParams{ID: id, UserID: userID}, db.InsertOutboxEventParams{
+		Exchange:   mq.UserEventsExchange,
+		RoutingKey: mq.ExportRequested,
+		Payload:    payload,
+	})
+	if isUniqueViolation(err, "data_exports_pending_idx") {
+		// A concurrent request got in first.
+		export, err = s.repo.GetPendingDataExport(ctx, userID)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error requesting data export", zap.Error(err))
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("data export requested", zap.String("export_id", export.ID.String()))
+	return &export, nil
+}
+
+func (s *service) GetDataExport(ctx context.Context, callerID, exportID uuid.UUID) (*db.DataExport, string, error) {
+	export, err := s.repo.GetDataExport(ctx, exportID)
+	if stdErrors.Is(err, pgx.ErrNoRows) || (err == nil && export.UserID != callerID) {
+		return nil, "", errors.ErrDataExportNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading data export", zap.Error(err))
+		return nil, "", err
+	}
+	if export.Status != "ready" || !export.BlobKey.Valid {
+		return &export, "", nil
+	}
+
+	expires := time.Now().Add(s.opts.ExportLinkTTL)
+	if export.ExpiresAt.Valid && export.ExpiresAt.Time.Before(expires) {
+		expires = export.ExpiresAt.Time
+	}
+	url, err := s.exports.SignedURL(export.BlobKey.String, expires)
+	if err != nil {
+		logger.FromContext(ctx).Error("error signing data export url", zap.Error(err))
+		return nil, "", err
+	}
+	return &export, url, nil
+}
+
+func (s *service) SaveExportSection(ctx context.Context, event mq.ExportSectionEvent) error {
+	files, err := json.Marshal(event.Files)
+	if err != nil {
+		return err
+	}
+	if event.Files == nil {
+		files = []byte("{}")
+	}
+
+	parts := max(event.Parts, 1)
+	if event.Part < 0 || event.Part >= parts {
+		// Retrying would not help.
+		logger.FromContext(ctx).Warn("dropping data export section with an invalid part number",
+			zap.String("export_id", event.ExportID.String()), zap.String("service", event.Service),
+			zap.Int32("part", event.Part), zap.Int32("parts", event.Parts))
+		return nil
+	}
+
+	saved, err := s.repo.SaveDataExportSection(ctx, db.SaveDataExportSectionParams{
+		ExportID: event.ExportID,
+		UserID:   event.UserID,
+		Service:  event.Service,
+		Part:     event.Part,
+		Parts:    parts,
+		Files:    files,
+		Error:    pgtype.Text{String: event.Error, Valid: event.Error != ""},
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("error saving data export section", zap.Error(err))
+		return err
+	}
+	if !saved {
+		// Timed out, erased or redelivered after completion, or sent with the
+		// wrong user: the data of one user never goes into another's export.
+		logger.FromContext(ctx).Warn("rejected data export section for an export no longer pending or of another user",
+			zap.String("export_id", event.ExportID.String()), zap.String("user_id", event.UserID.String()), zap.String("service", event.Service))
+	}
+	return nil
+}
+
+// Documents of the user-service section of data exports.
+type (
+	exportedProfile struct {
+		ID            uuid.UUID       `json:"id"`
+		Email         string          `json:"email"`
+		Username      string          `json:"username"`
+		DisplayName   string          `json:"display_name"`
+		Bio           string          `json:"bio"`
+		StatusMessage string          `json:"status_message"`
+		Timezone      string          `json:"timezone"`
+		Locale        string          `json:"locale"`
+		CreatedAt     *time.Time      `json:"created_at,omitempty"`
+		Privacy       exportedPrivacy `json:"privacy"`
+		// Avatar maps the avatar variants to their file in the archive.
+		Avatar map[string]string `json:"avatar,omitempty"`
+	}
+	exportedPrivacy struct {
+		EmailVisibility     string `json:"email_visibility"`
+		FriendRequestPolicy string `json:"friend_request_policy"`
+		Searchable          bool   `json:"searchable"`
+	}
+	exportedUsername struct {
+		Username   string    `json:"username"`
+		ReleasedAt time.Time `json:"released_at"`
+	}
+	exportedFriendship struct {
+		ID            uuid.UUID  `json:"id"`
+		Status        string     `json:"status"`
+		Direction     string     `json:"direction"`
+		OtherUserID   uuid.UUID  `json:"other_user_id"`
+		OtherUsername string     `json:"other_username"`
+		CreatedAt     *time.Time `json:"created_at,omitempty"`
+		RespondedAt   *time.Time `json:"responded_at,omitempty"`
+	}
+	exportedBlock struct {
+		UserID    uuid.UUID  `json:"user_id"`
+		Username  string     `json:"username"`
+		BlockedAt *time.Time `json:"blocked_at,omitempty"`
+	}
+)
+
+func (s *service) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string][]byte, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+
+	profile := exportedProfile{
+		ID:            user.ID,
+		Email:         user.Email,
+		Username:      user.Username,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		StatusMessage: user.StatusMessage,
+		Timezone:      user.Timezone,
+		Locale:        user.Locale,
+		CreatedAt:     nullTime(user.CreatedAt),
+		Privacy: exportedPrivacy{
+			EmailVisibility:     user.EmailVisibility,
+			FriendRequestPolicy: user.FriendRequestPolicy,
+			Searchable:          user.Searchable,
+		},
+	}
+	for variant, key := range avatarKeys(user.AvatarKeys) {
+		name := "avatar/" + variant + path.Ext(key)
+		data, err := s.readBlob(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+		if profile.Avatar == nil {
+			profile.Avatar = map[string]string{}
+		}
+		profile.Avatar[variant] = name
+	}
+
+	history, err := s.repo.ListUsernameHistory(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading username history", zap.Error(err))
+		return nil, err
+	}
+	usernames := make([]exportedUsername, 0, len(history))
+	for _, h := range history {
+		usernames = append(usernames, exportedUsername{Username: h.Username, ReleasedAt: h.ReleasedAt})
+	}
+
+	rows, err := s.repo.ExportFriendships(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading friendships", zap.Error(err))
+		return nil, err
+	}
+	friendships := make([]exportedFriendship, 0, len(rows))
+	for _, f := range rows {
+		direction := "received"
+		if f.Sent {
+			direction = "sent"
+		}
+		friendships = append(friendships, exportedFriendship{
+			ID:            f.ID,
+			Status:        f.Status,
+			Direction:     direction,
+			OtherUserID:   f.OtherUserID,
+			OtherUsername: f.OtherUsername,
+			CreatedAt:     nullTime(f.CreatedAt),
+			RespondedAt:   nullTime(f.RespondedAt),
+		})
+	}
+
+	blockRows, err := s.repo.ExportBlocks(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading blocks", zap.Error(err))
+		return nil, err
+	}
+	blocks := make([]exportedBlock, 0, len(blockRows))
+	for _, b := range blockRows {
+		blocks = append(blocks, exportedBlock{UserID: b.ID, Username: b.Username, BlockedAt: nullTime(b.BlockedAt)})
+	}
+
+	for name, doc := range map[string]any{
+		"profile.json":          profile,
+		"username_history.json": usernames,
+		"friendships.json":      friendships,
+		"blocks.json":           blocks,
+	} {
+		if files[name], err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (s *service) readBlob(ctx context.Context, key string) ([]byte, error) {
+	r, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Error("error reading blob", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func nullTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
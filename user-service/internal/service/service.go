@@ -1,63 +1,907 @@
 package service
 
 import (
+	"bytes"
 	"context"
-	"database/sql"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/avatar"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/cache"
 	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/mq"
 	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/username"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/utils/errors"
+	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
 )
 
 type Service interface {
+	CreateUser(ctx context.Context, input model.CreateUserInput) (*db.User, error)
+	// GetProfile returns userID and how callerID relates to them. Users who
+	// blocked the caller, or were blocked by them, are reported as not found.
+	GetProfile(ctx context.Context, callerID, userID uuid.UUID) (*db.User, model.Relation, error)
+	// UpdateProfile changes the profile fields and privacy settings that are set.
+	UpdateProfile(ctx context.Context, input model.UpdateProfileInput) (*db.User, error)
+	// ChangeUsername renames userID, at most once per UsernameChangeInterval.
+	// The old username stays held for UsernameQuarantine.
+	ChangeUsername(ctx context.Context, userID uuid.UUID, name string) (*db.User, error)
+	// CheckUsernameAvailable returns nil if callerID could take name, or why
+	// not. callerID is uuid.Nil for someone who has no account yet.
+	CheckUsernameAvailable(ctx context.Context, callerID uuid.UUID, name string) error
+	// BatchGetProfiles returns the users of ids in the same order, with nil for
+	// those that do not exist or are blocked either way with callerID, as
+	// GetProfile does. Profiles are served from an in-process cache kept fresh
+	// by InvalidateProfile.
+	BatchGetProfiles(ctx context.Context, callerID uuid.UUID, ids []uuid.UUID) ([]*db.User, error)
+	// InvalidateProfile drops the cached profile of a user who changed or was deleted.
+	InvalidateProfile(ctx context.Context, event mq.ProfileUpdatedEvent) error
+	// UploadAvatar validates the image read from r, stores its variants and
+	// makes them the avatar of userID, replacing the previous ones.
+	UploadAvatar(ctx context.Context, userID uuid.UUID, r io.Reader) (*db.User, error)
+	// SendFriendRequest asks to to become friends with from, if to's
+	// friend_request_policy allows it. If to already asked from, that request is
+	// accepted instead and returned.
+	SendFriendRequest(ctx context.Context, from, to uuid.UUID) (*db.Friendship, error)
+	// RespondToFriendRequest accepts or rejects a pending request addressed to callerID.
+	RespondToFriendRequest(ctx context.Context, callerID, requestID uuid.UUID, accept bool) (*db.Friendship, error)
+	// RemoveFriend ends the friendship between userID and friendID.
+	RemoveFriend(ctx context.Context, userID, friendID uuid.UUID) error
+	// CancelFriendRequest withdraws a pending request sent by callerID.
+	CancelFriendRequest(ctx context.Context, callerID, requestID uuid.UUID) error
+	ListFriendRequests(ctx context.Context, input model.ListFriendRequestsInput) ([]db.Friendship, *model.TimeCursor, error)
+	GetFriends(ctx context.Context, userID uuid.UUID) ([]db.User, error)
+	// GetMutualFriends returns one page of the friends UserA and UserB share.
+	// The caller must be one of them.
+	GetMutualFriends(ctx context.Context, input model.MutualFriendsInput) ([]db.User, *model.NameCursor, error)
+	// SuggestFriends returns up to limit friends of friends, most mutual friends
+	// first. Only a sample of the user's friends is looked at, see Options.
+	SuggestFriends(ctx context.Context, userID uuid.UUID, limit int) ([]db.SuggestFriendsRow, error)
+	// SearchUsers returns one page of users matching the keyword, best matches
+	// first, and the cursor of the next page if there is one. Users who are not
+	// searchable only show up for their friends.
+	SearchUsers(ctx context.Context, input model.SearchUsersInput) ([]db.SearchUsersRow, *model.SearchCursor, error)
+
+	// BlockUser ends any friendship between the two users and stops them from
+	// seeing or befriending each other. Blocking twice is not an error.
+	BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	ListBlockedUsers(ctx context.Context, input model.ListBlockedUsersInput) ([]db.ListBlockedUsersRow, *model.TimeCursor, error)
+
+	// EraseUser removes everything user-service holds about a deleted account.
+	EraseUser(ctx context.Context, userID uuid.UUID) error
+
+	// RequestDataExport starts a personal data export for userID, or returns
+	// the one already in progress.
+	RequestDataExport(ctx context.Context, userID uuid.UUID) (*db.DataExport, error)
+	// GetDataExport returns an export of callerID and, once it is ready, a
+	// download URL valid for ExportLinkTTL.
+	GetDataExport(ctx context.Context, callerID, exportID uuid.UUID) (*db.DataExport, string, error)
+	// SaveExportSection stores the section another service contributed to an export.
+	SaveExportSection(ctx context.Context, event mq.ExportSectionEvent) error
+	// ExportUserData returns the user-service section of an export of userID,
+	// as file contents by name.
+	ExportUserData(ctx context.Context, userID uuid.UUID) (map[string][]byte, error)
+}
+
+// Options are the tunables of the service.
+type Options struct {
+	// FriendRequestCooldown is how long a rejected requester must wait to ask again.
+	FriendRequestCooldown time.Duration
+	AvatarMaxBytes        int64
+	// UsernameChangeInterval is the minimum time between two username changes.
+	UsernameChangeInterval time.Duration
+	// UsernameQuarantine is how long a released username is held from others.
+	UsernameQuarantine time.Duration
+	// ExportLinkTTL is how long data export download links are valid.
+	ExportLinkTTL time.Duration
+	// SuggestFriendsSample and SuggestFriendsFanOut bound the friends of
+	// friends SuggestFriends ranks: how many friends are sampled, and how many
+	// friends of each are counted.
+	SuggestFriendsSample int
+	SuggestFriendsFanOut int
+	// BatchGetProfilesMax is the most users BatchGetProfiles takes at once.
+	BatchGetProfilesMax int
+	ProfileCacheSize    int
+	// ProfileCacheTTL bounds how stale a cached profile can get if an
+	// invalidation is missed.
+	ProfileCacheTTL time.Duration
 }
 
 type service struct {
-	repo repository.Repository
+	repo  repository.Repository
+	blobs storage.BlobStore
+	// exports holds the data export archives, which are not public.
+	exports  storage.BlobStore
+	profiles *cache.LRU[uuid.UUID, db.User]
+	opts     Options
 }
 
-func NewSercice(repo repository.Repository) Service {
-	return &service{repo: repo}
+func NewSercice(repo repository.Repository, blobs, exports storage.BlobStore, opts Options) Service {
+	return &service{
+		repo:     repo,
+		blobs:    blobs,
+		exports:  exports,
+		profiles: cache.NewLRU[uuid.UUID, db.User](opts.ProfileCacheSize, opts.ProfileCacheTTL),
+		opts:     opts,
+	}
 }
 
 func (s *service) CreateUser(ctx context.Context, input model.CreateUserInput) (*db.User, error) {
+	id := uuid.New()
+	if err := s.CheckUsernameAvailable(ctx, id, input.Username); err != nil {
+		return nil, err
+	}
+
 	arg := db.CreateUserParams{
-		ID:       uuid.New(),
+		ID:       id,
 		Email:    input.Email,
 		Username: input.Username,
-		Avatar:   sql.NullString{String: input.Avatar, Valid: true},
+		Avatar:   pgtype.Text{String: input.Avatar, Valid: true},
 	}
 	user, err := s.repo.CreateUser(ctx, arg)
+	if isUniqueViolation(err, "users_username_lower_idx") {
+		return nil, errors.ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *service) ChangeUsername(ctx context.Context, userID uuid.UUID, name string) (*db.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Username == name {
+		return &user, nil
+	}
+	if err := s.CheckUsernameAvailable(ctx, userID, name); err != nil {
+		return nil, err
+	}
+	if user.UsernameChangedAt.Valid && time.Since(user.UsernameChangedAt.Time) < s.opts.UsernameChangeInterval {
+		return nil, errors.ErrUsernameChangeTooSoon
+	}
+
+	event, err := profileEvent(userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.repo.ChangeUsername(ctx, db.ChangeUsernameParams{
+		UserID:        userID,
+		Username:      name,
+		ChangedBefore: time.Now().Add(-s.opts.UsernameChangeInterval),
+	}, event)
+	switch {
+	case stdErrors.Is(err, pgx.ErrNoRows):
+		// Another change got in first.
+		return nil, errors.ErrUsernameChangeTooSoon
+	case isUniqueViolation(err, "users_username_lower_idx"):
+		return nil, errors.ErrUsernameTaken
+	case err != nil:
+		logger.FromContext(ctx).Error("error changing username", zap.Error(err))
+		return nil, err
+	}
+
+	s.profiles.Invalidate(userID)
+	logger.FromContext(ctx).Info("username changed")
+	return &user, nil
+}
+
+func (s *service) CheckUsernameAvailable(ctx context.Context, callerID uuid.UUID, name string) error {
+	err := username.Validate(name)
+	if stdErrors.Is(err, username.ErrReserved) {
+		return errors.ErrUsernameReserved
+	}
+	if err != nil {
+		return errors.InvalidField("username", err.Error())
+	}
+
+	taken, err := s.repo.IsUsernameTaken(ctx, db.IsUsernameTakenParams{
+		Username:  name,
+		UserID:    callerID,
+		HeldSince: time.Now().Add(-s.opts.UsernameQuarantine),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("error checking username", zap.Error(err))
+		return err
+	}
+	if taken {
+		return errors.ErrUsernameTaken
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a violation of the unique constraint
+// or index named constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return stdErrors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+func (s *service) GetProfile(ctx context.Context, callerID, userID uuid.UUID) (*db.User, model.Relation, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, model.RelationStranger, err
+	}
+	if callerID == userID {
+		return &user, model.RelationSelf, nil
+	}
+
+	if err := s.ensureNotBlocked(ctx, callerID, userID, errors.ErrUserNotFound); err != nil {
+		return nil, model.RelationStranger, err
+	}
+	friends, err := s.repo.AreFriends(ctx, db.AreFriendsParams{RequesterID: callerID, AddresseeID: userID})
+	if err != nil {
+		logger.FromContext(ctx).Error("error checking friendship", zap.Error(err))
+		return nil, model.RelationStranger, err
+	}
+	if friends {
+		return &user, model.RelationFriend, nil
+	}
+	return &user, model.RelationStranger, nil
+}
+
+func (s *service) UpdateProfile(ctx context.Context, input model.UpdateProfileInput) (*db.User, error) {
+	if err := validator.New().Struct(input); err != nil {
+		return nil, err
+	}
+
+	event, err := profileEvent(input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.UpdateProfile(ctx, db.UpdateProfileParams{
+		ID:                  input.UserID,
+		DisplayName:         nullString(input.DisplayName),
+		Bio:                 nullString(input.Bio),
+		StatusMessage:       nullString(input.StatusMessage),
+		Timezone:            nullString(input.Timezone),
+		Locale:              nullString(input.Locale),
+		EmailVisibility:     nullString(input.EmailVisibility),
+		FriendRequestPolicy: nullString(input.FriendRequestPolicy),
+		Searchable:          nullBool(input.Searchable),
+	}, event)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error updating profile", zap.Error(err))
+		return nil, err
+	}
+	s.profiles.Invalidate(input.UserID)
+	return &user, nil
+}
+
+// profileEvent returns the outbox entry announcing a profile change of userID.
+func profileEvent(userID uuid.UUID) (db.InsertOutboxEventParams, error) {
+	return userEvent(mq.ProfileUpdated, mq.ProfileUpdatedEvent{UserID: userID, UpdatedAt: time.Now().UTC()})
+}
+
+// userEvent returns the outbox entry publishing event on user.events with
+// routingKey.
+func userEvent(routingKey string, event any) (db.InsertOutboxEventParams, error) {
+	payload, err := json.Marshal(event)
+	return db.InsertOutboxEventParams{
+		Exchange:   mq.UserEventsExchange,
+		RoutingKey: routingKey,
+		Payload:    payload,
+	}, err
+}
+
+func (s *service) BatchGetProfiles(ctx context.Context, callerID uuid.UUID, ids []uuid.UUID) ([]*db.User, error) {
+	if len(ids) > s.opts.BatchGetProfilesMax {
+		return nil, errors.InvalidField("user_ids", fmt.Sprintf("must have at most %d entries", s.opts.BatchGetProfilesMax))
+	}
+
+	found := make(map[uuid.UUID]db.User, len(ids))
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		if user, ok := s.profiles.Get(id); ok {
+			found[id] = user
+		} else if !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		version := s.profiles.Version()
+		users, err := s.repo.GetUsersByIDs(ctx, missing)
+		if err != nil {
+			logger.FromContext(ctx).Error("error loading users", zap.Error(err))
+			return nil, err
+		}
+		loaded := make(map[uuid.UUID]db.User, len(users))
+		for _, user := range users {
+			loaded[user.ID] = user
+			found[user.ID] = user
+		}
+		s.profiles.Add(version, loaded)
+	}
+
+	// Blocks are checked on every call: the cache is shared by all callers.
+	others := make([]uuid.UUID, 0, len(found))
+	for id := range found {
+		if id != callerID {
+			others = append(others, id)
+		}
+	}
+	if len(others) > 0 {
+		blocked, err := s.repo.ListBlockedEitherWayAmong(ctx, db.ListBlockedEitherWayAmongParams{UserID: callerID, Ids: others})
+		if err != nil {
+			logger.FromContext(ctx).Error("error checking blocks", zap.Error(err))
+			return nil, err
+		}
+		for _, id := range blocked {
+			delete(found, id)
+		}
+	}
+
+	profiles := make([]*db.User, len(ids))
+	for i, id := range ids {
+		if user, ok := found[id]; ok {
+			profiles[i] = &user
+		}
+	}
+	return profiles, nil
+}
+
+func (s *service) InvalidateProfile(ctx context.Context, event mq.ProfileUpdatedEvent) error {
+	s.profiles.Invalidate(event.UserID)
+	return nil
+}
+
+func nullString(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func nullBool(b *bool) pgtype.Bool {
+	if b == nil {
+		return pgtype.Bool{}
+	}
+	return pgtype.Bool{Bool: *b, Valid: true}
+}
+
+func (s *service) UploadAvatar(ctx context.Context, userID uuid.UUID, r io.Reader) (*db.User, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.opts.AvatarMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.opts.AvatarMaxBytes {
+		return nil, errors.ErrAvatarTooLarge
+	}
+
+	variants, err := avatar.Process(data)
+	switch {
+	case stdErrors.Is(err, avatar.ErrUnsupportedFormat):
+		return nil, errors.ErrAvatarUnsupportedFormat
+	case stdErrors.Is(err, avatar.ErrDimensionsTooLarge):
+		return nil, errors.ErrAvatarTooLarge
+	case stdErrors.Is(err, avatar.ErrInvalidImage):
+		return nil, errors.ErrAvatarInvalid
+	case err != nil:
+		logger.FromContext(ctx).Error("error processing avatar", zap.Error(err))
+		return nil, err
+	}
+
+	user, err := s.getUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
+	oldKeys := avatarKeys(user.AvatarKeys)
+
+	// Each upload gets its own keys so cached variants never go stale.
+	uploadID := uuid.New()
+	keys := make(map[string]string, len(variants))
+	for _, v := range variants {
+		key := fmt.Sprintf("avatars/%s/%s/%s.%s", userID, uploadID, v.Name, v.Ext)
+		if err := s.blobs.Put(ctx, key, bytes.NewReader(v.Data)); err != nil {
+			logger.FromContext(ctx).Error("error storing avatar", zap.String("key", key), zap.Error(err))
+			s.deleteBlobs(ctx, keys)
+			return nil, err
+		}
+		keys[v.Name] = key
+	}
+
+	encoded, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	event, err := profileEvent(userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err = s.repo.SetAvatarKeys(ctx, db.SetAvatarKeysParams{ID: userID, AvatarKeys: encoded}, event)
+	if err != nil {
+		logger.FromContext(ctx).Error("error saving avatar", zap.Error(err))
+		s.deleteBlobs(ctx, keys)
+		return nil, err
+	}
+	s.profiles.Invalidate(userID)
+
+	s.deleteBlobs(ctx, oldKeys)
+	logger.FromContext(ctx).Info("avatar uploaded", zap.String("upload_id", uploadID.String()))
 	return &user, nil
 }
 
-func (s *service) SendFriendRequest(ctx context.Context, from, to uuid.UUID) error {
-	_, err := s.repo.SendFriendRequest(ctx, db.SendFriendRequestParams{
+// avatarKeys decodes the avatar_keys column, tolerating bad data.
+func avatarKeys(raw json.RawMessage) map[string]string {
+	var keys map[string]string
+	_ = json.Unmarshal(raw, &keys)
+	return keys
+}
+
+// deleteBlobs removes blobs that are no longer referenced. Failures only leave
+// garbage behind so they are logged, not returned.
+func (s *service) deleteBlobs(ctx context.Context, keys map[string]string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func (s *service) SendFriendRequest(ctx context.Context, from, to uuid.UUID) (*db.Friendship, error) {
+	if from == to {
+		return nil, errors.InvalidField("to_user_id", "cannot send a friend request to yourself")
+	}
+	if err := s.ensureNotBlocked(ctx, from, to, errors.ErrFriendRequestNotAllowed); err != nil {
+		return nil, err
+	}
+	target, err := s.getUser(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.SendFriendRequestParams{
 		ID:          uuid.New(),
 		RequesterID: from,
 		AddresseeID: to,
-	})
+	}
+
+	existing, err := s.repo.GetFriendshipBetween(ctx, db.GetFriendshipBetweenParams{RequesterID: from, AddresseeID: to})
+	switch {
+	case stdErrors.Is(err, pgx.ErrNoRows):
+		if err := s.ensureFriendRequestAllowed(ctx, from, target); err != nil {
+			return nil, err
+		}
+		friendship, err := s.repo.SendFriendRequest(ctx, arg, friendEvent(mq.FriendRequested, from))
+		return s.sentFriendRequest(ctx, friendship, err)
+	case err != nil:
+		logger.FromContext(ctx).Error("error loading friendship", zap.Error(err))
+		return nil, err
+	}
+
+	switch existing.Status {
+	case "accepted":
+		return nil, errors.ErrAlreadyFriends
+	case "pending":
+		if existing.RequesterID == from {
+			return nil, errors.ErrFriendRequestExists
+		}
+		// Both want it: accept theirs rather than leaving two requests around.
+		return s.respond(ctx, existing.ID, from, true)
+	default: // rejected
+		if existing.RequesterID == from && existing.RespondedAt.Valid &&
+			time.Since(existing.RespondedAt.Time) < s.opts.FriendRequestCooldown {
+			return nil, errors.ErrFriendRequestCooldown
+		}
+		if err := s.ensureFriendRequestAllowed(ctx, from, target); err != nil {
+			return nil, err
+		}
+		friendship, err := s.repo.ResendFriendRequest(ctx, existing.ID, arg, friendEvent(mq.FriendRequested, from))
+		return s.sentFriendRequest(ctx, friendship, err)
+	}
+}
+
+// ensureFriendRequestAllowed applies the friend_request_policy of target to a
+// request from from.
+func (s *service) ensureFriendRequestAllowed(ctx context.Context, from uuid.UUID, target db.User) error {
+	switch target.FriendRequestPolicy {
+	case model.FriendRequestsFromNobody:
+		return errors.ErrFriendRequestNotAllowed
+	case model.FriendRequestsFromFriendsOfFriends:
+		mutual, err := s.repo.HaveMutualFriend(ctx, db.HaveMutualFriendParams{UserA: from, UserB: target.ID})
+		if err != nil {
+			logger.FromContext(ctx).Error("error checking mutual friends", zap.Error(err))
+			return err
+		}
+		if !mutual {
+			return errors.ErrFriendRequestNotAllowed
+		}
+	}
+	return nil
+}
+
+// sentFriendRequest maps the outcome of storing a new friend request.
+func (s *service) sentFriendRequest(ctx context.Context, friendship db.Friendship, err error) (*db.Friendship, error) {
+	var pgErr *pgconn.PgError
+	if stdErrors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// Lost a race with another request between the same users.
+		return nil, errors.ErrFriendRequestExists
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error sending friend request", zap.Error(err))
+		return nil, err
+	}
+	return &friendship, nil
+}
+
+func (s *service) RespondToFriendRequest(ctx context.Context, callerID, requestID uuid.UUID, accept bool) (*db.Friendship, error) {
+	request, err := s.repo.GetFriendRequestByID(ctx, requestID)
+	if stdErrors.Is(err, pgx.ErrNoRows) || (err == nil && request.AddresseeID != callerID) {
+		// Requests of other users are not disclosed.
+		return nil, errors.ErrFriendRequestNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading friend request", zap.Error(err))
+		return nil, err
+	}
+	if request.Status != "pending" {
+		return nil, errors.ErrFriendRequestNotPending
+	}
+
+	return s.respond(ctx, requestID, callerID, accept)
+}
+
+func (s *service) respond(ctx context.Context, requestID, actorID uuid.UUID, accept bool) (*db.Friendship, error) {
+	respond, event := s.repo.RejectFriendRequest, friendEvent(mq.FriendRejected, actorID)
+	if accept {
+		respond, event = s.repo.AcceptFriendRequest, friendEvent(mq.FriendAccepted, actorID)
+	}
+
+	friendship, err := respond(ctx, requestID, event)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		// Answered, cancelled or torn down by a block in the meantime.
+		return nil, errors.ErrFriendRequestNotPending
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error responding to friend request", zap.Error(err))
+		return nil, err
+	}
+	return &friendship, nil
+}
+
+func (s *service) RemoveFriend(ctx context.Context, userID, friendID uuid.UUID) error {
+	_, err := s.repo.RemoveFriend(ctx, db.RemoveFriendParams{RequesterID: userID, AddresseeID: friendID}, friendEvent(mq.FriendRemoved, userID))
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return errors.ErrFriendshipNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error removing friend", zap.Error(err))
+	}
 	return err
 }
 
-func (s *service) AcceptFriendRequest(ctx context.Context, arg db.AcceptFriendRequestParams) error {
-	return s.repo.AcceptFriendRequest(ctx, db.AcceptFriendRequestParams{
-		RequesterID: arg.RequesterID,
-		AddresseeID: arg.AddresseeID,
-	})
+// friendEvent returns the outbox entry announcing a friendship change made by actorID.
+func friendEvent(routingKey string, actorID uuid.UUID) repository.FriendshipEvent {
+	return func(f db.Friendship) (db.InsertOutboxEventParams, error) {
+		payload, err := json.Marshal(mq.FriendshipEvent{
+			FriendshipID: f.ID,
+			RequesterID:  f.RequesterID,
+			AddresseeID:  f.AddresseeID,
+			ActorID:      actorID,
+			OccurredAt:   time.Now().UTC(),
+		})
+		return db.InsertOutboxEventParams{
+			Exchange:   mq.FriendEventsExchange,
+			RoutingKey: routingKey,
+			Payload:    payload,
+		}, err
+	}
 }
 
-func (s *service) RejectFriendRequest(ctx context.Context, arg db.RejectFriendRequestParams) error {
-	return s.repo.RejectFriendRequest(ctx, db.RejectFriendRequestParams{
-		RequesterID: arg.RequesterID,
-		AddresseeID: arg.AddresseeID,
-	})
+func (s *service) CancelFriendRequest(ctx context.Context, callerID, requestID uuid.UUID) error {
+	deleted, err := s.repo.CancelFriendRequest(ctx, db.CancelFriendRequestParams{ID: requestID, RequesterID: callerID})
+	if err != nil {
+		logger.FromContext(ctx).Error("error cancelling friend request", zap.Error(err))
+		return err
+	}
+	if !deleted {
+		return errors.ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+func (s *service) ListFriendRequests(ctx context.Context, input model.ListFriendRequestsInput) ([]db.Friendship, *model.TimeCursor, error) {
+	pageSize := normalizePageSize(input.PageSize)
+
+	var cursorCreatedAt pgtype.Timestamptz
+	var cursorID uuid.NullUUID
+	if input.Cursor != nil {
+		cursorCreatedAt = pgtype.Timestamptz{Time: input.Cursor.Time, Valid: true}
+		cursorID = uuid.NullUUID{UUID: input.Cursor.ID, Valid: true}
+	}
+
+	var rows []db.Friendship
+	var err error
+	if input.Direction == model.OutgoingFriendRequests {
+		rows, err = s.repo.ListOutgoingFriendRequests(ctx, db.ListOutgoingFriendRequestsParams{
+			UserID:          input.UserID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        int32(pageSize + 1),
+		})
+	} else {
+		rows, err = s.repo.ListIncomingFriendRequests(ctx, db.ListIncomingFriendRequestsParams{
+			UserID:          input.UserID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			PageSize:        int32(pageSize + 1),
+		})
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error listing friend requests", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if len(rows) <= pageSize {
+		return rows, nil, nil
+	}
+	rows = rows[:pageSize]
+	last := rows[len(rows)-1]
+	return rows, &model.TimeCursor{Time: last.CreatedAt.Time, ID: last.ID}, nil
 }
 
 func (s *service) GetFriends(ctx context.Context, userID uuid.UUID) ([]db.User, error) {
 	return s.repo.GetFriends(ctx, userID)
 }
+
+func (s *service) GetMutualFriends(ctx context.Context, input model.MutualFriendsInput) ([]db.User, *model.NameCursor, error) {
+	other := input.UserB
+	switch input.CallerID {
+	case input.UserA:
+	case input.UserB:
+		other = input.UserA
+	default:
+		return nil, nil, errors.ErrPermissionDenied
+	}
+	if other != input.CallerID {
+		if err := s.ensureNotBlocked(ctx, input.CallerID, other, errors.ErrUserNotFound); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pageSize := normalizePageSize(input.PageSize)
+	params := db.GetMutualFriendsParams{
+		CallerID: input.CallerID,
+		UserA:    input.UserA,
+		UserB:    input.UserB,
+		PageSize: int32(pageSize + 1),
+	}
+	if input.Cursor != nil {
+		params.CursorUsername = pgtype.Text{String: input.Cursor.Username, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: input.Cursor.ID, Valid: true}
+	}
+
+	users, err := s.repo.GetMutualFriends(ctx, params)
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading mutual friends", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if len(users) <= pageSize {
+		return users, nil, nil
+	}
+	users = users[:pageSize]
+	last := users[len(users)-1]
+	return users, &model.NameCursor{Username: last.Username, ID: last.ID}, nil
+}
+
+func (s *service) SuggestFriends(ctx context.Context, userID uuid.UUID, limit int) ([]db.SuggestFriendsRow, error) {
+	suggestions, err := s.repo.SuggestFriends(ctx, db.SuggestFriendsParams{
+		UserID:       userID,
+		PageSize:     int32(normalizePageSize(limit)),
+		FriendSample: int32(s.opts.SuggestFriendsSample),
+		FanOut:       int32(s.opts.SuggestFriendsFanOut),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("error suggesting friends", zap.Error(err))
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+)
+
+func (s *service) SearchUsers(ctx context.Context, input model.SearchUsersInput) ([]db.SearchUsersRow, *model.SearchCursor, error) {
+	input.Keyword = strings.TrimSpace(input.Keyword)
+	if err := validator.New().Struct(input); err != nil {
+		return nil, nil, err
+	}
+
+	pageSize := normalizePageSize(input.PageSize)
+
+	params := db.SearchUsersParams{
+		CallerID:      input.CallerID,
+		Keyword:       input.Keyword,
+		PrefixPattern: escapeLike(input.Keyword) + "%",
+		// One extra row tells whether there is a next page.
+		PageSize: int32(pageSize + 1),
+	}
+	if input.Cursor != nil {
+		params.CursorScore = pgtype.Float8{Float64: input.Cursor.Score, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: input.Cursor.ID, Valid: true}
+	}
+
+	rows, err := s.repo.SearchUsers(ctx, params)
+	if err != nil {
+		logger.FromContext(ctx).Error("error searching users", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if len(rows) <= pageSize {
+		return rows, nil, nil
+	}
+	rows = rows[:pageSize]
+	last := rows[len(rows)-1]
+	return rows, &model.SearchCursor{Score: last.Score, ID: last.User.ID}, nil
+}
+
+func (s *service) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	if blockerID == blockedID {
+		return errors.InvalidField("user_id", "cannot block yourself")
+	}
+	if _, err := s.getUser(ctx, blockedID); err != nil {
+		return err
+	}
+
+	event, err := userEvent(mq.UserBlocked, mq.UserBlockedEvent{BlockerID: blockerID, BlockedID: blockedID, BlockedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	// Blocking twice is not an error, and announces nothing the second time.
+	_, err = s.repo.BlockUser(ctx, db.BlockUserParams{
+		ID:          uuid.New(),
+		RequesterID: blockerID,
+		AddresseeID: blockedID,
+	}, event, friendEvent(mq.FriendRemoved, blockerID))
+	if err != nil {
+		logger.FromContext(ctx).Error("error blocking user", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (s *service) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	event, err := userEvent(mq.UserUnblocked, mq.UserUnblockedEvent{BlockerID: blockerID, BlockedID: blockedID, UnblockedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.UnblockUser(ctx, db.UnblockUserParams{
+		RequesterID: blockerID,
+		AddresseeID: blockedID,
+	}, event)
+	if err != nil {
+		logger.FromContext(ctx).Error("error unblocking user", zap.Error(err))
+		return err
+	}
+	if !deleted {
+		return errors.ErrBlockNotFound
+	}
+	return nil
+}
+
+func (s *service) ListBlockedUsers(ctx context.Context, input model.ListBlockedUsersInput) ([]db.ListBlockedUsersRow, *model.TimeCursor, error) {
+	pageSize := normalizePageSize(input.PageSize)
+
+	params := db.ListBlockedUsersParams{
+		BlockerID: input.BlockerID,
+		PageSize:  int32(pageSize + 1),
+	}
+	if input.Cursor != nil {
+		params.CursorBlockedAt = pgtype.Timestamptz{Time: input.Cursor.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: input.Cursor.ID, Valid: true}
+	}
+
+	rows, err := s.repo.ListBlockedUsers(ctx, params)
+	if err != nil {
+		logger.FromContext(ctx).Error("error listing blocked users", zap.Error(err))
+		return nil, nil, err
+	}
+
+	if len(rows) <= pageSize {
+		return rows, nil, nil
+	}
+	rows = rows[:pageSize]
+	last := rows[len(rows)-1]
+	return rows, &model.TimeCursor{Time: last.BlockedAt.Time, ID: last.User.ID}, nil
+}
+
+// ensureNotBlocked returns blockedErr if either user has blocked the other.
+func (s *service) ensureNotBlocked(ctx context.Context, a, b uuid.UUID, blockedErr error) error {
+	blocked, err := s.repo.IsBlockedEitherWay(ctx, db.IsBlockedEitherWayParams{RequesterID: a, AddresseeID: b})
+	if err != nil {
+		logger.FromContext(ctx).Error("error checking blocks", zap.Error(err))
+		return err
+	}
+	if blocked {
+		return blockedErr
+	}
+	return nil
+}
+
+func (s *service) getUser(ctx context.Context, id uuid.UUID) (db.User, error) {
+	user, err := s.repo.GetUserByID(ctx, id)
+	if stdErrors.Is(err, pgx.ErrNoRows) {
+		return db.User{}, errors.ErrUserNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("error loading user", zap.Error(err))
+	}
+	return user, err
+}
+
+// normalizePageSize applies the default and maximum page sizes.
+func normalizePageSize(n int) int {
+	if n <= 0 {
+		return defaultPageSize
+	}
+	return min(n, maxPageSize)
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (s *service) EraseUser(ctx context.Context, userID uuid.UUID) error {
+	// Blobs are looked up first: their keys go with the rows. A user already
+	// gone, e.g. on a redelivered event, has none left.
+	var avatars map[string]string
+	user, err := s.repo.GetUserByID(ctx, userID)
+	switch {
+	case stdErrors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		logger.FromContext(ctx).Error("error loading user", zap.Error(err))
+		return err
+	default:
+		avatars = avatarKeys(user.AvatarKeys)
+	}
+	exportKeys, err := s.repo.ListDataExportBlobKeys(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("error listing data exports", zap.Error(err))
+		return err
+	}
+
+	erased, err := s.repo.EraseUser(ctx, userID, friendEvent(mq.FriendRemoved, userID))
+	if err != nil {
+		logger.FromContext(ctx).Error("error erasing user", zap.String("user_id", userID.String()), zap.Error(err))
+		return err
+	}
+
+	s.profiles.Invalidate(userID)
+	s.deleteBlobs(ctx, avatars)
+	for _, key := range exportKeys {
+		if err := s.exports.Delete(ctx, key); err != nil {
+			logger.FromContext(ctx).Warn("failed to delete data export", zap.String("key", key), zap.Error(err))
+		}
+	}
+
+	// Erasure is idempotent: redelivered events for a user already gone are fine.
+	logger.FromContext(ctx).Info("user erased", zap.String("user_id", userID.String()), zap.Bool("found", erased))
+	return nil
+}
@@ -0,0 +1,564 @@
+package service_test
+
+import (
+	"context"
+	stdErrors "errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/apperror"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	db "github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/db/generated"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/model"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/mq"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/repository/memory"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/service"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/storage"
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/utils/errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap/zaptest"
+)
+
+const friendRequestCooldown = time.Hour
+
+type testEnv struct {
+	ctx   context.Context
+	svc   service.Service
+	repo  repository.Repository
+	blobs storage.BlobStore
+	// backdate is how far in the past the repository records changes.
+	backdate time.Duration
+	users    map[string]uuid.UUID
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	env := &testEnv{
+		ctx:   logger.NewContext(context.Background(), zaptest.NewLogger(t)),
+		users: make(map[string]uuid.UUID),
+	}
+	env.repo = memory.NewRepository(func() time.Time { return time.Now().Add(-env.backdate) })
+	blobs, err := storage.NewLocalStore(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	env.blobs = blobs
+	env.svc = service.NewSercice(env.repo, blobs, nil, service.Options{
+		FriendRequestCooldown:  friendRequestCooldown,
+		UsernameChangeInterval: 30 * 24 * time.Hour,
+		UsernameQuarantine:     14 * 24 * time.Hour,
+		SuggestFriendsSample:   100,
+		SuggestFriendsFanOut:   100,
+		BatchGetProfilesMax:    100,
+		ProfileCacheSize:       100,
+		ProfileCacheTTL:        time.Minute,
+	})
+	return env
+}
+
+// user returns the id of the user called name, creating them if needed.
+func (e *testEnv) user(t *testing.T, name string) uuid.UUID {
+	t.Helper()
+	if id, ok := e.users[name]; ok {
+		return id
+	}
+	user, err := e.svc.CreateUser(e.ctx, model.CreateUserInput{Email: name + "@example.com", Username: name})
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", name, err)
+	}
+	e.users[name] = user.ID
+	return user.ID
+}
+
+func (e *testEnv) sendRequest(t *testing.T, from, to string) *db.Friendship {
+	t.Helper()
+	request, err := e.svc.SendFriendRequest(e.ctx, e.user(t, from), e.user(t, to))
+	if err != nil {
+		t.Fatalf("SendFriendRequest(%s -> %s): %v", from, to, err)
+	}
+	return request
+}
+
+func (e *testEnv) respond(t *testing.T, to string, request *db.Friendship, accept bool) {
+	t.Helper()
+	if _, err := e.svc.RespondToFriendRequest(e.ctx, e.user(t, to), request.ID, accept); err != nil {
+		t.Fatalf("RespondToFriendRequest(%s, accept=%v): %v", to, accept, err)
+	}
+}
+
+func (e *testEnv) befriend(t *testing.T, a, b string) {
+	t.Helper()
+	e.respond(t, b, e.sendRequest(t, a, b), true)
+}
+
+func (e *testEnv) block(t *testing.T, blocker, blocked string) {
+	t.Helper()
+	if err := e.svc.BlockUser(e.ctx, e.user(t, blocker), e.user(t, blocked)); err != nil {
+		t.Fatalf("BlockUser(%s, %s): %v", blocker, blocked, err)
+	}
+}
+
+func (e *testEnv) setPolicy(t *testing.T, name, policy string) {
+	t.Helper()
+	_, err := e.svc.UpdateProfile(e.ctx, model.UpdateProfileInput{UserID: e.user(t, name), FriendRequestPolicy: &policy})
+	if err != nil {
+		t.Fatalf("UpdateProfile(%s): %v", name, err)
+	}
+}
+
+// drainOutbox returns the routing keys of the outbox events stored since the
+// last call.
+func (e *testEnv) drainOutbox(t *testing.T) []string {
+	t.Helper()
+	var keys []string
+	_, err := e.repo.PublishOutbox(e.ctx, 1000, func(event db.OutboxEvent) error {
+		keys = append(keys, event.RoutingKey)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PublishOutbox: %v", err)
+	}
+	return keys
+}
+
+func (e *testEnv) areFriends(t *testing.T, a, b string) bool {
+	t.Helper()
+	friends, err := e.repo.AreFriends(e.ctx, db.AreFriendsParams{RequesterID: e.user(t, a), AddresseeID: e.user(t, b)})
+	if err != nil {
+		t.Fatalf("AreFriends: %v", err)
+	}
+	return friends
+}
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     model.CreateUserInput
+		wantErr   error
+		wantPgErr string
+	}{
+		{name: "new user", input: model.CreateUserInput{Email: "bob@example.com", Username: "bob"}},
+		{name: "username taken", input: model.CreateUserInput{Email: "bob@example.com", Username: "alice"}, wantErr: errors.ErrUsernameTaken},
+		{name: "username taken in another case", input: model.CreateUserInput{Email: "bob@example.com", Username: "ALICE"}, wantErr: errors.ErrUsernameTaken},
+		{name: "reserved username", input: model.CreateUserInput{Email: "bob@example.com", Username: "Ad_min"}, wantErr: errors.ErrUsernameReserved},
+		{name: "duplicate email", input: model.CreateUserInput{Email: "alice@example.com", Username: "bob"}, wantPgErr: "users_email_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.user(t, "alice")
+
+			user, err := env.svc.CreateUser(env.ctx, tt.input)
+			var pgErr *pgconn.PgError
+			switch {
+			case tt.wantErr != nil:
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("CreateUser error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantPgErr != "":
+				if !stdErrors.As(err, &pgErr) || pgErr.ConstraintName != tt.wantPgErr {
+					t.Fatalf("CreateUser error = %v, want a violation of %s", err, tt.wantPgErr)
+				}
+			case err != nil:
+				t.Fatalf("CreateUser: %v", err)
+			case user.Username != tt.input.Username || user.FriendRequestPolicy != model.FriendRequestsFromEveryone:
+				t.Errorf("CreateUser = %+v, want username %q with the default policy", user, tt.input.Username)
+			}
+		})
+	}
+}
+
+func TestSendFriendRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, env *testEnv)
+		// to is the addressee of alice's request, bob if empty.
+		to          string
+		wantErr     error
+		wantInvalid bool
+		wantStatus  string
+		wantEvents  []string
+	}{
+		{
+			name:       "new request",
+			wantStatus: "pending",
+			wantEvents: []string{mq.FriendRequested},
+		},
+		{
+			name:        "to yourself",
+			to:          "alice",
+			wantInvalid: true,
+		},
+		{
+			name: "already sent",
+			setup: func(t *testing.T, env *testEnv) {
+				env.sendRequest(t, "alice", "bob")
+			},
+			wantErr: errors.ErrFriendRequestExists,
+		},
+		{
+			name: "addressee already asked",
+			setup: func(t *testing.T, env *testEnv) {
+				env.sendRequest(t, "bob", "alice")
+			},
+			wantStatus: "accepted",
+			wantEvents: []string{mq.FriendAccepted},
+		},
+		{
+			name: "already friends",
+			setup: func(t *testing.T, env *testEnv) {
+				env.befriend(t, "bob", "alice")
+			},
+			wantErr: errors.ErrAlreadyFriends,
+		},
+		{
+			name: "rejected within cooldown",
+			setup: func(t *testing.T, env *testEnv) {
+				env.respond(t, "bob", env.sendRequest(t, "alice", "bob"), false)
+			},
+			wantErr: errors.ErrFriendRequestCooldown,
+		},
+		{
+			name: "rejected before cooldown",
+			setup: func(t *testing.T, env *testEnv) {
+				env.backdate = friendRequestCooldown + time.Minute
+				env.respond(t, "bob", env.sendRequest(t, "alice", "bob"), false)
+				env.backdate = 0
+			},
+			wantStatus: "pending",
+			wantEvents: []string{mq.FriendRequested},
+		},
+		{
+			name: "rejected the addressee",
+			setup: func(t *testing.T, env *testEnv) {
+				env.respond(t, "alice", env.sendRequest(t, "bob", "alice"), false)
+			},
+			wantStatus: "pending",
+			wantEvents: []string{mq.FriendRequested},
+		},
+		{
+			name: "blocked by addressee",
+			setup: func(t *testing.T, env *testEnv) {
+				env.block(t, "bob", "alice")
+			},
+			wantErr: errors.ErrFriendRequestNotAllowed,
+		},
+		{
+			name: "addressee blocked",
+			setup: func(t *testing.T, env *testEnv) {
+				env.block(t, "alice", "bob")
+			},
+			wantErr: errors.ErrFriendRequestNotAllowed,
+		},
+		{
+			name: "addressee accepts nobody",
+			setup: func(t *testing.T, env *testEnv) {
+				env.setPolicy(t, "bob", model.FriendRequestsFromNobody)
+			},
+			wantErr: errors.ErrFriendRequestNotAllowed,
+		},
+		{
+			name: "friends of friends without a mutual friend",
+			setup: func(t *testing.T, env *testEnv) {
+				env.befriend(t, "carol", "bob")
+				env.setPolicy(t, "bob", model.FriendRequestsFromFriendsOfFriends)
+			},
+			wantErr: errors.ErrFriendRequestNotAllowed,
+		},
+		{
+			name: "friends of friends with a mutual friend",
+			setup: func(t *testing.T, env *testEnv) {
+				env.befriend(t, "carol", "bob")
+				env.setPolicy(t, "bob", model.FriendRequestsFromFriendsOfFriends)
+				env.befriend(t, "alice", "carol")
+			},
+			wantStatus: "pending",
+			wantEvents: []string{mq.FriendRequested},
+		},
+		{
+			name:    "unknown user",
+			to:      "nobody",
+			wantErr: errors.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			alice, bob := env.user(t, "alice"), env.user(t, "bob")
+			if tt.setup != nil {
+				tt.setup(t, env)
+			}
+			env.drainOutbox(t)
+
+			to := bob
+			switch tt.to {
+			case "alice":
+				to = alice
+			case "nobody":
+				to = uuid.New()
+			}
+			request, err := env.svc.SendFriendRequest(env.ctx, alice, to)
+			if tt.wantErr != nil || tt.wantInvalid {
+				var invalid *apperror.ValidationError
+				if tt.wantInvalid && !stdErrors.As(err, &invalid) {
+					t.Fatalf("SendFriendRequest error = %v, want a validation error", err)
+				}
+				if tt.wantErr != nil && !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("SendFriendRequest error = %v, want %v", err, tt.wantErr)
+				}
+				if events := env.drainOutbox(t); len(events) > 0 {
+					t.Errorf("failed request stored events %v", events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendFriendRequest: %v", err)
+			}
+
+			if request.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", request.Status, tt.wantStatus)
+			}
+			if events := env.drainOutbox(t); !slices.Equal(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+			if got, want := env.areFriends(t, "alice", "bob"), tt.wantStatus == "accepted"; got != want {
+				t.Errorf("AreFriends = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRespondToFriendRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		// responder answers alice's request to bob.
+		responder  string
+		accept     bool
+		setup      func(t *testing.T, env *testEnv, request *db.Friendship)
+		wantErr    error
+		wantStatus string
+		wantEvents []string
+	}{
+		{name: "accept", responder: "bob", accept: true, wantStatus: "accepted", wantEvents: []string{mq.FriendAccepted}},
+		{name: "reject", responder: "bob", wantStatus: "rejected", wantEvents: []string{mq.FriendRejected}},
+		{name: "by the requester", responder: "alice", accept: true, wantErr: errors.ErrFriendRequestNotFound},
+		{name: "by someone else", responder: "carol", accept: true, wantErr: errors.ErrFriendRequestNotFound},
+		{
+			name:      "already answered",
+			responder: "bob",
+			accept:    true,
+			setup: func(t *testing.T, env *testEnv, request *db.Friendship) {
+				env.respond(t, "bob", request, false)
+			},
+			wantErr: errors.ErrFriendRequestNotPending,
+		},
+		{
+			name:      "cancelled",
+			responder: "bob",
+			accept:    true,
+			setup: func(t *testing.T, env *testEnv, request *db.Friendship) {
+				if err := env.svc.CancelFriendRequest(env.ctx, env.user(t, "alice"), request.ID); err != nil {
+					t.Fatalf("CancelFriendRequest: %v", err)
+				}
+			},
+			wantErr: errors.ErrFriendRequestNotFound,
+		},
+		{
+			name:      "torn down by a block",
+			responder: "bob",
+			accept:    true,
+			setup: func(t *testing.T, env *testEnv, request *db.Friendship) {
+				env.block(t, "bob", "alice")
+			},
+			wantErr: errors.ErrFriendRequestNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			request := env.sendRequest(t, "alice", "bob")
+			if tt.setup != nil {
+				tt.setup(t, env, request)
+			}
+			env.drainOutbox(t)
+
+			answered, err := env.svc.RespondToFriendRequest(env.ctx, env.user(t, tt.responder), request.ID, tt.accept)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("RespondToFriendRequest error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RespondToFriendRequest: %v", err)
+			}
+
+			if answered.Status != tt.wantStatus || !answered.RespondedAt.Valid {
+				t.Errorf("request = %+v, want status %q with a response time", answered, tt.wantStatus)
+			}
+			if events := env.drainOutbox(t); !slices.Equal(events, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", events, tt.wantEvents)
+			}
+			if got, want := env.areFriends(t, "bob", "alice"), tt.accept; got != want {
+				t.Errorf("AreFriends = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCancelFriendRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		canceller string
+		setup     func(t *testing.T, env *testEnv, request *db.Friendship)
+		wantErr   error
+	}{
+		{name: "by the requester", canceller: "alice"},
+		{name: "by the addressee", canceller: "bob", wantErr: errors.ErrFriendRequestNotFound},
+		{
+			name:      "already accepted",
+			canceller: "alice",
+			setup: func(t *testing.T, env *testEnv, request *db.Friendship) {
+				env.respond(t, "bob", request, true)
+			},
+			wantErr: errors.ErrFriendRequestNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			request := env.sendRequest(t, "alice", "bob")
+			if tt.setup != nil {
+				tt.setup(t, env, request)
+			}
+
+			err := env.svc.CancelFriendRequest(env.ctx, env.user(t, tt.canceller), request.ID)
+			if tt.wantErr != nil {
+				if !stdErrors.Is(err, tt.wantErr) {
+					t.Fatalf("CancelFriendRequest error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CancelFriendRequest: %v", err)
+			}
+
+			incoming, _, err := env.svc.ListFriendRequests(env.ctx, model.ListFriendRequestsInput{UserID: env.user(t, "bob")})
+			if err != nil {
+				t.Fatalf("ListFriendRequests: %v", err)
+			}
+			if len(incoming) != 0 {
+				t.Errorf("incoming requests = %v, want none", incoming)
+			}
+		})
+	}
+}
+
+func TestRemoveFriend(t *testing.T) {
+	env := newTestEnv(t)
+	env.befriend(t, "alice", "bob")
+	env.drainOutbox(t)
+
+	if err := env.svc.RemoveFriend(env.ctx, env.user(t, "bob"), env.user(t, "alice")); err != nil {
+		t.Fatalf("RemoveFriend: %v", err)
+	}
+	if env.areFriends(t, "alice", "bob") {
+		t.Error("still friends after RemoveFriend")
+	}
+	if events := env.drainOutbox(t); !slices.Equal(events, []string{mq.FriendRemoved}) {
+		t.Errorf("events = %v, want %v", events, []string{mq.FriendRemoved})
+	}
+
+	err := env.svc.RemoveFriend(env.ctx, env.user(t, "bob"), env.user(t, "alice"))
+	if !stdErrors.Is(err, errors.ErrFriendshipNotFound) {
+		t.Errorf("second RemoveFriend error = %v, want %v", err, errors.ErrFriendshipNotFound)
+	}
+}
+
+func TestBlockUserEndsFriendship(t *testing.T) {
+	env := newTestEnv(t)
+	env.befriend(t, "alice", "bob")
+	env.drainOutbox(t)
+
+	env.block(t, "alice", "bob")
+	env.block(t, "alice", "bob")
+	if env.areFriends(t, "alice", "bob") {
+		t.Error("still friends after BlockUser")
+	}
+	if events, want := env.drainOutbox(t), []string{mq.FriendRemoved, mq.UserBlocked}; !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	if err := env.svc.UnblockUser(env.ctx, env.user(t, "alice"), env.user(t, "bob")); err != nil {
+		t.Fatalf("UnblockUser: %v", err)
+	}
+	if events, want := env.drainOutbox(t), []string{mq.UserUnblocked}; !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if request := env.sendRequest(t, "bob", "alice"); request.Status != "pending" {
+		t.Errorf("request after unblocking has status %q, want pending", request.Status)
+	}
+}
+
+func TestBatchGetProfilesHidesBlockedUsers(t *testing.T) {
+	env := newTestEnv(t)
+	alice, bob, carol := env.user(t, "alice"), env.user(t, "bob"), env.user(t, "carol")
+	dave := env.user(t, "dave")
+	env.block(t, "alice", "bob")
+	env.block(t, "carol", "alice")
+
+	// The first call fills the cache, the second is served from it.
+	for range 2 {
+		profiles, err := env.svc.BatchGetProfiles(env.ctx, alice, []uuid.UUID{alice, bob, carol, dave})
+		if err != nil {
+			t.Fatalf("BatchGetProfiles: %v", err)
+		}
+		var got []bool
+		for _, p := range profiles {
+			got = append(got, p != nil)
+		}
+		if want := []bool{true, false, false, true}; !slices.Equal(got, want) {
+			t.Errorf("found = %v, want %v", got, want)
+		}
+	}
+
+	profiles, err := env.svc.BatchGetProfiles(env.ctx, dave, []uuid.UUID{bob, carol})
+	if err != nil {
+		t.Fatalf("BatchGetProfiles: %v", err)
+	}
+	if profiles[0] == nil || profiles[1] == nil {
+		t.Errorf("blocks between others hid profiles: %v", profiles)
+	}
+}
+
+func TestEraseUserDeletesAvatar(t *testing.T) {
+	env := newTestEnv(t)
+	alice := env.user(t, "alice")
+	const key = "avatars/alice/256.png"
+	if err := env.blobs.Put(env.ctx, key, strings.NewReader("png")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	arg := db.SetAvatarKeysParams{ID: alice, AvatarKeys: []byte(`{"256":"` + key + `"}`)}
+	if _, err := env.repo.SetAvatarKeys(env.ctx, arg, db.InsertOutboxEventParams{}); err != nil {
+		t.Fatalf("SetAvatarKeys: %v", err)
+	}
+
+	// The second call is a redelivered event for a user already gone.
+	for range 2 {
+		if err := env.svc.EraseUser(env.ctx, alice); err != nil {
+			t.Fatalf("EraseUser: %v", err)
+		}
+	}
+	if blob, err := env.blobs.Get(env.ctx, key); err == nil {
+		blob.Close()
+		t.Error("avatar blob still stored after EraseUser")
+	}
+}
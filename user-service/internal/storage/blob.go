@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects such as avatars under slash separated keys.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can download key.
+	URL(key string) string
+	// SignedURL returns a URL that lets whoever holds it download key until
+	// expires, for stores whose blobs are not public.
+	SignedURL(key string, expires time.Time) (string, error)
+}
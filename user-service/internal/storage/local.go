@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/logger"
+	"go.uber.org/zap"
+)
+
+var errInvalidKey = errors.New("invalid blob key")
+
+// LocalStore is a BlobStore on the local filesystem. It also serves the blobs
+// over HTTP at the base URL given to NewLocalStore.
+type LocalStore struct {
+	root    string
+	baseURL string
+	// signingKey is set for private stores, which only serve signed URLs.
+	signingKey []byte
+}
+
+// NewLocalStore returns a store whose blobs anyone can download.
+func NewLocalStore(root, baseURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{root: root, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// NewPrivateLocalStore returns a store that only serves blobs through URLs
+// signed with signingKey, see SignedURL.
+func NewPrivateLocalStore(root, baseURL string, signingKey []byte) (*LocalStore, error) {
+	if len(signingKey) == 0 {
+		return nil, errors.New("private blob store needs a signing key")
+	}
+	s, err := NewLocalStore(root, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	s.signingKey = signingKey
+	return s, nil
+}
+
+// Put writes to a temporary file first so readers never see a partial blob.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// SignedURL returns the plain URL for public stores.
+func (s *LocalStore) SignedURL(key string, expires time.Time) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	if s.signingKey == nil {
+		return s.URL(key), nil
+	}
+
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {s.sign(key, exp)}}
+	return s.URL(key) + "?" + q.Encode(), nil
+}
+
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify reports whether the query of r holds a valid, unexpired signature for key.
+func (s *LocalStore) verify(r *http.Request, key string) bool {
+	exp := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, exp)))
+}
+
+// ServeHTTP serves the blob named by the request path, relative to the base URL.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if s.signingKey != nil && !s.verify(r, key) {
+		http.Error(w, "link is invalid or has expired", http.StatusForbidden)
+		return
+	}
+
+	blob, err := s.Get(r.Context(), key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer blob.Close()
+
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	if s.signingKey != nil {
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(key)))
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	if _, err := io.Copy(w, blob); err != nil {
+		// The status is sent already; the client sees a truncated body.
+		logger.FromContext(r.Context()).Warn("failed to send blob", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// path maps key into the root directory, rejecting keys that would escape it.
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", fmt.Errorf("%w: %q", errInvalidKey, key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStorePath(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStore(root, "/media")
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "avatars/alice/1.png"},
+		{key: "a..b/c"},
+		{key: "", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../x", wantErr: true},
+		{key: "a/../../b", wantErr: true},
+		{key: "a/../b", wantErr: true},
+		{key: "/etc/passwd", wantErr: true},
+		{key: "./a", wantErr: true},
+		{key: "a//b", wantErr: true},
+		{key: "a/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			p, err := s.path(tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidKey) {
+					t.Errorf("path(%q) = %q, %v, want %v", tt.key, p, err, errInvalidKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("path(%q): %v", tt.key, err)
+			}
+			if rel, err := filepath.Rel(root, p); err != nil || strings.HasPrefix(rel, "..") {
+				t.Errorf("path(%q) = %q, outside %q", tt.key, p, root)
+			}
+		})
+	}
+}
+
+func TestLocalStoreVerify(t *testing.T) {
+	s, err := NewPrivateLocalStore(t.TempDir(), "/exports", []byte("signing-key"))
+	if err != nil {
+		t.Fatalf("NewPrivateLocalStore: %v", err)
+	}
+	const key = "exports/alice/1.zip"
+	signed, err := s.SignedURL(key, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse %q: %v", signed, err)
+	}
+	query := u.Query()
+	expired, err := s.SignedURL(key, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	expiredURL, err := url.Parse(expired)
+	if err != nil {
+		t.Fatalf("parse %q: %v", expired, err)
+	}
+	other, err := NewPrivateLocalStore(t.TempDir(), "/exports", []byte("other-key"))
+	if err != nil {
+		t.Fatalf("NewPrivateLocalStore: %v", err)
+	}
+	otherSigned, err := other.SignedURL(key, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	otherURL, err := url.Parse(otherSigned)
+	if err != nil {
+		t.Fatalf("parse %q: %v", otherSigned, err)
+	}
+
+	// with returns the signed query with name set to value.
+	with := func(name, value string) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set(name, value)
+		return q.Encode()
+	}
+	tests := []struct {
+		name  string
+		key   string
+		query string
+		want  bool
+	}{
+		{name: "signed", key: key, query: u.RawQuery, want: true},
+		{name: "expired", key: key, query: expiredURL.RawQuery},
+		{name: "expiry pushed back", key: key, query: with("expires", "99999999999")},
+		{name: "tampered signature", key: key, query: with("signature", strings.ToUpper(query.Get("signature")))},
+		{name: "signed with another key", key: key, query: otherURL.RawQuery},
+		{name: "another blob", key: "exports/bob/1.zip", query: u.RawQuery},
+		{name: "no signature", key: key, query: url.Values{"expires": {query.Get("expires")}}.Encode()},
+		{name: "no expiry", key: key, query: url.Values{"signature": {query.Get("signature")}}.Encode()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/"+tt.key+"?"+tt.query, nil)
+			if got := s.verify(r, tt.key); got != tt.want {
+				t.Errorf("verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
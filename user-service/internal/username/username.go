@@ -0,0 +1,68 @@
+// Package username holds the rules usernames must follow.
+package username
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 30
+)
+
+var (
+	ErrLength     = fmt.Errorf("must be between %d and %d characters long", MinLength, MaxLength)
+	ErrCharacters = errors.New("may only contain letters, digits, underscores and single dots, and must start and end with a letter or digit")
+	ErrReserved   = errors.New("is reserved")
+)
+
+// reserved are names that could be mistaken for the service itself or clash
+// with routes. They are compared after Normalize.
+var reserved = map[string]struct{}{
+	"admin": {}, "administrator": {}, "root": {}, "system": {}, "sysadmin": {},
+	"support": {}, "help": {}, "helpdesk": {}, "info": {}, "contact": {},
+	"moderator": {}, "mod": {}, "staff": {}, "team": {}, "official": {},
+	"security": {}, "abuse": {}, "postmaster": {}, "webmaster": {}, "noreply": {},
+	"api": {}, "www": {}, "mail": {}, "email": {}, "media": {}, "static": {},
+	"login": {}, "logout": {}, "signin": {}, "signup": {}, "register": {},
+	"settings": {}, "account": {}, "accounts": {}, "profile": {}, "search": {},
+	"me": {}, "self": {}, "user": {}, "users": {}, "friends": {}, "blocks": {},
+	"everyone": {}, "here": {}, "channel": {}, "chat": {}, "bot": {},
+	"null": {}, "nil": {}, "undefined": {}, "anonymous": {}, "unknown": {},
+}
+
+// Normalize returns the form usernames are compared in: lower case, without
+// dots and underscores, so "Ad_min" matches the reserved "admin".
+func Normalize(name string) string {
+	return strings.NewReplacer(".", "", "_", "").Replace(strings.ToLower(name))
+}
+
+// Validate returns ErrLength, ErrCharacters or ErrReserved if name is not
+// acceptable as a username. Case is kept but ignored for uniqueness.
+func Validate(name string) error {
+	if len(name) < MinLength || len(name) > MaxLength {
+		return ErrLength
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isAlnum(c):
+		case (c == '_' || c == '.') && i > 0 && i < len(name)-1:
+			if c == '.' && name[i-1] == '.' {
+				return ErrCharacters
+			}
+		default:
+			return ErrCharacters
+		}
+	}
+	if _, ok := reserved[Normalize(name)]; ok {
+		return ErrReserved
+	}
+	return nil
+}
+
+func isAlnum(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
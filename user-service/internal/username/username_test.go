@@ -0,0 +1,90 @@
+package username_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/user-service/internal/username"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    error
+		comment string
+	}{
+		{name: "alice"},
+		{name: "Alice_Smith.99"},
+		{name: "a_b"},
+		{name: "a._b"},
+		{name: "a__b"},
+		{name: "abc"},
+		{name: strings.Repeat("a", username.MaxLength)},
+
+		{name: "", want: username.ErrLength},
+		{name: "ab", want: username.ErrLength},
+		{name: strings.Repeat("a", username.MaxLength+1), want: username.ErrLength},
+		{name: "éé", comment: "two characters but four bytes", want: username.ErrCharacters},
+
+		{name: "_alice", want: username.ErrCharacters},
+		{name: "alice_", want: username.ErrCharacters},
+		{name: ".alice", want: username.ErrCharacters},
+		{name: "alice.", want: username.ErrCharacters},
+		{name: "al..ice", want: username.ErrCharacters},
+		{name: "al...ice", want: username.ErrCharacters},
+		{name: "al ice", want: username.ErrCharacters},
+		{name: "al-ice", want: username.ErrCharacters},
+		{name: "al@ice", want: username.ErrCharacters},
+		{name: "al/ice", want: username.ErrCharacters},
+		{name: "josé", want: username.ErrCharacters},
+		{name: "аlice", comment: "Cyrillic a", want: username.ErrCharacters},
+		{name: "ａｄｍｉｎ", comment: "full-width letters", want: username.ErrCharacters},
+		{name: "alice\u200b", comment: "zero-width space", want: username.ErrCharacters},
+		{name: "ali\x00ce", want: username.ErrCharacters},
+
+		{name: "admin", want: username.ErrReserved},
+		{name: "ADMIN", want: username.ErrReserved},
+		{name: "Ad_min", want: username.ErrReserved},
+		{name: "a.d.m.i.n", want: username.ErrReserved},
+		{name: "Sys_Admin", want: username.ErrReserved},
+		{name: "no.reply", want: username.ErrReserved},
+		{name: "m_e", want: username.ErrReserved},
+		{name: "admin1"},
+		{name: "theadmin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := username.Validate(tt.name); !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v %s", tt.name, err, tt.want, tt.comment)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "alice", want: "alice"},
+		{name: "Alice", want: "alice"},
+		{name: "ALICE", want: "alice"},
+		{name: "Ad_min", want: "admin"},
+		{name: "a.d.m.i.n", want: "admin"},
+		{name: "a._b", want: "ab"},
+		{name: "alice_smith.99", want: "alicesmith99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := username.Normalize(tt.name); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	// Names that differ only by case, dots or underscores collide.
+	if username.Normalize("Alice.Smith") != username.Normalize("alice_smith") {
+		t.Error("Alice.Smith and alice_smith normalize differently")
+	}
+}
@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"github.com/Thanhbinh1905/realtime-chat-v2-go/shared/apperror"
+	"google.golang.org/grpc/codes"
+)
+
+// Domain is reported in google.rpc.ErrorInfo for errors raised by this service.
+const Domain = "user-service"
+
+// ToGRPC maps an error returned by the service layer to a gRPC status error.
+func ToGRPC(err error) error {
+	return apperror.ToGRPC(Domain, err)
+}
+
+// InvalidField returns a validation error for a single request field.
+func InvalidField(field, description string) error {
+	return apperror.InvalidField(field, description)
+}
+
+var ErrUserNotFound = apperror.New(codes.NotFound, "USER_NOT_FOUND", "user not found")
+var ErrUnauthorized = apperror.New(codes.Unauthenticated, "UNAUTHORIZED", "unauthorized")
+var ErrPermissionDenied = apperror.New(codes.PermissionDenied, "PERMISSION_DENIED", "permission denied")
+
+// ErrFriendRequestNotAllowed deliberately does not say which side blocked the other.
+var ErrFriendRequestNotAllowed = apperror.New(codes.FailedPrecondition, "FRIEND_REQUEST_NOT_ALLOWED", "cannot send a friend request to this user")
+var ErrBlockNotFound = apperror.New(codes.NotFound, "BLOCK_NOT_FOUND", "user is not blocked")
+
+var ErrFriendRequestNotFound = apperror.New(codes.NotFound, "FRIEND_REQUEST_NOT_FOUND", "friend request not found")
+var ErrFriendRequestNotPending = apperror.New(codes.FailedPrecondition, "FRIEND_REQUEST_NOT_PENDING", "friend request has already been answered")
+var ErrFriendRequestExists = apperror.New(codes.AlreadyExists, "FRIEND_REQUEST_EXISTS", "friend request already sent")
+var ErrFriendRequestCooldown = apperror.New(codes.FailedPrecondition, "FRIEND_REQUEST_COOLDOWN", "friend request was rejected recently, try again later")
+var ErrAlreadyFriends = apperror.New(codes.AlreadyExists, "ALREADY_FRIENDS", "users are already friends")
+var ErrFriendshipNotFound = apperror.New(codes.NotFound, "FRIENDSHIP_NOT_FOUND", "users are not friends")
+
+var ErrAvatarTooLarge = apperror.New(codes.InvalidArgument, "AVATAR_TOO_LARGE", "avatar is too large")
+var ErrAvatarUnsupportedFormat = apperror.New(codes.InvalidArgument, "AVATAR_UNSUPPORTED_FORMAT", "avatar must be a JPEG, PNG, GIF or WebP image")
+var ErrAvatarInvalid = apperror.New(codes.InvalidArgument, "AVATAR_INVALID", "avatar image could not be decoded")
+
+var ErrUsernameReserved = apperror.New(codes.InvalidArgument, "USERNAME_RESERVED", "username is reserved")
+var ErrUsernameTaken = apperror.New(codes.AlreadyExists, "USERNAME_TAKEN", "username is already taken")
+var ErrUsernameChangeTooSoon = apperror.New(codes.FailedPrecondition, "USERNAME_CHANGE_TOO_SOON", "username was changed recently, try again later")
+
+var ErrDataExportNotFound = apperror.New(codes.NotFound, "DATA_EXPORT_NOT_FOUND", "data export not found")